@@ -182,6 +182,35 @@ func TestAsmDisasm(t *testing.T) {
 	}
 }
 
+// Check that the Linux extensions assemble to loads at their magic
+// offsets, and disassemble back.
+func TestLoadExtensionAsmDisasm(t *testing.T) {
+	tests := []struct {
+		ext Extension
+		raw RawInstruction
+	}{
+		{ExtLen, RawInstruction{Op: opClsLoadA | opLoadWidth4 | opAddrModePacketLen}},
+		{ExtProto, RawInstruction{Op: opClsLoadA | opLoadWidth4 | opAddrModeAbsolute, K: 0xfffff000}},
+		{ExtType, RawInstruction{Op: opClsLoadA | opLoadWidth4 | opAddrModeAbsolute, K: 0xfffff004}},
+		{ExtCPUID, RawInstruction{Op: opClsLoadA | opLoadWidth4 | opAddrModeAbsolute, K: 0xfffff024}},
+		{ExtVLANTag, RawInstruction{Op: opClsLoadA | opLoadWidth4 | opAddrModeAbsolute, K: 0xfffff02c}},
+		{ExtRand, RawInstruction{Op: opClsLoadA | opLoadWidth4 | opAddrModeAbsolute, K: 0xfffff038}},
+	}
+	for _, tt := range tests {
+		raw, err := LoadExtension{Num: tt.ext}.Assemble()
+		if err != nil {
+			t.Errorf("extension %d: assembly failed: %v", tt.ext, err)
+			continue
+		}
+		if raw != tt.raw {
+			t.Errorf("extension %d: assembled to %#v, want %#v", tt.ext, raw, tt.raw)
+		}
+		if got, want := raw.Disassemble(), (LoadExtension{Num: tt.ext}); got != want {
+			t.Errorf("extension %d: disassembled to %#v, want %#v", tt.ext, got, want)
+		}
+	}
+}
+
 type InvalidInstruction struct{}
 
 func (a InvalidInstruction) Assemble() (RawInstruction, error) {
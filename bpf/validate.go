@@ -0,0 +1,93 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bpf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxInstructions is the maximum length of a program accepted by the
+// Linux kernel, BPF_MAXINSNS.
+const maxInstructions = 4096
+
+// Validate checks that insts is a program that an operating system BPF
+// virtual machine will accept, so that a malformed program is reported
+// with a useful error rather than an opaque failure, such as EINVAL,
+// from the kernel loader.
+//
+// Validate checks that every instruction assembles to one that the
+// virtual machine knows, that jumps stay within the program, that the
+// program cannot run off its end without executing RetA or RetConstant,
+// that there is no division or modulus by a constant zero, and that every
+// read of a scratch memory slot follows a store to that slot on all paths
+// to it.
+func Validate(insts []Instruction) error {
+	if len(insts) == 0 {
+		return errors.New("one or more Instructions must be specified")
+	}
+	if len(insts) > maxInstructions {
+		return fmt.Errorf("program has %d instructions, more than the maximum of %d", len(insts), maxInstructions)
+	}
+	raw, err := Assemble(insts)
+	if err != nil {
+		return err
+	}
+	// Validate the decoded form, so that a RawInstruction which encodes,
+	// say, a jump is checked like the equivalent Jump. Any instruction
+	// that is still a RawInstruction once decoded is not a valid one.
+	insts, _ = Disassemble(raw)
+
+	// stored[i] is the set of scratch slots, as a bitmask, that have been
+	// stored to on every path to instruction i. As jumps only go forwards,
+	// it is complete for i once all instructions before i are visited.
+	stored := make([]uint16, len(insts))
+	for i := range stored {
+		stored[i] = 0xffff
+	}
+	stored[0] = 0
+
+	for i, ins := range insts {
+		cur := stored[i]
+		var next []uint32 // the offsets of the successors of ins, past i+1
+		switch ins := ins.(type) {
+		case Jump:
+			next = []uint32{ins.Skip}
+		case JumpIf:
+			next = []uint32{uint32(ins.SkipTrue), uint32(ins.SkipFalse)}
+		case JumpIfX:
+			next = []uint32{uint32(ins.SkipTrue), uint32(ins.SkipFalse)}
+		case RetA, RetConstant:
+		case ALUOpConstant:
+			if ins.Val == 0 && (ins.Op == ALUOpDiv || ins.Op == ALUOpMod) {
+				return fmt.Errorf("instruction %d: cannot divide by zero using ALUOpConstant", i+1)
+			}
+			next = []uint32{0}
+		case LoadScratch:
+			if cur&(1<<uint(ins.N)) == 0 {
+				return fmt.Errorf("instruction %d: reading scratch slot %d before it is stored", i+1, ins.N)
+			}
+			next = []uint32{0}
+		case StoreScratch:
+			cur |= 1 << uint(ins.N)
+			next = []uint32{0}
+		case RawInstruction:
+			return fmt.Errorf("instruction %d: unknown instruction %#v", i+1, ins)
+		default:
+			next = []uint32{0}
+		}
+		for _, skip := range next {
+			j := uint64(i) + 1 + uint64(skip)
+			if j >= uint64(len(insts)) {
+				if skip == 0 {
+					return errors.New("BPF program must end with RetA or RetConstant")
+				}
+				return fmt.Errorf("instruction %d: cannot jump %d instructions; jumping past program bounds", i+1, skip)
+			}
+			stored[j] &= cur
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bpf_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/bpf"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		insts []bpf.Instruction
+		err   string
+	}{
+		{
+			name: "valid",
+			insts: []bpf.Instruction{
+				bpf.LoadAbsolute{Off: 12, Size: 2},
+				bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0x0800, SkipFalse: 3},
+				bpf.StoreScratch{Src: bpf.RegA, N: 15},
+				bpf.LoadScratch{Dst: bpf.RegX, N: 15},
+				bpf.RetConstant{Val: 4096},
+				bpf.RetConstant{Val: 0},
+			},
+			err: "<nil>",
+		},
+		{
+			name: "valid raw jump",
+			insts: []bpf.Instruction{
+				bpf.RawInstruction{Op: 0x05, K: 1}, // ja +1
+				bpf.RetConstant{Val: 0},
+				bpf.RetA{},
+			},
+			err: "<nil>",
+		},
+		{
+			name:  "empty",
+			insts: nil,
+			err:   "one or more Instructions must be specified",
+		},
+		{
+			name: "jump past end",
+			insts: []bpf.Instruction{
+				bpf.Jump{Skip: 2},
+				bpf.RetA{},
+			},
+			err: "instruction 1: cannot jump 2 instructions; jumping past program bounds",
+		},
+		{
+			name: "conditional jump past end",
+			insts: []bpf.Instruction{
+				bpf.LoadConstant{Dst: bpf.RegA, Val: 1},
+				bpf.JumpIf{Cond: bpf.JumpEqual, Val: 1, SkipTrue: 1},
+				bpf.RetA{},
+			},
+			err: "instruction 2: cannot jump 1 instructions; jumping past program bounds",
+		},
+		{
+			name: "missing return",
+			insts: []bpf.Instruction{
+				bpf.LoadConstant{Dst: bpf.RegA, Val: 1},
+			},
+			err: "BPF program must end with RetA or RetConstant",
+		},
+		{
+			name: "bad scratch index",
+			insts: []bpf.Instruction{
+				bpf.StoreScratch{Src: bpf.RegA, N: 16},
+				bpf.RetA{},
+			},
+			err: "assembling instruction 1: invalid scratch slot 16",
+		},
+		{
+			name: "bad raw scratch index",
+			insts: []bpf.Instruction{
+				bpf.RawInstruction{Op: 0x02, K: 100}, // st M[100]
+				bpf.RetA{},
+			},
+			err: "instruction 1: unknown instruction bpf.RawInstruction{Op:0x2, Jt:0x0, Jf:0x0, K:0x64}",
+		},
+		{
+			name: "scratch read before store",
+			insts: []bpf.Instruction{
+				bpf.LoadScratch{Dst: bpf.RegA, N: 3},
+				bpf.RetA{},
+			},
+			err: "instruction 1: reading scratch slot 3 before it is stored",
+		},
+		{
+			name: "scratch stored on one path only",
+			insts: []bpf.Instruction{
+				bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0, SkipTrue: 1},
+				bpf.StoreScratch{Src: bpf.RegA, N: 0},
+				bpf.LoadScratch{Dst: bpf.RegA, N: 0},
+				bpf.RetA{},
+			},
+			err: "instruction 3: reading scratch slot 0 before it is stored",
+		},
+		{
+			name: "divide by zero",
+			insts: []bpf.Instruction{
+				bpf.ALUOpConstant{Op: bpf.ALUOpMod, Val: 0},
+				bpf.RetA{},
+			},
+			err: "instruction 1: cannot divide by zero using ALUOpConstant",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bpf.Validate(tt.insts)
+			if errStr(err) != tt.err {
+				t.Fatalf("unexpected error:\n- want: %q\n-  got: %q", tt.err, errStr(err))
+			}
+		})
+	}
+}
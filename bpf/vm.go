@@ -15,6 +15,10 @@ type VM struct {
 }
 
 // NewVM returns a new VM using the input BPF program.
+//
+// Of the Linux extensions, the VM emulates ExtLen and ExtRand, which do
+// not depend on the kernel's metadata about a packet. NewVM rejects
+// programs that use any other extension.
 func NewVM(filter []Instruction) (*VM, error) {
 	if len(filter) == 0 {
 		return nil, errors.New("one or more Instructions must be specified")
@@ -55,7 +59,7 @@ func NewVM(filter []Instruction) (*VM, error) {
 		// Check for unknown extensions
 		case LoadExtension:
 			switch ins.Num {
-			case ExtLen:
+			case ExtLen, ExtRand:
 			default:
 				return nil, fmt.Errorf("extension %d not implemented", ins.Num)
 			}
@@ -47,3 +47,34 @@ func TestVMLoadExtensionExtLen(t *testing.T) {
 			want, got)
 	}
 }
+
+func TestVMLoadExtensionExtRand(t *testing.T) {
+	// The OS VM draws different random numbers, so only the Go VM is used.
+	vm, err := bpf.NewVM([]bpf.Instruction{
+		bpf.LoadExtension{
+			Num: bpf.ExtRand,
+		},
+		bpf.ALUOpConstant{
+			Op:  bpf.ALUOpAnd,
+			Val: 0x3,
+		},
+		bpf.ALUOpConstant{
+			Op:  bpf.ALUOpAdd,
+			Val: 1,
+		},
+		bpf.RetA{},
+	})
+	if err != nil {
+		t.Fatalf("failed to load BPF program: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		out, err := vm.Run([]byte{0, 1, 2, 3})
+		if err != nil {
+			t.Fatalf("unexpected error while running program: %v", err)
+		}
+		if out < 1 || out > 4 {
+			t.Fatalf("unexpected number of output bytes:\n- want: 1 to 4\n-  got: %d", out)
+		}
+	}
+}
@@ -7,6 +7,7 @@ package bpf
 import (
 	"encoding/binary"
 	"fmt"
+	"math/rand"
 )
 
 func aluOpConstant(ins ALUOpConstant, regA uint32) uint32 {
@@ -114,6 +115,8 @@ func loadExtension(ins LoadExtension, in []byte) uint32 {
 	switch ins.Num {
 	case ExtLen:
 		return uint32(len(in))
+	case ExtRand:
+		return rand.Uint32()
 	default:
 		panic(fmt.Sprintf("unimplemented extension: %d", ins.Num))
 	}
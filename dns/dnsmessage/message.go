@@ -23,16 +23,23 @@ type Type uint16
 
 const (
 	// ResourceHeader.Type and Question.Type
-	TypeA     Type = 1
-	TypeNS    Type = 2
-	TypeCNAME Type = 5
-	TypeSOA   Type = 6
-	TypePTR   Type = 12
-	TypeMX    Type = 15
-	TypeTXT   Type = 16
-	TypeAAAA  Type = 28
-	TypeSRV   Type = 33
-	TypeOPT   Type = 41
+	TypeA      Type = 1
+	TypeNS     Type = 2
+	TypeCNAME  Type = 5
+	TypeSOA    Type = 6
+	TypePTR    Type = 12
+	TypeMX     Type = 15
+	TypeTXT    Type = 16
+	TypeAAAA   Type = 28
+	TypeSRV    Type = 33
+	TypeOPT    Type = 41
+	TypeDS     Type = 43
+	TypeRRSIG  Type = 46
+	TypeNSEC   Type = 47
+	TypeDNSKEY Type = 48
+	TypeNSEC3  Type = 50
+	TypeSVCB   Type = 64
+	TypeHTTPS  Type = 65
 
 	// Question.Type
 	TypeWKS   Type = 11
@@ -43,21 +50,28 @@ const (
 )
 
 var typeNames = map[Type]string{
-	TypeA:     "TypeA",
-	TypeNS:    "TypeNS",
-	TypeCNAME: "TypeCNAME",
-	TypeSOA:   "TypeSOA",
-	TypePTR:   "TypePTR",
-	TypeMX:    "TypeMX",
-	TypeTXT:   "TypeTXT",
-	TypeAAAA:  "TypeAAAA",
-	TypeSRV:   "TypeSRV",
-	TypeOPT:   "TypeOPT",
-	TypeWKS:   "TypeWKS",
-	TypeHINFO: "TypeHINFO",
-	TypeMINFO: "TypeMINFO",
-	TypeAXFR:  "TypeAXFR",
-	TypeALL:   "TypeALL",
+	TypeA:      "TypeA",
+	TypeNS:     "TypeNS",
+	TypeCNAME:  "TypeCNAME",
+	TypeSOA:    "TypeSOA",
+	TypePTR:    "TypePTR",
+	TypeMX:     "TypeMX",
+	TypeTXT:    "TypeTXT",
+	TypeAAAA:   "TypeAAAA",
+	TypeSRV:    "TypeSRV",
+	TypeOPT:    "TypeOPT",
+	TypeDS:     "TypeDS",
+	TypeRRSIG:  "TypeRRSIG",
+	TypeNSEC:   "TypeNSEC",
+	TypeDNSKEY: "TypeDNSKEY",
+	TypeNSEC3:  "TypeNSEC3",
+	TypeSVCB:   "TypeSVCB",
+	TypeHTTPS:  "TypeHTTPS",
+	TypeWKS:    "TypeWKS",
+	TypeHINFO:  "TypeHINFO",
+	TypeMINFO:  "TypeMINFO",
+	TypeAXFR:   "TypeAXFR",
+	TypeALL:    "TypeALL",
 }
 
 // String implements fmt.Stringer.String.
@@ -272,6 +286,13 @@ var (
 	errNonCanonicalName   = errors.New("name is not in canonical format (it must end with a .)")
 	errStringTooLong      = errors.New("character string exceeds maximum length (255)")
 	errCompressedSRV      = errors.New("compressed name in SRV resource data")
+	errTCPMsgTooLong      = errors.New("message too long for TCP (>65535 bytes)")
+	errParamOutOfOrder    = errors.New("SVCB params not in strictly increasing key order")
+	errInvalidParamValue  = errors.New("invalid SVCB param value")
+	errMandatoryParam     = errors.New("mandatory SVCB param not present")
+	errNoDefaultALPN      = errors.New("SVCB no-default-alpn param without alpn")
+	errTypesOutOfOrder    = errors.New("types not in strictly increasing order")
+	errInvalidTypeBitmap  = errors.New("invalid type bitmap")
 )
 
 // Internal constants.
@@ -1035,6 +1056,132 @@ func (p *Parser) OPTResource() (OPTResource, error) {
 	return r, nil
 }
 
+// SVCBResource parses a single SVCBResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) SVCBResource() (SVCBResource, error) {
+	if !p.resHeaderValid || p.resHeader.Type != TypeSVCB {
+		return SVCBResource{}, ErrNotStarted
+	}
+	r, err := unpackSVCBResource(p.msg, p.off, p.resHeader.Length)
+	if err != nil {
+		return SVCBResource{}, err
+	}
+	p.off += int(p.resHeader.Length)
+	p.resHeaderValid = false
+	p.index++
+	return r, nil
+}
+
+// HTTPSResource parses a single HTTPSResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) HTTPSResource() (HTTPSResource, error) {
+	if !p.resHeaderValid || p.resHeader.Type != TypeHTTPS {
+		return HTTPSResource{}, ErrNotStarted
+	}
+	r, err := unpackSVCBResource(p.msg, p.off, p.resHeader.Length)
+	if err != nil {
+		return HTTPSResource{}, err
+	}
+	p.off += int(p.resHeader.Length)
+	p.resHeaderValid = false
+	p.index++
+	return HTTPSResource{r}, nil
+}
+
+// DSResource parses a single DSResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) DSResource() (DSResource, error) {
+	if !p.resHeaderValid || p.resHeader.Type != TypeDS {
+		return DSResource{}, ErrNotStarted
+	}
+	r, err := unpackDSResource(p.msg, p.off, p.resHeader.Length)
+	if err != nil {
+		return DSResource{}, err
+	}
+	p.off += int(p.resHeader.Length)
+	p.resHeaderValid = false
+	p.index++
+	return r, nil
+}
+
+// RRSIGResource parses a single RRSIGResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) RRSIGResource() (RRSIGResource, error) {
+	if !p.resHeaderValid || p.resHeader.Type != TypeRRSIG {
+		return RRSIGResource{}, ErrNotStarted
+	}
+	r, err := unpackRRSIGResource(p.msg, p.off, p.resHeader.Length)
+	if err != nil {
+		return RRSIGResource{}, err
+	}
+	p.off += int(p.resHeader.Length)
+	p.resHeaderValid = false
+	p.index++
+	return r, nil
+}
+
+// NSECResource parses a single NSECResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) NSECResource() (NSECResource, error) {
+	if !p.resHeaderValid || p.resHeader.Type != TypeNSEC {
+		return NSECResource{}, ErrNotStarted
+	}
+	r, err := unpackNSECResource(p.msg, p.off, p.resHeader.Length)
+	if err != nil {
+		return NSECResource{}, err
+	}
+	p.off += int(p.resHeader.Length)
+	p.resHeaderValid = false
+	p.index++
+	return r, nil
+}
+
+// DNSKEYResource parses a single DNSKEYResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) DNSKEYResource() (DNSKEYResource, error) {
+	if !p.resHeaderValid || p.resHeader.Type != TypeDNSKEY {
+		return DNSKEYResource{}, ErrNotStarted
+	}
+	r, err := unpackDNSKEYResource(p.msg, p.off, p.resHeader.Length)
+	if err != nil {
+		return DNSKEYResource{}, err
+	}
+	p.off += int(p.resHeader.Length)
+	p.resHeaderValid = false
+	p.index++
+	return r, nil
+}
+
+// NSEC3Resource parses a single NSEC3Resource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) NSEC3Resource() (NSEC3Resource, error) {
+	if !p.resHeaderValid || p.resHeader.Type != TypeNSEC3 {
+		return NSEC3Resource{}, ErrNotStarted
+	}
+	r, err := unpackNSEC3Resource(p.msg, p.off, p.resHeader.Length)
+	if err != nil {
+		return NSEC3Resource{}, err
+	}
+	p.off += int(p.resHeader.Length)
+	p.resHeaderValid = false
+	p.index++
+	return r, nil
+}
+
 // UnknownResource parses a single UnknownResource.
 //
 // One of the XXXHeader methods must have been called before calling this
@@ -1080,6 +1227,67 @@ func (m *Message) Pack() ([]byte, error) {
 	return m.AppendPack(make([]byte, 0, packStartingCap))
 }
 
+// PackTCP is like Pack but prefixes the message with its length as a
+// two-byte big-endian integer, which is the wire format for DNS over TCP
+// (RFC 1035, section 4.2.2).
+func (m *Message) PackTCP() ([]byte, error) {
+	b, err := m.AppendPack(make([]byte, 2, 2+packStartingCap))
+	if err != nil {
+		return nil, err
+	}
+	if err := putTCPLength(b, 0); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// putTCPLength writes the length of the message at b[start+2:] into
+// b[start:start+2].
+func putTCPLength(b []byte, start int) error {
+	l := len(b) - start - 2
+	if l > int(^uint16(0)) {
+		return errTCPMsgTooLong
+	}
+	b[start], b[start+1] = byte(l>>8), byte(l)
+	return nil
+}
+
+// maxTTL is the largest TTL allowed by RFC 2181, section 8.
+const maxTTL = 1<<31 - 1
+
+// AdjustTTL adds delta seconds, which may be negative, to the TTL of
+// every Resource in m and returns the packed result. As required by
+// RFC 2181, section 8, a TTL with the most significant bit set is
+// treated as zero. The results are clamped to the range from 0 to
+// 2^31-1, so a decremented TTL never wraps below 0.
+// OPT pseudo-records are left unchanged, as their TTL field carries the
+// extended RCode and flags (RFC 6891, section 6.1.3).
+//
+// This is intended for caching resolvers serving stored responses.
+func (m *Message) AdjustTTL(delta int64) ([]byte, error) {
+	for _, rs := range [][]Resource{m.Answers, m.Authorities, m.Additionals} {
+		for i := range rs {
+			r := &rs[i]
+			if r.Header.Type == TypeOPT || (r.Body != nil && r.Body.realType() == TypeOPT) {
+				continue
+			}
+			ttl := int64(r.Header.TTL)
+			if ttl > maxTTL {
+				ttl = 0
+			}
+			ttl += delta
+			switch {
+			case ttl < 0:
+				ttl = 0
+			case ttl > maxTTL:
+				ttl = maxTTL
+			}
+			r.Header.TTL = uint32(ttl)
+		}
+	}
+	return m.Pack()
+}
+
 // AppendPack is like Pack but appends the full Message to b and returns the
 // extended buffer.
 func (m *Message) AppendPack(b []byte) ([]byte, error) {
@@ -1588,6 +1796,174 @@ func (b *Builder) OPTResource(h ResourceHeader, r OPTResource) error {
 	return nil
 }
 
+// SVCBResource adds a single SVCBResource.
+func (b *Builder) SVCBResource(h ResourceHeader, r SVCBResource) error {
+	if err := b.checkResourceSection(); err != nil {
+		return err
+	}
+	h.Type = r.realType()
+	msg, lenOff, err := h.pack(b.msg, b.compression, b.start)
+	if err != nil {
+		return &nestedError{"ResourceHeader", err}
+	}
+	preLen := len(msg)
+	if msg, err = r.pack(msg, b.compression, b.start); err != nil {
+		return &nestedError{"SVCBResource body", err}
+	}
+	if err := h.fixLen(msg, lenOff, preLen); err != nil {
+		return err
+	}
+	if err := b.incrementSectionCount(); err != nil {
+		return err
+	}
+	b.msg = msg
+	return nil
+}
+
+// HTTPSResource adds a single HTTPSResource.
+func (b *Builder) HTTPSResource(h ResourceHeader, r HTTPSResource) error {
+	if err := b.checkResourceSection(); err != nil {
+		return err
+	}
+	h.Type = r.realType()
+	msg, lenOff, err := h.pack(b.msg, b.compression, b.start)
+	if err != nil {
+		return &nestedError{"ResourceHeader", err}
+	}
+	preLen := len(msg)
+	if msg, err = r.pack(msg, b.compression, b.start); err != nil {
+		return &nestedError{"HTTPSResource body", err}
+	}
+	if err := h.fixLen(msg, lenOff, preLen); err != nil {
+		return err
+	}
+	if err := b.incrementSectionCount(); err != nil {
+		return err
+	}
+	b.msg = msg
+	return nil
+}
+
+// DSResource adds a single DSResource.
+func (b *Builder) DSResource(h ResourceHeader, r DSResource) error {
+	if err := b.checkResourceSection(); err != nil {
+		return err
+	}
+	h.Type = r.realType()
+	msg, lenOff, err := h.pack(b.msg, b.compression, b.start)
+	if err != nil {
+		return &nestedError{"ResourceHeader", err}
+	}
+	preLen := len(msg)
+	if msg, err = r.pack(msg, b.compression, b.start); err != nil {
+		return &nestedError{"DSResource body", err}
+	}
+	if err := h.fixLen(msg, lenOff, preLen); err != nil {
+		return err
+	}
+	if err := b.incrementSectionCount(); err != nil {
+		return err
+	}
+	b.msg = msg
+	return nil
+}
+
+// RRSIGResource adds a single RRSIGResource.
+func (b *Builder) RRSIGResource(h ResourceHeader, r RRSIGResource) error {
+	if err := b.checkResourceSection(); err != nil {
+		return err
+	}
+	h.Type = r.realType()
+	msg, lenOff, err := h.pack(b.msg, b.compression, b.start)
+	if err != nil {
+		return &nestedError{"ResourceHeader", err}
+	}
+	preLen := len(msg)
+	if msg, err = r.pack(msg, b.compression, b.start); err != nil {
+		return &nestedError{"RRSIGResource body", err}
+	}
+	if err := h.fixLen(msg, lenOff, preLen); err != nil {
+		return err
+	}
+	if err := b.incrementSectionCount(); err != nil {
+		return err
+	}
+	b.msg = msg
+	return nil
+}
+
+// NSECResource adds a single NSECResource.
+func (b *Builder) NSECResource(h ResourceHeader, r NSECResource) error {
+	if err := b.checkResourceSection(); err != nil {
+		return err
+	}
+	h.Type = r.realType()
+	msg, lenOff, err := h.pack(b.msg, b.compression, b.start)
+	if err != nil {
+		return &nestedError{"ResourceHeader", err}
+	}
+	preLen := len(msg)
+	if msg, err = r.pack(msg, b.compression, b.start); err != nil {
+		return &nestedError{"NSECResource body", err}
+	}
+	if err := h.fixLen(msg, lenOff, preLen); err != nil {
+		return err
+	}
+	if err := b.incrementSectionCount(); err != nil {
+		return err
+	}
+	b.msg = msg
+	return nil
+}
+
+// DNSKEYResource adds a single DNSKEYResource.
+func (b *Builder) DNSKEYResource(h ResourceHeader, r DNSKEYResource) error {
+	if err := b.checkResourceSection(); err != nil {
+		return err
+	}
+	h.Type = r.realType()
+	msg, lenOff, err := h.pack(b.msg, b.compression, b.start)
+	if err != nil {
+		return &nestedError{"ResourceHeader", err}
+	}
+	preLen := len(msg)
+	if msg, err = r.pack(msg, b.compression, b.start); err != nil {
+		return &nestedError{"DNSKEYResource body", err}
+	}
+	if err := h.fixLen(msg, lenOff, preLen); err != nil {
+		return err
+	}
+	if err := b.incrementSectionCount(); err != nil {
+		return err
+	}
+	b.msg = msg
+	return nil
+}
+
+// NSEC3Resource adds a single NSEC3Resource.
+func (b *Builder) NSEC3Resource(h ResourceHeader, r NSEC3Resource) error {
+	if err := b.checkResourceSection(); err != nil {
+		return err
+	}
+	h.Type = r.realType()
+	msg, lenOff, err := h.pack(b.msg, b.compression, b.start)
+	if err != nil {
+		return &nestedError{"ResourceHeader", err}
+	}
+	preLen := len(msg)
+	if msg, err = r.pack(msg, b.compression, b.start); err != nil {
+		return &nestedError{"NSEC3Resource body", err}
+	}
+	if err := h.fixLen(msg, lenOff, preLen); err != nil {
+		return err
+	}
+	if err := b.incrementSectionCount(); err != nil {
+		return err
+	}
+	b.msg = msg
+	return nil
+}
+
 // UnknownResource adds a single UnknownResource.
 func (b *Builder) UnknownResource(h ResourceHeader, r UnknownResource) error {
 	if err := b.checkResourceSection(); err != nil {
@@ -1623,6 +1999,23 @@ func (b *Builder) Finish() ([]byte, error) {
 	return b.msg, nil
 }
 
+// FinishTCP is like Finish but prefixes the built message with its length as
+// a two-byte big-endian integer, which is the wire format for DNS over TCP
+// (RFC 1035, section 4.2.2). The prefix is inserted after the initial buffer
+// provided to NewBuilder.
+func (b *Builder) FinishTCP() ([]byte, error) {
+	msg, err := b.Finish()
+	if err != nil {
+		return nil, err
+	}
+	msg = append(msg, 0, 0)
+	copy(msg[b.start+2:], msg[b.start:])
+	if err := putTCPLength(msg, b.start); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
 // A ResourceHeader is the header of a DNS resource record. There are
 // many types of DNS resource records, but they all share the same header.
 type ResourceHeader struct {
@@ -1782,8 +2175,15 @@ func packUint16(msg []byte, field uint16) []byte {
 	return append(msg, byte(field>>8), byte(field))
 }
 
-func unpackUint16(msg []byte, off int) (uint16, int, error) {
-	if off+uint16Len > len(msg) {
+func unpackUint8(msg []byte, off int) (uint8, int, error) {
+	if off >= len(msg) {
+		return 0, off, errBaseLen
+	}
+	return msg[off], off + 1, nil
+}
+
+func unpackUint16(msg []byte, off int) (uint16, int, error) {
+	if off+uint16Len > len(msg) {
 		return 0, off, errBaseLen
 	}
 	return uint16(msg[off])<<8 | uint16(msg[off+1]), off + uint16Len, nil
@@ -1926,6 +2326,51 @@ func (n *Name) GoString() string {
 	return `dnsmessage.MustNewName("` + printString(n.Data[:n.Length]) + `")`
 }
 
+// Equal reports whether n and other are the same domain name. Names are
+// compared case-insensitively, folding only the ASCII letters A to Z as
+// described in RFC 4343, and a trailing root label dot is optional, so
+// "Example.COM" and "example.com." are equal.
+func (n Name) Equal(other Name) bool {
+	a, b := n.Data[:n.Length], other.Data[:other.Length]
+	if len(a) > 0 && a[len(a)-1] == '.' {
+		a = a[:len(a)-1]
+	}
+	if len(b) > 0 && b[len(b)-1] == '.' {
+		b = b[:len(b)-1]
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if lowerASCII(a[i]) != lowerASCII(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Canonical returns n in the canonical form used for DNSSEC (RFC 4034,
+// Section 6.2): the ASCII letters A to Z are lowercased, leaving all
+// other bytes untouched, and a trailing root label dot is added if n
+// does not already end with one and there is room for it.
+func (n Name) Canonical() Name {
+	for i := 0; i < int(n.Length); i++ {
+		n.Data[i] = lowerASCII(n.Data[i])
+	}
+	if (n.Length == 0 || n.Data[n.Length-1] != '.') && n.Length < nameLen {
+		n.Data[n.Length] = '.'
+		n.Length++
+	}
+	return n
+}
+
+func lowerASCII(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + ('a' - 'A')
+	}
+	return b
+}
+
 // pack appends the wire format of the Name to msg.
 //
 // Domain names are a sequence of counted strings split at the dots. They end
@@ -2190,6 +2635,41 @@ func unpackResourceBody(msg []byte, off int, hdr ResourceHeader) (ResourceBody,
 		rb, err = unpackOPTResource(msg, off, hdr.Length)
 		r = &rb
 		name = "OPT"
+	case TypeSVCB:
+		var rb SVCBResource
+		rb, err = unpackSVCBResource(msg, off, hdr.Length)
+		r = &rb
+		name = "SVCB"
+	case TypeHTTPS:
+		var rb SVCBResource
+		rb, err = unpackSVCBResource(msg, off, hdr.Length)
+		r = &HTTPSResource{rb}
+		name = "HTTPS"
+	case TypeDS:
+		var rb DSResource
+		rb, err = unpackDSResource(msg, off, hdr.Length)
+		r = &rb
+		name = "DS"
+	case TypeRRSIG:
+		var rb RRSIGResource
+		rb, err = unpackRRSIGResource(msg, off, hdr.Length)
+		r = &rb
+		name = "RRSIG"
+	case TypeNSEC:
+		var rb NSECResource
+		rb, err = unpackNSECResource(msg, off, hdr.Length)
+		r = &rb
+		name = "NSEC"
+	case TypeDNSKEY:
+		var rb DNSKEYResource
+		rb, err = unpackDNSKEYResource(msg, off, hdr.Length)
+		r = &rb
+		name = "DNSKEY"
+	case TypeNSEC3:
+		var rb NSEC3Resource
+		rb, err = unpackNSEC3Resource(msg, off, hdr.Length)
+		r = &rb
+		name = "NSEC3"
 	default:
 		var rb UnknownResource
 		rb, err = unpackUnknownResource(hdr.Type, msg, off, hdr.Length)
@@ -2643,6 +3123,838 @@ func unpackOPTResource(msg []byte, off int, length uint16) (OPTResource, error)
 	return OPTResource{opts}, nil
 }
 
+// An SVCBResource is an SVCB Resource record, as defined in RFC 9460.
+//
+// A Priority of zero denotes the alias form of the record, which has no
+// Params.
+type SVCBResource struct {
+	Priority uint16
+	Target   Name // Not compressed as per RFC 9460.
+
+	// Params holds the SvcParams of the record, in strictly increasing
+	// order of Key.
+	Params []SVCParam
+}
+
+// An HTTPSResource is an HTTPS Resource record, as defined in RFC 9460.
+// It has the same format as an SVCB Resource record.
+type HTTPSResource struct {
+	SVCBResource
+}
+
+// An SVCParamKey is the key of an SvcParam of an SVCB or HTTPS Resource
+// record.
+type SVCParamKey uint16
+
+// SVCParamKey values, see
+// https://www.iana.org/assignments/dns-svcb/dns-svcb.xhtml.
+const (
+	SVCParamMandatory     SVCParamKey = 0
+	SVCParamALPN          SVCParamKey = 1
+	SVCParamNoDefaultALPN SVCParamKey = 2
+	SVCParamPort          SVCParamKey = 3
+	SVCParamIPv4Hint      SVCParamKey = 4
+	SVCParamECH           SVCParamKey = 5
+	SVCParamIPv6Hint      SVCParamKey = 6
+)
+
+var svcParamKeyNames = map[SVCParamKey]string{
+	SVCParamMandatory:     "SVCParamMandatory",
+	SVCParamALPN:          "SVCParamALPN",
+	SVCParamNoDefaultALPN: "SVCParamNoDefaultALPN",
+	SVCParamPort:          "SVCParamPort",
+	SVCParamIPv4Hint:      "SVCParamIPv4Hint",
+	SVCParamECH:           "SVCParamECH",
+	SVCParamIPv6Hint:      "SVCParamIPv6Hint",
+}
+
+// String implements fmt.Stringer.String.
+func (k SVCParamKey) String() string {
+	if n, ok := svcParamKeyNames[k]; ok {
+		return n
+	}
+	return printUint16(uint16(k))
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (k SVCParamKey) GoString() string {
+	if n, ok := svcParamKeyNames[k]; ok {
+		return "dnsmessage." + n
+	}
+	return printUint16(uint16(k))
+}
+
+// An SVCParam is a key/value pair of an SVCB or HTTPS Resource record.
+//
+// Value is the wire format of the value; for instance, the value of
+// SVCParamPort is a two-byte big-endian port number, and the value of
+// SVCParamIPv4Hint is a sequence of four-byte addresses.
+type SVCParam struct {
+	Key   SVCParamKey
+	Value []byte
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (p *SVCParam) GoString() string {
+	return "dnsmessage.SVCParam{" +
+		"Key: " + p.Key.GoString() + ", " +
+		"Value: []byte{" + printByteSlice(p.Value) + "}}"
+}
+
+// GetParam returns the value of the param with the given key, and
+// whether it is present.
+func (r *SVCBResource) GetParam(key SVCParamKey) (value []byte, ok bool) {
+	for _, p := range r.Params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetParam sets the value of the param with the given key, keeping
+// r.Params ordered by key.
+func (r *SVCBResource) SetParam(key SVCParamKey, value []byte) {
+	i := 0
+	for ; i < len(r.Params) && r.Params[i].Key < key; i++ {
+	}
+	if i < len(r.Params) && r.Params[i].Key == key {
+		r.Params[i].Value = value
+		return
+	}
+	r.Params = append(r.Params, SVCParam{})
+	copy(r.Params[i+1:], r.Params[i:])
+	r.Params[i] = SVCParam{key, value}
+}
+
+// DeleteParam removes the param with the given key, and reports whether
+// it was present.
+func (r *SVCBResource) DeleteParam(key SVCParamKey) bool {
+	for i, p := range r.Params {
+		if p.Key == key {
+			r.Params = append(r.Params[:i], r.Params[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Mandatory returns the keys of the mandatory param. It reports false if
+// the param is absent or its value is malformed.
+func (r *SVCBResource) Mandatory() ([]SVCParamKey, bool) {
+	v, ok := r.GetParam(SVCParamMandatory)
+	if !ok || len(v) == 0 || len(v)%2 != 0 {
+		return nil, false
+	}
+	keys := make([]SVCParamKey, len(v)/2)
+	for i := range keys {
+		keys[i] = SVCParamKey(uint16(v[2*i])<<8 | uint16(v[2*i+1]))
+	}
+	return keys, true
+}
+
+// SetMandatory sets the mandatory param to keys, which are sorted as
+// required by RFC 9460. Each of the keys must also be set for r to be
+// packed.
+func (r *SVCBResource) SetMandatory(keys ...SVCParamKey) {
+	sorted := append([]SVCParamKey(nil), keys...)
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && sorted[j] < sorted[j-1]; j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+	var v []byte
+	for i, k := range sorted {
+		if i > 0 && k == sorted[i-1] {
+			continue
+		}
+		v = packUint16(v, uint16(k))
+	}
+	r.SetParam(SVCParamMandatory, v)
+}
+
+// ALPN returns the protocol IDs of the alpn param. It reports false if
+// the param is absent or its value is malformed.
+func (r *SVCBResource) ALPN() ([]string, bool) {
+	v, ok := r.GetParam(SVCParamALPN)
+	if !ok || len(v) == 0 {
+		return nil, false
+	}
+	var ids []string
+	for len(v) > 0 {
+		n := int(v[0])
+		if n == 0 || 1+n > len(v) {
+			return nil, false
+		}
+		ids = append(ids, string(v[1:1+n]))
+		v = v[1+n:]
+	}
+	return ids, true
+}
+
+// SetALPN sets the alpn param to the protocol IDs ids, which must each be
+// between 1 and 255 bytes long.
+func (r *SVCBResource) SetALPN(ids ...string) error {
+	if len(ids) == 0 {
+		return errInvalidParamValue
+	}
+	var v []byte
+	for _, id := range ids {
+		if len(id) == 0 || len(id) > 255 {
+			return errInvalidParamValue
+		}
+		v = append(v, byte(len(id)))
+		v = append(v, id...)
+	}
+	r.SetParam(SVCParamALPN, v)
+	return nil
+}
+
+// NoDefaultALPN reports whether the no-default-alpn param is present.
+func (r *SVCBResource) NoDefaultALPN() bool {
+	_, ok := r.GetParam(SVCParamNoDefaultALPN)
+	return ok
+}
+
+// SetNoDefaultALPN adds or removes the no-default-alpn param. When it is
+// present, the alpn param must be set as well for r to be packed.
+func (r *SVCBResource) SetNoDefaultALPN(noDefault bool) {
+	if noDefault {
+		r.SetParam(SVCParamNoDefaultALPN, []byte{})
+	} else {
+		r.DeleteParam(SVCParamNoDefaultALPN)
+	}
+}
+
+// Port returns the value of the port param. It reports false if the
+// param is absent or its value is malformed.
+func (r *SVCBResource) Port() (uint16, bool) {
+	v, ok := r.GetParam(SVCParamPort)
+	if !ok || len(v) != 2 {
+		return 0, false
+	}
+	return uint16(v[0])<<8 | uint16(v[1]), true
+}
+
+// SetPort sets the port param.
+func (r *SVCBResource) SetPort(port uint16) {
+	r.SetParam(SVCParamPort, packUint16(nil, port))
+}
+
+// IPv4Hint returns the addresses of the ipv4hint param. It reports false
+// if the param is absent or its value is malformed.
+func (r *SVCBResource) IPv4Hint() ([][4]byte, bool) {
+	v, ok := r.GetParam(SVCParamIPv4Hint)
+	if !ok || len(v) == 0 || len(v)%4 != 0 {
+		return nil, false
+	}
+	addrs := make([][4]byte, len(v)/4)
+	for i := range addrs {
+		copy(addrs[i][:], v[4*i:])
+	}
+	return addrs, true
+}
+
+// SetIPv4Hint sets the ipv4hint param to addrs, which must not be empty
+// for r to be packed.
+func (r *SVCBResource) SetIPv4Hint(addrs ...[4]byte) {
+	v := make([]byte, 0, 4*len(addrs))
+	for _, a := range addrs {
+		v = append(v, a[:]...)
+	}
+	r.SetParam(SVCParamIPv4Hint, v)
+}
+
+// IPv6Hint returns the addresses of the ipv6hint param. It reports false
+// if the param is absent or its value is malformed.
+func (r *SVCBResource) IPv6Hint() ([][16]byte, bool) {
+	v, ok := r.GetParam(SVCParamIPv6Hint)
+	if !ok || len(v) == 0 || len(v)%16 != 0 {
+		return nil, false
+	}
+	addrs := make([][16]byte, len(v)/16)
+	for i := range addrs {
+		copy(addrs[i][:], v[16*i:])
+	}
+	return addrs, true
+}
+
+// SetIPv6Hint sets the ipv6hint param to addrs, which must not be empty
+// for r to be packed.
+func (r *SVCBResource) SetIPv6Hint(addrs ...[16]byte) {
+	v := make([]byte, 0, 16*len(addrs))
+	for _, a := range addrs {
+		v = append(v, a[:]...)
+	}
+	r.SetParam(SVCParamIPv6Hint, v)
+}
+
+// ECH returns the ECHConfigList of the ech param. It reports false if the
+// param is absent or empty.
+func (r *SVCBResource) ECH() ([]byte, bool) {
+	v, ok := r.GetParam(SVCParamECH)
+	if !ok || len(v) == 0 {
+		return nil, false
+	}
+	return v, true
+}
+
+// SetECH sets the ech param to the ECHConfigList config, which must not be
+// empty for r to be packed.
+func (r *SVCBResource) SetECH(config []byte) {
+	r.SetParam(SVCParamECH, config)
+}
+
+// checkParams reports whether the values of the params defined by RFC 9460
+// are well formed and consistent with each other. Params with other keys
+// are not checked.
+func (r *SVCBResource) checkParams() error {
+	for _, p := range r.Params {
+		var ok bool
+		switch p.Key {
+		case SVCParamMandatory:
+			var keys []SVCParamKey
+			if keys, ok = r.Mandatory(); !ok {
+				break
+			}
+			for i, k := range keys {
+				if k == SVCParamMandatory || i > 0 && k <= keys[i-1] {
+					ok = false
+					break
+				}
+				if _, present := r.GetParam(k); !present {
+					return &nestedError{p.Key.String(), errMandatoryParam}
+				}
+			}
+		case SVCParamALPN:
+			_, ok = r.ALPN()
+		case SVCParamNoDefaultALPN:
+			if len(p.Value) != 0 {
+				break
+			}
+			if _, ok = r.GetParam(SVCParamALPN); !ok {
+				return &nestedError{p.Key.String(), errNoDefaultALPN}
+			}
+		case SVCParamPort:
+			_, ok = r.Port()
+		case SVCParamIPv4Hint:
+			_, ok = r.IPv4Hint()
+		case SVCParamECH:
+			_, ok = r.ECH()
+		case SVCParamIPv6Hint:
+			_, ok = r.IPv6Hint()
+		default:
+			ok = true
+		}
+		if !ok {
+			return &nestedError{p.Key.String(), errInvalidParamValue}
+		}
+	}
+	return nil
+}
+
+func (r *SVCBResource) realType() Type {
+	return TypeSVCB
+}
+
+// pack appends the wire format of the SVCBResource to msg.
+func (r *SVCBResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	oldMsg := msg
+	msg = packUint16(msg, r.Priority)
+	msg, err := r.Target.pack(msg, nil, compressionOff)
+	if err != nil {
+		return oldMsg, &nestedError{"SVCBResource.Target", err}
+	}
+	for i, p := range r.Params {
+		if i > 0 && p.Key <= r.Params[i-1].Key {
+			return oldMsg, &nestedError{"SVCBResource.Params", errParamOutOfOrder}
+		}
+		if len(p.Value) > int(^uint16(0)) {
+			return oldMsg, &nestedError{"SVCBResource.Params", errResTooLong}
+		}
+		msg = packUint16(msg, uint16(p.Key))
+		msg = packUint16(msg, uint16(len(p.Value)))
+		msg = packBytes(msg, p.Value)
+	}
+	if err := r.checkParams(); err != nil {
+		return oldMsg, &nestedError{"SVCBResource.Params", err}
+	}
+	return msg, nil
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (r *SVCBResource) GoString() string {
+	s := "dnsmessage.SVCBResource{" +
+		"Priority: " + printUint16(r.Priority) + ", " +
+		"Target: " + r.Target.GoString() + ", " +
+		"Params: []dnsmessage.SVCParam{"
+	if len(r.Params) == 0 {
+		return s + "}}"
+	}
+	s += r.Params[0].GoString()
+	for _, p := range r.Params[1:] {
+		s += ", " + p.GoString()
+	}
+	return s + "}}"
+}
+
+func unpackSVCBResource(msg []byte, off int, length uint16) (SVCBResource, error) {
+	end := off + int(length)
+	if end > len(msg) {
+		return SVCBResource{}, errResourceLen
+	}
+	priority, off, err := unpackUint16(msg, off)
+	if err != nil {
+		return SVCBResource{}, &nestedError{"Priority", err}
+	}
+	var target Name
+	if off, err = target.unpackCompressed(msg, off, false /* allowCompression */); err != nil {
+		return SVCBResource{}, &nestedError{"Target", err}
+	}
+	var params []SVCParam
+	for off < end {
+		var p SVCParam
+		var key, l uint16
+		key, off, err = unpackUint16(msg[:end], off)
+		if err != nil {
+			return SVCBResource{}, &nestedError{"Params", err}
+		}
+		p.Key = SVCParamKey(key)
+		if len(params) > 0 && p.Key <= params[len(params)-1].Key {
+			return SVCBResource{}, &nestedError{"Params", errParamOutOfOrder}
+		}
+		l, off, err = unpackUint16(msg[:end], off)
+		if err != nil {
+			return SVCBResource{}, &nestedError{"Params", err}
+		}
+		p.Value = make([]byte, l)
+		if off, err = unpackBytes(msg[:end], off, p.Value); err != nil {
+			return SVCBResource{}, &nestedError{"Params", err}
+		}
+		params = append(params, p)
+	}
+	if off != end {
+		return SVCBResource{}, &nestedError{"Target", errResourceLen}
+	}
+	return SVCBResource{priority, target, params}, nil
+}
+
+func (r *HTTPSResource) realType() Type {
+	return TypeHTTPS
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (r *HTTPSResource) GoString() string {
+	return "dnsmessage.HTTPSResource{SVCBResource: " + r.SVCBResource.GoString() + "}"
+}
+
+// A DSResource is a DS (Delegation Signer) Resource record, as defined in
+// RFC 4034, Section 5.
+type DSResource struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     []byte
+}
+
+func (r *DSResource) realType() Type {
+	return TypeDS
+}
+
+// pack appends the wire format of the DSResource to msg.
+func (r *DSResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	msg = packUint16(msg, r.KeyTag)
+	msg = append(msg, r.Algorithm, r.DigestType)
+	return packBytes(msg, r.Digest), nil
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (r *DSResource) GoString() string {
+	return "dnsmessage.DSResource{" +
+		"KeyTag: " + printUint16(r.KeyTag) + ", " +
+		"Algorithm: " + printUint16(uint16(r.Algorithm)) + ", " +
+		"DigestType: " + printUint16(uint16(r.DigestType)) + ", " +
+		"Digest: []byte{" + printByteSlice(r.Digest) + "}}"
+}
+
+func unpackDSResource(msg []byte, off int, length uint16) (DSResource, error) {
+	end := off + int(length)
+	if end > len(msg) {
+		return DSResource{}, errResourceLen
+	}
+	var r DSResource
+	var err error
+	if r.KeyTag, off, err = unpackUint16(msg[:end], off); err != nil {
+		return DSResource{}, &nestedError{"KeyTag", err}
+	}
+	if r.Algorithm, off, err = unpackUint8(msg[:end], off); err != nil {
+		return DSResource{}, &nestedError{"Algorithm", err}
+	}
+	if r.DigestType, off, err = unpackUint8(msg[:end], off); err != nil {
+		return DSResource{}, &nestedError{"DigestType", err}
+	}
+	r.Digest = make([]byte, end-off)
+	copy(r.Digest, msg[off:end])
+	return r, nil
+}
+
+// An RRSIGResource is an RRSIG Resource record, as defined in RFC 4034,
+// Section 3.
+//
+// The fields preceding Signature, with SignerName in canonical form, are
+// part of the data covered by the signature; see RFC 4034, Section 3.1.8.1.
+type RRSIGResource struct {
+	TypeCovered Type
+	Algorithm   uint8
+	Labels      uint8
+	OriginalTTL uint32
+
+	// Expiration and Inception are in seconds since the Unix epoch,
+	// modulo 2**32 as per RFC 4034, Section 3.1.5.
+	Expiration uint32
+	Inception  uint32
+
+	KeyTag     uint16
+	SignerName Name // Not compressed as per RFC 4034.
+	Signature  []byte
+}
+
+func (r *RRSIGResource) realType() Type {
+	return TypeRRSIG
+}
+
+// pack appends the wire format of the RRSIGResource to msg.
+func (r *RRSIGResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	oldMsg := msg
+	msg = packType(msg, r.TypeCovered)
+	msg = append(msg, r.Algorithm, r.Labels)
+	msg = packUint32(msg, r.OriginalTTL)
+	msg = packUint32(msg, r.Expiration)
+	msg = packUint32(msg, r.Inception)
+	msg = packUint16(msg, r.KeyTag)
+	msg, err := r.SignerName.pack(msg, nil, compressionOff)
+	if err != nil {
+		return oldMsg, &nestedError{"RRSIGResource.SignerName", err}
+	}
+	return packBytes(msg, r.Signature), nil
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (r *RRSIGResource) GoString() string {
+	return "dnsmessage.RRSIGResource{" +
+		"TypeCovered: " + r.TypeCovered.GoString() + ", " +
+		"Algorithm: " + printUint16(uint16(r.Algorithm)) + ", " +
+		"Labels: " + printUint16(uint16(r.Labels)) + ", " +
+		"OriginalTTL: " + printUint32(r.OriginalTTL) + ", " +
+		"Expiration: " + printUint32(r.Expiration) + ", " +
+		"Inception: " + printUint32(r.Inception) + ", " +
+		"KeyTag: " + printUint16(r.KeyTag) + ", " +
+		"SignerName: " + r.SignerName.GoString() + ", " +
+		"Signature: []byte{" + printByteSlice(r.Signature) + "}}"
+}
+
+func unpackRRSIGResource(msg []byte, off int, length uint16) (RRSIGResource, error) {
+	end := off + int(length)
+	if end > len(msg) {
+		return RRSIGResource{}, errResourceLen
+	}
+	var r RRSIGResource
+	var err error
+	if r.TypeCovered, off, err = unpackType(msg[:end], off); err != nil {
+		return RRSIGResource{}, &nestedError{"TypeCovered", err}
+	}
+	if r.Algorithm, off, err = unpackUint8(msg[:end], off); err != nil {
+		return RRSIGResource{}, &nestedError{"Algorithm", err}
+	}
+	if r.Labels, off, err = unpackUint8(msg[:end], off); err != nil {
+		return RRSIGResource{}, &nestedError{"Labels", err}
+	}
+	if r.OriginalTTL, off, err = unpackUint32(msg[:end], off); err != nil {
+		return RRSIGResource{}, &nestedError{"OriginalTTL", err}
+	}
+	if r.Expiration, off, err = unpackUint32(msg[:end], off); err != nil {
+		return RRSIGResource{}, &nestedError{"Expiration", err}
+	}
+	if r.Inception, off, err = unpackUint32(msg[:end], off); err != nil {
+		return RRSIGResource{}, &nestedError{"Inception", err}
+	}
+	if r.KeyTag, off, err = unpackUint16(msg[:end], off); err != nil {
+		return RRSIGResource{}, &nestedError{"KeyTag", err}
+	}
+	if off, err = r.SignerName.unpackCompressed(msg[:end], off, false /* allowCompression */); err != nil {
+		return RRSIGResource{}, &nestedError{"SignerName", err}
+	}
+	r.Signature = make([]byte, end-off)
+	copy(r.Signature, msg[off:end])
+	return r, nil
+}
+
+// An NSECResource is an NSEC Resource record, as defined in RFC 4034,
+// Section 4.
+type NSECResource struct {
+	NextDomain Name // Not compressed as per RFC 4034.
+
+	// Types holds the types present at the owner name, in strictly
+	// increasing order.
+	Types []Type
+}
+
+func (r *NSECResource) realType() Type {
+	return TypeNSEC
+}
+
+// pack appends the wire format of the NSECResource to msg.
+func (r *NSECResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	oldMsg := msg
+	msg, err := r.NextDomain.pack(msg, nil, compressionOff)
+	if err != nil {
+		return oldMsg, &nestedError{"NSECResource.NextDomain", err}
+	}
+	if msg, err = packTypeBitmap(msg, r.Types); err != nil {
+		return oldMsg, &nestedError{"NSECResource.Types", err}
+	}
+	return msg, nil
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (r *NSECResource) GoString() string {
+	return "dnsmessage.NSECResource{" +
+		"NextDomain: " + r.NextDomain.GoString() + ", " +
+		"Types: " + printTypes(r.Types) + "}"
+}
+
+func unpackNSECResource(msg []byte, off int, length uint16) (NSECResource, error) {
+	end := off + int(length)
+	if end > len(msg) {
+		return NSECResource{}, errResourceLen
+	}
+	var r NSECResource
+	var err error
+	if off, err = r.NextDomain.unpackCompressed(msg[:end], off, false /* allowCompression */); err != nil {
+		return NSECResource{}, &nestedError{"NextDomain", err}
+	}
+	if r.Types, err = unpackTypeBitmap(msg[:end], off); err != nil {
+		return NSECResource{}, &nestedError{"Types", err}
+	}
+	return r, nil
+}
+
+// A DNSKEYResource is a DNSKEY Resource record, as defined in RFC 4034,
+// Section 2.
+type DNSKEYResource struct {
+	Flags     uint16
+	Protocol  uint8 // Always 3 as per RFC 4034.
+	Algorithm uint8
+	PublicKey []byte
+}
+
+func (r *DNSKEYResource) realType() Type {
+	return TypeDNSKEY
+}
+
+// pack appends the wire format of the DNSKEYResource to msg.
+func (r *DNSKEYResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	msg = packUint16(msg, r.Flags)
+	msg = append(msg, r.Protocol, r.Algorithm)
+	return packBytes(msg, r.PublicKey), nil
+}
+
+// KeyTag returns the key tag of the key, as computed in RFC 4034,
+// Appendix B. It does not support the obsolete algorithm 1 (RSA/MD5).
+func (r *DNSKEYResource) KeyTag() uint16 {
+	rdata, _ := r.pack(make([]byte, 0, 4+len(r.PublicKey)), nil, 0)
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 0 {
+			ac += uint32(b) << 8
+		} else {
+			ac += uint32(b)
+		}
+	}
+	ac += ac >> 16 & 0xffff
+	return uint16(ac)
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (r *DNSKEYResource) GoString() string {
+	return "dnsmessage.DNSKEYResource{" +
+		"Flags: " + printUint16(r.Flags) + ", " +
+		"Protocol: " + printUint16(uint16(r.Protocol)) + ", " +
+		"Algorithm: " + printUint16(uint16(r.Algorithm)) + ", " +
+		"PublicKey: []byte{" + printByteSlice(r.PublicKey) + "}}"
+}
+
+func unpackDNSKEYResource(msg []byte, off int, length uint16) (DNSKEYResource, error) {
+	end := off + int(length)
+	if end > len(msg) {
+		return DNSKEYResource{}, errResourceLen
+	}
+	var r DNSKEYResource
+	var err error
+	if r.Flags, off, err = unpackUint16(msg[:end], off); err != nil {
+		return DNSKEYResource{}, &nestedError{"Flags", err}
+	}
+	if r.Protocol, off, err = unpackUint8(msg[:end], off); err != nil {
+		return DNSKEYResource{}, &nestedError{"Protocol", err}
+	}
+	if r.Algorithm, off, err = unpackUint8(msg[:end], off); err != nil {
+		return DNSKEYResource{}, &nestedError{"Algorithm", err}
+	}
+	r.PublicKey = make([]byte, end-off)
+	copy(r.PublicKey, msg[off:end])
+	return r, nil
+}
+
+// An NSEC3Resource is an NSEC3 Resource record, as defined in RFC 5155,
+// Section 3.
+type NSEC3Resource struct {
+	HashAlgorithm   uint8
+	Flags           uint8
+	Iterations      uint16
+	Salt            []byte
+	NextHashedOwner []byte // The raw hash, not its Base32 encoding.
+
+	// Types holds the types present at the original owner name, in
+	// strictly increasing order.
+	Types []Type
+}
+
+func (r *NSEC3Resource) realType() Type {
+	return TypeNSEC3
+}
+
+// pack appends the wire format of the NSEC3Resource to msg.
+func (r *NSEC3Resource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	oldMsg := msg
+	if len(r.Salt) > 255 {
+		return oldMsg, &nestedError{"NSEC3Resource.Salt", errStringTooLong}
+	}
+	if len(r.NextHashedOwner) > 255 {
+		return oldMsg, &nestedError{"NSEC3Resource.NextHashedOwner", errStringTooLong}
+	}
+	msg = append(msg, r.HashAlgorithm, r.Flags)
+	msg = packUint16(msg, r.Iterations)
+	msg = append(msg, byte(len(r.Salt)))
+	msg = packBytes(msg, r.Salt)
+	msg = append(msg, byte(len(r.NextHashedOwner)))
+	msg = packBytes(msg, r.NextHashedOwner)
+	msg, err := packTypeBitmap(msg, r.Types)
+	if err != nil {
+		return oldMsg, &nestedError{"NSEC3Resource.Types", err}
+	}
+	return msg, nil
+}
+
+// GoString implements fmt.GoStringer.GoString.
+func (r *NSEC3Resource) GoString() string {
+	return "dnsmessage.NSEC3Resource{" +
+		"HashAlgorithm: " + printUint16(uint16(r.HashAlgorithm)) + ", " +
+		"Flags: " + printUint16(uint16(r.Flags)) + ", " +
+		"Iterations: " + printUint16(r.Iterations) + ", " +
+		"Salt: []byte{" + printByteSlice(r.Salt) + "}, " +
+		"NextHashedOwner: []byte{" + printByteSlice(r.NextHashedOwner) + "}, " +
+		"Types: " + printTypes(r.Types) + "}"
+}
+
+func unpackNSEC3Resource(msg []byte, off int, length uint16) (NSEC3Resource, error) {
+	end := off + int(length)
+	if end > len(msg) {
+		return NSEC3Resource{}, errResourceLen
+	}
+	var r NSEC3Resource
+	var err error
+	if r.HashAlgorithm, off, err = unpackUint8(msg[:end], off); err != nil {
+		return NSEC3Resource{}, &nestedError{"HashAlgorithm", err}
+	}
+	if r.Flags, off, err = unpackUint8(msg[:end], off); err != nil {
+		return NSEC3Resource{}, &nestedError{"Flags", err}
+	}
+	if r.Iterations, off, err = unpackUint16(msg[:end], off); err != nil {
+		return NSEC3Resource{}, &nestedError{"Iterations", err}
+	}
+	var l uint8
+	if l, off, err = unpackUint8(msg[:end], off); err != nil {
+		return NSEC3Resource{}, &nestedError{"Salt", err}
+	}
+	r.Salt = make([]byte, l)
+	if off, err = unpackBytes(msg[:end], off, r.Salt); err != nil {
+		return NSEC3Resource{}, &nestedError{"Salt", err}
+	}
+	if l, off, err = unpackUint8(msg[:end], off); err != nil {
+		return NSEC3Resource{}, &nestedError{"NextHashedOwner", err}
+	}
+	r.NextHashedOwner = make([]byte, l)
+	if off, err = unpackBytes(msg[:end], off, r.NextHashedOwner); err != nil {
+		return NSEC3Resource{}, &nestedError{"NextHashedOwner", err}
+	}
+	if r.Types, err = unpackTypeBitmap(msg[:end], off); err != nil {
+		return NSEC3Resource{}, &nestedError{"Types", err}
+	}
+	return r, nil
+}
+
+// packTypeBitmap appends the type bitmap encoding of types, as used by
+// NSEC and NSEC3 records (RFC 4034, Section 4.1.2), to msg.
+func packTypeBitmap(msg []byte, types []Type) ([]byte, error) {
+	var bitmap [32]byte
+	for i := 0; i < len(types); {
+		window := byte(types[i] >> 8)
+		bitmap = [32]byte{}
+		var l int
+		for ; i < len(types) && byte(types[i]>>8) == window; i++ {
+			if i > 0 && types[i] <= types[i-1] {
+				return nil, errTypesOutOfOrder
+			}
+			b := byte(types[i])
+			bitmap[b/8] |= 0x80 >> (b % 8)
+			l = int(b/8) + 1
+		}
+		msg = append(msg, window, byte(l))
+		msg = append(msg, bitmap[:l]...)
+	}
+	return msg, nil
+}
+
+// unpackTypeBitmap unpacks the type bitmap from off to the end of msg.
+func unpackTypeBitmap(msg []byte, off int) ([]Type, error) {
+	var types []Type
+	lastWindow := -1
+	for off < len(msg) {
+		if off+2 > len(msg) {
+			return nil, errBaseLen
+		}
+		window, l := int(msg[off]), int(msg[off+1])
+		off += 2
+		if window <= lastWindow || l == 0 || l > 32 {
+			return nil, errInvalidTypeBitmap
+		}
+		if off+l > len(msg) {
+			return nil, errBaseLen
+		}
+		for i, b := range msg[off : off+l] {
+			for j := 0; j < 8; j++ {
+				if b&(0x80>>j) != 0 {
+					types = append(types, Type(window<<8|i*8+j))
+				}
+			}
+		}
+		off += l
+		lastWindow = window
+	}
+	return types, nil
+}
+
+func printTypes(types []Type) string {
+	s := "[]dnsmessage.Type{"
+	for i, t := range types {
+		if i > 0 {
+			s += ", "
+		}
+		s += t.GoString()
+	}
+	return s + "}"
+}
+
 // An UnknownResource is a catch-all container for unknown record types.
 type UnknownResource struct {
 	Type Type
@@ -6,12 +6,18 @@ package dnsmessage
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -211,6 +217,63 @@ func TestName(t *testing.T) {
 	}
 }
 
+func TestNameEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"example.com.", "example.com.", true},
+		{"Example.COM.", "eXample.com.", true},
+		{"example.com", "EXAMPLE.com.", true},
+		{".", "", true},
+		{".", ".", true},
+		{"example.com..", "example.com.", false},
+		{"example.com.", "example.org.", false},
+		{"example.com.", "www.example.com.", false},
+		{"a-b.", "a_b.", false},
+		{"@.", "`.", false}, // just outside 'A'-'Z'
+		{"[.", "{.", false},
+		{"\xc3\x89.", "\xc3\xa9.", false}, // É and é
+		{"\xc3\x89.", "\xc3\x89", true},
+	}
+	for _, test := range tests {
+		a, b := MustNewName(test.a), MustNewName(test.b)
+		if got := a.Equal(b); got != test.want {
+			t.Errorf("%#v.Equal(%#v) = %t, want = %t", a, b, got, test.want)
+		}
+		if got := b.Equal(a); got != test.want {
+			t.Errorf("%#v.Equal(%#v) = %t, want = %t", b, a, got, test.want)
+		}
+	}
+}
+
+func TestNameCanonical(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"", "."},
+		{".", "."},
+		{"Example.COM.", "example.com."},
+		{"WWW.Example.com", "www.example.com."},
+		{"@[`{.", "@[`{."},
+		{"\xc3\x89XAMPLE.", "\xc3\x89xample."},
+		{strings.Repeat("A", nameLen), strings.Repeat("a", nameLen)},
+	}
+	for _, test := range tests {
+		n := MustNewName(test.name)
+		got := n.Canonical()
+		if got.String() != test.want {
+			t.Errorf("%#v.Canonical() = %#v, want = %q", n, got, test.want)
+		}
+		if n.String() != test.name {
+			t.Errorf("Canonical modified the receiver: got %#v, want = %q", n, test.name)
+		}
+		if !got.Equal(n) {
+			t.Errorf("%#v.Canonical() = %#v, which is not Equal to it", n, got)
+		}
+	}
+}
+
 func TestNamePackUnpack(t *testing.T) {
 	tests := []struct {
 		in   string
@@ -1599,3 +1662,779 @@ func TestNoFmt(t *testing.T) {
 		}
 	}
 }
+
+// readTCPMessage reads a length-prefixed DNS message from r.
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	b := make([]byte, int(l[0])<<8|int(l[1]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func TestPackTCP(t *testing.T) {
+	msgs := []Message{smallTestMsg(), largeTestMsg()}
+	var stream bytes.Buffer
+	for i, msg := range msgs {
+		b, err := msg.PackTCP()
+		if err != nil {
+			t.Fatalf("%d: Message.PackTCP() = %v", i, err)
+		}
+		stream.Write(b)
+	}
+	for i, want := range msgs {
+		b, err := readTCPMessage(&stream)
+		if err != nil {
+			t.Fatalf("%d: readTCPMessage() = %v", i, err)
+		}
+		var got Message
+		if err := got.Unpack(b); err != nil {
+			t.Fatalf("%d: Message.Unpack() = %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d: Message.PackTCP/Unpack() roundtrip: got = %+v, want = %+v", i, &got, &want)
+		}
+	}
+	if stream.Len() != 0 {
+		t.Errorf("got %d trailing bytes, want 0", stream.Len())
+	}
+}
+
+func TestBuilderFinishTCP(t *testing.T) {
+	msg := smallTestMsg()
+	want, err := msg.Pack()
+	if err != nil {
+		t.Fatal("Message.Pack() =", err)
+	}
+
+	b := NewBuilder([]byte("prefix"), msg.Header)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		t.Fatal("Builder.StartQuestions() =", err)
+	}
+	for _, q := range msg.Questions {
+		if err := b.Question(q); err != nil {
+			t.Fatalf("Builder.Question(%#v) = %v", q, err)
+		}
+	}
+	sections := []struct {
+		start     func() error
+		resources []Resource
+	}{
+		{b.StartAnswers, msg.Answers},
+		{b.StartAuthorities, msg.Authorities},
+		{b.StartAdditionals, msg.Additionals},
+	}
+	for _, s := range sections {
+		if err := s.start(); err != nil {
+			t.Fatal("Builder.StartXXX() =", err)
+		}
+		for _, r := range s.resources {
+			if err := b.AResource(r.Header, *r.Body.(*AResource)); err != nil {
+				t.Fatalf("Builder.AResource(%#v) = %v", r, err)
+			}
+		}
+	}
+
+	got, err := b.FinishTCP()
+	if err != nil {
+		t.Fatal("Builder.FinishTCP() =", err)
+	}
+	if !bytes.HasPrefix(got, []byte("prefix")) {
+		t.Fatalf("got %q, want initial buffer %q preserved", got[:6], "prefix")
+	}
+	r := bytes.NewReader(got[6:])
+	m, err := readTCPMessage(r)
+	if err != nil {
+		t.Fatal("readTCPMessage() =", err)
+	}
+	if !bytes.Equal(m, want) || r.Len() != 0 {
+		t.Errorf("got from Builder.FinishTCP() = %#v\nwant = %#v", got[6:], want)
+	}
+}
+
+func TestPackTCPTooLong(t *testing.T) {
+	msg := Message{}
+	txt := &TXTResource{[]string{strings.Repeat(".", 255)}}
+	for i := 0; i < 300; i++ {
+		msg.Answers = append(msg.Answers, Resource{
+			Header: ResourceHeader{
+				Name:  MustNewName("foo.bar.example.com."),
+				Type:  TypeTXT,
+				Class: ClassINET,
+			},
+			Body: txt,
+		})
+	}
+	if _, err := msg.Pack(); err != nil {
+		t.Fatal("Message.Pack() =", err)
+	}
+	if _, err := msg.PackTCP(); err != errTCPMsgTooLong {
+		t.Errorf("got Message.PackTCP() = %v, want = %v", err, errTCPMsgTooLong)
+	}
+}
+
+func httpsTestMsg() Message {
+	name := MustNewName("example.com.")
+	return Message{
+		Header: Header{Response: true, Authoritative: true},
+		Questions: []Question{
+			{
+				Name:  name,
+				Type:  TypeHTTPS,
+				Class: ClassINET,
+			},
+		},
+		Answers: []Resource{
+			{
+				ResourceHeader{
+					Name:  name,
+					Type:  TypeHTTPS,
+					Class: ClassINET,
+					TTL:   300,
+				},
+				&HTTPSResource{SVCBResource{
+					Priority: 1,
+					Target:   MustNewName("."),
+					Params: []SVCParam{
+						{Key: SVCParamALPN, Value: []byte("\x02h3\x02h2")},
+						{Key: SVCParamPort, Value: []byte{0x01, 0xbb}},
+						{Key: SVCParamIPv4Hint, Value: []byte{192, 0, 2, 1, 192, 0, 2, 2}},
+					},
+				}},
+			},
+			{
+				ResourceHeader{
+					Name:  name,
+					Type:  TypeSVCB,
+					Class: ClassINET,
+					TTL:   300,
+				},
+				&SVCBResource{
+					Priority: 0,
+					Target:   MustNewName("svc.example.net."),
+					Params:   []SVCParam{},
+				},
+			},
+		},
+		Authorities: []Resource{},
+		Additionals: []Resource{},
+	}
+}
+
+func TestAdjustTTL(t *testing.T) {
+	name := MustNewName("example.com.")
+	var opt ResourceHeader
+	if err := opt.SetEDNS0(4096, RCode(0xff0), true); err != nil {
+		t.Fatalf("ResourceHeader.SetEDNS0() = %v", err)
+	}
+	optTTL := opt.TTL
+	opt.Name = MustNewName(".")
+	a := func(ttl uint32) Resource {
+		return Resource{
+			ResourceHeader{Name: name, Type: TypeA, Class: ClassINET, TTL: ttl},
+			&AResource{[4]byte{127, 0, 0, 1}},
+		}
+	}
+	msg := Message{
+		Header:      Header{Response: true},
+		Questions:   []Question{{Name: name, Type: TypeA, Class: ClassINET}},
+		Answers:     []Resource{a(300), a(10)},
+		Authorities: []Resource{a(3600)},
+		Additionals: []Resource{a(60), {opt, &OPTResource{}}},
+	}
+
+	// AdjustTTL updates msg, so each test builds on the previous one.
+	tests := []struct {
+		delta int64
+		want  []uint32 // answers, authorities, then additionals
+	}{
+		{-30, []uint32{270, 0, 3570, 30, optTTL}},
+		{-1 << 40, []uint32{0, 0, 0, 0, optTTL}},
+		{100, []uint32{100, 100, 100, 100, optTTL}},
+		{1 << 40, []uint32{1<<31 - 1, 1<<31 - 1, 1<<31 - 1, 1<<31 - 1, optTTL}},
+	}
+	for _, tt := range tests {
+		b, err := msg.AdjustTTL(tt.delta)
+		if err != nil {
+			t.Fatalf("Message.AdjustTTL(%d) = %v", tt.delta, err)
+		}
+		var got Message
+		if err := got.Unpack(b); err != nil {
+			t.Fatalf("Message.Unpack() = %v", err)
+		}
+		var ttls []uint32
+		for _, rs := range [][]Resource{got.Answers, got.Authorities, got.Additionals} {
+			for _, r := range rs {
+				ttls = append(ttls, r.Header.TTL)
+			}
+		}
+		if !reflect.DeepEqual(ttls, tt.want) {
+			t.Errorf("Message.AdjustTTL(%d): got TTLs %v, want %v", tt.delta, ttls, tt.want)
+		}
+		h := got.Additionals[1].Header
+		if !h.DNSSECAllowed() || h.ExtendedRCode(RCodeSuccess) != RCode(0xff0) {
+			t.Errorf("Message.AdjustTTL(%d): OPT record flags changed: %s", tt.delta, h.GoString())
+		}
+	}
+
+	// TTLs with the most significant bit set are treated as zero.
+	msg.Answers[0].Header.TTL = 1<<32 - 1
+	msg.Answers[1].Header.TTL = 1 << 31
+	if _, err := msg.AdjustTTL(10); err != nil {
+		t.Fatalf("Message.AdjustTTL(10) = %v", err)
+	}
+	for i, r := range msg.Answers {
+		if got, want := r.Header.TTL, uint32(10); got != want {
+			t.Errorf("Message.AdjustTTL(10): got TTL %d for answer %d, want %d", got, i, want)
+		}
+	}
+}
+
+func TestSVCBPackUnpack(t *testing.T) {
+	want := httpsTestMsg()
+	buf, err := want.Pack()
+	if err != nil {
+		t.Fatal("Message.Pack() =", err)
+	}
+	var got Message
+	if err := got.Unpack(buf); err != nil {
+		t.Fatal("Message.Unpack() =", err)
+	}
+	// Unpacking produces nil rather than empty Params.
+	got.Answers[1].Body.(*SVCBResource).Params = []SVCParam{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Message.Pack/Unpack() roundtrip: got = %#v, want = %#v", got, want)
+	}
+
+	var p Parser
+	if _, err := p.Start(buf); err != nil {
+		t.Fatal("Parser.Start() =", err)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		t.Fatal("Parser.SkipAllQuestions() =", err)
+	}
+	if _, err := p.AnswerHeader(); err != nil {
+		t.Fatal("Parser.AnswerHeader() =", err)
+	}
+	if _, err := p.SVCBResource(); err != ErrNotStarted {
+		t.Errorf("Parser.SVCBResource() on an HTTPS record = %v, want = %v", err, ErrNotStarted)
+	}
+	https, err := p.HTTPSResource()
+	if err != nil {
+		t.Fatal("Parser.HTTPSResource() =", err)
+	}
+	if alpn, ok := https.GetParam(SVCParamALPN); !ok || string(alpn) != "\x02h3\x02h2" {
+		t.Errorf("got alpn = %q, %t, want = %q, true", alpn, ok, "\x02h3\x02h2")
+	}
+	if hint, ok := https.GetParam(SVCParamIPv4Hint); !ok || !bytes.Equal(hint, []byte{192, 0, 2, 1, 192, 0, 2, 2}) {
+		t.Errorf("got ipv4hint = %v, %t, want = [192 0 2 1 192 0 2 2], true", hint, ok)
+	}
+	if _, ok := https.GetParam(SVCParamECH); ok {
+		t.Error("got ech param, want none")
+	}
+	if _, err := p.AnswerHeader(); err != nil {
+		t.Fatal("Parser.AnswerHeader() =", err)
+	}
+	svcb, err := p.SVCBResource()
+	if err != nil {
+		t.Fatal("Parser.SVCBResource() =", err)
+	}
+	if svcb.Priority != 0 || svcb.Target.String() != "svc.example.net." || len(svcb.Params) != 0 {
+		t.Errorf("got %#v", &svcb)
+	}
+}
+
+func TestSVCBBuilder(t *testing.T) {
+	msg := httpsTestMsg()
+	want, err := msg.Pack()
+	if err != nil {
+		t.Fatal("Message.Pack() =", err)
+	}
+
+	b := NewBuilder(nil, msg.Header)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		t.Fatal("Builder.StartQuestions() =", err)
+	}
+	if err := b.Question(msg.Questions[0]); err != nil {
+		t.Fatal("Builder.Question() =", err)
+	}
+	if err := b.StartAnswers(); err != nil {
+		t.Fatal("Builder.StartAnswers() =", err)
+	}
+	if err := b.HTTPSResource(msg.Answers[0].Header, *msg.Answers[0].Body.(*HTTPSResource)); err != nil {
+		t.Fatal("Builder.HTTPSResource() =", err)
+	}
+	if err := b.SVCBResource(msg.Answers[1].Header, *msg.Answers[1].Body.(*SVCBResource)); err != nil {
+		t.Fatal("Builder.SVCBResource() =", err)
+	}
+	got, err := b.Finish()
+	if err != nil {
+		t.Fatal("Builder.Finish() =", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got from Builder.Finish() = %#v\nwant = %#v", got, want)
+	}
+}
+
+func TestSVCBParams(t *testing.T) {
+	var r SVCBResource
+	r.SetParam(SVCParamIPv4Hint, []byte{192, 0, 2, 1})
+	r.SetParam(SVCParamALPN, []byte("\x02h3"))
+	r.SetParam(SVCParamPort, []byte{0x01, 0xbb})
+	r.SetParam(SVCParamALPN, []byte("\x02h2"))
+	want := []SVCParam{
+		{SVCParamALPN, []byte("\x02h2")},
+		{SVCParamPort, []byte{0x01, 0xbb}},
+		{SVCParamIPv4Hint, []byte{192, 0, 2, 1}},
+	}
+	if !reflect.DeepEqual(r.Params, want) {
+		t.Fatalf("got Params = %#v, want = %#v", r.Params, want)
+	}
+	if !r.DeleteParam(SVCParamPort) || r.DeleteParam(SVCParamPort) {
+		t.Error("DeleteParam(SVCParamPort) did not report the param as present only once")
+	}
+	if _, ok := r.GetParam(SVCParamPort); ok {
+		t.Error("got port param after deleting it")
+	}
+
+	// Params must be in strictly increasing key order.
+	r.Target = MustNewName(".")
+	r.Params = []SVCParam{{SVCParamPort, nil}, {SVCParamALPN, nil}}
+	if _, err := r.pack(nil, nil, 0); !checkErrorPrefix(err, "SVCBResource.Params") {
+		t.Errorf("got SVCBResource.pack() = %v, want = %v", err, errParamOutOfOrder)
+	}
+	buf := []byte{
+		0x00, 0x01, // priority
+		0x00,                   // target
+		0x00, 0x03, 0x00, 0x00, // port
+		0x00, 0x01, 0x00, 0x00, // alpn
+	}
+	if _, err := unpackSVCBResource(buf, 0, uint16(len(buf))); !checkErrorPrefix(err, "Params") {
+		t.Errorf("got unpackSVCBResource() = %v, want = %v", err, errParamOutOfOrder)
+	}
+	// A param value may not extend beyond the record.
+	buf = []byte{
+		0x00, 0x01, // priority
+		0x00,                   // target
+		0x00, 0x03, 0x00, 0x02, // port
+		0x01, 0xbb,
+	}
+	if _, err := unpackSVCBResource(buf, 0, uint16(len(buf)-1)); err == nil {
+		t.Error("got unpackSVCBResource() = nil, want error for truncated param value")
+	}
+}
+
+func TestSVCBTypedParams(t *testing.T) {
+	v6 := [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}
+	r := SVCBResource{Priority: 1, Target: MustNewName("svc.example.com.")}
+	r.SetMandatory(SVCParamPort, SVCParamALPN, SVCParamPort)
+	if err := r.SetALPN("h3", "h2"); err != nil {
+		t.Fatal("SVCBResource.SetALPN() =", err)
+	}
+	r.SetNoDefaultALPN(true)
+	r.SetPort(8443)
+	r.SetIPv4Hint([4]byte{192, 0, 2, 1}, [4]byte{192, 0, 2, 2})
+	r.SetECH([]byte{0x00, 0x01, 0xfe})
+	r.SetIPv6Hint(v6)
+
+	buf, err := r.pack(nil, nil, 0)
+	if err != nil {
+		t.Fatal("SVCBResource.pack() =", err)
+	}
+	got, err := unpackSVCBResource(buf, 0, uint16(len(buf)))
+	if err != nil {
+		t.Fatal("unpackSVCBResource() =", err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Fatalf("roundtrip: got = %#v, want = %#v", &got, &r)
+	}
+
+	if keys, ok := got.Mandatory(); !ok || !reflect.DeepEqual(keys, []SVCParamKey{SVCParamALPN, SVCParamPort}) {
+		t.Errorf("got Mandatory() = %v, %t, want = [alpn port], true", keys, ok)
+	}
+	if ids, ok := got.ALPN(); !ok || !reflect.DeepEqual(ids, []string{"h3", "h2"}) {
+		t.Errorf("got ALPN() = %q, %t, want = [h3 h2], true", ids, ok)
+	}
+	if !got.NoDefaultALPN() {
+		t.Error("got NoDefaultALPN() = false, want = true")
+	}
+	if port, ok := got.Port(); !ok || port != 8443 {
+		t.Errorf("got Port() = %d, %t, want = 8443, true", port, ok)
+	}
+	if addrs, ok := got.IPv4Hint(); !ok || !reflect.DeepEqual(addrs, [][4]byte{{192, 0, 2, 1}, {192, 0, 2, 2}}) {
+		t.Errorf("got IPv4Hint() = %v, %t, want = [192.0.2.1 192.0.2.2], true", addrs, ok)
+	}
+	if ech, ok := got.ECH(); !ok || !bytes.Equal(ech, []byte{0x00, 0x01, 0xfe}) {
+		t.Errorf("got ECH() = %v, %t, want = [0 1 254], true", ech, ok)
+	}
+	if addrs, ok := got.IPv6Hint(); !ok || !reflect.DeepEqual(addrs, [][16]byte{v6}) {
+		t.Errorf("got IPv6Hint() = %v, %t, want = [2001:db8::1], true", addrs, ok)
+	}
+
+	got.SetNoDefaultALPN(false)
+	if got.NoDefaultALPN() {
+		t.Error("got NoDefaultALPN() = true after removing it")
+	}
+	var empty SVCBResource
+	if _, ok := empty.Port(); ok {
+		t.Error("got port of a record without params")
+	}
+	if err := empty.SetALPN("h2", ""); err == nil {
+		t.Error("SetALPN() with an empty protocol ID succeeded")
+	}
+}
+
+func TestSVCBParamsCheckedOnPack(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []SVCParam
+	}{
+		{"mandatory odd length", []SVCParam{{SVCParamMandatory, []byte{0x00}}}},
+		{"mandatory lists itself", []SVCParam{{SVCParamMandatory, []byte{0x00, 0x00}}}},
+		{"mandatory out of order", []SVCParam{{SVCParamMandatory, []byte{0x00, 0x03, 0x00, 0x01}}, {SVCParamALPN, []byte("\x02h2")}, {SVCParamPort, []byte{0x01, 0xbb}}}},
+		{"mandatory key missing", []SVCParam{{SVCParamMandatory, []byte{0x00, 0x03}}}},
+		{"alpn empty", []SVCParam{{SVCParamALPN, nil}}},
+		{"alpn truncated", []SVCParam{{SVCParamALPN, []byte("\x03h2")}}},
+		{"alpn empty id", []SVCParam{{SVCParamALPN, []byte("\x02h2\x00")}}},
+		{"no-default-alpn with value", []SVCParam{{SVCParamALPN, []byte("\x02h2")}, {SVCParamNoDefaultALPN, []byte{0}}}},
+		{"no-default-alpn without alpn", []SVCParam{{SVCParamNoDefaultALPN, nil}}},
+		{"port too short", []SVCParam{{SVCParamPort, []byte{0x01}}}},
+		{"ipv4hint empty", []SVCParam{{SVCParamIPv4Hint, nil}}},
+		{"ipv4hint partial", []SVCParam{{SVCParamIPv4Hint, []byte{192, 0, 2}}}},
+		{"ech empty", []SVCParam{{SVCParamECH, nil}}},
+		{"ipv6hint partial", []SVCParam{{SVCParamIPv6Hint, make([]byte, 15)}}},
+	}
+	for _, tt := range tests {
+		r := SVCBResource{Priority: 1, Target: MustNewName("."), Params: tt.params}
+		if _, err := r.pack(nil, nil, 0); !checkErrorPrefix(err, "SVCBResource.Params") {
+			t.Errorf("%s: got SVCBResource.pack() = %v, want an SVCBResource.Params error", tt.name, err)
+		}
+	}
+
+	// Keys this package does not know are not checked.
+	r := SVCBResource{Priority: 1, Target: MustNewName("."), Params: []SVCParam{{SVCParamKey(65000), nil}}}
+	if _, err := r.pack(nil, nil, 0); err != nil {
+		t.Errorf("got SVCBResource.pack() = %v with an unknown key, want = nil", err)
+	}
+}
+
+// The DNSSEC records below are the examples of RFC 4034 and RFC 5155.
+
+func mustDecodeBase64(s string) []byte {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func dnssecTime(s string) uint32 {
+	t, err := time.Parse("20060102150405", s)
+	if err != nil {
+		panic(err)
+	}
+	return uint32(t.Unix())
+}
+
+var (
+	// example.com. 86400 IN DNSKEY 256 3 5 (...)
+	testDNSKEY = DNSKEYResource{
+		Flags:     256,
+		Protocol:  3,
+		Algorithm: 5,
+		PublicKey: mustDecodeBase64("AQPSKmynfzW4kyBv015MUG2DeIQ3" +
+			"Cbl+BBZH4b/0PY1kxkmvHjcZc8no" +
+			"kfzj31GajIQKY+5CptLr3buXA10h" +
+			"WqTkF7H6RfoRqXQeogmMHfpftf6z" +
+			"Mv1LyBUgia7za6ZEzOJBOztyvhjL" +
+			"742iU/TpPSEDhm2SNKLijfUppn1U" +
+			"aNvv4w=="),
+	}
+
+	// dskey.example.com. 86400 IN DNSKEY 256 3 5 (...)
+	testDSKEY = DNSKEYResource{
+		Flags:     256,
+		Protocol:  3,
+		Algorithm: 5,
+		PublicKey: mustDecodeBase64("AQOeiiR0GOMYkDshWoSKz9Xz" +
+			"fwJr1AYtsmx3TGkJaNXVbfi/" +
+			"2pHm822aJ5iI9BMzNXxeYCmZ" +
+			"DRD99WYwYqUSdjMmmAphXdvx" +
+			"egXd/M5+X7OrzKBaMbCVdFLU" +
+			"Uh6DhweJBjEVv5f2wwjM9Xzc" +
+			"nOf+EPbtG9DMBmADjFDc2w/r" +
+			"ljwvFw=="),
+	}
+
+	// dskey.example.com. 86400 IN DS 60485 5 1 (...)
+	testDS = DSResource{
+		KeyTag:     60485,
+		Algorithm:  5,
+		DigestType: 1,
+		Digest:     mustDecodeHex("2bb183af5f22588179a53b0a98631fad1a292118"),
+	}
+
+	// host.example.com. 86400 IN RRSIG A 5 3 86400 20030322173103 (...)
+	testRRSIG = RRSIGResource{
+		TypeCovered: TypeA,
+		Algorithm:   5,
+		Labels:      3,
+		OriginalTTL: 86400,
+		Expiration:  dnssecTime("20030322173103"),
+		Inception:   dnssecTime("20030220173103"),
+		KeyTag:      2642,
+		SignerName:  MustNewName("example.com."),
+		Signature: mustDecodeBase64("oJB1W6WNGv+ldvQ3WDG0MQkg5IEhjRip8WTr" +
+			"PYGv07h108dUKGMeDPKijVCHX3DDKdfb+v6o" +
+			"B9wfuh3DTJXUAfI/M0zmO/zz8bW0Rznl8O3t" +
+			"GNazPwQKkRN20XPXV6nwwfoXmJQbsLNrLfkG" +
+			"J5D6fwFm8nN+6pBzeDQfsS3Ap3o="),
+	}
+
+	// alfa.example.com. 86400 IN NSEC host.example.com. (
+	//     A MX RRSIG NSEC TYPE1234 )
+	testNSEC = NSECResource{
+		NextDomain: MustNewName("host.example.com."),
+		Types:      []Type{TypeA, TypeMX, TypeRRSIG, TypeNSEC, 1234},
+	}
+
+	// 0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example. NSEC3 1 1 12 aabbccdd (
+	//     2t7b4g4vsa5smi47k61mv5bv1a22bojr MX DNSKEY NS
+	//     SOA NSEC3PARAM RRSIG )
+	testNSEC3 = NSEC3Resource{
+		HashAlgorithm: 1,
+		Flags:         1,
+		Iterations:    12,
+		Salt:          mustDecodeHex("aabbccdd"),
+		NextHashedOwner: func() []byte {
+			b, err := base32.HexEncoding.DecodeString(strings.ToUpper("2t7b4g4vsa5smi47k61mv5bv1a22bojr"))
+			if err != nil {
+				panic(err)
+			}
+			return b
+		}(),
+		Types: []Type{TypeNS, TypeSOA, TypeMX, TypeRRSIG, TypeDNSKEY, 51},
+	}
+)
+
+func dnssecTestMsg() Message {
+	hdr := func(name string, typ Type) ResourceHeader {
+		return ResourceHeader{Name: MustNewName(name), Type: typ, Class: ClassINET, TTL: 86400}
+	}
+	dnskey, dskey, ds, rrsig, nsec, nsec3 := testDNSKEY, testDSKEY, testDS, testRRSIG, testNSEC, testNSEC3
+	return Message{
+		Header:    Header{Response: true, Authoritative: true},
+		Questions: []Question{},
+		Answers: []Resource{
+			{hdr("example.com.", TypeDNSKEY), &dnskey},
+			{hdr("dskey.example.com.", TypeDNSKEY), &dskey},
+			{hdr("dskey.example.com.", TypeDS), &ds},
+			{hdr("host.example.com.", TypeRRSIG), &rrsig},
+			{hdr("alfa.example.com.", TypeNSEC), &nsec},
+			{hdr("0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example.", TypeNSEC3), &nsec3},
+		},
+		Authorities: []Resource{},
+		Additionals: []Resource{},
+	}
+}
+
+func TestDNSSECPackUnpack(t *testing.T) {
+	want := dnssecTestMsg()
+	buf, err := want.Pack()
+	if err != nil {
+		t.Fatal("Message.Pack() =", err)
+	}
+	var got Message
+	if err := got.Unpack(buf); err != nil {
+		t.Fatal("Message.Unpack() =", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Message.Pack/Unpack() roundtrip: got = %#v, want = %#v", got, want)
+	}
+
+	b := NewBuilder(nil, want.Header)
+	b.EnableCompression()
+	if err := b.StartAnswers(); err != nil {
+		t.Fatal("Builder.StartAnswers() =", err)
+	}
+	for _, r := range want.Answers {
+		var err error
+		switch body := r.Body.(type) {
+		case *DNSKEYResource:
+			err = b.DNSKEYResource(r.Header, *body)
+		case *DSResource:
+			err = b.DSResource(r.Header, *body)
+		case *RRSIGResource:
+			err = b.RRSIGResource(r.Header, *body)
+		case *NSECResource:
+			err = b.NSECResource(r.Header, *body)
+		case *NSEC3Resource:
+			err = b.NSEC3Resource(r.Header, *body)
+		}
+		if err != nil {
+			t.Fatalf("Builder: adding %#v = %v", r.Body, err)
+		}
+	}
+	built, err := b.Finish()
+	if err != nil {
+		t.Fatal("Builder.Finish() =", err)
+	}
+	if !bytes.Equal(built, buf) {
+		t.Errorf("Builder output differs from Message.Pack():\ngot  = %v\nwant = %v", built, buf)
+	}
+}
+
+func TestDNSSECParser(t *testing.T) {
+	msg := dnssecTestMsg()
+	buf, err := msg.Pack()
+	if err != nil {
+		t.Fatal("Message.Pack() =", err)
+	}
+	var p Parser
+	if _, err := p.Start(buf); err != nil {
+		t.Fatal("Parser.Start() =", err)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		t.Fatal("Parser.SkipAllQuestions() =", err)
+	}
+	next := func() {
+		t.Helper()
+		if _, err := p.AnswerHeader(); err != nil {
+			t.Fatal("Parser.AnswerHeader() =", err)
+		}
+	}
+
+	next()
+	if _, err := p.DSResource(); err != ErrNotStarted {
+		t.Errorf("Parser.DSResource() on a DNSKEY record = %v, want = %v", err, ErrNotStarted)
+	}
+	dnskey, err := p.DNSKEYResource()
+	if err != nil {
+		t.Fatal("Parser.DNSKEYResource() =", err)
+	}
+	if got := dnskey.KeyTag(); got != testRRSIG.KeyTag {
+		t.Errorf("DNSKEY key tag = %d, want = %d", got, testRRSIG.KeyTag)
+	}
+
+	next()
+	dskey, err := p.DNSKEYResource()
+	if err != nil {
+		t.Fatal("Parser.DNSKEYResource() =", err)
+	}
+	next()
+	ds, err := p.DSResource()
+	if err != nil {
+		t.Fatal("Parser.DSResource() =", err)
+	}
+	if got := dskey.KeyTag(); got != ds.KeyTag {
+		t.Errorf("DNSKEY key tag = %d, want = %d", got, ds.KeyTag)
+	}
+	// The digest is computed over the canonical owner name and the
+	// DNSKEY RDATA (RFC 4034, Section 5.1.4).
+	owner := MustNewName("dskey.example.com.")
+	data, err := owner.pack(nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = dskey.pack(data, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := sha1.Sum(data); !bytes.Equal(got[:], ds.Digest) {
+		t.Errorf("DS digest = %x, want = %x", got, ds.Digest)
+	}
+
+	next()
+	rrsig, err := p.RRSIGResource()
+	if err != nil {
+		t.Fatal("Parser.RRSIGResource() =", err)
+	}
+	if !reflect.DeepEqual(rrsig, testRRSIG) {
+		t.Errorf("got %#v, want = %#v", &rrsig, &testRRSIG)
+	}
+
+	next()
+	nsec, err := p.NSECResource()
+	if err != nil {
+		t.Fatal("Parser.NSECResource() =", err)
+	}
+	if !reflect.DeepEqual(nsec, testNSEC) {
+		t.Errorf("got %#v, want = %#v", &nsec, &testNSEC)
+	}
+
+	next()
+	nsec3, err := p.NSEC3Resource()
+	if err != nil {
+		t.Fatal("Parser.NSEC3Resource() =", err)
+	}
+	if !reflect.DeepEqual(nsec3, testNSEC3) {
+		t.Errorf("got %#v, want = %#v", &nsec3, &testNSEC3)
+	}
+}
+
+func TestNSECWireFormat(t *testing.T) {
+	// RFC 4034, Section 4.3.
+	want := []byte{
+		0x04, 'h', 'o', 's', 't',
+		0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e',
+		0x03, 'c', 'o', 'm', 0x00,
+		0x00, 0x06, 0x40, 0x01, 0x00, 0x00, 0x00, 0x03,
+		0x04, 0x1b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x20,
+	}
+	got, err := testNSEC.pack(nil, map[string]int{}, 0)
+	if err != nil {
+		t.Fatal("NSECResource.pack() =", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("NSECResource.pack() = %#v, want = %#v", got, want)
+	}
+	r, err := unpackNSECResource(want, 0, uint16(len(want)))
+	if err != nil {
+		t.Fatal("unpackNSECResource() =", err)
+	}
+	if !reflect.DeepEqual(r, testNSEC) {
+		t.Errorf("unpackNSECResource() = %#v, want = %#v", &r, &testNSEC)
+	}
+}
+
+func TestTypeBitmapErrors(t *testing.T) {
+	for _, types := range [][]Type{
+		{TypeMX, TypeA},
+		{TypeA, TypeA},
+		{1234, TypeRRSIG},
+	} {
+		if _, err := packTypeBitmap(nil, types); err != errTypesOutOfOrder {
+			t.Errorf("packTypeBitmap(%v) = %v, want = %v", types, err, errTypesOutOfOrder)
+		}
+	}
+	for _, tt := range []struct {
+		bitmap []byte
+		want   error
+	}{
+		{[]byte{0x00}, errBaseLen},
+		{[]byte{0x00, 0x02, 0x40}, errBaseLen},
+		{[]byte{0x00, 0x00}, errInvalidTypeBitmap},
+		{[]byte{0x00, 0x21}, errInvalidTypeBitmap},
+		{[]byte{0x01, 0x01, 0x40, 0x00, 0x01, 0x40}, errInvalidTypeBitmap},
+	} {
+		if _, err := unpackTypeBitmap(tt.bitmap, 0); err != tt.want {
+			t.Errorf("unpackTypeBitmap(%v) = %v, want = %v", tt.bitmap, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mdns implements multicast DNS queries as specified in RFC
+// 6762.
+//
+// A Conn joins the mDNS group on a network interface. Its Query method
+// sends questions to the group and collects the records of the
+// responses. ReadMessage and WriteMessage provide access to the raw
+// messages, for instance to answer queries.
+package mdns // import "golang.org/x/net/dns/mdns"
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Port is the UDP port used by multicast DNS.
+const Port = 5353
+
+// The multicast groups of multicast DNS.
+var (
+	IPv4Group = net.IPv4(224, 0, 0, 251)
+	IPv6Group = net.ParseIP("ff02::fb")
+)
+
+const (
+	// UnicastResponse is the bit of the class of a question that
+	// requests a unicast response, the "QU" bit (RFC 6762, section
+	// 5.4). Questions without it are "QM" questions.
+	UnicastResponse dnsmessage.Class = 1 << 15
+
+	// CacheFlush is the bit of the class of a resource record that
+	// marks the record as unique to its responder, the "cache-flush"
+	// bit (RFC 6762, section 10.2).
+	CacheFlush dnsmessage.Class = 1 << 15
+)
+
+// maxMessageLen is the largest mDNS message, including the IP and UDP
+// headers (RFC 6762, section 17).
+const maxMessageLen = 9000
+
+// aLongTimeAgo is a non-zero time, far in the past, used for
+// immediate cancelation of pending reads.
+var aLongTimeAgo = time.Unix(1, 0)
+
+var errInvalidConn = errors.New("invalid connection")
+
+// A Conn is a multicast DNS endpoint on a network interface.
+type Conn struct {
+	c     net.PacketConn
+	p4    *ipv4.PacketConn
+	p6    *ipv6.PacketConn
+	group *net.UDPAddr
+}
+
+func (c *Conn) ok() bool { return c != nil && c.c != nil }
+
+// Listen listens on the mDNS port and joins the mDNS group of network,
+// which must be "udp4" or "udp6", on the interface ifi. If ifi is nil,
+// the system chooses the interface.
+//
+// The port is shared with other mDNS endpoints on the host, so
+// unicast responses may be delivered to another one of them.
+func Listen(network string, ifi *net.Interface) (*Conn, error) {
+	var group net.IP
+	switch network {
+	case "udp4":
+		group = IPv4Group
+	case "udp6":
+		group = IPv6Group
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) { serr = setReuseAddr(fd) }); err != nil {
+				return err
+			}
+			return serr
+		},
+	}
+	c, err := lc.ListenPacket(context.Background(), network, net.JoinHostPort("", strconv.Itoa(Port)))
+	if err != nil {
+		return nil, err
+	}
+	mc := &Conn{c: c, group: &net.UDPAddr{IP: group, Port: Port}}
+	if ifi != nil && network == "udp6" {
+		mc.group.Zone = ifi.Name
+	}
+	if err := mc.join(network, ifi); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return mc, nil
+}
+
+// join joins the mDNS group and sets up the sending of multicast
+// messages on ifi, with the hop limit of 255 that RFC 6762, section 11
+// requires.
+func (c *Conn) join(network string, ifi *net.Interface) error {
+	if network == "udp4" {
+		c.p4 = ipv4.NewPacketConn(c.c)
+		if err := c.p4.JoinGroup(ifi, c.group); err != nil {
+			return err
+		}
+		if ifi != nil {
+			if err := c.p4.SetMulticastInterface(ifi); err != nil {
+				return err
+			}
+		}
+		if err := c.p4.SetMulticastTTL(255); err != nil {
+			return err
+		}
+		return c.p4.SetMulticastLoopback(true)
+	}
+	c.p6 = ipv6.NewPacketConn(c.c)
+	if err := c.p6.JoinGroup(ifi, c.group); err != nil {
+		return err
+	}
+	if ifi != nil {
+		if err := c.p6.SetMulticastInterface(ifi); err != nil {
+			return err
+		}
+	}
+	if err := c.p6.SetMulticastHopLimit(255); err != nil {
+		return err
+	}
+	return c.p6.SetMulticastLoopback(true)
+}
+
+// Close leaves the mDNS group and closes the endpoint.
+func (c *Conn) Close() error {
+	if !c.ok() {
+		return errInvalidConn
+	}
+	return c.c.Close()
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	if !c.ok() {
+		return nil
+	}
+	return c.c.LocalAddr()
+}
+
+// SetReadDeadline sets the read deadline associated with the
+// endpoint.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	if !c.ok() {
+		return errInvalidConn
+	}
+	return c.c.SetReadDeadline(t)
+}
+
+// ReadMessage reads the next message received by c, which may be a
+// query or a response, and returns it with its source address.
+// Datagrams that are not valid DNS messages are skipped.
+func (c *Conn) ReadMessage() (*dnsmessage.Message, net.Addr, error) {
+	if !c.ok() {
+		return nil, nil, errInvalidConn
+	}
+	b := make([]byte, maxMessageLen)
+	for {
+		n, src, err := c.c.ReadFrom(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		var m dnsmessage.Message
+		if err := m.Unpack(b[:n]); err != nil {
+			continue
+		}
+		return &m, src, nil
+	}
+}
+
+// WriteMessage sends m to dst, or to the mDNS group if dst is nil.
+func (c *Conn) WriteMessage(m *dnsmessage.Message, dst net.Addr) error {
+	if !c.ok() {
+		return errInvalidConn
+	}
+	b, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	if dst == nil {
+		dst = c.group
+	}
+	_, err = c.c.WriteTo(b, dst)
+	return err
+}
+
+// Query sends a query for questions to the mDNS group and collects the
+// records of the answer and additional sections of the responses until
+// ctx is done. If unicast is true, the UnicastResponse bit is set in
+// the class of each question, asking responders to reply directly
+// rather than to the group.
+//
+// Each distinct record is returned once, the first time it is seen.
+// Records are compared by name, type, class and data; the CacheFlush
+// bit of the class is ignored for this purpose but kept in the
+// returned records.
+//
+// Query returns the records collected so far when ctx is done. It uses
+// the read deadline of c, which is cleared on return, so c must not be
+// read concurrently.
+func (c *Conn) Query(ctx context.Context, questions []dnsmessage.Question, unicast bool) ([]dnsmessage.Resource, error) {
+	if !c.ok() {
+		return nil, errInvalidConn
+	}
+	// RFC 6762, section 18 requires a zero ID and no flags in
+	// multicast queries.
+	q := dnsmessage.Message{Questions: make([]dnsmessage.Question, len(questions))}
+	for i, question := range questions {
+		if unicast {
+			question.Class |= UnicastResponse
+		}
+		q.Questions[i] = question
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// The read deadline is only set once ctx is done, so that a read
+	// error can be told apart from the end of the query.
+	if ctx.Done() != nil {
+		stop, stopped := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(stopped)
+			select {
+			case <-ctx.Done():
+				c.SetReadDeadline(aLongTimeAgo)
+			case <-stop:
+			}
+		}()
+		defer func() {
+			close(stop)
+			<-stopped
+			c.SetReadDeadline(time.Time{})
+		}()
+	}
+
+	if err := c.WriteMessage(&q, nil); err != nil {
+		return nil, err
+	}
+	var rrs []dnsmessage.Resource
+	seen := make(map[string]bool)
+	for {
+		m, _, err := c.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return rrs, nil
+			}
+			return rrs, err
+		}
+		if !m.Header.Response || m.Header.OpCode != 0 || m.Header.RCode != dnsmessage.RCodeSuccess {
+			continue
+		}
+		for _, rs := range [][]dnsmessage.Resource{m.Answers, m.Additionals} {
+			for _, r := range rs {
+				if r.Header.Type == dnsmessage.TypeOPT {
+					continue
+				}
+				k := recordKey(r)
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				rrs = append(rrs, r)
+			}
+		}
+	}
+}
+
+// recordKey returns a string identifying the name, type, class and
+// data of r. Names are compared case-insensitively, including those in
+// the data.
+func recordKey(r dnsmessage.Resource) string {
+	h := r.Header
+	h.Name = h.Name.Canonical()
+	h.Class &^= CacheFlush
+	h.TTL = 0
+	h.Length = 0
+	return h.GoString() + canonicalBody(r.Body).GoString()
+}
+
+// canonicalBody returns a copy of b with the names it holds in canonical
+// form, or b itself if it holds none.
+func canonicalBody(b dnsmessage.ResourceBody) dnsmessage.ResourceBody {
+	switch b := b.(type) {
+	case *dnsmessage.CNAMEResource:
+		c := *b
+		c.CNAME = c.CNAME.Canonical()
+		return &c
+	case *dnsmessage.MXResource:
+		c := *b
+		c.MX = c.MX.Canonical()
+		return &c
+	case *dnsmessage.NSResource:
+		c := *b
+		c.NS = c.NS.Canonical()
+		return &c
+	case *dnsmessage.PTRResource:
+		c := *b
+		c.PTR = c.PTR.Canonical()
+		return &c
+	case *dnsmessage.SOAResource:
+		c := *b
+		c.NS = c.NS.Canonical()
+		c.MBox = c.MBox.Canonical()
+		return &c
+	case *dnsmessage.SRVResource:
+		c := *b
+		c.Target = c.Target.Canonical()
+		return &c
+	case *dnsmessage.SVCBResource:
+		c := *b
+		c.Target = c.Target.Canonical()
+		return &c
+	case *dnsmessage.HTTPSResource:
+		c := *b
+		c.Target = c.Target.Canonical()
+		return &c
+	case *dnsmessage.NSECResource:
+		c := *b
+		c.NextDomain = c.NextDomain.Canonical()
+		return &c
+	}
+	return b
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mdns_test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/dns/mdns"
+	"golang.org/x/net/nettest"
+)
+
+func TestQuery(t *testing.T) {
+	switch runtime.GOOS {
+	case "fuchsia", "hurd", "illumos", "js", "nacl", "plan9", "solaris", "windows", "zos":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+
+	for _, network := range []string{"udp4", "udp6"} {
+		t.Run(network, func(t *testing.T) {
+			ifi, err := nettest.RoutedInterface("ip"+network[3:], net.FlagUp|net.FlagMulticast|net.FlagLoopback)
+			if err != nil {
+				t.Skip(err)
+			}
+			// The responder listens first so that unicast responses,
+			// which go to the most recent listener on some platforms,
+			// reach the querier.
+			r, err := mdns.Listen(network, ifi)
+			if err != nil {
+				t.Skip(err)
+			}
+			defer r.Close()
+			q, err := mdns.Listen(network, ifi)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer q.Close()
+
+			testQuery(t, r, q)
+		})
+	}
+}
+
+func testQuery(t *testing.T, r, q *mdns.Conn) {
+	name := dnsmessage.MustNewName("gopher.local.")
+	other := dnsmessage.MustNewName("other.local.")
+	a := func(name dnsmessage.Name, class dnsmessage.Class, ip byte) dnsmessage.Resource {
+		return dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA, Class: class, TTL: 120},
+			Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, ip}},
+		}
+	}
+
+	// The responder answers each query for name twice, repeating
+	// records across and within sections.
+	unicasts := make(chan bool, 10)
+	errc := make(chan error, 1)
+	done := make(chan struct{})
+	defer func() {
+		r.Close()
+		<-done
+		select {
+		case err := <-errc:
+			t.Error(err)
+		default:
+		}
+	}()
+	go func() {
+		defer close(done)
+		for {
+			m, src, err := r.ReadMessage()
+			if err != nil {
+				return
+			}
+			if m.Header.Response || len(m.Questions) != 1 || !m.Questions[0].Name.Equal(name) {
+				continue
+			}
+			unicast := m.Questions[0].Class&mdns.UnicastResponse != 0
+			unicasts <- unicast
+			resp := dnsmessage.Message{
+				Header: dnsmessage.Header{Response: true, Authoritative: true},
+				Answers: []dnsmessage.Resource{
+					a(name, dnsmessage.ClassINET|mdns.CacheFlush, 1),
+					a(name, dnsmessage.ClassINET, 1),
+				},
+				Additionals: []dnsmessage.Resource{
+					a(name, dnsmessage.ClassINET|mdns.CacheFlush, 1),
+					a(other, dnsmessage.ClassINET|mdns.CacheFlush, 2),
+				},
+			}
+			dst := src
+			if !unicast {
+				dst = nil
+			}
+			for i := 0; i < 2; i++ {
+				if err := r.WriteMessage(&resp, dst); err != nil {
+					errc <- err
+					return
+				}
+			}
+		}
+	}()
+
+	for _, unicast := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		rrs, err := q.Query(ctx, []dnsmessage.Question{{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}}, unicast)
+		cancel()
+		if err != nil {
+			t.Fatalf("Query(unicast=%t) = %v", unicast, err)
+		}
+		var got []dnsmessage.Resource
+		for _, rr := range rrs {
+			// Skip records of other responders on the link.
+			if rr.Header.Name.Equal(name) || rr.Header.Name.Equal(other) {
+				got = append(got, rr)
+			}
+		}
+		if len(got) != 2 {
+			t.Fatalf("Query(unicast=%t) returned %d records, want 2: %v", unicast, len(got), got)
+		}
+		for i, want := range []dnsmessage.Resource{
+			a(name, dnsmessage.ClassINET|mdns.CacheFlush, 1),
+			a(other, dnsmessage.ClassINET|mdns.CacheFlush, 2),
+		} {
+			if !got[i].Header.Name.Equal(want.Header.Name) || got[i].Header.Class != want.Header.Class || got[i].Body.GoString() != want.Body.GoString() {
+				t.Errorf("Query(unicast=%t) record %d = %s, want %s", unicast, i, got[i].GoString(), want.GoString())
+			}
+		}
+		select {
+		case u := <-unicasts:
+			if u != unicast {
+				t.Errorf("Query(unicast=%t) sent a question with unicast response bit %t", unicast, u)
+			}
+		default:
+			t.Errorf("Query(unicast=%t) did not reach the responder", unicast)
+		}
+	}
+}
+
+func TestRecordKey(t *testing.T) {
+	ptr := func(owner, target string, class dnsmessage.Class) dnsmessage.Resource {
+		return dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(owner), Type: dnsmessage.TypePTR, Class: class, TTL: 120},
+			Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(target)},
+		}
+	}
+	srv := func(target string, port uint16) dnsmessage.Resource {
+		return dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("gopher._http._tcp.local."), Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: 120},
+			Body:   &dnsmessage.SRVResource{Port: port, Target: dnsmessage.MustNewName(target)},
+		}
+	}
+	for _, tt := range []struct {
+		a, b dnsmessage.Resource
+		same bool
+	}{
+		{ptr("_http._tcp.local.", "gopher._http._tcp.local.", dnsmessage.ClassINET), ptr("_HTTP._tcp.local.", "Gopher._HTTP._tcp.local.", dnsmessage.ClassINET|mdns.CacheFlush), true},
+		{ptr("_http._tcp.local.", "gopher._http._tcp.local.", dnsmessage.ClassINET), ptr("_http._tcp.local.", "other._http._tcp.local.", dnsmessage.ClassINET), false},
+		{srv("gopher.local.", 80), srv("GOPHER.local.", 80), true},
+		{srv("gopher.local.", 80), srv("gopher.local.", 8080), false},
+	} {
+		if same := mdns.RecordKey(tt.a) == mdns.RecordKey(tt.b); same != tt.same {
+			t.Errorf("RecordKey(%s) == RecordKey(%s) is %t, want %t", tt.a.GoString(), tt.b.GoString(), same, tt.same)
+		}
+	}
+	// The records themselves are left untouched.
+	r := ptr("_http._tcp.local.", "Gopher._HTTP._tcp.local.", dnsmessage.ClassINET)
+	mdns.RecordKey(r)
+	if got := r.Body.(*dnsmessage.PTRResource).PTR.String(); got != "Gopher._HTTP._tcp.local." {
+		t.Errorf("RecordKey changed the PTR target to %s", got)
+	}
+}
@@ -7,6 +7,7 @@ package html
 import (
 	"bytes"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -183,6 +184,64 @@ func unescape(b []byte, attribute bool) []byte {
 	return b
 }
 
+// validEntities reports whether the character references in b are
+// well-formed. Numeric references must have at least one digit, a
+// terminating semicolon and a valid code point. Named references that are
+// terminated by a semicolon must be known.
+func validEntities(b []byte) bool {
+	for {
+		i := bytes.IndexByte(b, '&')
+		if i < 0 {
+			return true
+		}
+		s := b[i+1:]
+		j := 0
+		if len(s) > 0 && s[0] == '#' {
+			j++
+			base := 10
+			if j < len(s) && (s[j] == 'x' || s[j] == 'X') {
+				base = 16
+				j++
+			}
+			start := j
+			x := 0
+			for ; j < len(s); j++ {
+				var d int
+				c := s[j]
+				if '0' <= c && c <= '9' {
+					d = int(c - '0')
+				} else if base == 16 && 'a' <= c && c <= 'f' {
+					d = int(c-'a') + 10
+				} else if base == 16 && 'A' <= c && c <= 'F' {
+					d = int(c-'A') + 10
+				} else {
+					break
+				}
+				if x <= unicode.MaxRune {
+					x = base*x + d
+				}
+			}
+			if j == start || j == len(s) || s[j] != ';' {
+				return false
+			}
+			if x == 0 || 0xD800 <= x && x <= 0xDFFF || x > unicode.MaxRune {
+				return false
+			}
+		} else {
+			for j < len(s) && ('a' <= s[j] && s[j] <= 'z' || 'A' <= s[j] && s[j] <= 'Z' || '0' <= s[j] && s[j] <= '9') {
+				j++
+			}
+			if j > 0 && j < len(s) && s[j] == ';' {
+				name := string(s[:j+1])
+				if entity[name] == 0 && entity2[name][0] == 0 {
+					return false
+				}
+			}
+		}
+		b = s[j:]
+	}
+}
+
 // lower lower-cases the A-Z bytes in b in-place, so that "aBc" becomes "abc".
 func lower(b []byte) []byte {
 	for i, c := range b {
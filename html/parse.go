@@ -49,6 +49,10 @@ type parser struct {
 	// context is the context element when parsing an HTML fragment
 	// (section 12.4).
 	context *Node
+	// attrOffsets, if non-nil, receives the attribute offsets of the
+	// elements created, and tokAttrOffsets holds those of tok.
+	attrOffsets    AttributeOffsets
+	tokAttrOffsets []int
 }
 
 func (p *parser) top() *Node {
@@ -323,12 +327,26 @@ func (p *parser) addText(text string) {
 
 // addElement adds a child element based on the current token.
 func (p *parser) addElement() {
-	p.addChild(&Node{
+	n := &Node{
 		Type:     ElementNode,
 		DataAtom: p.tok.DataAtom,
 		Data:     p.tok.Data,
 		Attr:     p.tok.Attr,
-	})
+	}
+	if p.attrOffsets != nil && len(p.tokAttrOffsets) > 0 {
+		p.attrOffsets[n] = p.tokAttrOffsets
+	}
+	p.addChild(n)
+}
+
+// clone is like n.clone, but also records the attribute offsets of n
+// for the clone.
+func (p *parser) clone(n *Node) *Node {
+	m := n.clone()
+	if offs, ok := p.attrOffsets[n]; ok {
+		p.attrOffsets[m] = offs
+	}
+	return m
 }
 
 // Section 12.2.4.3.
@@ -407,7 +425,7 @@ func (p *parser) reconstructActiveFormattingElements() {
 	}
 	for {
 		i++
-		clone := p.afe[i].clone()
+		clone := p.clone(p.afe[i])
 		p.addChild(clone)
 		p.afe[i] = clone
 		if i == len(p.afe)-1 {
@@ -843,19 +861,23 @@ func afterHeadIM(p *parser) bool {
 	return false
 }
 
-// copyAttributes copies attributes of src not found on dst to dst.
-func copyAttributes(dst *Node, src Token) {
-	if len(src.Attr) == 0 {
+// copyAttributes copies attributes of the current token not found on dst
+// to dst.
+func (p *parser) copyAttributes(dst *Node) {
+	if len(p.tok.Attr) == 0 {
 		return
 	}
 	attr := map[string]string{}
 	for _, t := range dst.Attr {
 		attr[t.Key] = t.Val
 	}
-	for _, t := range src.Attr {
+	for i, t := range p.tok.Attr {
 		if _, ok := attr[t.Key]; !ok {
 			dst.Attr = append(dst.Attr, t)
 			attr[t.Key] = t.Val
+			if p.attrOffsets != nil {
+				p.attrOffsets[dst] = append(p.attrOffsets[dst], p.tokAttrOffsets[i])
+			}
 		}
 	}
 }
@@ -893,7 +915,7 @@ func inBodyIM(p *parser) bool {
 			if p.oe.contains(a.Template) {
 				return true
 			}
-			copyAttributes(p.oe[0], p.tok)
+			p.copyAttributes(p.oe[0])
 		case a.Base, a.Basefont, a.Bgsound, a.Link, a.Meta, a.Noframes, a.Script, a.Style, a.Template, a.Title:
 			return inHeadIM(p)
 		case a.Body:
@@ -904,7 +926,7 @@ func inBodyIM(p *parser) bool {
 				body := p.oe[1]
 				if body.Type == ElementNode && body.DataAtom == a.Body {
 					p.framesetOK = false
-					copyAttributes(body, p.tok)
+					p.copyAttributes(body)
 				}
 			}
 		case a.Frameset:
@@ -1311,7 +1333,7 @@ func (p *parser) inBodyEndTagFormatting(tagAtom a.Atom, tagName string) {
 				continue
 			}
 			// Step 14.7.
-			clone := node.clone()
+			clone := p.clone(node)
 			p.afe[p.afe.index(node)] = clone
 			p.oe[p.oe.index(node)] = clone
 			node = clone
@@ -1342,7 +1364,7 @@ func (p *parser) inBodyEndTagFormatting(tagAtom a.Atom, tagName string) {
 
 		// Steps 16-18. Reparent nodes from the furthest block's children
 		// to a clone of the formatting element.
-		clone := formattingElement.clone()
+		clone := p.clone(formattingElement)
 		reparentChildren(clone, furthestBlock)
 		furthestBlock.AppendChild(clone)
 
@@ -2274,15 +2296,16 @@ func (p *parser) inForeignContent() bool {
 // parseImpliedToken parses a token as though it had appeared in the parser's
 // input.
 func (p *parser) parseImpliedToken(t TokenType, dataAtom a.Atom, data string) {
-	realToken, selfClosing := p.tok, p.hasSelfClosingToken
+	realToken, realOffsets, selfClosing := p.tok, p.tokAttrOffsets, p.hasSelfClosingToken
 	p.tok = Token{
 		Type:     t,
 		DataAtom: dataAtom,
 		Data:     data,
 	}
+	p.tokAttrOffsets = nil
 	p.hasSelfClosingToken = false
 	p.parseCurrentToken()
-	p.tok, p.hasSelfClosingToken = realToken, selfClosing
+	p.tok, p.tokAttrOffsets, p.hasSelfClosingToken = realToken, realOffsets, selfClosing
 }
 
 // parseCurrentToken runs the current token through the parsing routines
@@ -2318,6 +2341,9 @@ func (p *parser) parse() error {
 		// Read and parse the next token.
 		p.tokenizer.Next()
 		p.tok = p.tokenizer.Token()
+		if p.attrOffsets != nil {
+			p.tokAttrOffsets = p.tokenizer.attrOffsets()
+		}
 		if p.tok.Type == ErrorToken {
 			err = p.tokenizer.Err()
 			if err != nil && err != io.EOF {
@@ -2366,6 +2392,25 @@ func ParseOptionEnableScripting(enable bool) ParseOption {
 	}
 }
 
+// AttributeOffsets maps element nodes to the byte offsets in the input of
+// the keys of their attributes: offsets[n][i] is the offset of n.Attr[i].
+// Elements without attributes from the input have no entry.
+type AttributeOffsets map[*Node][]int
+
+// ParseOptionAttributeOffsets configures the parser to record the offsets
+// of element attributes in offsets.
+//
+// Attributes are always kept in source order, including any duplicates,
+// so together with the offsets they allow a tool to relate every
+// attribute of the tree back to the original markup.
+//
+// By default, offsets are not recorded.
+func ParseOptionAttributeOffsets(offsets AttributeOffsets) ParseOption {
+	return func(p *parser) {
+		p.attrOffsets = offsets
+	}
+}
+
 // ParseWithOptions is like Parse, with options.
 func ParseWithOptions(r io.Reader, opts ...ParseOption) (*Node, error) {
 	p := &parser{
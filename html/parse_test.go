@@ -13,10 +13,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sort"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"golang.org/x/net/html/atom"
 )
@@ -300,6 +302,99 @@ func TestParserWithoutScripting(t *testing.T) {
 	}
 }
 
+func TestParseOptionAttributeOffsets(t *testing.T) {
+	// Reading one byte at a time makes the tokenizer shift its buffer
+	// before reaching the tag.
+	prefix := "<!--" + strings.Repeat("x", 5000) + "-->"
+	tag := `<p data-b="1" id=x data-a='2' ID="dup" data-b=3>`
+	text := prefix + tag + "</p>"
+
+	offsets := AttributeOffsets{}
+	r := iotest.OneByteReader(strings.NewReader(text))
+	doc, err := ParseWithOptions(r, ParseOptionAttributeOffsets(offsets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := findElement(doc, atom.P)
+	if p == nil {
+		t.Fatal("no <p> element")
+	}
+
+	wantAttr := []Attribute{
+		{Key: "data-b", Val: "1"},
+		{Key: "id", Val: "x"},
+		{Key: "data-a", Val: "2"},
+		{Key: "id", Val: "dup"},
+		{Key: "data-b", Val: "3"},
+	}
+	if !reflect.DeepEqual(p.Attr, wantAttr) {
+		t.Errorf("got attributes %v; want %v", p.Attr, wantAttr)
+	}
+	wantOffs := []int{
+		strings.Index(tag, "data-b"),
+		strings.Index(tag, "id"),
+		strings.Index(tag, "data-a"),
+		strings.Index(tag, "ID"),
+		strings.LastIndex(tag, "data-b"),
+	}
+	for i := range wantOffs {
+		wantOffs[i] += len(prefix)
+	}
+	if got := offsets[p]; !reflect.DeepEqual(got, wantOffs) {
+		t.Errorf("got offsets %v; want %v", got, wantOffs)
+	}
+	if len(offsets) != 1 {
+		t.Errorf("got offsets for %d elements; want 1", len(offsets))
+	}
+}
+
+func TestParseOptionAttributeOffsetsCopied(t *testing.T) {
+	// Attributes of a second <body> are added to the first, and the
+	// formatting element <b> is cloned when reconstructed in the <p>.
+	text := `<body class=a><b id=x>1<p>2</b>3</p><body lang=en class=z>`
+	offsets := AttributeOffsets{}
+	doc, err := ParseWithOptions(strings.NewReader(text), ParseOptionAttributeOffsets(offsets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var check func(*Node)
+	check = func(n *Node) {
+		if n.Type == ElementNode && len(n.Attr) > 0 {
+			offs := offsets[n]
+			if len(offs) != len(n.Attr) {
+				t.Errorf("<%s>: got %d offsets for %d attributes", n.Data, len(offs), len(n.Attr))
+				return
+			}
+			for i, a := range n.Attr {
+				if !strings.HasPrefix(text[offs[i]:], a.Key+"="+a.Val) {
+					t.Errorf("<%s> attribute %q: input at offset %d is %.10q", n.Data, a.Key, offs[i], text[offs[i]:])
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			check(c)
+		}
+	}
+	check(doc)
+	if body := findElement(doc, atom.Body); body == nil || len(body.Attr) != 2 {
+		t.Errorf("got <body> %v; want the attributes of both tags", body)
+	}
+}
+
+// findElement returns the first element of n with the given atom, in
+// depth-first order.
+func findElement(n *Node, a atom.Atom) *Node {
+	if n.Type == ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if m := findElement(c, a); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
 // testParseCase tests one test case from the test files. If the test does not
 // pass, it returns an error that explains the failure.
 // text is the HTML to be parsed, want is a dump of the correct parse tree,
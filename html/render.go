@@ -43,11 +43,67 @@ type writer interface {
 // Another example is that the programmatic equivalent of "a<head>b</head>c"
 // becomes "<html><head><head/><body>abc</body></html>".
 func Render(w io.Writer, n *Node) error {
+	return RenderWithOptions(w, n, RenderOptions{})
+}
+
+// VoidStyle specifies how void elements, such as <br>, are rendered.
+type VoidStyle int
+
+const (
+	// VoidSlash renders void elements as "<br/>". This is the style
+	// used by Render.
+	VoidSlash VoidStyle = iota
+	// VoidXHTML renders void elements as "<br />", which is also
+	// understood by XHTML parsers predating XML namespaces.
+	VoidXHTML
+	// VoidHTML renders void elements without a slash, as "<br>".
+	VoidHTML
+)
+
+// AttrQuoteStyle specifies how attribute values are quoted.
+type AttrQuoteStyle int
+
+const (
+	// QuoteDouble quotes attribute values with double quotes. This is
+	// the style used by Render.
+	QuoteDouble AttrQuoteStyle = iota
+	// QuoteSingle quotes attribute values with single quotes.
+	QuoteSingle
+	// QuoteMinimal leaves attribute values unquoted when the HTML
+	// syntax allows it, and uses double quotes otherwise.
+	QuoteMinimal
+)
+
+// RenderOptions configures RenderWithOptions.
+// The zero value renders exactly as Render does.
+type RenderOptions struct {
+	// Indent, if non-empty, pretty-prints the tree: every element,
+	// comment and non-whitespace text node is placed on its own line,
+	// indented by one copy of Indent per level of nesting. Leading and
+	// trailing whitespace of text nodes is dropped, and elements whose
+	// only child is a text node are kept on a single line.
+	//
+	// The contents of elements whose whitespace is significant, such as
+	// <pre>, <textarea> and <script>, are never reformatted. Elsewhere,
+	// pretty-printing changes the whitespace between elements, which
+	// may alter how the document is displayed.
+	Indent string
+
+	// Void specifies how void elements are rendered.
+	Void VoidStyle
+
+	// AttrQuote specifies how attribute values are quoted.
+	AttrQuote AttrQuoteStyle
+}
+
+// RenderWithOptions renders the parse tree n to the given writer, like
+// Render, but in the style configured by opts.
+func RenderWithOptions(w io.Writer, n *Node, opts RenderOptions) error {
 	if x, ok := w.(writer); ok {
-		return render(x, n)
+		return render(x, n, &opts)
 	}
 	buf := bufio.NewWriter(w)
-	if err := render(buf, n); err != nil {
+	if err := render(buf, n, &opts); err != nil {
 		return err
 	}
 	return buf.Flush()
@@ -57,15 +113,20 @@ func Render(w io.Writer, n *Node) error {
 // has been rendered. No more end tags should be rendered after that.
 var plaintextAbort = errors.New("html: internal error (plaintext abort)")
 
-func render(w writer, n *Node) error {
-	err := render1(w, n)
+func render(w writer, n *Node, o *RenderOptions) error {
+	err := render1(w, n, o, 0)
+	if err == nil && o.Indent != "" && n.Type == DocumentNode {
+		err = w.WriteByte('\n')
+	}
 	if err == plaintextAbort {
 		err = nil
 	}
 	return err
 }
 
-func render1(w writer, n *Node) error {
+// render1 renders n, which is nested depth levels below the root of the
+// tree being rendered.
+func render1(w writer, n *Node, o *RenderOptions, depth int) error {
 	// Render non-element nodes; these are the easy cases.
 	switch n.Type {
 	case ErrorNode:
@@ -73,8 +134,11 @@ func render1(w writer, n *Node) error {
 	case TextNode:
 		return escape(w, n.Data)
 	case DocumentNode:
+		if o.Indent != "" {
+			return renderIndented(w, n, o, depth-1)
+		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			if err := render1(w, c); err != nil {
+			if err := render1(w, c, o, depth); err != nil {
 				return err
 			}
 		}
@@ -148,7 +212,7 @@ func render1(w writer, n *Node) error {
 	if _, err := w.WriteString(n.Data); err != nil {
 		return err
 	}
-	for _, a := range n.Attr {
+	for i, a := range n.Attr {
 		if err := w.WriteByte(' '); err != nil {
 			return err
 		}
@@ -163,13 +227,15 @@ func render1(w writer, n *Node) error {
 		if _, err := w.WriteString(a.Key); err != nil {
 			return err
 		}
-		if _, err := w.WriteString(`="`); err != nil {
+		if err := w.WriteByte('='); err != nil {
 			return err
 		}
-		if err := escape(w, a.Val); err != nil {
-			return err
+		quote := o.AttrQuote
+		if quote == QuoteMinimal && o.Void == VoidSlash && i == len(n.Attr)-1 && voidElements[n.Data] {
+			// An unquoted value would take in the slash of "/>".
+			quote = QuoteDouble
 		}
-		if err := w.WriteByte('"'); err != nil {
+		if err := writeAttrVal(w, a.Val, quote); err != nil {
 			return err
 		}
 	}
@@ -177,7 +243,15 @@ func render1(w writer, n *Node) error {
 		if n.FirstChild != nil {
 			return fmt.Errorf("html: void element <%s> has child nodes", n.Data)
 		}
-		_, err := w.WriteString("/>")
+		var err error
+		switch o.Void {
+		case VoidXHTML:
+			_, err = w.WriteString(" />")
+		case VoidHTML:
+			err = w.WriteByte('>')
+		default:
+			_, err = w.WriteString("/>")
+		}
 		return err
 	}
 	if err := w.WriteByte('>'); err != nil {
@@ -203,7 +277,7 @@ func render1(w writer, n *Node) error {
 					return err
 				}
 			} else {
-				if err := render1(w, c); err != nil {
+				if err := render1(w, c, o, depth+1); err != nil {
 					return err
 				}
 			}
@@ -213,9 +287,24 @@ func render1(w writer, n *Node) error {
 			// last element in the file, with no closing tag.
 			return plaintextAbort
 		}
+	case "pre", "listing", "textarea":
+		// Whitespace is significant, so never reformat the contents.
+		unindented := *o
+		unindented.Indent = ""
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if err := render1(w, c, &unindented, depth+1); err != nil {
+				return err
+			}
+		}
 	default:
+		if o.Indent != "" && !(n.FirstChild != nil && n.FirstChild == n.LastChild && n.FirstChild.Type == TextNode) {
+			if err := renderIndented(w, n, o, depth); err != nil {
+				return err
+			}
+			break
+		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			if err := render1(w, c); err != nil {
+			if err := render1(w, c, o, depth+1); err != nil {
 				return err
 			}
 		}
@@ -231,6 +320,78 @@ func render1(w writer, n *Node) error {
 	return w.WriteByte('>')
 }
 
+// renderIndented renders the children of n, which is nested depth levels
+// below the root, each on its own line. Whitespace-only text nodes are
+// dropped and other text nodes are trimmed. If n has any rendered
+// children, a line break and the indentation for n follow them.
+func renderIndented(w writer, n *Node, o *RenderOptions, depth int) error {
+	wrote := false
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == TextNode && strings.Trim(c.Data, whitespace) == "" {
+			continue
+		}
+		if n.Type != DocumentNode || wrote {
+			if err := writeIndent(w, o.Indent, depth+1); err != nil {
+				return err
+			}
+		}
+		wrote = true
+		if c.Type == TextNode {
+			if err := escape(w, strings.Trim(c.Data, whitespace)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := render1(w, c, o, depth+1); err != nil {
+			return err
+		}
+	}
+	if wrote && n.Type != DocumentNode {
+		return writeIndent(w, o.Indent, depth)
+	}
+	return nil
+}
+
+// writeIndent writes a line break followed by depth copies of indent.
+func writeIndent(w writer, indent string, depth int) error {
+	if err := w.WriteByte('\n'); err != nil {
+		return err
+	}
+	for i := 0; i < depth; i++ {
+		if _, err := w.WriteString(indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAttrVal writes the escaped attribute value s to w, quoted in the
+// given style.
+func writeAttrVal(w writer, s string, style AttrQuoteStyle) error {
+	var q byte
+	switch style {
+	case QuoteSingle:
+		q = '\''
+	case QuoteMinimal:
+		// See "Unquoted attribute value syntax" in section 13.1.2.3.
+		// A trailing slash is quoted too, so that it is not mistaken
+		// for a self-closing tag.
+		if s != "" && !strings.ContainsAny(s, whitespace+"\"'=<>`") && s[len(s)-1] != '/' {
+			return escape(w, s)
+		}
+		q = '"'
+	default:
+		q = '"'
+	}
+	if err := w.WriteByte(q); err != nil {
+		return err
+	}
+	if err := escape(w, s); err != nil {
+		return err
+	}
+	return w.WriteByte(q)
+}
+
 // writeQuoted writes s to w surrounded by quotes. Normally it will use double
 // quotes, but if s contains a double quote, it will use single quotes.
 // It is used for writing the identifiers in a doctype declaration.
@@ -6,6 +6,7 @@ package html
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -169,3 +170,158 @@ func TestRenderer(t *testing.T) {
 		t.Errorf("got vs want:\n%s\n%s\n", got, want)
 	}
 }
+
+func TestRenderWithOptions(t *testing.T) {
+	// <div id="a" class="x y"><p>Hello, <b>world</b></p><br><img src="i.png" alt=""><pre>
+	//  keep  </pre></div>
+	text := func(s string) *Node { return &Node{Type: TextNode, Data: s} }
+	elem := func(tag string, attr []Attribute, children ...*Node) *Node {
+		n := &Node{Type: ElementNode, Data: tag, Attr: attr}
+		for _, c := range children {
+			n.AppendChild(c)
+		}
+		return n
+	}
+	tree := func() *Node {
+		return elem("div", []Attribute{{Key: "id", Val: "a"}, {Key: "class", Val: "x y"}},
+			elem("p", nil, text("Hello, "), elem("b", nil, text("world"))),
+			text("\n  "),
+			elem("br", nil),
+			elem("img", []Attribute{{Key: "src", Val: "i.png"}, {Key: "alt", Val: ""}}),
+			elem("pre", nil, text(" keep  ")),
+		)
+	}
+
+	testCases := []struct {
+		desc string
+		opts RenderOptions
+		want string
+	}{
+		{
+			"default",
+			RenderOptions{},
+			`<div id="a" class="x y"><p>Hello, <b>world</b></p>` + "\n  " + `<br/><img src="i.png" alt=""/><pre> keep  </pre></div>`,
+		},
+		{
+			"xhtml void",
+			RenderOptions{Void: VoidXHTML},
+			`<div id="a" class="x y"><p>Hello, <b>world</b></p>` + "\n  " + `<br /><img src="i.png" alt="" /><pre> keep  </pre></div>`,
+		},
+		{
+			"html void",
+			RenderOptions{Void: VoidHTML},
+			`<div id="a" class="x y"><p>Hello, <b>world</b></p>` + "\n  " + `<br><img src="i.png" alt=""><pre> keep  </pre></div>`,
+		},
+		{
+			"single quotes",
+			RenderOptions{AttrQuote: QuoteSingle},
+			`<div id='a' class='x y'><p>Hello, <b>world</b></p>` + "\n  " + `<br/><img src='i.png' alt=''/><pre> keep  </pre></div>`,
+		},
+		{
+			"minimal quotes",
+			RenderOptions{AttrQuote: QuoteMinimal, Void: VoidHTML},
+			`<div id=a class="x y"><p>Hello, <b>world</b></p>` + "\n  " + `<br><img src=i.png alt=""><pre> keep  </pre></div>`,
+		},
+		{
+			"minimal quotes with slash void",
+			RenderOptions{AttrQuote: QuoteMinimal},
+			`<div id=a class="x y"><p>Hello, <b>world</b></p>` + "\n  " + `<br/><img src=i.png alt=""/><pre> keep  </pre></div>`,
+		},
+		{
+			"indent",
+			RenderOptions{Indent: "  "},
+			`<div id="a" class="x y">
+  <p>
+    Hello,
+    <b>world</b>
+  </p>
+  <br/>
+  <img src="i.png" alt=""/>
+  <pre> keep  </pre>
+</div>`,
+		},
+		{
+			"indent with xhtml void and minimal quotes",
+			RenderOptions{Indent: "\t", Void: VoidXHTML, AttrQuote: QuoteMinimal},
+			"<div id=a class=\"x y\">\n\t<p>\n\t\tHello,\n\t\t<b>world</b>\n\t</p>\n\t<br />\n\t<img src=i.png alt=\"\" />\n\t<pre> keep  </pre>\n</div>",
+		},
+	}
+	for _, tc := range testCases {
+		var b bytes.Buffer
+		if err := RenderWithOptions(&b, tree(), tc.opts); err != nil {
+			t.Errorf("%s: %v", tc.desc, err)
+			continue
+		}
+		if got := b.String(); got != tc.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestRenderMinimalQuotesRoundTrip(t *testing.T) {
+	const src = `<img src=a.png><a href=b/ title=c>x</a><input name=d value=e/>`
+	for _, void := range []VoidStyle{VoidSlash, VoidXHTML, VoidHTML} {
+		doc, err := Parse(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := RenderWithOptions(&b, doc, RenderOptions{AttrQuote: QuoteMinimal, Void: void}); err != nil {
+			t.Fatal(err)
+		}
+		doc2, err := Parse(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want, got bytes.Buffer
+		if err := Render(&want, doc); err != nil {
+			t.Fatal(err)
+		}
+		if err := Render(&got, doc2); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("void style %d: round trip changed\n%s\nto\n%s", void, want.String(), got.String())
+		}
+	}
+}
+
+func TestRenderWithOptionsDocument(t *testing.T) {
+	doc, err := Parse(strings.NewReader("<!DOCTYPE html><title>T</title><p>a<br>b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := RenderWithOptions(&b, doc, RenderOptions{Indent: " ", Void: VoidHTML}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<!DOCTYPE html>
+<html>
+ <head>
+  <title>T</title>
+ </head>
+ <body>
+  <p>
+   a
+   <br>
+   b
+  </p>
+ </body>
+</html>
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// Render is RenderWithOptions with the zero RenderOptions.
+	var b0, b1 bytes.Buffer
+	if err := Render(&b0, doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenderWithOptions(&b1, doc, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if b0.String() != b1.String() {
+		t.Errorf("Render = %q; RenderWithOptions with zero options = %q", b0.String(), b1.String())
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package html
+
+import (
+	"errors"
+	"strings"
+)
+
+// Select returns the element nodes that are descendants of root and
+// match the CSS selector, in document order.
+//
+// The supported subset of selector syntax is:
+//
+//	E          an element of type E; * matches any element
+//	E#id       an element with id "id"
+//	E.class    an element whose class attribute contains "class"
+//	E[a]       an element with an "a" attribute
+//	E[a=v]     an element whose "a" attribute value is exactly v;
+//	           v may be quoted with " or '
+//	E F        an F element descendant of an E element
+//	E > F      an F element child of an E element
+//
+// Type and attribute names are matched case-insensitively, ids, classes
+// and attribute values case-sensitively. As with the DOM's
+// querySelectorAll, root itself is never returned but combinators may
+// match ancestors of root.
+//
+// Select returns nil if selector is invalid or unsupported.
+func Select(root *Node, selector string) []*Node {
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return nil
+	}
+	var ns []*Node
+	var f func(*Node)
+	f = func(n *Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if sel.match(c, len(sel)-1) {
+				ns = append(ns, c)
+			}
+			f(c)
+		}
+	}
+	f(root)
+	return ns
+}
+
+var errInvalidSelector = errors.New("html: invalid selector")
+
+// A selector is a sequence of compound selectors, each related to the
+// previous one by its combinator.
+type selector []compoundSelector
+
+// A compoundSelector matches a single element.
+type compoundSelector struct {
+	combinator byte // ' ' (descendant) or '>' (child); zero for the first
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrSelector
+}
+
+type attrSelector struct {
+	key, val string
+	hasVal   bool
+}
+
+// match reports whether n matches sel[:i+1].
+func (sel selector) match(n *Node, i int) bool {
+	if !sel[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch sel[i].combinator {
+	case '>':
+		return n.Parent != nil && sel.match(n.Parent, i-1)
+	default:
+		for p := n.Parent; p != nil; p = p.Parent {
+			if sel.match(p, i-1) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (c *compoundSelector) match(n *Node) bool {
+	if n.Type != ElementNode {
+		return false
+	}
+	if c.tag != "" && c.tag != "*" && !strings.EqualFold(c.tag, n.Data) {
+		return false
+	}
+	if c.id != "" {
+		if v, ok := attrValue(n, "id"); !ok || v != c.id {
+			return false
+		}
+	}
+	if len(c.classes) > 0 {
+		v, _ := attrValue(n, "class")
+		fields := strings.Fields(v)
+	outer:
+		for _, class := range c.classes {
+			for _, f := range fields {
+				if f == class {
+					continue outer
+				}
+			}
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		v, ok := attrValue(n, a.key)
+		if !ok || (a.hasVal && v != a.val) {
+			return false
+		}
+	}
+	return true
+}
+
+// attrValue returns the value of the first attribute of n named key,
+// ignoring namespaced attributes.
+func attrValue(n *Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// selectorParser parses the selector syntax documented on Select.
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+func parseSelector(s string) (selector, error) {
+	p := &selectorParser{s: s}
+	p.skipWhitespace()
+	c, err := p.parseCompound()
+	if err != nil {
+		return nil, err
+	}
+	sel := selector{c}
+	if err := p.parseTail(&sel); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+// parseTail parses the remaining combinator and compound pairs.
+func (p *selectorParser) parseTail(sel *selector) error {
+	for {
+		hadSpace := p.skipWhitespace()
+		if p.pos == len(p.s) {
+			return nil
+		}
+		var comb byte = ' '
+		if p.s[p.pos] == '>' {
+			comb = '>'
+			p.pos++
+			p.skipWhitespace()
+		} else if !hadSpace {
+			return errInvalidSelector
+		}
+		if p.pos == len(p.s) {
+			return errInvalidSelector
+		}
+		c, err := p.parseCompound()
+		if err != nil {
+			return err
+		}
+		c.combinator = comb
+		*sel = append(*sel, c)
+	}
+}
+
+func (p *selectorParser) skipWhitespace() bool {
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte(whitespace, p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var c compoundSelector
+	start := p.pos
+	if p.pos < len(p.s) && p.s[p.pos] == '*' {
+		c.tag = "*"
+		p.pos++
+	} else if ident := p.parseIdent(); ident != "" {
+		c.tag = strings.ToLower(ident)
+	}
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '#':
+			p.pos++
+			if c.id = p.parseIdent(); c.id == "" {
+				return c, errInvalidSelector
+			}
+		case '.':
+			p.pos++
+			class := p.parseIdent()
+			if class == "" {
+				return c, errInvalidSelector
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			p.pos++
+			a, err := p.parseAttr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, a)
+		default:
+			if p.pos == start {
+				return c, errInvalidSelector
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, errInvalidSelector
+	}
+	return c, nil
+}
+
+// parseAttr parses an attribute selector after its opening bracket.
+func (p *selectorParser) parseAttr() (attrSelector, error) {
+	var a attrSelector
+	p.skipWhitespace()
+	if a.key = strings.ToLower(p.parseIdent()); a.key == "" {
+		return a, errInvalidSelector
+	}
+	p.skipWhitespace()
+	if p.pos < len(p.s) && p.s[p.pos] == '=' {
+		p.pos++
+		p.skipWhitespace()
+		a.hasVal = true
+		if p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '\'') {
+			q := p.s[p.pos]
+			i := strings.IndexByte(p.s[p.pos+1:], q)
+			if i < 0 {
+				return a, errInvalidSelector
+			}
+			a.val = p.s[p.pos+1 : p.pos+1+i]
+			p.pos += i + 2
+		} else if a.val = p.parseIdent(); a.val == "" {
+			return a, errInvalidSelector
+		}
+		p.skipWhitespace()
+	}
+	if p.pos == len(p.s) || p.s[p.pos] != ']' {
+		return a, errInvalidSelector
+	}
+	p.pos++
+	return a, nil
+}
+
+// parseIdent parses a run of name characters: ASCII letters and digits,
+// '-', '_' and any non-ASCII byte.
+func (p *selectorParser) parseIdent() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c >= 0x80 {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.s[start:p.pos]
+}
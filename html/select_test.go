@@ -0,0 +1,142 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package html
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const selectTestDoc = `<!DOCTYPE html>
+<html>
+<head><title>Select</title></head>
+<body>
+<div id="main" class="container wide">
+	<p id="p1" class="intro">One</p>
+	<section>
+		<p id="p2" class="intro note">Two</p>
+		<a id="a1" href="/x" data-kind="nav">X</a>
+	</section>
+	<p id="p3">Three</p>
+</div>
+<div id="aside">
+	<p id="p4" class="note">Four</p>
+	<a id="a2" href="/y" data-kind='foot er'>Y</a>
+	<input id="i1" type="checkbox" disabled>
+</div>
+</body>
+</html>`
+
+func TestSelect(t *testing.T) {
+	doc, err := Parse(strings.NewReader(selectTestDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		selector string
+		want     []string // ids of the matched elements
+	}{
+		// Type selectors.
+		{"p", []string{"p1", "p2", "p3", "p4"}},
+		{"P", []string{"p1", "p2", "p3", "p4"}},
+		{"input", []string{"i1"}},
+		{"table", nil},
+		{"*#aside", []string{"aside"}},
+
+		// Id selectors.
+		{"#p3", []string{"p3"}},
+		{"p#p3", []string{"p3"}},
+		{"a#p3", nil},
+
+		// Class selectors.
+		{".intro", []string{"p1", "p2"}},
+		{".note", []string{"p2", "p4"}},
+		{".intro.note", []string{"p2"}},
+		{"div.wide", []string{"main"}},
+		{".int", nil},
+
+		// Attribute selectors.
+		{"[href]", []string{"a1", "a2"}},
+		{"[HREF]", []string{"a1", "a2"}},
+		{`a[href="/x"]`, []string{"a1"}},
+		{"[data-kind=nav]", []string{"a1"}},
+		{"[data-kind='foot er']", []string{"a2"}},
+		{`[ type = "checkbox" ][disabled]`, []string{"i1"}},
+		{"[data-kind=NAV]", nil},
+
+		// Descendant combinator.
+		{"div p", []string{"p1", "p2", "p3", "p4"}},
+		{"#main p", []string{"p1", "p2", "p3"}},
+		{"section p", []string{"p2"}},
+		{"html div section a", []string{"a1"}},
+		{"body   .note", []string{"p2", "p4"}},
+
+		// Child combinator.
+		{"#main > p", []string{"p1", "p3"}},
+		{"#main>p", []string{"p1", "p3"}},
+		{"div > a", []string{"a2"}},
+		{"body > div > section > p.intro", []string{"p2"}},
+		{"body > p", nil},
+
+		// Mixed combinators.
+		{"body div > section a[href]", []string{"a1"}},
+		{"div > * > p", []string{"p2"}},
+	}
+	for _, tc := range testCases {
+		var got []string
+		for _, n := range Select(doc, tc.selector) {
+			id, _ := attrValue(n, "id")
+			got = append(got, id)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Select(%q) = %q; want %q", tc.selector, got, tc.want)
+		}
+	}
+}
+
+func TestSelectRoot(t *testing.T) {
+	doc, err := Parse(strings.NewReader(selectTestDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := Select(doc, "#main")[0]
+
+	// The root itself is not a candidate.
+	if got := Select(main, "div"); len(got) != 0 {
+		t.Errorf("got %d matches for the root element; want 0", len(got))
+	}
+	// Ancestors of the root take part in combinator matching.
+	if got := Select(main, "body > div > p"); len(got) != 2 {
+		t.Errorf("got %d matches; want 2", len(got))
+	}
+}
+
+func TestSelectInvalid(t *testing.T) {
+	doc, err := Parse(strings.NewReader(selectTestDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"",
+		"  ",
+		"#",
+		"p.",
+		"p >",
+		"> p",
+		"p > > a",
+		"[href",
+		"[=x]",
+		"a[href=/x]", // unquoted values must be identifiers
+		`[href="/x]`,
+		"p:first-child",
+		"p, a",
+	} {
+		if got := Select(doc, s); got != nil {
+			t.Errorf("Select(%q) = %v; want nil", s, got)
+		}
+	}
+}
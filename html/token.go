@@ -37,6 +37,10 @@ const (
 // ErrBufferExceeded means that the buffering limit was exceeded.
 var ErrBufferExceeded = errors.New("max buffer exceeded")
 
+// ErrInvalidEntity means that a malformed character reference was found
+// by a Tokenizer with strict entities enabled.
+var ErrInvalidEntity = errors.New("invalid character reference")
+
 // String returns a string representation of the TokenType.
 func (t TokenType) String() string {
 	switch t {
@@ -146,6 +150,8 @@ type Tokenizer struct {
 	// buf[raw.end:] is buffered input that will yield future tokens.
 	raw span
 	buf []byte
+	// offset is the position in the input of buf[0].
+	offset int
 	// maxBuf limits the data buffered in buf. A value of 0 means unlimited.
 	maxBuf int
 	// buf[data.start:data.end] holds the raw bytes of the current token's data:
@@ -169,6 +175,12 @@ type Tokenizer struct {
 	convertNUL bool
 	// allowCDATA is whether CDATA sections are allowed in the current context.
 	allowCDATA bool
+	// keepEntities is whether character references are left escaped in
+	// text and attribute values.
+	keepEntities bool
+	// strictEntities is whether malformed character references are reported
+	// as errors.
+	strictEntities bool
 }
 
 // AllowCDATA sets whether or not the tokenizer recognizes <![CDATA[foo]]> as
@@ -189,6 +201,30 @@ func (z *Tokenizer) AllowCDATA(allowCDATA bool) {
 	z.allowCDATA = allowCDATA
 }
 
+// KeepEntities sets whether or not the tokenizer leaves character references
+// such as "&amp;" escaped in the text and attribute values returned by Text,
+// TagAttr and Token. The default value is false, which means to unescape
+// them, so that "a&lt;b" is returned as "a<b".
+func (z *Tokenizer) KeepEntities(keep bool) {
+	z.keepEntities = keep
+}
+
+// StrictEntities sets whether or not the tokenizer reports malformed
+// character references in text and attribute values. The default value is
+// false, which means to handle them as the HTML5 tokenization algorithm
+// does, passing most of them through as literal text.
+//
+// If enabled, a numeric reference without digits, without a terminating
+// semicolon or for an invalid code point, such as "&#xZZ;", or a semicolon
+// terminated named reference that is not known, such as "&foo;", is an
+// error. An ampersand that does not start a reference, such as in "a & b",
+// is not. The token containing the reference is still returned, and the
+// subsequent call to Next returns an ErrorToken with Err returning
+// ErrInvalidEntity.
+func (z *Tokenizer) StrictEntities(strict bool) {
+	z.strictEntities = strict
+}
+
 // NextIsNotRawText instructs the tokenizer that the next token should not be
 // considered as 'raw text'. Some elements, such as script and title elements,
 // normally require the next token after the opening tag to be 'raw text' that
@@ -253,6 +289,7 @@ func (z *Tokenizer) readByte() byte {
 		copy(buf1, z.buf[z.raw.start:z.raw.end])
 		if x := z.raw.start; x != 0 {
 			// Adjust the data/attr spans to refer to the same contents after the copy.
+			z.offset += x
 			z.data.start -= x
 			z.data.end -= x
 			z.pendingAttr[0].start -= x
@@ -952,6 +989,30 @@ func (z *Tokenizer) readTagAttrVal() {
 
 // Next scans the next token and returns its type.
 func (z *Tokenizer) Next() TokenType {
+	tt := z.next()
+	if z.strictEntities && (z.err == nil || z.err == io.EOF) && !z.validEntities() {
+		z.err = ErrInvalidEntity
+	}
+	return tt
+}
+
+// validEntities reports whether the character references in the current
+// token's text or attribute values are well-formed.
+func (z *Tokenizer) validEntities() bool {
+	switch z.tt {
+	case TextToken:
+		return z.textIsRaw || validEntities(z.buf[z.data.start:z.data.end])
+	case StartTagToken, SelfClosingTagToken:
+		for _, a := range z.attr {
+			if !validEntities(z.buf[a[1].start:a[1].end]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (z *Tokenizer) next() TokenType {
 	z.raw.start = z.raw.end
 	z.data.start = z.raw.end
 	z.data.end = z.raw.end
@@ -1129,7 +1190,7 @@ func (z *Tokenizer) Text() []byte {
 		if (z.convertNUL || z.tt == CommentToken) && bytes.Contains(s, nul) {
 			s = bytes.Replace(s, nul, replacement, -1)
 		}
-		if !z.textIsRaw {
+		if !z.textIsRaw && !z.keepEntities {
 			s = unescape(s, false)
 		}
 		return s
@@ -1164,7 +1225,11 @@ func (z *Tokenizer) TagAttr() (key, val []byte, moreAttr bool) {
 			z.nAttrReturned++
 			key = z.buf[x[0].start:x[0].end]
 			val = z.buf[x[1].start:x[1].end]
-			return lower(key), unescape(convertNewlines(val), true), z.nAttrReturned < len(z.attr)
+			val = convertNewlines(val)
+			if !z.keepEntities {
+				val = unescape(val, true)
+			}
+			return lower(key), val, z.nAttrReturned < len(z.attr)
 		}
 	}
 	return nil, nil, false
@@ -1193,6 +1258,19 @@ func (z *Tokenizer) Token() Token {
 	return t
 }
 
+// attrOffsets returns the byte offsets in the input of the keys of the
+// current tag's attributes, in the order that Token returns them.
+func (z *Tokenizer) attrOffsets() []int {
+	if len(z.attr) == 0 {
+		return nil
+	}
+	offs := make([]int, len(z.attr))
+	for i, x := range z.attr {
+		offs[i] = z.offset + x[0].start
+	}
+	return offs
+}
+
 // SetMaxBuf sets a limit on the amount of data buffered during tokenization.
 // A value of 0 means unlimited.
 func (z *Tokenizer) SetMaxBuf(n int) {
@@ -506,6 +506,72 @@ func TestMaxBuffer(t *testing.T) {
 	}
 }
 
+func TestEntities(t *testing.T) {
+	testCases := []struct {
+		in      string
+		want    string // text with entities unescaped
+		invalid bool   // whether StrictEntities reports an error
+	}{
+		{"a &amp; b", "a & b", false},
+		{"a & b", "a & b", false},
+		{"AT&T", "AT&T", false},
+		{"&lt&gt;", "<>", false},
+		{"&#65;&#x42;", "AB", false},
+		{"&#xZZ;", "&#xZZ;", true},
+		{"&#;", "&#;", true},
+		{"&#65", "A", true},
+		{"&#0;", "\ufffd", true},
+		{"&#x110000;", "\ufffd", true},
+		{"&foo;", "&foo;", true},
+	}
+	for _, tc := range testCases {
+		for _, keep := range []bool{false, true} {
+			for _, strict := range []bool{false, true} {
+				z := NewTokenizer(strings.NewReader(tc.in))
+				z.KeepEntities(keep)
+				z.StrictEntities(strict)
+				if tt := z.Next(); tt != TextToken {
+					t.Fatalf("%q: got token type %v; want %v", tc.in, tt, TextToken)
+				}
+				want := tc.want
+				if keep {
+					want = tc.in
+				}
+				if got := string(z.Text()); got != want {
+					t.Errorf("%q (keep=%v): got text %q; want %q", tc.in, keep, got, want)
+				}
+				wantErr := io.EOF
+				if strict && tc.invalid {
+					wantErr = ErrInvalidEntity
+				}
+				if tt := z.Next(); tt != ErrorToken || z.Err() != wantErr {
+					t.Errorf("%q (strict=%v): got %v with error %v; want %v with error %v", tc.in, strict, tt, z.Err(), ErrorToken, wantErr)
+				}
+			}
+		}
+	}
+}
+
+func TestEntitiesInAttributes(t *testing.T) {
+	const in = `<a title="x &amp; y" href="/?a=1&b=2"><b class="&#xZZ;">`
+	z := NewTokenizer(strings.NewReader(in))
+	z.KeepEntities(true)
+	z.StrictEntities(true)
+	if tt := z.Next(); tt != StartTagToken {
+		t.Fatalf("got token type %v; want %v", tt, StartTagToken)
+	}
+	want := []Attribute{{Key: "title", Val: "x &amp; y"}, {Key: "href", Val: "/?a=1&b=2"}}
+	if got := z.Token().Attr; !reflect.DeepEqual(got, want) {
+		t.Errorf("got attributes %v; want %v", got, want)
+	}
+	if tt := z.Next(); tt != StartTagToken {
+		t.Fatalf("got token type %v; want %v", tt, StartTagToken)
+	}
+	if tt := z.Next(); tt != ErrorToken || z.Err() != ErrInvalidEntity {
+		t.Errorf("got %v with error %v; want %v with error %v", tt, z.Err(), ErrorToken, ErrInvalidEntity)
+	}
+}
+
 func TestMaxBufferReconstruction(t *testing.T) {
 	// Exceeding the maximum buffer size at any point while tokenizing permits
 	// reconstructing the original input.
@@ -0,0 +1,645 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sfv implements Structured Field Values for HTTP, as specified
+// in RFC 8941.
+//
+// A structured field is an Item, a List or a Dictionary. The bare value
+// of an Item, or of a parameter, is one of the following Go types:
+//
+//	int64    Integer
+//	float64  Decimal
+//	string   String
+//	Token    Token
+//	[]byte   Byte Sequence
+//	bool     Boolean
+//
+// A field sent in several header lines must be joined with commas
+// before being parsed, as described in RFC 7230, section 3.2.2.
+package sfv // import "golang.org/x/net/http/sfv"
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// A Token is a short textual word, such as an identifier.
+type Token string
+
+// An Item is a bare item with parameters.
+type Item struct {
+	Value  interface{}
+	Params Params
+}
+
+// An InnerList is a list of items, with parameters.
+type InnerList struct {
+	Items  []Item
+	Params Params
+}
+
+// A Member is a member of a List or a Dictionary, either an Item or
+// an InnerList.
+type Member interface {
+	member()
+}
+
+func (Item) member()      {}
+func (InnerList) member() {}
+
+// A Param is a key and bare item pair.
+type Param struct {
+	Key   string
+	Value interface{}
+}
+
+// Params is an ordered list of parameters with unique keys.
+type Params []Param
+
+// Get returns the value of the parameter with the key k, or nil if
+// there is none.
+func (ps Params) Get(k string) interface{} {
+	for _, p := range ps {
+		if p.Key == k {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+// A List is a list of members.
+type List []Member
+
+// A DictMember is a member of a Dictionary.
+type DictMember struct {
+	Key   string
+	Value Member
+}
+
+// A Dictionary is an ordered map of keys to members.
+type Dictionary []DictMember
+
+// Get returns the member with the key k, or nil if there is none.
+func (d Dictionary) Get(k string) Member {
+	for _, m := range d {
+		if m.Key == k {
+			return m.Value
+		}
+	}
+	return nil
+}
+
+// Integers and decimals are limited to 15 and 12 integer digits.
+const (
+	maxInteger     = 999999999999999
+	maxDecimalPart = 999999999999
+)
+
+var (
+	errEmpty        = errors.New("sfv: empty field")
+	errTrailingData = errors.New("sfv: unexpected data after value")
+	errTrailingList = errors.New("sfv: trailing comma")
+	errUnterminated = errors.New("sfv: unterminated value")
+	errNotKey       = errors.New("sfv: invalid key")
+	errNotItem      = errors.New("sfv: invalid bare item")
+	errNotNumber    = errors.New("sfv: invalid number")
+	errNotString    = errors.New("sfv: invalid string")
+	errNotBytes     = errors.New("sfv: invalid byte sequence")
+	errNotBoolean   = errors.New("sfv: invalid boolean")
+	errInnerList    = errors.New("sfv: invalid inner list")
+)
+
+// ParseItem parses s as an Item.
+func ParseItem(s string) (Item, error) {
+	p := parser{s: s}
+	p.discardSP()
+	if p.empty() {
+		return Item{}, errEmpty
+	}
+	it, err := p.item()
+	if err != nil {
+		return Item{}, err
+	}
+	if err := p.finish(); err != nil {
+		return Item{}, err
+	}
+	return it, nil
+}
+
+// ParseList parses s as a List. An empty s is an empty List.
+func ParseList(s string) (List, error) {
+	p := parser{s: s}
+	p.discardSP()
+	var l List
+	for !p.empty() {
+		m, err := p.member()
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, m)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// ParseDictionary parses s as a Dictionary. An empty s is an empty
+// Dictionary. Of duplicate keys, the last value is kept at the
+// position of the first one.
+func ParseDictionary(s string) (Dictionary, error) {
+	p := parser{s: s}
+	p.discardSP()
+	var d Dictionary
+	for !p.empty() {
+		k, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var m Member
+		if p.peek() == '=' {
+			p.s = p.s[1:]
+			if m, err = p.member(); err != nil {
+				return nil, err
+			}
+		} else {
+			ps, err := p.params()
+			if err != nil {
+				return nil, err
+			}
+			m = Item{Value: true, Params: ps}
+		}
+		d = d.set(k, m)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (d Dictionary) set(k string, m Member) Dictionary {
+	for i := range d {
+		if d[i].Key == k {
+			d[i].Value = m
+			return d
+		}
+	}
+	return append(d, DictMember{Key: k, Value: m})
+}
+
+func (ps Params) set(k string, v interface{}) Params {
+	for i := range ps {
+		if ps[i].Key == k {
+			ps[i].Value = v
+			return ps
+		}
+	}
+	return append(ps, Param{Key: k, Value: v})
+}
+
+type parser struct {
+	s string
+}
+
+func (p *parser) empty() bool { return len(p.s) == 0 }
+
+func (p *parser) peek() byte {
+	if len(p.s) == 0 {
+		return 0
+	}
+	return p.s[0]
+}
+
+func (p *parser) discardSP() {
+	for len(p.s) > 0 && p.s[0] == ' ' {
+		p.s = p.s[1:]
+	}
+}
+
+func (p *parser) discardOWS() {
+	for len(p.s) > 0 && (p.s[0] == ' ' || p.s[0] == '\t') {
+		p.s = p.s[1:]
+	}
+}
+
+// finish checks that only spaces remain after a top-level value.
+func (p *parser) finish() error {
+	p.discardSP()
+	if !p.empty() {
+		return errTrailingData
+	}
+	return nil
+}
+
+// next consumes the separator after a List or Dictionary member.
+func (p *parser) next() error {
+	p.discardOWS()
+	if p.empty() {
+		return nil
+	}
+	if p.s[0] != ',' {
+		return errTrailingData
+	}
+	p.s = p.s[1:]
+	p.discardOWS()
+	if p.empty() {
+		return errTrailingList
+	}
+	return nil
+}
+
+func (p *parser) member() (Member, error) {
+	if p.peek() == '(' {
+		return p.innerList()
+	}
+	return p.item()
+}
+
+func (p *parser) innerList() (InnerList, error) {
+	p.s = p.s[1:] // '('
+	var il InnerList
+	for {
+		p.discardSP()
+		if p.empty() {
+			return InnerList{}, errUnterminated
+		}
+		if p.s[0] == ')' {
+			p.s = p.s[1:]
+			ps, err := p.params()
+			if err != nil {
+				return InnerList{}, err
+			}
+			il.Params = ps
+			return il, nil
+		}
+		it, err := p.item()
+		if err != nil {
+			return InnerList{}, err
+		}
+		il.Items = append(il.Items, it)
+		if c := p.peek(); c != ' ' && c != ')' {
+			if c == 0 {
+				return InnerList{}, errUnterminated
+			}
+			return InnerList{}, errInnerList
+		}
+	}
+}
+
+func (p *parser) item() (Item, error) {
+	v, err := p.bareItem()
+	if err != nil {
+		return Item{}, err
+	}
+	ps, err := p.params()
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{Value: v, Params: ps}, nil
+}
+
+func (p *parser) params() (Params, error) {
+	var ps Params
+	for p.peek() == ';' {
+		p.s = p.s[1:]
+		p.discardSP()
+		k, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var v interface{} = true
+		if p.peek() == '=' {
+			p.s = p.s[1:]
+			if v, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		ps = ps.set(k, v)
+	}
+	return ps, nil
+}
+
+func (p *parser) key() (string, error) {
+	if c := p.peek(); !isLCAlpha(c) && c != '*' {
+		return "", errNotKey
+	}
+	i := 1
+	for i < len(p.s) && isKeyChar(p.s[i]) {
+		i++
+	}
+	k := p.s[:i]
+	p.s = p.s[i:]
+	return k, nil
+}
+
+func (p *parser) bareItem() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '-' || isDigit(c):
+		return p.number()
+	case c == '"':
+		return p.quotedString()
+	case isAlpha(c) || c == '*':
+		return p.token(), nil
+	case c == ':':
+		return p.byteSequence()
+	case c == '?':
+		return p.boolean()
+	}
+	return nil, errNotItem
+}
+
+func (p *parser) number() (interface{}, error) {
+	i := 0
+	if p.s[0] == '-' {
+		i++
+	}
+	if i == len(p.s) || !isDigit(p.s[i]) {
+		return nil, errNotNumber
+	}
+	start, dot := i, -1
+	for ; i < len(p.s); i++ {
+		c := p.s[i]
+		if c == '.' && dot < 0 {
+			if i-start > 12 {
+				return nil, errNotNumber
+			}
+			dot = i
+			continue
+		}
+		if !isDigit(c) {
+			break
+		}
+		if n := i - start + 1; dot < 0 && n > 15 || dot >= 0 && n > 16 {
+			return nil, errNotNumber
+		}
+	}
+	num := p.s[:i]
+	p.s = p.s[i:]
+	if dot < 0 {
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return nil, errNotNumber
+		}
+		return n, nil
+	}
+	if frac := i - dot - 1; frac == 0 || frac > 3 {
+		return nil, errNotNumber
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return nil, errNotNumber
+	}
+	return f, nil
+}
+
+func (p *parser) quotedString() (string, error) {
+	var b strings.Builder
+	for i := 1; i < len(p.s); i++ {
+		switch c := p.s[i]; {
+		case c == '\\':
+			i++
+			if i == len(p.s) {
+				return "", errUnterminated
+			}
+			if c := p.s[i]; c != '"' && c != '\\' {
+				return "", errNotString
+			}
+			b.WriteByte(p.s[i])
+		case c == '"':
+			p.s = p.s[i+1:]
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", errNotString
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errUnterminated
+}
+
+func (p *parser) token() Token {
+	i := 1
+	for i < len(p.s) && isTokenChar(p.s[i]) {
+		i++
+	}
+	t := Token(p.s[:i])
+	p.s = p.s[i:]
+	return t
+}
+
+func (p *parser) byteSequence() ([]byte, error) {
+	i := strings.IndexByte(p.s[1:], ':')
+	if i < 0 {
+		return nil, errUnterminated
+	}
+	enc := p.s[1 : i+1]
+	for j := 0; j < len(enc); j++ {
+		if c := enc[j]; !isAlpha(c) && !isDigit(c) && c != '+' && c != '/' && c != '=' {
+			return nil, errNotBytes
+		}
+	}
+	p.s = p.s[i+2:]
+	// RFC 8941, section 4.2.7 allows parsers to accept unpadded
+	// values.
+	b, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		if b, err = base64.RawStdEncoding.DecodeString(enc); err != nil {
+			return nil, errNotBytes
+		}
+	}
+	return b, nil
+}
+
+func (p *parser) boolean() (bool, error) {
+	if len(p.s) < 2 || (p.s[1] != '0' && p.s[1] != '1') {
+		return false, errNotBoolean
+	}
+	v := p.s[1] == '1'
+	p.s = p.s[2:]
+	return v, nil
+}
+
+func isDigit(c byte) bool   { return '0' <= c && c <= '9' }
+func isLCAlpha(c byte) bool { return 'a' <= c && c <= 'z' }
+func isAlpha(c byte) bool   { return isLCAlpha(c) || 'A' <= c && c <= 'Z' }
+
+func isKeyChar(c byte) bool {
+	return isLCAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '.' || c == '*'
+}
+
+func isTokenChar(c byte) bool {
+	return c == ':' || c == '/' || httpguts.IsTokenRune(rune(c))
+}
+
+// FormatItem returns the serialization of it.
+func FormatItem(it Item) (string, error) {
+	var b strings.Builder
+	if err := writeItem(&b, it); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// FormatList returns the serialization of l.
+func FormatList(l List) (string, error) {
+	var b strings.Builder
+	for i, m := range l {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := writeMember(&b, m); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// FormatDictionary returns the serialization of d.
+func FormatDictionary(d Dictionary) (string, error) {
+	var b strings.Builder
+	for i, m := range d {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := writeKey(&b, m.Key); err != nil {
+			return "", err
+		}
+		if it, ok := m.Value.(Item); ok && it.Value == true {
+			if err := writeParams(&b, it.Params); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte('=')
+		if err := writeMember(&b, m.Value); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func writeMember(b *strings.Builder, m Member) error {
+	switch m := m.(type) {
+	case Item:
+		return writeItem(b, m)
+	case InnerList:
+		b.WriteByte('(')
+		for i, it := range m.Items {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			if err := writeItem(b, it); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(')')
+		return writeParams(b, m.Params)
+	}
+	return fmt.Errorf("sfv: invalid member type %T", m)
+}
+
+func writeItem(b *strings.Builder, it Item) error {
+	if err := writeBareItem(b, it.Value); err != nil {
+		return err
+	}
+	return writeParams(b, it.Params)
+}
+
+func writeParams(b *strings.Builder, ps Params) error {
+	for _, p := range ps {
+		b.WriteByte(';')
+		if err := writeKey(b, p.Key); err != nil {
+			return err
+		}
+		if p.Value == true {
+			continue
+		}
+		b.WriteByte('=')
+		if err := writeBareItem(b, p.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeKey(b *strings.Builder, k string) error {
+	if k == "" || !isLCAlpha(k[0]) && k[0] != '*' {
+		return errNotKey
+	}
+	for i := 1; i < len(k); i++ {
+		if !isKeyChar(k[i]) {
+			return errNotKey
+		}
+	}
+	b.WriteString(k)
+	return nil
+}
+
+func writeBareItem(b *strings.Builder, v interface{}) error {
+	switch v := v.(type) {
+	case int64:
+		if v < -maxInteger || v > maxInteger {
+			return errNotNumber
+		}
+		b.WriteString(strconv.FormatInt(v, 10))
+	case int:
+		return writeBareItem(b, int64(v))
+	case float64:
+		v = math.RoundToEven(v*1000) / 1000
+		if math.IsNaN(v) || math.Abs(v) >= maxDecimalPart+1 {
+			return errNotNumber
+		}
+		if v == 0 {
+			v = 0 // no negative zero
+		}
+		s := strconv.FormatFloat(v, 'f', 3, 64)
+		s = strings.TrimRight(s, "0")
+		if s[len(s)-1] == '.' {
+			s += "0"
+		}
+		b.WriteString(s)
+	case string:
+		b.WriteByte('"')
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if c < 0x20 || c > 0x7e {
+				return errNotString
+			}
+			if c == '"' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		}
+		b.WriteByte('"')
+	case Token:
+		if v == "" || !isAlpha(v[0]) && v[0] != '*' {
+			return errNotItem
+		}
+		for i := 1; i < len(v); i++ {
+			if !isTokenChar(v[i]) {
+				return errNotItem
+			}
+		}
+		b.WriteString(string(v))
+	case []byte:
+		b.WriteByte(':')
+		b.WriteString(base64.StdEncoding.EncodeToString(v))
+		b.WriteByte(':')
+	case bool:
+		if v {
+			b.WriteString("?1")
+		} else {
+			b.WriteString("?0")
+		}
+	default:
+		return fmt.Errorf("sfv: invalid bare item type %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func item(v interface{}, ps ...Param) Item {
+	return Item{Value: v, Params: ps}
+}
+
+// The test cases follow those of the structured field test suite at
+// https://github.com/httpwg/structured-field-tests. A non-empty
+// canonical is the expected serialization when it differs from raw.
+var itemTests = []struct {
+	name      string
+	raw       string
+	want      Item
+	canonical string
+	fail      bool
+}{
+	// boolean.json
+	{name: "basic true boolean", raw: "?1", want: item(true)},
+	{name: "basic false boolean", raw: "?0", want: item(false)},
+	{name: "unknown boolean", raw: "?Q", fail: true},
+	{name: "whitespace boolean", raw: "? 1", fail: true},
+	{name: "truncated boolean", raw: "?", fail: true},
+
+	// number.json
+	{name: "basic integer", raw: "42", want: item(int64(42))},
+	{name: "zero integer", raw: "0", want: item(int64(0))},
+	{name: "negative zero", raw: "-0", want: item(int64(0)), canonical: "0"},
+	{name: "double negative zero", raw: "--0", fail: true},
+	{name: "negative integer", raw: "-42", want: item(int64(-42))},
+	{name: "leading 0 integer", raw: "042", want: item(int64(42)), canonical: "42"},
+	{name: "comma", raw: "2,3", fail: true},
+	{name: "long integer", raw: "123456789012345", want: item(int64(123456789012345))},
+	{name: "long negative integer", raw: "-123456789012345", want: item(int64(-123456789012345))},
+	{name: "too long integer", raw: "1234567890123456", fail: true},
+	{name: "simple decimal", raw: "1.23", want: item(1.23)},
+	{name: "negative decimal", raw: "-1.23", want: item(-1.23)},
+	{name: "decimal, whitespace after decimal", raw: "1. 23", fail: true},
+	{name: "decimal, whitespace before decimal", raw: "1 .23", fail: true},
+	{name: "negative decimal, whitespace after sign", raw: "- 1.23", fail: true},
+	{name: "tetra decimal", raw: "1.5.4", fail: true},
+	{name: "double decimal", raw: "1..4", fail: true},
+	{name: "adjacent double decimal", raw: "1.5.", fail: true},
+	{name: "decimal with three fractional digits", raw: "1.123", want: item(1.123)},
+	{name: "decimal with four fractional digits", raw: "1.1234", fail: true},
+	{name: "decimal with trailing dot", raw: "1.", fail: true},
+	{name: "long decimal", raw: "123456789012.123", want: item(123456789012.123)},
+	{name: "too long decimal", raw: "1234567890123.0", fail: true},
+
+	// string.json
+	{name: "basic string", raw: `"foo bar"`, want: item("foo bar")},
+	{name: "empty string", raw: `""`, want: item("")},
+	{name: "long string", raw: `"` + longString + `"`, want: item(longString)},
+	{name: "whitespace string", raw: `"   "`, want: item("   ")},
+	{name: "non-ascii string", raw: "\"füü\"", fail: true},
+	{name: "tab in string", raw: "\"\t\"", fail: true},
+	{name: "newline in string", raw: "\" \n \"", fail: true},
+	{name: "single quoted string", raw: "'foo'", fail: true},
+	{name: "unbalanced string", raw: `"foo`, fail: true},
+	{name: "string quoting", raw: `"foo \"bar\" \\ baz"`, want: item(`foo "bar" \ baz`)},
+	{name: "bad string quoting", raw: `"foo \,"`, fail: true},
+	{name: "ending string quote", raw: `"foo \"`, fail: true},
+	{name: "abruptly ending string quote", raw: `"foo \`, fail: true},
+
+	// token.json
+	{name: "basic token - item", raw: "a_b-c.d3:f%00/*", want: item(Token("a_b-c.d3:f%00/*"))},
+	{name: "token with capitals - item", raw: "fooBar", want: item(Token("fooBar"))},
+	{name: "token starting with capitals - item", raw: "FooBar", want: item(Token("FooBar"))},
+	{name: "token starting with asterisk", raw: "*foo", want: item(Token("*foo"))},
+	{name: "token starting with digit", raw: "1foo", fail: true},
+
+	// binary.json
+	{name: "basic binary", raw: ":aGVsbG8=:", want: item([]byte("hello"))},
+	{name: "empty binary", raw: "::", want: item([]byte{})},
+	{name: "bad paddding", raw: ":aGVsbG8:", want: item([]byte("hello")), canonical: ":aGVsbG8=:"},
+	{name: "bad end delimiter", raw: ":aGVsbG8=", fail: true},
+	{name: "extra whitespace", raw: ":aGVsb G8=:", fail: true},
+	{name: "extra chars", raw: ":aGVsbG!8=:", fail: true},
+	{name: "suffix chars", raw: ":aGVsbG8=!:", fail: true},
+	{name: "base64url binary", raw: ":_-Ah:", fail: true},
+
+	// item.json and param-list.json
+	{name: "empty item", raw: "", fail: true},
+	{name: "leading space", raw: " \t 1", fail: true},
+	{name: "trailing space", raw: "1 \t ", fail: true},
+	{name: "leading and trailing space", raw: "  1  ", want: item(int64(1)), canonical: "1"},
+	{name: "basic parameters", raw: `1;a=2;b="x";c`, want: item(int64(1), Param{"a", int64(2)}, Param{"b", "x"}, Param{"c", true})},
+	{name: "whitespace before = parameterised item", raw: "1;a =2", fail: true},
+	{name: "whitespace after ; parameterised item", raw: "1; a=2", want: item(int64(1), Param{"a", int64(2)}), canonical: "1;a=2"},
+	{name: "duplicate parameter keys", raw: "1;a=2;b=3;a=4", want: item(int64(1), Param{"a", int64(4)}, Param{"b", int64(3)}), canonical: "1;a=4;b=3"},
+	{name: "false parameter", raw: "1;a=?0", want: item(int64(1), Param{"a", false})},
+	{name: "uppercase parameter key", raw: "1;A=2", fail: true},
+}
+
+const longString = "foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo foo"
+
+func TestParseItem(t *testing.T) {
+	for _, tt := range itemTests {
+		got, err := ParseItem(tt.raw)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%s: ParseItem(%q) = %#v; want error", tt.name, tt.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ParseItem(%q) = %v", tt.name, tt.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseItem(%q) = %#v; want %#v", tt.name, tt.raw, got, tt.want)
+		}
+		want := tt.canonical
+		if want == "" {
+			want = tt.raw
+		}
+		if s, err := FormatItem(got); err != nil || s != want {
+			t.Errorf("%s: FormatItem(%#v) = %q, %v; want %q", tt.name, got, s, err, want)
+		}
+	}
+}
+
+var listTests = []struct {
+	name      string
+	raw       string
+	want      List
+	canonical string
+	fail      bool
+}{
+	// list.json
+	{name: "basic list", raw: "1, 42", want: List{item(int64(1)), item(int64(42))}},
+	{name: "empty list", raw: "", want: nil},
+	{name: "leading SP list", raw: "  42, 43", want: List{item(int64(42)), item(int64(43))}, canonical: "42, 43"},
+	{name: "single item list", raw: "42", want: List{item(int64(42))}},
+	{name: "no whitespace list", raw: "1,42", want: List{item(int64(1)), item(int64(42))}, canonical: "1, 42"},
+	{name: "extra whitespace list", raw: "1 , 42", want: List{item(int64(1)), item(int64(42))}, canonical: "1, 42"},
+	{name: "tab separated list", raw: "1\t,\t42", want: List{item(int64(1)), item(int64(42))}, canonical: "1, 42"},
+	{name: "trailing comma list", raw: "1, 42,", fail: true},
+	{name: "empty item list", raw: "1,,42", fail: true},
+
+	// listlist.json
+	{
+		name: "basic list of lists",
+		raw:  "(1 2), (42 43)",
+		want: List{
+			InnerList{Items: []Item{item(int64(1)), item(int64(2))}},
+			InnerList{Items: []Item{item(int64(42)), item(int64(43))}},
+		},
+	},
+	{name: "single item list of lists", raw: "(42)", want: List{InnerList{Items: []Item{item(int64(42))}}}},
+	{name: "empty item list of lists", raw: "()", want: List{InnerList{}}},
+	{name: "empty middle item list of lists", raw: "(1),(),(42)", want: List{
+		InnerList{Items: []Item{item(int64(1))}},
+		InnerList{},
+		InnerList{Items: []Item{item(int64(42))}},
+	}, canonical: "(1), (), (42)"},
+	{name: "extra whitespace list of lists", raw: "( 1  42 )", want: List{InnerList{Items: []Item{item(int64(1)), item(int64(42))}}}, canonical: "(1 42)"},
+	{name: "wrong whitespace list of lists", raw: "(1\t 42)", fail: true},
+	{name: "no trailing parenthesis list of lists", raw: "(1 42", fail: true},
+	{name: "no trailing parenthesis middle list of lists", raw: "(1 2, (42 43)", fail: true},
+	{name: "no spaces in inner-list", raw: "(abc\"def\"?0123*dXZ3*xyz)", fail: true},
+
+	// param-list.json and param-listlist.json
+	{
+		name: "parameterised inner list",
+		raw:  "(abc_123);a=1;b=2, cdef_456",
+		want: List{
+			InnerList{Items: []Item{item(Token("abc_123"))}, Params: Params{{"a", int64(1)}, {"b", int64(2)}}},
+			item(Token("cdef_456")),
+		},
+	},
+	{
+		name: "parameterised inner list item",
+		raw:  "(abc_123;a=1;b=2;cdef_456)",
+		want: List{
+			InnerList{Items: []Item{item(Token("abc_123"), Param{"a", int64(1)}, Param{"b", int64(2)}, Param{"cdef_456", true})}},
+		},
+	},
+	{name: "whitespace before ; parameterised list", raw: "abc;a=1, def ;b=2", fail: true},
+	{name: "whitespace after ; parameterised list", raw: "abc; a=1", want: List{item(Token("abc"), Param{"a", int64(1)})}, canonical: "abc;a=1"},
+}
+
+func TestParseList(t *testing.T) {
+	for _, tt := range listTests {
+		got, err := ParseList(tt.raw)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%s: ParseList(%q) = %#v; want error", tt.name, tt.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ParseList(%q) = %v", tt.name, tt.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseList(%q) = %#v; want %#v", tt.name, tt.raw, got, tt.want)
+		}
+		want := tt.canonical
+		if want == "" {
+			want = tt.raw
+		}
+		if s, err := FormatList(got); err != nil || s != want {
+			t.Errorf("%s: FormatList(%#v) = %q, %v; want %q", tt.name, got, s, err, want)
+		}
+	}
+}
+
+var dictionaryTests = []struct {
+	name      string
+	raw       string
+	want      Dictionary
+	canonical string
+	fail      bool
+}{
+	// dictionary.json
+	{name: "basic dictionary", raw: `en="Applepie", da=:w4ZibGV0w6ZydGUK:`, want: Dictionary{
+		{"en", item("Applepie")},
+		{"da", item([]byte("\xc3\x86blet\xc3\xa6rte\n"))},
+	}},
+	{name: "empty dictionary", raw: "", want: nil},
+	{name: "single item dictionary", raw: "a=1", want: Dictionary{{"a", item(int64(1))}}},
+	{name: "list item dictionary", raw: "a=(1 2)", want: Dictionary{{"a", InnerList{Items: []Item{item(int64(1)), item(int64(2))}}}}},
+	{name: "single list item dictionary", raw: "a=(1)", want: Dictionary{{"a", InnerList{Items: []Item{item(int64(1))}}}}},
+	{name: "empty list item dictionary", raw: "a=()", want: Dictionary{{"a", InnerList{}}}},
+	{name: "no whitespace dictionary", raw: "a=1,b=2", want: Dictionary{{"a", item(int64(1))}, {"b", item(int64(2))}}, canonical: "a=1, b=2"},
+	{name: "extra whitespace dictionary", raw: "a=1 ,  b=2", want: Dictionary{{"a", item(int64(1))}, {"b", item(int64(2))}}, canonical: "a=1, b=2"},
+	{name: "tab separated dictionary", raw: "a=1\t,\tb=2", want: Dictionary{{"a", item(int64(1))}, {"b", item(int64(2))}}, canonical: "a=1, b=2"},
+	{name: "leading whitespace dictionary", raw: "     a=1 ,  b=2", want: Dictionary{{"a", item(int64(1))}, {"b", item(int64(2))}}, canonical: "a=1, b=2"},
+	{name: "whitespace before = dictionary", raw: "a =1, b=2", fail: true},
+	{name: "whitespace after = dictionary", raw: "a= 1, b=2", fail: true},
+	{name: "missing value dictionary", raw: "a=1, b, c=3", want: Dictionary{
+		{"a", item(int64(1))},
+		{"b", item(true)},
+		{"c", item(int64(3))},
+	}},
+	{name: "all missing value dictionary", raw: "a, b, c", want: Dictionary{{"a", item(true)}, {"b", item(true)}, {"c", item(true)}}},
+	{name: "start missing value dictionary", raw: "a, b=2", want: Dictionary{{"a", item(true)}, {"b", item(int64(2))}}},
+	{name: "end missing value dictionary", raw: "a=1, b", want: Dictionary{{"a", item(int64(1))}, {"b", item(true)}}},
+	{name: "missing value with params dictionary", raw: "a=1, b;foo=9, c=3", want: Dictionary{
+		{"a", item(int64(1))},
+		{"b", item(true, Param{"foo", int64(9)})},
+		{"c", item(int64(3))},
+	}},
+	{name: "explicit true value with params dictionary", raw: "a=1, b=?1;foo=9, c=3", want: Dictionary{
+		{"a", item(int64(1))},
+		{"b", item(true, Param{"foo", int64(9)})},
+		{"c", item(int64(3))},
+	}, canonical: "a=1, b;foo=9, c=3"},
+	{name: "trailing comma dictionary", raw: "a=1, b=2,", fail: true},
+	{name: "empty item dictionary", raw: "a=1,,b=2,", fail: true},
+	{name: "duplicate key dictionary", raw: "a=1,b=2,a=3", want: Dictionary{{"a", item(int64(3))}, {"b", item(int64(2))}}, canonical: "a=3, b=2"},
+	{name: "numeric key dictionary", raw: "a=1,1b=2,a=1", fail: true},
+	{name: "uppercase key dictionary", raw: "a=1,B=2,a=1", fail: true},
+	{name: "bad key dictionary", raw: "a=1,b!=2,a=1", fail: true},
+
+	// param-dict.json
+	{name: "basic parameterised dict", raw: `abc=123;a=1;b=2, def=456, ghi=789;q=9;r="+w"`, want: Dictionary{
+		{"abc", item(int64(123), Param{"a", int64(1)}, Param{"b", int64(2)})},
+		{"def", item(int64(456))},
+		{"ghi", item(int64(789), Param{"q", int64(9)}, Param{"r", "+w"})},
+	}},
+	{name: "single item parameterised dict", raw: "a=b; q=1.0", want: Dictionary{{"a", item(Token("b"), Param{"q", 1.0})}}, canonical: "a=b;q=1.0"},
+	{name: "list item parameterised dictionary", raw: "a=(1 2); q=1.0", want: Dictionary{
+		{"a", InnerList{Items: []Item{item(int64(1)), item(int64(2))}, Params: Params{{"q", 1.0}}}},
+	}, canonical: "a=(1 2);q=1.0"},
+	{name: "missing parameter value parameterised dict", raw: "a=3;c;d=5", want: Dictionary{
+		{"a", item(int64(3), Param{"c", true}, Param{"d", int64(5)})},
+	}},
+	{name: "whitespace before = parameterised dict", raw: "a=b;q =0.5", fail: true},
+}
+
+func TestParseDictionary(t *testing.T) {
+	for _, tt := range dictionaryTests {
+		got, err := ParseDictionary(tt.raw)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%s: ParseDictionary(%q) = %#v; want error", tt.name, tt.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ParseDictionary(%q) = %v", tt.name, tt.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseDictionary(%q) = %#v; want %#v", tt.name, tt.raw, got, tt.want)
+		}
+		want := tt.canonical
+		if want == "" {
+			want = tt.raw
+		}
+		if s, err := FormatDictionary(got); err != nil || s != want {
+			t.Errorf("%s: FormatDictionary(%#v) = %q, %v; want %q", tt.name, got, s, err, want)
+		}
+	}
+}
+
+func TestFormatItem(t *testing.T) {
+	for _, tt := range []struct {
+		it   Item
+		want string // empty if Item can't be serialized
+	}{
+		{item(1.0), "1.0"},
+		{item(1.5), "1.5"},
+		{item(0.0005), "0.0"},    // rounds to even
+		{item(0.0015), "0.002"},  // rounds to even
+		{item(-0.0001), "0.0"},   // no negative zero
+		{item(1.23456), "1.235"}, // rounds
+		{item(999999999999.999), "999999999999.999"},
+		{item(1e12), ""},
+		{item(int64(999999999999999)), "999999999999999"},
+		{item(int64(-999999999999999)), "-999999999999999"},
+		{item(int64(1000000000000000)), ""},
+		{item(7), "7"},
+		{item("é"), ""},
+		{item("a\nb"), ""},
+		{item(Token("")), ""},
+		{item(Token("a b")), ""},
+		{item(Token("1a")), ""},
+		{item(int64(1), Param{"A", true}), ""},
+		{item(int64(1), Param{"", true}), ""},
+		{item(struct{}{}), ""},
+		{item(nil), ""},
+	} {
+		got, err := FormatItem(tt.it)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("FormatItem(%#v) = %q; want error", tt.it, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("FormatItem(%#v) = %q, %v; want %q", tt.it, got, err, tt.want)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	d, err := ParseDictionary("u=3, i")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.Get("u"), Member(item(int64(3))); !reflect.DeepEqual(got, want) {
+		t.Errorf("Get(u) = %#v; want %#v", got, want)
+	}
+	if got := d.Get("x"); got != nil {
+		t.Errorf("Get(x) = %#v; want nil", got)
+	}
+	it, err := ParseItem("a;q=0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := it.Params.Get("q"); got != 0.5 {
+		t.Errorf("Params.Get(q) = %#v; want 0.5", got)
+	}
+	if got := it.Params.Get("x"); got != nil {
+		t.Errorf("Params.Get(x) = %#v; want nil", got)
+	}
+}
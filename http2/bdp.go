@@ -0,0 +1,108 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Bandwidth-delay product estimation
+
+package http2
+
+import "time"
+
+const (
+	// bdpGrowThreshold is the fraction of the current estimate that a
+	// sample must reach for the estimate to grow.
+	bdpGrowThreshold = 0.66
+
+	// bdpGrowFactor is how much larger than the sample that triggered
+	// it a new estimate is.
+	bdpGrowFactor = 2
+
+	// bdpRTTAlpha is the weight of new samples in the RTT average
+	// once it has settled.
+	bdpRTTAlpha = 0.9
+)
+
+// bdpPingData is the payload of the PING frames used to measure the
+// round-trip time.
+var bdpPingData = [8]byte{'h', '2', 'b', 'd', 'p', 0, 0, 0}
+
+// A bdpEstimator estimates the bandwidth-delay product of a
+// connection, so that its receive windows can be sized to keep the
+// pipe full.
+//
+// A sample starts with the first DATA frame received while no sample
+// is in progress, at which point a PING is sent. The sample counts the
+// bytes received until the PING is acknowledged, which is roughly the
+// amount of data in flight during one round trip. When a sample comes
+// close to the current estimate and the bandwidth is at its highest
+// yet, the estimate grows, up to a limit.
+//
+// The approach is the one of the BDP estimator of gRPC.
+type bdpEstimator struct {
+	bdp    int32 // current estimate
+	limit  int32 // maximum estimate
+	sample int32 // bytes received since sentAt
+
+	sampling    bool
+	sentAt      time.Time
+	sampleCount int
+	rtt         float64 // seconds
+	bwMax       float64 // bytes per second
+}
+
+// add records the receipt of n bytes of DATA. It reports whether a
+// PING with bdpPingData should be sent to start a sample.
+func (b *bdpEstimator) add(n int32, now time.Time) bool {
+	if b.bdp >= b.limit {
+		return false
+	}
+	if !b.sampling {
+		b.sampling = true
+		b.sample = n
+		b.sentAt = now
+		b.sampleCount++
+		return true
+	}
+	if b.sample < b.limit {
+		b.sample += n
+	}
+	return false
+}
+
+// calculate completes the sample in progress when the PING is
+// acknowledged. It returns the new estimate if the estimate grew, or
+// zero otherwise.
+func (b *bdpEstimator) calculate(now time.Time) int32 {
+	if !b.sampling {
+		return 0
+	}
+	b.sampling = false
+	rttSample := now.Sub(b.sentAt).Seconds()
+	if b.sampleCount < 10 {
+		// Bootstrap the average with the first samples.
+		b.rtt += (rttSample - b.rtt) / float64(b.sampleCount)
+	} else {
+		b.rtt += (rttSample - b.rtt) * bdpRTTAlpha
+	}
+	if b.rtt <= 0 {
+		return 0
+	}
+	// The 1.5 factor accounts for the sample covering slightly more
+	// than one round trip.
+	bw := float64(b.sample) / (b.rtt * 1.5)
+	if bw > b.bwMax {
+		b.bwMax = bw
+	}
+	if float64(b.sample) < bdpGrowThreshold*float64(b.bdp) || bw < b.bwMax {
+		return 0
+	}
+	n := int64(b.sample) * bdpGrowFactor
+	if n > int64(b.limit) {
+		n = int64(b.limit)
+	}
+	if n <= int64(b.bdp) {
+		return 0
+	}
+	b.bdp = int32(n)
+	return b.bdp
+}
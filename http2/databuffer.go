@@ -96,9 +96,26 @@ func (b *dataBuffer) Read(p []byte) (int, error) {
 			b.r = 0
 		}
 	}
+	if b.size == 0 {
+		// Everything written so far has been read, so the last chunk
+		// can be reused as well; the next Write allocates a new one.
+		b.free()
+	}
 	return ntotal, nil
 }
 
+// free returns all chunks to the pools, discarding any unread bytes.
+func (b *dataBuffer) free() {
+	for i, chunk := range b.chunks {
+		putDataBufferChunk(chunk)
+		b.chunks[i] = nil
+	}
+	b.chunks = b.chunks[:0]
+	b.r = 0
+	b.w = 0
+	b.size = 0
+}
+
 func (b *dataBuffer) bytesFromFirstChunk() []byte {
 	if len(b.chunks) == 1 {
 		return b.chunks[0][b.r:b.w]
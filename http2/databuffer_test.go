@@ -153,3 +153,29 @@ func TestDataBufferWriteAfterPartialRead(t *testing.T) {
 		return b
 	})
 }
+
+func TestDataBufferFreeAfterFullRead(t *testing.T) {
+	b := &dataBuffer{}
+	if n, err := b.Write([]byte("abcd")); n != 4 || err != nil {
+		t.Fatalf("Write(\"abcd\")=%v,%v want 4,nil", n, err)
+	}
+	p := make([]byte, 4)
+	if n, err := b.Read(p[:3]); n != 3 || err != nil {
+		t.Fatalf("Read()=%v,%v want 3,nil", n, err)
+	}
+	if len(b.chunks) != 1 {
+		t.Fatalf("got %d chunks after partial read; want 1", len(b.chunks))
+	}
+	if n, err := b.Read(p); n != 1 || err != nil || p[0] != 'd' {
+		t.Fatalf("Read()=%q,%v,%v want \"d\",1,nil", p[:n], n, err)
+	}
+	if len(b.chunks) != 0 || b.r != 0 || b.w != 0 {
+		t.Errorf("after full read: got %d chunks, r=%d, w=%d; want 0 chunks, r=0, w=0", len(b.chunks), b.r, b.w)
+	}
+	if n, err := b.Write([]byte("xyz")); n != 3 || err != nil {
+		t.Fatalf("Write(\"xyz\")=%v,%v want 3,nil", n, err)
+	}
+	if n, err := b.Read(p); n != 3 || err != nil || string(p[:n]) != "xyz" {
+		t.Fatalf("Read()=%q,%v,%v want \"xyz\",3,nil", p[:n], n, err)
+	}
+}
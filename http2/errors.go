@@ -139,6 +139,12 @@ func (e headerFieldValueError) Error() string {
 	return fmt.Sprintf("invalid header field value for %q", string(e))
 }
 
+type trailerFieldError string
+
+func (e trailerFieldError) Error() string {
+	return fmt.Sprintf("header field %q is not allowed in trailers", string(e))
+}
+
 var (
 	errMixPseudoHeaderTypes = errors.New("mix of request and response pseudo headers")
 	errPseudoAfterRegular   = errors.New("pseudo header field after regular")
@@ -383,6 +383,7 @@ func (f *Framer) logWrite() {
 		// Let us read anything, even if we accidentally wrote it
 		// in the wrong order:
 		f.debugFramer.AllowIllegalReads = true
+		f.debugFramer.SetMaxReadFrameSize(maxFrameSize)
 	}
 	f.debugFramerBuf.Write(f.wbuf)
 	fr, err := f.debugFramer.ReadFrame()
@@ -393,6 +394,19 @@ func (f *Framer) logWrite() {
 	f.debugWriteLoggerf("http2: Framer %p: wrote %v", f, summarizeFrame(fr))
 }
 
+// dumpFramesTo makes f write a one-line summary of each frame it reads
+// or writes to w, in place of the logging enabled by GODEBUG.
+func (f *Framer) dumpFramesTo(w io.Writer) {
+	var mu sync.Mutex // reads and writes happen on different goroutines
+	logf := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+	f.logReads, f.logWrites = true, true
+	f.debugReadLoggerf, f.debugWriteLoggerf = logf, logf
+}
+
 func (f *Framer) writeByte(v byte)     { f.wbuf = append(f.wbuf, v) }
 func (f *Framer) writeBytes(v []byte)  { f.wbuf = append(f.wbuf, v...) }
 func (f *Framer) writeUint16(v uint16) { f.wbuf = append(f.wbuf, byte(v>>8), byte(v)) }
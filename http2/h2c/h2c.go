@@ -12,6 +12,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -105,7 +106,18 @@ func (s h2cHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	// Handle Upgrade to h2c (RFC 7540 Section 3.2)
 	if isH2CUpgrade(r.Header) {
-		conn, settings, err := h2cUpgrade(w, r)
+		// RFC 7540 Section 3.2.1 says that "A server MUST NOT upgrade the
+		// connection to HTTP/2 if this header field is not present or if
+		// more than one is present."
+		settings, err := getH2Settings(r.Header)
+		if err != nil {
+			if http2VerboseLogs {
+				log.Printf("h2c: error h2c upgrade: %v", err)
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		conn, err := h2cUpgrade(w, r)
 		if err != nil {
 			if http2VerboseLogs {
 				log.Printf("h2c: error h2c upgrade: %v", err)
@@ -158,14 +170,10 @@ func initH2CWithPriorKnowledge(w http.ResponseWriter) (net.Conn, error) {
 }
 
 // h2cUpgrade establishes a h2c connection using the HTTP/1 upgrade (Section 3.2).
-func h2cUpgrade(w http.ResponseWriter, r *http.Request) (_ net.Conn, settings []byte, err error) {
-	settings, err = getH2Settings(r.Header)
-	if err != nil {
-		return nil, nil, err
-	}
+func h2cUpgrade(w http.ResponseWriter, r *http.Request) (net.Conn, error) {
 	hijacker, ok := w.(http.Hijacker)
 	if !ok {
-		return nil, nil, errors.New("h2c: connection does not support Hijack")
+		return nil, errors.New("h2c: connection does not support Hijack")
 	}
 
 	body, _ := ioutil.ReadAll(r.Body)
@@ -173,13 +181,13 @@ func h2cUpgrade(w http.ResponseWriter, r *http.Request) (_ net.Conn, settings []
 
 	conn, rw, err := hijacker.Hijack()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	rw.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n" +
 		"Connection: Upgrade\r\n" +
 		"Upgrade: h2c\r\n\r\n"))
-	return newBufConn(conn, rw), settings, nil
+	return newBufConn(conn, rw), nil
 }
 
 // isH2CUpgrade returns true if the header properly request an upgrade to h2c
@@ -198,9 +206,24 @@ func getH2Settings(h http.Header) ([]byte, error) {
 	if len(vals) != 1 {
 		return nil, fmt.Errorf("expected 1 HTTP2-Settings. Got: %v", vals)
 	}
-	settings, err := base64.RawURLEncoding.DecodeString(vals[0])
+	// The header value is a token68 with the trailing padding omitted, but
+	// some clients send it anyway.
+	settings, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(vals[0], "="))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid HTTP2-Settings: %v", err)
+	}
+	// The decoded value is the payload of a SETTINGS frame, see Section 6.5.
+	if len(settings)%6 != 0 {
+		return nil, fmt.Errorf("invalid HTTP2-Settings length %d", len(settings))
+	}
+	for b := settings; len(b) > 0; b = b[6:] {
+		s := http2.Setting{
+			ID:  http2.SettingID(binary.BigEndian.Uint16(b)),
+			Val: binary.BigEndian.Uint32(b[2:]),
+		}
+		if err := s.Valid(); err != nil {
+			return nil, fmt.Errorf("invalid HTTP2-Settings: %v", err)
+		}
 	}
 	return settings, nil
 }
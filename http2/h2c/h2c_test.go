@@ -5,9 +5,13 @@
 package h2c
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net"
@@ -15,8 +19,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
 )
 
 func ExampleNewHandler() {
@@ -134,3 +140,174 @@ func TestPropagation(t *testing.T) {
 		t.Fatal("expected server err, got nil")
 	}
 }
+
+// readH2Response reads frames from an HTTP/2 connection until the end of
+// the response on stream 1, and returns its status and body.
+func readH2Response(t *testing.T, fr *http2.Framer) (status, body string) {
+	t.Helper()
+	var buf bytes.Buffer
+	dec := hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		if f.Name == ":status" {
+			status = f.Value
+		}
+	})
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			t.Fatalf("ReadFrame: %v", err)
+		}
+		switch f := f.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				if err := fr.WriteSettingsAck(); err != nil {
+					t.Fatalf("WriteSettingsAck: %v", err)
+				}
+			}
+		case *http2.HeadersFrame:
+			if f.StreamID != 1 {
+				t.Fatalf("got HEADERS on stream %d, want 1", f.StreamID)
+			}
+			if _, err := dec.Write(f.HeaderBlockFragment()); err != nil {
+				t.Fatalf("decoding headers: %v", err)
+			}
+			if f.StreamEnded() {
+				return status, buf.String()
+			}
+		case *http2.DataFrame:
+			buf.Write(f.Data())
+			if f.StreamEnded() {
+				return status, buf.String()
+			}
+		case *http2.GoAwayFrame, *http2.RSTStreamFrame:
+			t.Fatalf("got %v", f)
+		}
+	}
+}
+
+func newH2CTestServer() *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Hello %s", r.URL.Path)
+	})
+	return httptest.NewServer(NewHandler(handler, &http2.Server{}))
+}
+
+func TestPriorKnowledge(t *testing.T) {
+	ts := newH2CTestServer()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if _, err := io.WriteString(conn, http2.ClientPreface); err != nil {
+		t.Fatal(err)
+	}
+	fr := http2.NewFramer(conn, conn)
+	if err := fr.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	var hbuf bytes.Buffer
+	enc := hpack.NewEncoder(&hbuf)
+	for _, f := range []hpack.HeaderField{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":authority", Value: ts.Listener.Addr().String()},
+		{Name: ":path", Value: "/prior"},
+	} {
+		enc.WriteField(f)
+	}
+	if err := fr.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: hbuf.Bytes(),
+		EndStream:     true,
+		EndHeaders:    true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := readH2Response(t, fr)
+	if status != "200" || body != "Hello /prior" {
+		t.Errorf("got %s %q, want 200 %q", status, body, "Hello /prior")
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	ts := newH2CTestServer()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	// SETTINGS_MAX_CONCURRENT_STREAMS = 100.
+	settings := base64.RawURLEncoding.EncodeToString([]byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x64})
+	fmt.Fprintf(conn, "GET /upgrade HTTP/1.1\r\n"+
+		"Host: %s\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\n"+
+		"Upgrade: h2c\r\n"+
+		"HTTP2-Settings: %s\r\n\r\n", ts.Listener.Addr(), settings)
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("got status %q, Upgrade %q; want 101 Switching Protocols, h2c", res.Status, res.Header.Get("Upgrade"))
+	}
+
+	// The upgraded request is answered on stream 1 once the client sends
+	// its connection preface.
+	if _, err := io.WriteString(conn, http2.ClientPreface); err != nil {
+		t.Fatal(err)
+	}
+	fr := http2.NewFramer(conn, br)
+	if err := fr.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	status, body := readH2Response(t, fr)
+	if status != "200" || body != "Hello /upgrade" {
+		t.Errorf("got %s %q, want 200 %q", status, body, "Hello /upgrade")
+	}
+}
+
+func TestUpgradeInvalidSettings(t *testing.T) {
+	ts := newH2CTestServer()
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		name     string
+		settings []string
+	}{
+		{"missing", nil},
+		{"repeated", []string{"", ""}},
+		{"bad encoding", []string{"!!!!"}},
+		{"bad length", []string{base64.RawURLEncoding.EncodeToString([]byte{0x00, 0x03, 0x00, 0x00, 0x00})}},
+		{"bad value", []string{base64.RawURLEncoding.EncodeToString([]byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x02})}},
+	} {
+		req, err := http.NewRequest("GET", ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Connection", "Upgrade, HTTP2-Settings")
+		req.Header.Set("Upgrade", "h2c")
+		for _, v := range tt.settings {
+			req.Header.Add("HTTP2-Settings", v)
+		}
+		res, err := ts.Client().Do(req)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: got status %q, want 400 Bad Request", tt.name, res.Status)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"sort"
@@ -171,6 +172,50 @@ func (s SettingID) String() string {
 	return fmt.Sprintf("UNKNOWN_SETTING_%d", uint16(s))
 }
 
+// PeerSettings holds the SETTINGS parameters advertised by the peer of
+// a connection. Parameters the peer has not sent have their initial
+// values, as defined in RFC 7540 Section 6.5.2.
+type PeerSettings struct {
+	HeaderTableSize      uint32
+	EnablePush           bool
+	MaxConcurrentStreams uint32 // math.MaxUint32 if unlimited
+	InitialWindowSize    uint32
+	MaxFrameSize         uint32
+	MaxHeaderListSize    uint32 // math.MaxUint32 if unlimited
+}
+
+// initialPeerSettings returns the settings of a peer that has not sent
+// any SETTINGS parameters yet.
+func initialPeerSettings() PeerSettings {
+	return PeerSettings{
+		HeaderTableSize:      initialHeaderTableSize,
+		EnablePush:           true,
+		MaxConcurrentStreams: math.MaxUint32,
+		InitialWindowSize:    initialWindowSize,
+		MaxFrameSize:         initialMaxFrameSize,
+		MaxHeaderListSize:    math.MaxUint32,
+	}
+}
+
+// apply updates ps with the setting s, which must be valid.
+// Unknown settings are ignored.
+func (ps *PeerSettings) apply(s Setting) {
+	switch s.ID {
+	case SettingHeaderTableSize:
+		ps.HeaderTableSize = s.Val
+	case SettingEnablePush:
+		ps.EnablePush = s.Val != 0
+	case SettingMaxConcurrentStreams:
+		ps.MaxConcurrentStreams = s.Val
+	case SettingInitialWindowSize:
+		ps.InitialWindowSize = s.Val
+	case SettingMaxFrameSize:
+		ps.MaxFrameSize = s.Val
+	case SettingMaxHeaderListSize:
+		ps.MaxHeaderListSize = s.Val
+	}
+}
+
 // validWireHeaderFieldName reports whether v is a valid header field
 // name (key). See httpguts.ValidHeaderName for the base rules.
 //
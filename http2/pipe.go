@@ -132,6 +132,11 @@ func (p *pipe) closeWithError(dst *error, err error, fn func()) {
 	if dst == &p.breakErr {
 		if p.b != nil {
 			p.unread += p.b.Len()
+			if b, ok := p.b.(*dataBuffer); ok {
+				// Nobody will read the rest of the data,
+				// so its chunks can be reused right away.
+				b.free()
+			}
 		}
 		p.b = nil
 	}
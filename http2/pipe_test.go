@@ -107,6 +107,19 @@ func TestPipeCloseWithError(t *testing.T) {
 	}
 }
 
+func TestPipeBreakWithErrorFreesDataBuffer(t *testing.T) {
+	b := &dataBuffer{}
+	p := &pipe{b: b}
+	io.WriteString(p, "foo")
+	p.BreakWithError(errors.New("test err"))
+	if len(b.chunks) != 0 || b.Len() != 0 {
+		t.Errorf("dataBuffer has %d chunks, %d bytes after BreakWithError; want none", len(b.chunks), b.Len())
+	}
+	if p.Len() != 3 {
+		t.Errorf("pipe should have 3 unread bytes")
+	}
+}
+
 func TestPipeBreakWithError(t *testing.T) {
 	p := &pipe{b: new(bytes.Buffer)}
 	io.WriteString(p, "foo")
@@ -96,6 +96,7 @@ type Server struct {
 	// which may be active globally, which is MaxHandlers.
 	// If zero, MaxConcurrentStreams defaults to at least 100, per
 	// the HTTP/2 spec's recommendations.
+	// See SetMaxConcurrentStreams to change the limit at runtime.
 	MaxConcurrentStreams uint32
 
 	// MaxReadFrameSize optionally specifies the largest frame
@@ -113,6 +114,27 @@ type Server struct {
 	// activity for the purposes of IdleTimeout.
 	IdleTimeout time.Duration
 
+	// MaxConnectionAge, if positive, specifies how long a connection
+	// may live before it is shut down gracefully with a GOAWAY frame,
+	// so that clients reconnect and load balancers can rebalance
+	// traffic. The connection is closed once its open streams complete.
+	MaxConnectionAge time.Duration
+
+	// MaxConnectionAgeGrace, if positive, bounds how long the open
+	// streams of a connection that has reached MaxConnectionAge may
+	// take to complete. When it elapses the connection is closed,
+	// interrupting any streams still open. If zero, the streams may
+	// take as long as they need.
+	MaxConnectionAgeGrace time.Duration
+
+	// HalfClosedStreamTimeout, if positive, specifies how long the
+	// response to a request whose body has been fully received may
+	// stay blocked on flow control because the client has stopped
+	// reading it. When it elapses the stream is reset with a CANCEL
+	// error, freeing its slot. If zero, such streams wait
+	// indefinitely (or until the WriteTimeout of the http.Server).
+	HalfClosedStreamTimeout time.Duration
+
 	// MaxUploadBufferPerConnection is the size of the initial flow
 	// control window for each connections. The HTTP/2 spec does not
 	// allow this to be smaller than 65535 or larger than 2^32-1.
@@ -126,6 +148,15 @@ type Server struct {
 	// maximum, a default value will be used instead.
 	MaxUploadBufferPerStream int32
 
+	// MaxAutoTunedUploadBuffer, if larger than the stream window set
+	// by MaxUploadBufferPerStream, enables the automatic tuning of the
+	// flow control windows of each connection. The server estimates
+	// the bandwidth-delay product of the connection from the round-trip
+	// time of PING frames and the amount of data received meanwhile,
+	// and grows the connection and stream windows to keep up with fast
+	// clients, up to MaxAutoTunedUploadBuffer bytes.
+	MaxAutoTunedUploadBuffer int32
+
 	// NewWriteScheduler constructs a write scheduler for a connection.
 	// If nil, a default scheduler is chosen.
 	NewWriteScheduler func() WriteScheduler
@@ -136,6 +167,27 @@ type Server struct {
 	// The errType consists of only ASCII word characters.
 	CountError func(errType string)
 
+	// DisableAutoContinue, if true, stops the server from sending a
+	// 100 Continue response when a handler first reads the body of a
+	// request with an "Expect: 100-continue" header. A handler may
+	// still send one itself with WriteHeader(http.StatusContinue).
+	DisableAutoContinue bool
+
+	// ContinueTimeout, if positive, is how long to wait for a handler
+	// to read the body of a request with an "Expect: 100-continue"
+	// header before sending the 100 Continue response anyway. If zero,
+	// the response is only sent when the handler reads the body.
+	// It is ignored if DisableAutoContinue is set.
+	ContinueTimeout time.Duration
+
+	// FrameDump, if non-nil, receives a one-line summary of each
+	// frame read or written on the server's connections, giving its
+	// direction, type, flags, stream ID, length and the main fields
+	// of SETTINGS, WINDOW_UPDATE, RST_STREAM and a few other frames.
+	// Writes to FrameDump from different connections may be
+	// concurrent.
+	FrameDump io.Writer
+
 	// Internal state. This is a pointer (rather than embedded directly)
 	// so that we don't embed a Mutex in this struct, which will make the
 	// struct non-copyable, which might break some callers.
@@ -182,30 +234,40 @@ func (s *Server) maxQueuedControlFrames() int {
 type serverInternalState struct {
 	mu          sync.Mutex
 	activeConns map[*serverConn]struct{}
+	maxStreams  uint32 // set by SetMaxConcurrentStreams; 0 means unset
 }
 
-func (s *serverInternalState) registerConn(sc *serverConn) {
-	if s == nil {
-		return // if the Server was used without calling ConfigureServer
+// serverStateMu guards the creation of the state of every Server.
+var serverStateMu sync.Mutex
+
+// internalState returns the state of s, creating it on first use so
+// that connections are tracked whether or not s was configured with
+// ConfigureServer.
+func (s *Server) internalState() *serverInternalState {
+	serverStateMu.Lock()
+	defer serverStateMu.Unlock()
+	if s.state == nil {
+		s.state = &serverInternalState{activeConns: make(map[*serverConn]struct{})}
 	}
+	return s.state
+}
+
+func (s *serverInternalState) registerConn(sc *serverConn) {
 	s.mu.Lock()
 	s.activeConns[sc] = struct{}{}
+	if s.maxStreams > 0 {
+		sc.advMaxStreams = s.maxStreams
+	}
 	s.mu.Unlock()
 }
 
 func (s *serverInternalState) unregisterConn(sc *serverConn) {
-	if s == nil {
-		return // if the Server was used without calling ConfigureServer
-	}
 	s.mu.Lock()
 	delete(s.activeConns, sc)
 	s.mu.Unlock()
 }
 
 func (s *serverInternalState) startGracefulShutdown() {
-	if s == nil {
-		return // if the Server was used without calling ConfigureServer
-	}
 	s.mu.Lock()
 	for sc := range s.activeConns {
 		sc.startGracefulShutdown()
@@ -213,6 +275,30 @@ func (s *serverInternalState) startGracefulShutdown() {
 	s.mu.Unlock()
 }
 
+// SetMaxConcurrentStreams changes the number of concurrent streams
+// that each client may have open at a time, overriding
+// MaxConcurrentStreams. If n is zero, the limit reverts to
+// MaxConcurrentStreams or its default.
+//
+// The new limit applies to connections accepted from then on and is
+// sent in a SETTINGS frame to each connection being served. Streams
+// already open beyond the new limit are allowed to finish, while new
+// streams are refused until the count drops below it.
+//
+// SetMaxConcurrentStreams may be called concurrently with serving.
+func (s *Server) SetMaxConcurrentStreams(n uint32) {
+	st := s.internalState()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.maxStreams = n
+	if n == 0 {
+		n = s.maxConcurrentStreams()
+	}
+	for sc := range st.activeConns {
+		sc.sendServeMsg(maxStreamsUpdate(n))
+	}
+}
+
 // ConfigureServer adds HTTP/2 support to a net/http Server.
 //
 // The configuration conf may be nil.
@@ -225,7 +311,6 @@ func ConfigureServer(s *http.Server, conf *Server) error {
 	if conf == nil {
 		conf = new(Server)
 	}
-	conf.state = &serverInternalState{activeConns: make(map[*serverConn]struct{})}
 	if h1, h2 := s, conf; h2.IdleTimeout == 0 {
 		if h1.IdleTimeout != 0 {
 			h2.IdleTimeout = h1.IdleTimeout
@@ -233,7 +318,7 @@ func ConfigureServer(s *http.Server, conf *Server) error {
 			h2.IdleTimeout = h1.ReadTimeout
 		}
 	}
-	s.RegisterOnShutdown(conf.state.startGracefulShutdown)
+	s.RegisterOnShutdown(conf.internalState().startGracefulShutdown)
 
 	if s.TLSConfig == nil {
 		s.TLSConfig = new(tls.Config)
@@ -398,10 +483,13 @@ func (s *Server) ServeConn(c net.Conn, opts *ServeConnOpts) {
 		serveG:                      newGoroutineLock(),
 		pushEnabled:                 true,
 		sawClientPreface:            opts.SawClientPreface,
+		peerSettings:                initialPeerSettings(),
 	}
+	sc.baseCtx = context.WithValue(sc.baseCtx, peerSettingsContextKey{}, sc)
 
-	s.state.registerConn(sc)
-	defer s.state.unregisterConn(sc)
+	state := s.internalState()
+	state.registerConn(sc)
+	defer state.unregisterConn(sc)
 
 	// The net/http package sets the write deadline from the
 	// http.Server.WriteTimeout during the TLS handshake, but then
@@ -429,11 +517,20 @@ func (s *Server) ServeConn(c net.Conn, opts *ServeConnOpts) {
 	if s.CountError != nil {
 		fr.countError = s.CountError
 	}
+	if s.FrameDump != nil {
+		fr.dumpFramesTo(s.FrameDump)
+	}
 	fr.ReadMetaHeaders = hpack.NewDecoder(initialHeaderTableSize, nil)
 	fr.MaxHeaderListSize = sc.maxHeaderListSize()
 	fr.SetMaxReadFrameSize(s.maxReadFrameSize())
 	sc.framer = fr
 
+	sc.connRecvWindow = s.initialConnRecvWindowSize()
+	sc.streamRecvWindow = s.initialStreamRecvWindowSize()
+	if s.MaxAutoTunedUploadBuffer > sc.streamRecvWindow {
+		sc.bdp = &bdpEstimator{bdp: sc.streamRecvWindow, limit: s.MaxAutoTunedUploadBuffer}
+	}
+
 	if tc, ok := c.(connectionStater); ok {
 		sc.tlsState = new(tls.ConnectionState)
 		*sc.tlsState = tc.ConnectionState()
@@ -504,6 +601,25 @@ func (s *Server) ServeConn(c net.Conn, opts *ServeConnOpts) {
 	sc.serve()
 }
 
+// peerSettingsContextKey is the context key under which a request's
+// *serverConn is stored, for PeerSettingsFromContext.
+type peerSettingsContextKey struct{}
+
+// PeerSettingsFromContext returns the SETTINGS parameters most recently
+// advertised by the client of the HTTP/2 server connection a request
+// arrived on. The context must be, or be derived from, the context of a
+// request passed to a Handler by a Server. The boolean result reports
+// whether ctx carries such a connection.
+func PeerSettingsFromContext(ctx context.Context) (PeerSettings, bool) {
+	sc, ok := ctx.Value(peerSettingsContextKey{}).(*serverConn)
+	if !ok {
+		return PeerSettings{}, false
+	}
+	sc.peerSettingsMu.Lock()
+	defer sc.peerSettingsMu.Unlock()
+	return sc.peerSettings, true
+}
+
 func serverConnBaseContext(c net.Conn, opts *ServeConnOpts) (ctx context.Context, cancel func()) {
 	ctx, cancel = context.WithCancel(opts.context())
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, c.LocalAddr())
@@ -558,9 +674,14 @@ type serverConn struct {
 	maxPushPromiseID            uint32 // ID of the last push promise (even), or 0 if there have been no pushes
 	streams                     map[uint32]*stream
 	initialStreamSendWindowSize int32
+	connRecvWindow              int32         // size of the conn-level inbound flow control window
+	streamRecvWindow            int32         // initial size of the stream-level inbound flow control windows
+	bdp                         *bdpEstimator // nil unless the inbound windows are auto-tuned
 	maxFrameSize                int32
 	headerTableSize             uint32
 	peerMaxHeaderListSize       uint32            // zero means unknown (default)
+	peerSettingsMu              sync.Mutex        // guards peerSettings
+	peerSettings                PeerSettings      // for PeerSettingsFromContext
 	canonHeader                 map[string]string // http2-lower-case -> Go-Canonical-Case
 	writingFrame                bool              // started writing a frame (on serve goroutine or separate)
 	writingFrameAsync           bool              // started a frame on its own goroutine but haven't heard back on wroteFrameCh
@@ -571,6 +692,7 @@ type serverConn struct {
 	goAwayCode                  ErrCode
 	shutdownTimer               *time.Timer // nil until used
 	idleTimer                   *time.Timer // nil if unused
+	maxAgeTimer                 *time.Timer // nil if unused
 
 	// Owned by the writeFrameAsync goroutine:
 	headerWriteBuf bytes.Buffer
@@ -621,8 +743,10 @@ type stream struct {
 	state            streamState
 	resetQueued      bool        // RST_STREAM queued for write; set by sc.resetStream
 	gotTrailerHeader bool        // HEADER frame for trailers was seen
-	wroteHeaders     bool        // whether we wrote headers (not status 100)
+	wroteHeaders     bool        // whether we wrote headers (not status 101-199)
 	writeDeadline    *time.Timer // nil if unused
+	continueTimer    *time.Timer // nil if unused
+	stallTimer       *time.Timer // nil unless stalled; see updateStallTimer
 
 	trailer    http.Header // accumulated trailers
 	reqTrailer http.Header // handler's Request.Trailer
@@ -862,7 +986,7 @@ func (sc *serverConn) serve() {
 			{SettingMaxFrameSize, sc.srv.maxReadFrameSize()},
 			{SettingMaxConcurrentStreams, sc.advMaxStreams},
 			{SettingMaxHeaderListSize, sc.maxHeaderListSize()},
-			{SettingInitialWindowSize, uint32(sc.srv.initialStreamRecvWindowSize())},
+			{SettingInitialWindowSize, uint32(sc.streamRecvWindow)},
 		},
 	})
 	sc.unackedSettings++
@@ -887,6 +1011,12 @@ func (sc *serverConn) serve() {
 		defer sc.idleTimer.Stop()
 	}
 
+	if sc.srv.MaxConnectionAge > 0 {
+		sc.maxAgeTimer = time.AfterFunc(sc.srv.MaxConnectionAge, sc.onMaxAgeTimer)
+		// The timer is replaced by the grace timer when it fires.
+		defer func() { sc.maxAgeTimer.Stop() }()
+	}
+
 	go sc.readFrames() // closed by defer sc.conn.Close above
 
 	settingsTimer := time.AfterFunc(firstSettingsTimeout, sc.onSettingsTimer)
@@ -936,6 +1066,15 @@ func (sc *serverConn) serve() {
 				case idleTimerMsg:
 					sc.vlogf("connection is idle")
 					sc.goAway(ErrCodeNo)
+				case maxAgeTimerMsg:
+					sc.vlogf("connection reached its maximum age")
+					sc.goAway(ErrCodeNo)
+					if grace := sc.srv.MaxConnectionAgeGrace; grace > 0 {
+						sc.maxAgeTimer = time.AfterFunc(grace, sc.onMaxAgeGraceTimer)
+					}
+				case maxAgeGraceTimerMsg:
+					sc.vlogf("maximum age grace period elapsed; closing conn from %v", sc.conn.RemoteAddr())
+					return
 				case shutdownTimerMsg:
 					sc.vlogf("GOAWAY close timer fired; closing conn from %v", sc.conn.RemoteAddr())
 					return
@@ -946,6 +1085,15 @@ func (sc *serverConn) serve() {
 				}
 			case *startPushRequest:
 				sc.startPush(v)
+			case maxStreamsUpdate:
+				sc.setMaxStreams(uint32(v))
+			case stalledStreamMsg:
+				// The timer may have fired just as the stream
+				// closed or got flow control back.
+				if st := v.st; sc.streams[st.id] == st && st.isStalled() && !st.resetQueued {
+					sc.vlogf("http2: stream %d stalled on flow control; resetting", st.id)
+					sc.resetStream(streamError(st.id, ErrCodeCancel))
+				}
 			default:
 				panic(fmt.Sprintf("unexpected type %T", v))
 			}
@@ -984,13 +1132,35 @@ type serverMessage int
 var (
 	settingsTimerMsg    = new(serverMessage)
 	idleTimerMsg        = new(serverMessage)
+	maxAgeTimerMsg      = new(serverMessage)
+	maxAgeGraceTimerMsg = new(serverMessage)
 	shutdownTimerMsg    = new(serverMessage)
 	gracefulShutdownMsg = new(serverMessage)
 )
 
-func (sc *serverConn) onSettingsTimer() { sc.sendServeMsg(settingsTimerMsg) }
-func (sc *serverConn) onIdleTimer()     { sc.sendServeMsg(idleTimerMsg) }
-func (sc *serverConn) onShutdownTimer() { sc.sendServeMsg(shutdownTimerMsg) }
+func (sc *serverConn) onSettingsTimer()    { sc.sendServeMsg(settingsTimerMsg) }
+func (sc *serverConn) onIdleTimer()        { sc.sendServeMsg(idleTimerMsg) }
+func (sc *serverConn) onMaxAgeTimer()      { sc.sendServeMsg(maxAgeTimerMsg) }
+func (sc *serverConn) onMaxAgeGraceTimer() { sc.sendServeMsg(maxAgeGraceTimerMsg) }
+func (sc *serverConn) onShutdownTimer()    { sc.sendServeMsg(shutdownTimerMsg) }
+
+// maxStreamsUpdate is sent to serveMsgCh with the new limit of
+// concurrent streams set by Server.SetMaxConcurrentStreams.
+type maxStreamsUpdate uint32
+
+// setMaxStreams advertises n as the new limit of concurrent streams
+// opened by the client.
+func (sc *serverConn) setMaxStreams(n uint32) {
+	sc.serveG.check()
+	if n == sc.advMaxStreams {
+		return
+	}
+	sc.advMaxStreams = n
+	sc.writeFrame(FrameWriteRequest{
+		write: writeSettings{{SettingMaxConcurrentStreams, n}},
+	})
+	sc.unackedSettings++
+}
 
 func (sc *serverConn) sendServeMsg(msg interface{}) {
 	sc.serveG.checkNotOn() // NOT
@@ -1145,10 +1315,13 @@ func (sc *serverConn) writeFrame(wr FrameWriteRequest) {
 	}
 
 	// Don't send a 100-continue response if we've already sent headers.
-	// See golang.org/issue/14030.
-	switch wr.write.(type) {
+	// See golang.org/issue/14030. Informational headers other than 100,
+	// such as 103 Early Hints, don't count.
+	switch w := wr.write.(type) {
 	case *writeResHeaders:
-		wr.stream.wroteHeaders = true
+		if w.httpResCode <= 100 || w.httpResCode >= 200 {
+			wr.stream.wroteHeaders = true
+		}
 	case write100ContinueHeadersFrame:
 		if wr.stream.wroteHeaders {
 			// We do not need to notify wr.done because this frame is
@@ -1272,6 +1445,14 @@ func (sc *serverConn) wroteFrame(res frameWriteResult) {
 			}
 		case handlerPanicRST:
 			sc.closeStream(wr.stream, errHandlerPanicked)
+		case *writeData:
+			if sc.flow.available() <= 0 {
+				// The connection window ran out, which stalls
+				// every stream, not just this one.
+				sc.updateStallTimers()
+			} else {
+				sc.updateStallTimer(wr.stream)
+			}
 		}
 	}
 
@@ -1491,6 +1672,9 @@ func (sc *serverConn) processPing(f *PingFrame) error {
 	if f.IsAck() {
 		// 6.7 PING: " An endpoint MUST NOT respond to PING frames
 		// containing this flag."
+		if sc.bdp != nil && f.Data == bdpPingData {
+			sc.growRecvWindows(sc.bdp.calculate(time.Now()))
+		}
 		return nil
 	}
 	if f.StreamID != 0 {
@@ -1531,10 +1715,12 @@ func (sc *serverConn) processWindowUpdate(f *WindowUpdateFrame) error {
 		if !st.flow.add(int32(f.Increment)) {
 			return sc.countError("bad_flow", streamError(f.StreamID, ErrCodeFlowControl))
 		}
+		sc.updateStallTimer(st)
 	default: // connection-level flow control
 		if !sc.flow.add(int32(f.Increment)) {
 			return goAwayFlowError{}
 		}
+		sc.updateStallTimers()
 	}
 	sc.scheduleFrameWrite()
 	return nil
@@ -1568,6 +1754,12 @@ func (sc *serverConn) closeStream(st *stream, err error) {
 	if st.writeDeadline != nil {
 		st.writeDeadline.Stop()
 	}
+	if st.continueTimer != nil {
+		st.continueTimer.Stop()
+	}
+	if st.stallTimer != nil {
+		st.stallTimer.Stop()
+	}
 	if st.isPushed() {
 		sc.curPushedStreams--
 	} else {
@@ -1630,6 +1822,9 @@ func (sc *serverConn) processSetting(s Setting) error {
 	if VerboseLogs {
 		sc.vlogf("http2: server processing setting %v", s)
 	}
+	sc.peerSettingsMu.Lock()
+	sc.peerSettings.apply(s)
+	sc.peerSettingsMu.Unlock()
 	switch s.ID {
 	case SettingHeaderTableSize:
 		sc.headerTableSize = s.Val
@@ -1679,6 +1874,7 @@ func (sc *serverConn) processSettingInitialWindowSize(val uint32) error {
 			// FLOW_CONTROL_ERROR."
 			return sc.countError("setting_win_size", ConnectionError(ErrCodeFlowControl))
 		}
+		sc.updateStallTimer(st)
 	}
 	return nil
 }
@@ -1766,6 +1962,9 @@ func (sc *serverConn) processData(f *DataFrame) error {
 			return sc.countError("flow_on_data_length", streamError(id, ErrCodeFlowControl))
 		}
 		st.inflow.take(int32(f.Length))
+		if sc.bdp != nil && sc.bdp.add(int32(f.Length), time.Now()) {
+			sc.writeFrame(FrameWriteRequest{write: writePing{bdpPingData}})
+		}
 
 		if len(data) > 0 {
 			wrote, err := st.body.Write(data)
@@ -1825,6 +2024,57 @@ func (st *stream) endStream() {
 		st.body.CloseWithError(io.EOF)
 	}
 	st.state = stateHalfClosedRemote
+	sc.updateStallTimer(st)
+}
+
+// updateStallTimer starts the stall timer of st if its response is
+// blocked on flow control after the request was fully received, and
+// stops it once the response may proceed. See
+// Server.HalfClosedStreamTimeout.
+func (sc *serverConn) updateStallTimer(st *stream) {
+	sc.serveG.check()
+	d := sc.srv.HalfClosedStreamTimeout
+	if d <= 0 {
+		return
+	}
+	if st.isStalled() {
+		if st.stallTimer == nil {
+			st.stallTimer = time.AfterFunc(d, st.onStallTimeout)
+		}
+	} else if st.stallTimer != nil {
+		st.stallTimer.Stop()
+		st.stallTimer = nil
+	}
+}
+
+// updateStallTimers updates the stall timers of all streams, after a
+// change to the connection-level flow control window.
+func (sc *serverConn) updateStallTimers() {
+	sc.serveG.check()
+	if sc.srv.HalfClosedStreamTimeout <= 0 {
+		return
+	}
+	for _, st := range sc.streams {
+		sc.updateStallTimer(st)
+	}
+}
+
+// isStalled reports whether st is half closed (remote) and out of
+// flow control to write its response.
+func (st *stream) isStalled() bool {
+	return st.state == stateHalfClosedRemote && st.flow.available() <= 0
+}
+
+// onStallTimeout is run on its own goroutine (from time.AfterFunc)
+// when the stream has been stalled for the HalfClosedStreamTimeout.
+func (st *stream) onStallTimeout() {
+	st.sc.sendServeMsg(stalledStreamMsg{st})
+}
+
+// stalledStreamMsg is sent to serveMsgCh when the stall timer of st
+// fires.
+type stalledStreamMsg struct {
+	st *stream
 }
 
 // copyTrailersToHandlerRequest is run in the Handler's goroutine in
@@ -1905,10 +2155,8 @@ func (sc *serverConn) processHeaders(f *MetaHeadersFrame) error {
 			return sc.countError("over_max_streams", streamError(id, ErrCodeProtocol))
 		}
 		// Assume it's a network race, where they just haven't
-		// received our last SETTINGS update. But actually
-		// this can't happen yet, because we don't yet provide
-		// a way for users to adjust server parameters at
-		// runtime.
+		// received our last SETTINGS update lowering the limit
+		// (see Server.SetMaxConcurrentStreams).
 		return sc.countError("over_max_streams_race", streamError(id, ErrCodeRefusedStream))
 	}
 
@@ -2049,10 +2297,11 @@ func (sc *serverConn) newStream(id, pusherID uint32, state streamState) *stream
 	st.flow.conn = &sc.flow // link to conn-level counter
 	st.flow.add(sc.initialStreamSendWindowSize)
 	st.inflow.conn = &sc.inflow // link to conn-level counter
-	st.inflow.add(sc.srv.initialStreamRecvWindowSize())
+	st.inflow.add(sc.streamRecvWindow)
 	if sc.hs.WriteTimeout != 0 {
 		st.writeDeadline = time.AfterFunc(sc.hs.WriteTimeout, st.onWriteTimeout)
 	}
+	sc.updateStallTimer(st)
 
 	sc.streams[id] = st
 	sc.writeSched.OpenStream(st.id, OpenStreamOptions{PusherID: pusherID})
@@ -2120,9 +2369,13 @@ func (sc *serverConn) newWriterAndRequest(st *stream, f *MetaHeadersFrame) (*res
 		} else {
 			req.ContentLength = -1
 		}
-		req.Body.(*requestBody).pipe = &pipe{
+		body := req.Body.(*requestBody)
+		body.pipe = &pipe{
 			b: &dataBuffer{expected: req.ContentLength},
 		}
+		if body.needsContinue && sc.srv.ContinueTimeout > 0 {
+			st.continueTimer = time.AfterFunc(sc.srv.ContinueTimeout, body.sendContinue)
+		}
 	}
 	return rw, req, nil
 }
@@ -2186,7 +2439,7 @@ func (sc *serverConn) newWriterAndRequestNoBody(st *stream, rp requestParam) (*r
 	body := &requestBody{
 		conn:          sc,
 		stream:        st,
-		needsContinue: needsContinue,
+		needsContinue: needsContinue && !sc.srv.DisableAutoContinue,
 	}
 	req := &http.Request{
 		Method:     rp.method,
@@ -2217,6 +2470,7 @@ func (sc *serverConn) newResponseWriter(st *stream, req *http.Request) *response
 	rws.bw.Reset(chunkWriter{rws})
 	rws.stream = st
 	rws.req = req
+	rws.body, _ = req.Body.(*requestBody)
 	return &responseWriter{rws: rws}
 }
 
@@ -2336,13 +2590,13 @@ func (sc *serverConn) sendWindowUpdate(st *stream) {
 
 	var n int32
 	if st == nil {
-		if avail, windowSize := sc.inflow.available(), sc.srv.initialConnRecvWindowSize(); avail > windowSize/2 {
+		if avail, windowSize := sc.inflow.available(), sc.connRecvWindow; avail > sc.windowUpdateThreshold(windowSize) {
 			return
 		} else {
 			n = windowSize - avail
 		}
 	} else {
-		if avail, windowSize := st.inflow.available(), sc.srv.initialStreamRecvWindowSize(); avail > windowSize/2 {
+		if avail, windowSize := st.inflow.available(), sc.streamRecvWindow; avail > sc.windowUpdateThreshold(windowSize) {
 			return
 		} else {
 			n = windowSize - avail
@@ -2361,6 +2615,17 @@ func (sc *serverConn) sendWindowUpdate(st *stream) {
 	sc.sendWindowUpdate32(st, int32(n))
 }
 
+// windowUpdateThreshold returns the number of tokens of a window of
+// windowSize below which the window is replenished. When the windows are
+// auto-tuned, they are replenished sooner to keep the pipe full while
+// the bandwidth-delay product is sampled.
+func (sc *serverConn) windowUpdateThreshold(windowSize int32) int32 {
+	if sc.bdp != nil {
+		return windowSize - windowSize/4
+	}
+	return windowSize / 2
+}
+
 // st may be nil for conn-level
 func (sc *serverConn) sendWindowUpdate32(st *stream, n int32) {
 	sc.serveG.check()
@@ -2389,6 +2654,31 @@ func (sc *serverConn) sendWindowUpdate32(st *stream, n int32) {
 	}
 }
 
+// growRecvWindows grows the inbound flow control windows to n bytes,
+// the new estimate of the bandwidth-delay product, if it is larger.
+func (sc *serverConn) growRecvWindows(n int32) {
+	sc.serveG.check()
+	if n > sc.streamRecvWindow {
+		delta := n - sc.streamRecvWindow
+		sc.streamRecvWindow = n
+		sc.writeFrame(FrameWriteRequest{
+			write: writeSettings{{SettingInitialWindowSize, uint32(n)}},
+		})
+		sc.unackedSettings++
+		// "a SETTINGS frame can alter the initial flow-control
+		// window size for streams with active flow-control windows"
+		// (RFC 7540, section 6.9.2), so the client can send delta
+		// more bytes on its open streams.
+		for _, st := range sc.streams {
+			st.inflow.add(delta)
+		}
+	}
+	if n > sc.connRecvWindow {
+		sc.sendWindowUpdate32(nil, n-sc.connRecvWindow)
+		sc.connRecvWindow = n
+	}
+}
+
 // requestBody is the Handler's Request.Body type.
 // Read and Close may be called concurrently.
 type requestBody struct {
@@ -2399,6 +2689,30 @@ type requestBody struct {
 	sawEOF        bool      // for use by Read only
 	pipe          *pipe     // non-nil if we have a HTTP entity message body
 	needsContinue bool      // need to send a 100-continue
+	continueOnce  sync.Once // for use by sendContinue only
+}
+
+// sendContinue sends a 100-continue response, at most once, and not
+// at all once the handler has written headers of its own. It is
+// called from the handler goroutine on the first Read, and from the
+// continue timer if Server.ContinueTimeout is set.
+func (b *requestBody) sendContinue() {
+	b.continueOnce.Do(func() {
+		b.conn.write100ContinueHeaders(b.stream)
+	})
+}
+
+// cancelContinue is called from the handler goroutine when it writes a
+// final or 100 Continue response header, after which an automatic 100
+// Continue response would be redundant or out of order.
+func (b *requestBody) cancelContinue() {
+	if b == nil {
+		return
+	}
+	b.continueOnce.Do(func() {})
+	if t := b.stream.continueTimer; t != nil {
+		t.Stop()
+	}
 }
 
 func (b *requestBody) Close() error {
@@ -2412,8 +2726,7 @@ func (b *requestBody) Close() error {
 
 func (b *requestBody) Read(p []byte) (n int, err error) {
 	if b.needsContinue {
-		b.needsContinue = false
-		b.conn.write100ContinueHeaders(b.stream)
+		b.sendContinue()
 	}
 	if b.pipe == nil || b.sawEOF {
 		return 0, io.EOF
@@ -2450,6 +2763,7 @@ type responseWriterState struct {
 	// immutable within a request:
 	stream *stream
 	req    *http.Request
+	body   *requestBody // nil for pushed requests
 	conn   *serverConn
 
 	// TODO: adjust buffer writing sizes based on server config, frame size updates from peer, etc
@@ -2749,6 +3063,9 @@ func (rws *responseWriterState) writeHeader(code int) {
 	}
 
 	checkWriteHeaderCode(code)
+	if code >= 200 || code == 100 {
+		rws.body.cancelContinue()
+	}
 
 	// Handle informational headers
 	if code >= 100 && code <= 199 {
@@ -2279,6 +2279,148 @@ func TestServer_Response_Empty_Data_Not_FlowControlled(t *testing.T) {
 	})
 }
 
+// testServer100Continue sends a request with an "Expect: 100-continue"
+// header to a server configured by opt and running handler. If
+// wantContinue, it waits for a 100 Continue response before sending the
+// request body; otherwise it sends the body straight away. It then
+// checks that the next response is the final 200.
+func testServer100Continue(t *testing.T, opt func(*Server), wantContinue bool, handler http.HandlerFunc) {
+	st := newServerTester(t, handler, opt)
+	defer st.Close()
+	st.greet()
+	st.writeHeaders(HeadersFrameParam{
+		StreamID:      1, // clients send odd numbers
+		BlockFragment: st.encodeHeader(":method", "POST", "expect", "100-continue"),
+		EndStream:     false,
+		EndHeaders:    true,
+	})
+	if wantContinue {
+		hf := st.wantHeaders()
+		goth := st.decodeHeader(hf.HeaderBlockFragment())
+		if wanth := [][2]string{{":status", "100"}}; !reflect.DeepEqual(goth, wanth) {
+			t.Fatalf("Got headers %v; want %v", goth, wanth)
+		}
+	}
+	st.writeData(1, true, []byte("foo"))
+	hf := st.wantHeaders()
+	goth := st.decodeHeader(hf.HeaderBlockFragment())
+	if len(goth) == 0 || goth[0] != [2]string{":status", "200"} {
+		t.Fatalf("Got headers %v; want :status 200", goth)
+	}
+	if df := st.wantData(); string(df.Data()) != "foo" {
+		t.Errorf("Client read %q; want %q", df.Data(), "foo")
+	}
+}
+
+func echoBodyHandler(w http.ResponseWriter, r *http.Request) {
+	io.Copy(w, r.Body)
+}
+
+func TestServer_DisableAutoContinue(t *testing.T) {
+	testServer100Continue(t, func(s *Server) {
+		s.DisableAutoContinue = true
+	}, false, echoBodyHandler)
+}
+
+func TestServer_DisableAutoContinue_Explicit100(t *testing.T) {
+	testServer100Continue(t, func(s *Server) {
+		s.DisableAutoContinue = true
+	}, true, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusContinue)
+		echoBodyHandler(w, r)
+	})
+}
+
+func TestServer_ContinueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		// Don't touch the body until the client has seen the 100
+		// Continue sent by the timer.
+		<-release
+		echoBodyHandler(w, r)
+	}, func(s *Server) {
+		s.ContinueTimeout = 10 * time.Millisecond
+	})
+	defer st.Close()
+	defer once.Do(func() { close(release) })
+	st.greet()
+	st.writeHeaders(HeadersFrameParam{
+		StreamID:      1, // clients send odd numbers
+		BlockFragment: st.encodeHeader(":method", "POST", "expect", "100-continue"),
+		EndStream:     false,
+		EndHeaders:    true,
+	})
+	hf := st.wantHeaders()
+	goth := st.decodeHeader(hf.HeaderBlockFragment())
+	if wanth := [][2]string{{":status", "100"}}; !reflect.DeepEqual(goth, wanth) {
+		t.Fatalf("Got headers %v; want %v", goth, wanth)
+	}
+	once.Do(func() { close(release) })
+
+	// The handler's read must not send a second 100 Continue.
+	st.writeData(1, true, []byte("foo"))
+	hf = st.wantHeaders()
+	goth = st.decodeHeader(hf.HeaderBlockFragment())
+	if len(goth) == 0 || goth[0] != [2]string{":status", "200"} {
+		t.Fatalf("Got headers %v; want :status 200", goth)
+	}
+	if df := st.wantData(); string(df.Data()) != "foo" {
+		t.Errorf("Client read %q; want %q", df.Data(), "foo")
+	}
+}
+
+func TestServer_ContinueTimeout_HandlerResponds(t *testing.T) {
+	const timeout = 10 * time.Millisecond
+	release := make(chan struct{})
+	var once sync.Once
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		// Respond without reading the body, and hold the headers
+		// in the buffer until well after the timeout has elapsed.
+		w.WriteHeader(http.StatusOK)
+		<-release
+		io.WriteString(w, "done")
+	}, func(s *Server) {
+		s.ContinueTimeout = timeout
+	})
+	defer st.Close()
+	defer once.Do(func() { close(release) })
+	st.greet()
+	st.writeHeaders(HeadersFrameParam{
+		StreamID:      1, // clients send odd numbers
+		BlockFragment: st.encodeHeader(":method", "POST", "expect", "100-continue"),
+		EndStream:     false,
+		EndHeaders:    true,
+	})
+	time.Sleep(5 * timeout)
+	once.Do(func() { close(release) })
+
+	hf := st.wantHeaders()
+	goth := st.decodeHeader(hf.HeaderBlockFragment())
+	if len(goth) == 0 || goth[0] != [2]string{":status", "200"} {
+		t.Fatalf("Got headers %v; want :status 200 and no 100 Continue", goth)
+	}
+	if df := st.wantData(); string(df.Data()) != "done" {
+		t.Errorf("Client read %q; want %q", df.Data(), "done")
+	}
+}
+
+func TestServer_ContinueTimeout_Explicit100(t *testing.T) {
+	const timeout = 10 * time.Millisecond
+	sent := make(chan struct{})
+	testServer100Continue(t, func(s *Server) {
+		s.ContinueTimeout = timeout
+	}, true, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusContinue)
+		// Let the timeout elapse; the timer must not send a
+		// second 100 Continue, nor must the read below.
+		time.Sleep(5 * timeout)
+		close(sent)
+		echoBodyHandler(w, r)
+	})
+	<-sent
+}
+
 func TestServer_Response_Automatic100Continue(t *testing.T) {
 	const msg = "foo"
 	const reply = "bar"
@@ -2346,6 +2488,56 @@ func TestServer_Response_Automatic100Continue(t *testing.T) {
 	})
 }
 
+func TestServer_Response_EarlyHintsThen100Continue(t *testing.T) {
+	const msg = "foo"
+	const reply = "bar"
+	testServerResponse(t, func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		buf := make([]byte, len(msg))
+		// An informational response other than 100 must not prevent
+		// this read from sending the 100-continue.
+		if n, err := io.ReadFull(r.Body, buf); err != nil || n != len(msg) || string(buf) != msg {
+			return fmt.Errorf("ReadFull = %q, %v; want %q, nil", buf[:n], err, msg)
+		}
+		_, err := io.WriteString(w, reply)
+		return err
+	}, func(st *serverTester) {
+		st.writeHeaders(HeadersFrameParam{
+			StreamID:      1, // clients send odd numbers
+			BlockFragment: st.encodeHeader(":method", "POST", "expect", "100-continue"),
+			EndStream:     false,
+			EndHeaders:    true,
+		})
+		for _, wanth := range [][][2]string{
+			{{":status", "103"}, {"link", "</style.css>; rel=preload; as=style"}},
+			{{":status", "100"}},
+		} {
+			hf := st.wantHeaders()
+			if hf.StreamEnded() {
+				t.Fatal("unexpected END_STREAM flag")
+			}
+			if goth := st.decodeHeader(hf.HeaderBlockFragment()); !reflect.DeepEqual(goth, wanth) {
+				t.Fatalf("Got headers %v; want %v", goth, wanth)
+			}
+		}
+
+		st.writeData(1, true, []byte(msg))
+
+		hf := st.wantHeaders()
+		if hf.StreamEnded() {
+			t.Fatal("expected data to follow")
+		}
+		if goth := st.decodeHeader(hf.HeaderBlockFragment()); len(goth) == 0 || goth[0] != [2]string{":status", "200"} {
+			t.Errorf("Got headers %v; want status 200", goth)
+		}
+		df := st.wantData()
+		if string(df.Data()) != reply {
+			t.Errorf("Client read %q; want %q", df.Data(), reply)
+		}
+	})
+}
+
 func TestServer_HandlerWriteErrorOnDisconnect(t *testing.T) {
 	errc := make(chan error, 1)
 	testServerResponse(t, func(w http.ResponseWriter, r *http.Request) error {
@@ -3198,6 +3390,59 @@ func BenchmarkServerPosts(b *testing.B) {
 	}
 }
 
+// BenchmarkServerConcurrentPosts sends batches of small POST requests
+// that are in flight at the same time, to measure the allocations of
+// buffering their request bodies.
+func BenchmarkServerConcurrentPosts(b *testing.B) {
+	defer disableGoroutineTracking()()
+	b.ReportAllocs()
+
+	const (
+		batch = 50
+		msg   = "Hello, world"
+	)
+	st := newServerTester(b, func(w http.ResponseWriter, r *http.Request) {
+		if n, err := io.Copy(ioutil.Discard, r.Body); n != int64(len(msg)) || err != nil {
+			b.Errorf("Copy error; got %v, %v; want %v, nil", n, err, len(msg))
+		}
+		io.WriteString(w, msg)
+	})
+	defer st.Close()
+	st.greet()
+
+	// Give the server quota to reply. (plus it has the 64KB)
+	if err := st.fr.WriteWindowUpdate(0, uint32(b.N*len(msg))); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += batch {
+		n := batch
+		if b.N-i < n {
+			n = b.N - i
+		}
+		for j := 0; j < n; j++ {
+			id := 1 + uint32(i+j)*2
+			st.writeHeaders(HeadersFrameParam{
+				StreamID:      id,
+				BlockFragment: st.encodeHeader(":method", "POST"),
+				EndStream:     false,
+				EndHeaders:    true,
+			})
+			st.writeData(id, true, []byte(msg))
+		}
+		for done := 0; done < n; {
+			f, err := st.readFrame()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if df, ok := f.(*DataFrame); ok && df.StreamEnded() {
+				done++
+			}
+		}
+	}
+}
+
 // Send a stream of messages from server to client in separate data frames.
 // Brings up performance issues seen in long streams.
 // Created to show problem in go issue #18502
@@ -3875,6 +4120,455 @@ func TestServerIdleTimeout_AfterRequest(t *testing.T) {
 	}
 }
 
+func TestServerMaxConnectionAge(t *testing.T) {
+	release := make(chan struct{})
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}, func(h2s *Server) {
+		h2s.MaxConnectionAge = 50 * time.Millisecond
+	})
+	defer st.Close()
+
+	st.greet()
+	st.bodylessReq1()
+
+	// The connection is shut down gracefully, letting the open stream
+	// complete.
+	ga := st.wantGoAway()
+	if ga.ErrCode != ErrCodeNo || ga.LastStreamID != 1 {
+		t.Errorf("GOAWAY = %v, %v; want ErrCodeNo, last stream 1", ga.ErrCode, ga.LastStreamID)
+	}
+	close(release)
+	st.wantHeaders()
+
+	for {
+		if _, err := st.readFrame(); err != nil {
+			if err != io.EOF {
+				t.Errorf("unexpected readFrame error: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestServerMaxConnectionAgeGrace(t *testing.T) {
+	const grace = 50 * time.Millisecond
+	handlerDone := make(chan error, 1)
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		handlerDone <- r.Context().Err()
+	}, func(h2s *Server) {
+		h2s.MaxConnectionAge = 50 * time.Millisecond
+		h2s.MaxConnectionAgeGrace = grace
+	})
+	defer st.Close()
+
+	st.greet()
+	st.bodylessReq1()
+
+	ga := st.wantGoAway()
+	if ga.ErrCode != ErrCodeNo {
+		t.Errorf("GOAWAY error = %v; want ErrCodeNo", ga.ErrCode)
+	}
+	sentGoAway := time.Now()
+
+	// The stream never completes, so the connection is closed when
+	// the grace period elapses.
+	for {
+		if _, err := st.readFrame(); err != nil {
+			if err != io.EOF {
+				t.Errorf("unexpected readFrame error: %v", err)
+			}
+			break
+		}
+	}
+	if d := time.Since(sentGoAway); d < grace/2 {
+		t.Errorf("connection closed %v after GOAWAY; want about %v", d, grace)
+	}
+	if err := <-handlerDone; err == nil {
+		t.Error("handler context not done after connection closed")
+	}
+}
+
+func TestServerSetMaxConcurrentStreams(t *testing.T) {
+	release := make(chan struct{})
+	inHandler := make(chan bool, 2)
+	var h2s *Server
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		inHandler <- true
+		<-release
+	}, func(s *Server) {
+		h2s = s
+	})
+	defer st.Close()
+
+	st.greet()
+	req := func(id uint32) {
+		st.writeHeaders(HeadersFrameParam{
+			StreamID:      id,
+			BlockFragment: st.encodeHeader(),
+			EndStream:     true,
+			EndHeaders:    true,
+		})
+	}
+	req(1)
+	req(3)
+	<-inHandler
+	<-inHandler
+
+	// Lower the limit below the number of open streams.
+	h2s.SetMaxConcurrentStreams(1)
+	sf := st.wantSettings()
+	if v, ok := sf.Value(SettingMaxConcurrentStreams); !ok || v != 1 {
+		t.Fatalf("SETTINGS_MAX_CONCURRENT_STREAMS = %v, %v; want 1", v, ok)
+	}
+
+	// A stream opened before the client acknowledged the new limit
+	// is refused.
+	req(5)
+	st.wantRSTStream(5, ErrCodeRefusedStream)
+
+	// Once acknowledged, exceeding the limit is a protocol error.
+	st.writeSettingsAck()
+	req(7)
+	st.wantRSTStream(7, ErrCodeProtocol)
+
+	// The streams opened before the limit was lowered complete.
+	close(release)
+	for i := 0; i < 2; i++ {
+		if hf := st.wantHeaders(); hf.StreamID != 1 && hf.StreamID != 3 {
+			t.Errorf("got HEADERS for stream %v; want stream 1 or 3", hf.StreamID)
+		}
+	}
+
+	// New streams within the limit are accepted again.
+	req(9)
+	<-inHandler
+	if hf := st.wantHeaders(); hf.StreamID != 9 {
+		t.Errorf("got HEADERS for stream %v; want stream 9", hf.StreamID)
+	}
+}
+
+// SetMaxConcurrentStreams also applies to the connections of a Server
+// that was not configured with ConfigureServer.
+func TestServerSetMaxConcurrentStreams_ServeConn(t *testing.T) {
+	var s Server
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	go s.ServeConn(c1, &ServeConnOpts{Handler: http.NotFoundHandler()})
+
+	c2.SetDeadline(time.Now().Add(10 * time.Second))
+	fr := NewFramer(c2, c2)
+	go func() {
+		io.WriteString(c2, ClientPreface)
+		fr.WriteSettings()
+	}()
+	// wantMaxStreams reads frames until the server sends SETTINGS,
+	// and returns its SETTINGS_MAX_CONCURRENT_STREAMS.
+	wantMaxStreams := func() uint32 {
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sf, ok := f.(*SettingsFrame); ok && !sf.IsAck() {
+				v, _ := sf.Value(SettingMaxConcurrentStreams)
+				return v
+			}
+		}
+	}
+	if v := wantMaxStreams(); v != defaultMaxStreams {
+		t.Fatalf("initial SETTINGS_MAX_CONCURRENT_STREAMS = %v; want %v", v, defaultMaxStreams)
+	}
+	s.SetMaxConcurrentStreams(7)
+	if v := wantMaxStreams(); v != 7 {
+		t.Fatalf("SETTINGS_MAX_CONCURRENT_STREAMS = %v; want 7", v)
+	}
+}
+
+func TestServerHalfClosedStreamTimeout(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	writeErr := make(chan error, 1)
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		// Write more than the initial flow control window.
+		_, err := w.Write(make([]byte, 2*initialWindowSize))
+		writeErr <- err
+	}, func(h2s *Server) {
+		h2s.HalfClosedStreamTimeout = timeout
+	})
+	defer st.Close()
+
+	st.greet()
+	st.bodylessReq1()
+	st.wantHeaders()
+
+	// The client never replenishes the window, so the response
+	// stalls once the window is used up.
+	var n int
+	for n < initialWindowSize {
+		df := st.wantData()
+		n += len(df.Data())
+	}
+	stalled := time.Now()
+	st.wantRSTStream(1, ErrCodeCancel)
+	if d := time.Since(stalled); d < timeout/2 {
+		t.Errorf("stream reset %v after stalling; want about %v", d, timeout)
+	}
+	if err := <-writeErr; err == nil {
+		t.Error("handler Write succeeded; want error after stream reset")
+	}
+}
+
+// A half-closed stream whose response is stalled because another
+// stream used up the connection-level window is reset as well.
+func TestServerHalfClosedStreamTimeout_ConnWindow(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hog" {
+			// Use up the whole connection window.
+			w.Write(make([]byte, initialWindowSize))
+			return
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}, func(h2s *Server) {
+		h2s.HalfClosedStreamTimeout = timeout
+	})
+	defer st.Close()
+
+	st.greet()
+	// Make the stream windows larger than the connection window, so
+	// that only the latter runs out.
+	if err := st.fr.WriteSettings(Setting{SettingInitialWindowSize, 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	st.wantSettingsAck()
+	st.writeHeaders(HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: st.encodeHeader(":path", "/idle"),
+		EndStream:     true,
+		EndHeaders:    true,
+	})
+	st.writeHeaders(HeadersFrameParam{
+		StreamID:      3,
+		BlockFragment: st.encodeHeader(":path", "/hog"),
+		EndStream:     true,
+		EndHeaders:    true,
+	})
+
+	// Without a stall timer on stream 1, no frame would come.
+	st.cc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var n int
+	var stalled time.Time
+	for {
+		f, err := st.readFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch f := f.(type) {
+		case *DataFrame:
+			if f.StreamID != 3 {
+				t.Fatalf("got DATA for stream %v; want stream 3", f.StreamID)
+			}
+			if n += len(f.Data()); n == initialWindowSize {
+				stalled = time.Now()
+			}
+			continue
+		case *HeadersFrame:
+			continue
+		case *RSTStreamFrame:
+			if f.StreamID != 1 || f.ErrCode != ErrCodeCancel {
+				t.Fatalf("got RST_STREAM stream=%v code=%v; want stream 1 with CANCEL", f.StreamID, f.ErrCode)
+			}
+		default:
+			t.Fatalf("got %v; want DATA, HEADERS or RST_STREAM", summarizeFrame(f))
+		}
+		break
+	}
+	if n != initialWindowSize {
+		t.Fatalf("stream 1 reset after %d bytes of stream 3; want %d", n, initialWindowSize)
+	}
+	if d := time.Since(stalled); d < timeout/2 {
+		t.Errorf("stream reset %v after stalling; want about %v", d, timeout)
+	}
+}
+
+func TestServerAutoTunedUploadBuffer(t *testing.T) {
+	const (
+		window = initialWindowSize
+		limit  = 1 << 20
+		rtt    = 50 * time.Millisecond
+	)
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+	}, func(h2s *Server) {
+		h2s.MaxUploadBufferPerConnection = window
+		h2s.MaxUploadBufferPerStream = window
+		h2s.MaxAutoTunedUploadBuffer = limit
+	})
+	defer st.Close()
+
+	st.writePreface()
+	st.writeInitialSettings()
+	st.wantSettings()
+	st.writeSettingsAck()
+	st.writeHeaders(HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: st.encodeHeader(":method", "POST"),
+		EndHeaders:    true,
+	})
+
+	// Frames are read concurrently with the writes of the client. A
+	// frame is only valid until the next one is read, so the reader
+	// waits for each frame to be handled.
+	frames := make(chan Frame)
+	handled := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(frames)
+		for {
+			f, err := st.readFrame()
+			if err != nil {
+				return
+			}
+			select {
+			case frames <- f:
+			case <-done:
+				return
+			}
+			select {
+			case <-handled:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// The client uploads as fast as the flow control windows allow,
+	// over a link that delays the acknowledgment of PING frames and
+	// the flow control updates by rtt.
+	type credit struct {
+		at           time.Time
+		stream, conn int64
+	}
+	var (
+		streamWindow int64 = window // latest SETTINGS_INITIAL_WINDOW_SIZE
+		streamAvail  int64 = window
+		connAvail    int64 = window
+		credits      []credit
+		ping         *PingFrame
+		windowSizes  []int64
+	)
+	handleFrame := func(f Frame) {
+		defer func() { handled <- struct{}{} }()
+		switch f := f.(type) {
+		case *PingFrame:
+			if f.IsAck() {
+				t.Fatalf("unexpected PING ack")
+			}
+			ping = f
+		case *WindowUpdateFrame:
+			c := credit{at: time.Now().Add(rtt)}
+			if f.StreamID == 0 {
+				c.conn = int64(f.Increment)
+			} else {
+				c.stream = int64(f.Increment)
+			}
+			credits = append(credits, c)
+		case *SettingsFrame:
+			if f.IsAck() {
+				return
+			}
+			if v, ok := f.Value(SettingInitialWindowSize); ok {
+				credits = append(credits, credit{at: time.Now().Add(rtt), stream: int64(v) - streamWindow})
+				streamWindow = int64(v)
+				windowSizes = append(windowSizes, streamWindow)
+			}
+			st.writeSettingsAck()
+		default:
+			t.Fatalf("unexpected frame %v", summarizeFrame(f))
+		}
+	}
+	send := func() {
+		now := time.Now()
+		for len(credits) > 0 && !credits[0].at.After(now) {
+			streamAvail += credits[0].stream
+			connAvail += credits[0].conn
+			credits = credits[1:]
+		}
+		n := streamAvail
+		if connAvail < n {
+			n = connAvail
+		}
+		streamAvail -= n
+		connAvail -= n
+		for n > 0 {
+			chunk := int64(initialMaxFrameSize)
+			if n < chunk {
+				chunk = n
+			}
+			st.writeData(1, false, make([]byte, chunk))
+			n -= chunk
+		}
+	}
+	wait := func() {
+		select {
+		case f, ok := <-frames:
+			if !ok {
+				t.Fatal("connection closed")
+			}
+			handleFrame(f)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	const maxRounds = 20
+	for round := 0; round < maxRounds && streamWindow < limit; round++ {
+		// A sample starts with the first DATA frame after the
+		// previous PING was acknowledged.
+		for ping == nil && streamWindow < limit {
+			send()
+			wait()
+		}
+		if ping == nil {
+			// The server stops sampling once at the limit.
+			break
+		}
+		for ackAt := time.Now().Add(rtt); time.Now().Before(ackAt); {
+			send()
+			wait()
+		}
+		if err := st.fr.WritePing(true, ping.Data); err != nil {
+			t.Fatal(err)
+		}
+		ping = nil
+	}
+	st.writeData(1, true, nil)
+	for {
+		f, ok := <-frames
+		if !ok {
+			t.Fatal("connection closed before the response")
+		}
+		if _, ok := f.(*HeadersFrame); ok {
+			break
+		}
+		handled <- struct{}{}
+	}
+
+	if streamWindow != limit {
+		t.Fatalf("stream window grew to %v in %v rounds; want %v", windowSizes, maxRounds, limit)
+	}
+	for i := 1; i < len(windowSizes); i++ {
+		if windowSizes[i] <= windowSizes[i-1] {
+			t.Errorf("stream window sizes = %v; want increasing sizes", windowSizes)
+		}
+	}
+}
+
 // grpc-go closes the Request.Body currently with a Read.
 // Verify that it doesn't race.
 // See https://github.com/grpc/grpc-go/pull/938
@@ -4490,3 +5184,129 @@ func TestServerInitialFlowControlWindow(t *testing.T) {
 		})
 	}
 }
+
+// recordingWriteScheduler is a WriteScheduler that records the calls
+// made to it before passing them on to an underlying scheduler.
+type recordingWriteScheduler struct {
+	WriteScheduler
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (ws *recordingWriteScheduler) record(format string, args ...interface{}) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.events = append(ws.events, fmt.Sprintf(format, args...))
+}
+
+func (ws *recordingWriteScheduler) eventsForStream(id uint32) []string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	var evs []string
+	suffix := fmt.Sprintf(" %d", id)
+	for _, ev := range ws.events {
+		if strings.HasSuffix(ev, suffix) {
+			evs = append(evs, strings.TrimSuffix(ev, suffix))
+		}
+	}
+	return evs
+}
+
+func (ws *recordingWriteScheduler) OpenStream(streamID uint32, options OpenStreamOptions) {
+	ws.record("open %d", streamID)
+	ws.WriteScheduler.OpenStream(streamID, options)
+}
+
+func (ws *recordingWriteScheduler) CloseStream(streamID uint32) {
+	ws.record("close %d", streamID)
+	ws.WriteScheduler.CloseStream(streamID)
+}
+
+func (ws *recordingWriteScheduler) AdjustStream(streamID uint32, priority PriorityParam) {
+	ws.record("adjust %d", streamID)
+	ws.WriteScheduler.AdjustStream(streamID, priority)
+}
+
+func (ws *recordingWriteScheduler) Push(wr FrameWriteRequest) {
+	ws.record("push %d", wr.StreamID())
+	ws.WriteScheduler.Push(wr)
+}
+
+func (ws *recordingWriteScheduler) Pop() (FrameWriteRequest, bool) {
+	wr, ok := ws.WriteScheduler.Pop()
+	if ok {
+		ws.record("pop %d", wr.StreamID())
+	}
+	return wr, ok
+}
+
+func TestServer_CustomWriteScheduler(t *testing.T) {
+	ws := &recordingWriteScheduler{WriteScheduler: NewPriorityWriteScheduler(nil)}
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello, ")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, r.URL.Path)
+	}, func(s *Server) {
+		s.NewWriteScheduler = func() WriteScheduler { return ws }
+	})
+	defer st.Close()
+	st.greet()
+
+	st.writePriority(3, PriorityParam{Weight: 255})
+	for _, id := range []uint32{1, 3} {
+		st.writeHeaders(HeadersFrameParam{
+			StreamID:      id,
+			BlockFragment: st.encodeHeader(":path", fmt.Sprintf("/%d", id)),
+			EndStream:     true,
+			EndHeaders:    true,
+		})
+	}
+	for ended := 0; ended < 2; {
+		f, err := st.readFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if df, ok := f.(*DataFrame); ok && df.StreamEnded() {
+			ended++
+		}
+	}
+
+	// Each stream is opened before any of its frames are pushed, every
+	// pushed frame is popped, and the stream is closed last.
+	for _, id := range []uint32{1, 3} {
+		var evs []string
+		if !waitCondition(5*time.Second, 10*time.Millisecond, func() bool {
+			evs = ws.eventsForStream(id)
+			return len(evs) > 0 && evs[len(evs)-1] == "close"
+		}) {
+			t.Fatalf("stream %d: events %q; want the last to be close", id, evs)
+		}
+		if id == 3 && evs[0] == "adjust" {
+			evs = evs[1:]
+		}
+		if evs[0] != "open" {
+			t.Errorf("stream %d: events %q; want the first to be open", id, evs)
+		}
+		queued, pushes := 0, 0
+		for _, ev := range evs[1 : len(evs)-1] {
+			switch ev {
+			case "push":
+				queued++
+				pushes++
+			case "pop":
+				if queued--; queued < 0 {
+					t.Errorf("stream %d: events %q; pop before push", id, evs)
+				}
+			default:
+				t.Errorf("stream %d: unexpected event %q in %q", id, ev, evs)
+			}
+		}
+		if pushes == 0 || queued != 0 {
+			t.Errorf("stream %d: events %q; want every pushed frame popped", id, evs)
+		}
+	}
+	if evs := ws.eventsForStream(3); len(evs) == 0 || evs[0] != "adjust" {
+		t.Errorf("stream 3: events %q; want the first to be adjust", evs)
+	}
+}
@@ -136,6 +136,20 @@ type Transport struct {
 	// If zero, no health check is performed.
 	ReadIdleTimeout time.Duration
 
+	// MaxReceiveBufferPerConnection is the size of the flow control
+	// window for each connection, that is how much response data the
+	// Transport lets a server send on a connection before it is read.
+	// Clients on links with a large bandwidth-delay product may need a
+	// larger window to download at full speed. The HTTP/2 spec does not
+	// allow this to be smaller than 65535; if it is, a default of 1GB
+	// is used instead.
+	MaxReceiveBufferPerConnection int32
+
+	// MaxReceiveBufferPerStream is the size of the initial flow control
+	// window for each stream. If zero or negative, a default of 4MB is
+	// used.
+	MaxReceiveBufferPerStream int32
+
 	// PingTimeout is the timeout after which the connection will be closed
 	// if a response to Ping is not received.
 	// Defaults to 15s.
@@ -152,6 +166,14 @@ type Transport struct {
 	// The errType consists of only ASCII word characters.
 	CountError func(errType string)
 
+	// FrameDump, if non-nil, receives a one-line summary of each
+	// frame read or written on the transport's connections, giving
+	// its direction, type, flags, stream ID, length and the main
+	// fields of SETTINGS, WINDOW_UPDATE, RST_STREAM and a few other
+	// frames. Writes to FrameDump from different connections may be
+	// concurrent.
+	FrameDump io.Writer
+
 	// t1, if non-nil, is the standard library Transport using
 	// this transport. Its settings are used (but not its
 	// RoundTrip method, etc).
@@ -171,6 +193,20 @@ func (t *Transport) maxHeaderListSize() uint32 {
 	return t.MaxHeaderListSize
 }
 
+func (t *Transport) initialConnRecvWindowSize() int32 {
+	if t.MaxReceiveBufferPerConnection >= initialWindowSize {
+		return t.MaxReceiveBufferPerConnection
+	}
+	return transportDefaultConnFlow + initialWindowSize
+}
+
+func (t *Transport) initialStreamRecvWindowSize() int32 {
+	if t.MaxReceiveBufferPerStream > 0 {
+		return t.MaxReceiveBufferPerStream
+	}
+	return transportDefaultStreamFlow
+}
+
 func (t *Transport) disableCompression() bool {
 	return t.DisableCompression || (t.t1 != nil && t.t1.DisableCompression)
 }
@@ -297,6 +333,7 @@ type ClientConn struct {
 	maxConcurrentStreams  uint32
 	peerMaxHeaderListSize uint64
 	initialWindowSize     uint32
+	peerSettings          PeerSettings // as advertised, for PeerSettings; guarded by mu
 
 	// reqHeaderMu is a 1-element semaphore channel controlling access to sending new requests.
 	// Write to reqHeaderMu to lock it, read from it to unlock.
@@ -519,17 +556,18 @@ func (t *Transport) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Res
 		traceGotConn(req, cc, reused)
 		res, err := cc.RoundTrip(req)
 		if err != nil && retry <= 6 {
+			roundTripErr := err
 			if req, err = shouldRetryRequest(req, err); err == nil {
 				// After the first retry, do exponential backoff with 10% jitter.
 				if retry == 0 {
-					t.vlogf("RoundTrip retrying after failure: %v", err)
+					t.vlogf("RoundTrip retrying after failure: %v", roundTripErr)
 					continue
 				}
 				backoff := float64(uint(1) << (uint(retry) - 1))
 				backoff += backoff * (0.1 * mathrand.Float64())
 				select {
 				case <-time.After(time.Second * time.Duration(backoff)):
-					t.vlogf("RoundTrip retrying after failure: %v", err)
+					t.vlogf("RoundTrip retrying after failure: %v", roundTripErr)
 					continue
 				case <-req.Context().Done():
 					err = req.Context().Err()
@@ -669,6 +707,13 @@ func (t *Transport) expectContinueTimeout() time.Duration {
 	return t.t1.ExpectContinueTimeout
 }
 
+// NewClientConn returns a ClientConn that speaks HTTP/2 over c, which
+// may have been obtained from a custom dialer, a tunnel or a protocol
+// upgrade. It writes the client connection preface and initial
+// SETTINGS to c before returning. The ClientConn is not added to the
+// Transport's connection pool; the caller is responsible for closing it.
+//
+// If writing the preface fails, c is closed and the error is returned.
 func (t *Transport) NewClientConn(c net.Conn) (*ClientConn, error) {
 	return t.newClientConn(c, t.disableKeepAlives())
 }
@@ -683,6 +728,7 @@ func (t *Transport) newClientConn(c net.Conn, singleUse bool) (*ClientConn, erro
 		initialWindowSize:     65535,                       // spec default
 		maxConcurrentStreams:  initialMaxConcurrentStreams, // "infinite", per spec. Use a smaller value until we have received server settings.
 		peerMaxHeaderListSize: 0xffffffffffffffff,          // "infinite", per spec. Use 2^64-1 instead.
+		peerSettings:          initialPeerSettings(),
 		streams:               make(map[uint32]*clientStream),
 		singleUse:             singleUse,
 		wantSettingsAck:       true,
@@ -712,6 +758,9 @@ func (t *Transport) newClientConn(c net.Conn, singleUse bool) (*ClientConn, erro
 	if t.CountError != nil {
 		cc.fr.countError = t.CountError
 	}
+	if t.FrameDump != nil {
+		cc.fr.dumpFramesTo(t.FrameDump)
+	}
 	cc.fr.ReadMetaHeaders = hpack.NewDecoder(initialHeaderTableSize, nil)
 	cc.fr.MaxHeaderListSize = t.maxHeaderListSize()
 
@@ -730,7 +779,7 @@ func (t *Transport) newClientConn(c net.Conn, singleUse bool) (*ClientConn, erro
 
 	initialSettings := []Setting{
 		{ID: SettingEnablePush, Val: 0},
-		{ID: SettingInitialWindowSize, Val: transportDefaultStreamFlow},
+		{ID: SettingInitialWindowSize, Val: uint32(t.initialStreamRecvWindowSize())},
 	}
 	if max := t.maxHeaderListSize(); max != 0 {
 		initialSettings = append(initialSettings, Setting{ID: SettingMaxHeaderListSize, Val: max})
@@ -738,8 +787,11 @@ func (t *Transport) newClientConn(c net.Conn, singleUse bool) (*ClientConn, erro
 
 	cc.bw.Write(clientPreface)
 	cc.fr.WriteSettings(initialSettings...)
-	cc.fr.WriteWindowUpdate(0, transportDefaultConnFlow)
-	cc.inflow.add(transportDefaultConnFlow + initialWindowSize)
+	connFlow := t.initialConnRecvWindowSize()
+	if diff := connFlow - initialWindowSize; diff > 0 {
+		cc.fr.WriteWindowUpdate(0, uint32(diff))
+	}
+	cc.inflow.add(connFlow)
 	cc.bw.Flush()
 	if cc.werr != nil {
 		cc.Close()
@@ -874,6 +926,14 @@ func (cc *ClientConn) State() ClientConnState {
 	}
 }
 
+// PeerSettings returns the SETTINGS parameters most recently advertised
+// by the server.
+func (cc *ClientConn) PeerSettings() PeerSettings {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.peerSettings
+}
+
 // clientConnIdleState describes the suitability of a client
 // connection to initiate a new RoundTrip request.
 type clientConnIdleState struct {
@@ -1342,7 +1402,10 @@ func (cs *clientStream) writeRequest(req *http.Request) (err error) {
 			}
 		}
 
-		if err = cs.writeRequestBody(req); err != nil {
+		stop := cs.abortOnCancel()
+		err = cs.writeRequestBody(req)
+		stop()
+		if err != nil {
 			if err != errStopReqBodyWrite {
 				traceWroteRequest(cs.trace, err)
 				return err
@@ -1384,6 +1447,37 @@ func (cs *clientStream) writeRequest(req *http.Request) (err error) {
 	}
 }
 
+// abortOnCancel aborts the stream if the request is canceled before
+// the returned stop function is called.
+//
+// Once RoundTrip has returned, nothing else watches for cancelation
+// while the request body is being written, so a write blocked in
+// Request.Body.Read or waiting for flow control would otherwise never
+// notice it, leaving the stream open and the response body blocked.
+func (cs *clientStream) abortOnCancel() (stop func()) {
+	ctx := cs.ctx
+	if ctx.Done() == nil && cs.reqCancel == nil {
+		return func() {}
+	}
+	stopc := make(chan struct{})
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		select {
+		case <-ctx.Done():
+			cs.abortStream(ctx.Err())
+		case <-cs.reqCancel:
+			cs.abortStream(errRequestCanceled)
+		case <-cs.abort:
+		case <-stopc:
+		}
+	}()
+	return func() {
+		close(stopc)
+		<-donec
+	}
+}
+
 func (cs *clientStream) encodeAndWriteHeaders(req *http.Request) error {
 	cc := cs.cc
 	ctx := cs.ctx
@@ -2001,7 +2095,7 @@ type resAndError struct {
 func (cc *ClientConn) addStreamLocked(cs *clientStream) {
 	cs.flow.add(int32(cc.initialWindowSize))
 	cs.flow.setConnFlow(&cc.flow)
-	cs.inflow.add(transportDefaultStreamFlow)
+	cs.inflow.add(cc.t.initialStreamRecvWindowSize())
 	cs.inflow.setConnFlow(&cc.inflow)
 	cs.ID = cc.nextStreamID
 	cc.nextStreamID += 2
@@ -2310,7 +2404,10 @@ func (rl *clientConnReadLoop) handleResponse(cs *clientStream, f *MetaHeadersFra
 				res.Trailer = t
 			}
 			foreachHeaderElement(hf.Value, func(v string) {
-				t[http.CanonicalHeaderKey(v)] = nil
+				v = http.CanonicalHeaderKey(v)
+				if httpguts.ValidTrailerHeader(v) {
+					t[v] = nil
+				}
 			})
 		} else {
 			vv := header[key]
@@ -2407,15 +2504,31 @@ func (rl *clientConnReadLoop) processTrailers(cs *clientStream, f *MetaHeadersFr
 		// has END_STREAM.
 		return ConnectionError(ErrCodeProtocol)
 	}
-	if len(f.PseudoFields()) > 0 {
+	if pf := f.PseudoFields(); len(pf) > 0 {
 		// No pseudo header fields are defined for trailers.
-		// TODO: ConnectionError might be overly harsh? Check.
-		return ConnectionError(ErrCodeProtocol)
+		// A malformed response is a stream error (RFC 7540, Section 8.1.2.6).
+		rl.endStreamError(cs, StreamError{
+			StreamID: f.StreamID,
+			Code:     ErrCodeProtocol,
+			Cause:    pseudoHeaderError(pf[0].Name),
+		})
+		return nil
 	}
 
 	trailer := make(http.Header)
 	for _, hf := range f.RegularFields() {
 		key := http.CanonicalHeaderKey(hf.Name)
+		if !httpguts.ValidTrailerHeader(key) {
+			// Fields used for framing, routing, authentication
+			// or payload processing may not be sent in trailers
+			// (RFC 7230, Section 4.1.2).
+			rl.endStreamError(cs, StreamError{
+				StreamID: f.StreamID,
+				Code:     ErrCodeProtocol,
+				Cause:    trailerFieldError(key),
+			})
+			return nil
+		}
 		trailer[key] = append(trailer[key], hf.Value)
 	}
 	cs.trailer = trailer
@@ -2463,17 +2576,20 @@ func (b transportResponseBody) Read(p []byte) (n int, err error) {
 	cc.mu.Lock()
 	var connAdd, streamAdd int32
 	// Check the conn-level first, before the stream-level.
-	if v := cc.inflow.available(); v < transportDefaultConnFlow/2 {
-		connAdd = transportDefaultConnFlow - v
+	connFlow := cc.t.initialConnRecvWindowSize()
+	if v := cc.inflow.available(); v < connFlow/2 {
+		connAdd = connFlow - v
 		cc.inflow.add(connAdd)
 	}
 	if err == nil { // No need to refresh if the stream is over or failed.
 		// Consider any buffered body data (read from the conn but not
 		// consumed by the client) when computing flow control for this
 		// stream.
+		// Small windows are refreshed once half of them is used.
+		streamFlow := int(cc.t.initialStreamRecvWindowSize())
 		v := int(cs.inflow.available()) + cs.bufPipe.Len()
-		if v < transportDefaultStreamFlow-transportDefaultStreamMinRefresh {
-			streamAdd = int32(transportDefaultStreamFlow - v)
+		if v < streamFlow-transportDefaultStreamMinRefresh || v < streamFlow/2 {
+			streamAdd = int32(streamFlow - v)
 			cs.inflow.add(streamAdd)
 		}
 	}
@@ -2765,6 +2881,7 @@ func (rl *clientConnReadLoop) processSettingsNoWrite(f *SettingsFrame) error {
 			// TODO(bradfitz): handle more settings? SETTINGS_HEADER_TABLE_SIZE probably.
 			cc.vlogf("Unhandled Setting: %v", s)
 		}
+		cc.peerSettings.apply(s)
 		return nil
 	})
 	if err != nil {
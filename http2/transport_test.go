@@ -16,6 +16,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"math/rand"
 	"net"
 	"net/http"
@@ -192,6 +193,72 @@ func TestTransport(t *testing.T) {
 	}
 }
 
+func TestTransportFrameDump(t *testing.T) {
+	var serverDump, clientDump safeBuffer
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "sup")
+	}, optOnlyServer, func(s *Server) {
+		s.FrameDump = &serverDump
+	})
+	defer st.Close()
+
+	tr := &Transport{TLSClientConfig: tlsConfigInsecure, FrameDump: &clientDump}
+	defer tr.CloseIdleConnections()
+
+	req, err := http.NewRequest("GET", st.ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	// Only frames that were necessarily dumped by the time the
+	// response body was read are checked.
+	for _, tt := range []struct {
+		name string
+		dump *safeBuffer
+		want []string
+	}{{
+		name: "client",
+		dump: &clientDump,
+		want: []string{
+			": wrote SETTINGS len=",
+			": wrote WINDOW_UPDATE len=4 (conn) incr=",
+			": wrote HEADERS flags=END_STREAM|END_HEADERS stream=1 len=",
+			": read SETTINGS len=",
+			": read HEADERS flags=END_HEADERS stream=1 len=",
+			": read DATA flags=END_STREAM stream=1 len=3 data=\"sup\"",
+		},
+	}, {
+		name: "server",
+		dump: &serverDump,
+		want: []string{
+			": wrote SETTINGS len=",
+			": read HEADERS flags=END_STREAM|END_HEADERS stream=1 len=",
+			": wrote HEADERS flags=END_HEADERS stream=1 len=",
+			": wrote DATA flags=END_STREAM stream=1 len=3 data=\"sup\"",
+		},
+	}} {
+		dump := string(tt.dump.Bytes())
+		for _, want := range tt.want {
+			if !strings.Contains(dump, want) {
+				t.Errorf("%s dump does not contain %q:\n%s", tt.name, want, dump)
+			}
+		}
+		for _, line := range strings.SplitAfter(dump, "\n") {
+			if line != "" && (!strings.HasPrefix(line, "http2: Framer ") || !strings.HasSuffix(line, "\n")) {
+				t.Errorf("%s dump has malformed line %q", tt.name, line)
+			}
+		}
+	}
+}
+
 func testTransportReusesConns(t *testing.T, useClient, wantSame bool, modReq func(*http.Request)) {
 	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
 		io.WriteString(w, r.RemoteAddr)
@@ -1460,6 +1527,73 @@ func TestTransportReceiveUndeclaredTrailer(t *testing.T) {
 	ct.run()
 }
 
+func TestTransportReceiveDeclaredAndUndeclaredTrailers(t *testing.T) {
+	ct := newClientTester(t)
+	ct.client = func() error {
+		req, _ := http.NewRequest("GET", "https://dummy.tld/", nil)
+		res, err := ct.tr.RoundTrip(req)
+		if err != nil {
+			return fmt.Errorf("RoundTrip: %v", err)
+		}
+		defer res.Body.Close()
+		// Declared trailers are known before the body is read, except
+		// for those which may never appear in trailers.
+		wantDeclared := http.Header{"Some-Declared": nil}
+		if !reflect.DeepEqual(res.Trailer, wantDeclared) {
+			return fmt.Errorf("Trailer before body = %#v; want %#v", res.Trailer, wantDeclared)
+		}
+		if _, err := ioutil.ReadAll(res.Body); err != nil {
+			return fmt.Errorf("res.Body ReadAll error = %v", err)
+		}
+		want := http.Header{
+			"Some-Declared":   {"declared"},
+			"Some-Undeclared": {"undeclared1", "undeclared2"},
+		}
+		if !reflect.DeepEqual(res.Trailer, want) {
+			return fmt.Errorf("Trailer = %#v; want %#v", res.Trailer, want)
+		}
+		return nil
+	}
+	ct.server = func() error {
+		ct.greet()
+
+		var n int
+		var hf *HeadersFrame
+		for hf == nil && n < 10 {
+			f, err := ct.fr.ReadFrame()
+			if err != nil {
+				return err
+			}
+			hf, _ = f.(*HeadersFrame)
+			n++
+		}
+
+		var buf bytes.Buffer
+		enc := hpack.NewEncoder(&buf)
+		enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+		enc.WriteField(hpack.HeaderField{Name: "trailer", Value: "some-declared, content-length"})
+		ct.fr.WriteHeaders(HeadersFrameParam{
+			StreamID:      hf.StreamID,
+			EndHeaders:    true,
+			EndStream:     false,
+			BlockFragment: buf.Bytes(),
+		})
+
+		buf.Reset()
+		enc.WriteField(hpack.HeaderField{Name: "some-declared", Value: "declared"})
+		enc.WriteField(hpack.HeaderField{Name: "some-undeclared", Value: "undeclared1"})
+		enc.WriteField(hpack.HeaderField{Name: "some-undeclared", Value: "undeclared2"})
+		ct.fr.WriteHeaders(HeadersFrameParam{
+			StreamID:      hf.StreamID,
+			EndHeaders:    true,
+			EndStream:     true,
+			BlockFragment: buf.Bytes(),
+		})
+		return nil
+	}
+	ct.run()
+}
+
 func TestTransportInvalidTrailer_Pseudo1(t *testing.T) {
 	testTransportInvalidTrailer_Pseudo(t, oneHeader)
 }
@@ -1496,6 +1630,23 @@ func TestTransportInvalidTrailer_BinaryFieldValue(t *testing.T) {
 	})
 }
 
+func TestTransportInvalidTrailer_ResponsePseudo(t *testing.T) {
+	testInvalidTrailer(t, oneHeader, pseudoHeaderError(":status"), func(enc *hpack.Encoder) {
+		enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+		enc.WriteField(hpack.HeaderField{Name: "declared", Value: "foo"})
+	})
+}
+func TestTransportInvalidTrailer_DisallowedField(t *testing.T) {
+	for _, name := range []string{"content-length", "transfer-encoding", "host", "trailer", "if-match"} {
+		t.Run(name, func(t *testing.T) {
+			testInvalidTrailer(t, oneHeader, trailerFieldError(http.CanonicalHeaderKey(name)), func(enc *hpack.Encoder) {
+				enc.WriteField(hpack.HeaderField{Name: "declared", Value: "foo"})
+				enc.WriteField(hpack.HeaderField{Name: name, Value: "1"})
+			})
+		})
+	}
+}
+
 func testInvalidTrailer(t *testing.T, trailers headerType, wantErr error, writeTrailer func(*hpack.Encoder)) {
 	ct := newClientTester(t)
 	ct.client = func() error {
@@ -2798,6 +2949,90 @@ func TestTransportFlowControl(t *testing.T) {
 	}
 }
 
+// Tests that the Transport advertises the receive windows it is
+// configured with, and that a server may fill them without the
+// response being read.
+func TestTransportMaxReceiveBuffer(t *testing.T) {
+	const window = transportDefaultStreamFlow + 1<<20
+	ct := newClientTester(t)
+	ct.tr.MaxReceiveBufferPerConnection = window
+	ct.tr.MaxReceiveBufferPerStream = window
+	serverDone := make(chan struct{})
+	ct.client = func() error {
+		req, _ := http.NewRequest("GET", "https://dummy.tld/", nil)
+		res, err := ct.tr.RoundTrip(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		<-serverDone
+		slurp, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		if len(slurp) != window {
+			return fmt.Errorf("read %d bytes of body; want %d", len(slurp), window)
+		}
+		return nil
+	}
+	ct.server = func() error {
+		defer close(serverDone)
+		buf := make([]byte, len(ClientPreface))
+		if _, err := io.ReadFull(ct.sc, buf); err != nil {
+			return err
+		}
+		f, err := ct.fr.ReadFrame()
+		if err != nil {
+			return err
+		}
+		sf, ok := f.(*SettingsFrame)
+		if !ok {
+			return fmt.Errorf("got %v; want SETTINGS", summarizeFrame(f))
+		}
+		if v, ok := sf.Value(SettingInitialWindowSize); !ok || v != window {
+			return fmt.Errorf("SETTINGS_INITIAL_WINDOW_SIZE = %v, %v; want %v", v, ok, window)
+		}
+		f, err = ct.fr.ReadFrame()
+		if err != nil {
+			return err
+		}
+		if wuf, ok := f.(*WindowUpdateFrame); !ok || wuf.StreamID != 0 || wuf.Increment != window-initialWindowSize {
+			return fmt.Errorf("got %v; want conn WINDOW_UPDATE for %d bytes", summarizeFrame(f), window-initialWindowSize)
+		}
+		ct.fr.WriteSettings()
+		ct.fr.WriteSettingsAck()
+		for {
+			f, err := ct.fr.ReadFrame()
+			if err != nil {
+				return err
+			}
+			hf, ok := f.(*HeadersFrame)
+			if !ok {
+				continue
+			}
+			var buf bytes.Buffer
+			enc := hpack.NewEncoder(&buf)
+			enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+			ct.fr.WriteHeaders(HeadersFrameParam{
+				StreamID:      hf.StreamID,
+				EndHeaders:    true,
+				BlockFragment: buf.Bytes(),
+			})
+			data := make([]byte, initialMaxFrameSize)
+			for n := 0; n < window; n += len(data) {
+				if window-n < len(data) {
+					data = data[:window-n]
+				}
+				if err := ct.fr.WriteData(hf.StreamID, n+len(data) == window, data); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	ct.run()
+}
+
 // golang.org/issue/14627 -- if the server sends a GOAWAY frame, make
 // the Transport remember it and return it back to users (via
 // RoundTrip or request body reads) if needed (e.g. if the server
@@ -3393,6 +3628,69 @@ func TestRoundTripDoesntConsumeRequestBodyEarly(t *testing.T) {
 	}
 }
 
+func TestPeerSettings(t *testing.T) {
+	serverGot := make(chan PeerSettings, 1)
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		ps, ok := PeerSettingsFromContext(r.Context())
+		if !ok {
+			t.Error("PeerSettingsFromContext: no settings in request context")
+		}
+		serverGot <- ps
+	}, optOnlyServer, func(s *Server) {
+		s.MaxReadFrameSize = 1 << 20
+		s.MaxConcurrentStreams = 42
+		s.MaxUploadBufferPerStream = 1 << 18
+	}, func(ts *httptest.Server) {
+		ts.Config.MaxHeaderBytes = 4096
+	})
+	defer st.Close()
+	tr := &Transport{
+		TLSClientConfig:   tlsConfigInsecure,
+		MaxHeaderListSize: 8192,
+	}
+	defer tr.CloseIdleConnections()
+	cc, err := tr.dialClientConn(context.Background(), st.ts.Listener.Addr().String(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", st.ts.URL, nil)
+	res, err := cc.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	// The server's SETTINGS frame precedes its response.
+	want := PeerSettings{
+		HeaderTableSize:      initialHeaderTableSize,
+		EnablePush:           true,
+		MaxConcurrentStreams: 42,
+		InitialWindowSize:    1 << 18,
+		MaxFrameSize:         1 << 20,
+		MaxHeaderListSize:    4096 + 10*32,
+	}
+	if got := cc.PeerSettings(); got != want {
+		t.Errorf("ClientConn.PeerSettings() = %+v; want %+v", got, want)
+	}
+
+	// The client's SETTINGS frame precedes its request.
+	want = PeerSettings{
+		HeaderTableSize:      initialHeaderTableSize,
+		EnablePush:           false,
+		MaxConcurrentStreams: math.MaxUint32,
+		InitialWindowSize:    transportDefaultStreamFlow,
+		MaxFrameSize:         initialMaxFrameSize,
+		MaxHeaderListSize:    8192,
+	}
+	if got := <-serverGot; got != want {
+		t.Errorf("PeerSettingsFromContext() = %+v; want %+v", got, want)
+	}
+
+	if _, ok := PeerSettingsFromContext(context.Background()); ok {
+		t.Error("PeerSettingsFromContext(context.Background()) reported settings")
+	}
+}
+
 func TestClientConnPing(t *testing.T) {
 	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {}, optOnlyServer)
 	defer st.Close()
@@ -3463,6 +3761,97 @@ func TestTransportCancelDataResponseRace(t *testing.T) {
 	}
 }
 
+// Canceling a request's context mid-download must reset the stream and
+// unblock the response body promptly, even while the request body is
+// still being written, without disturbing other streams.
+func TestTransportCancelMidBodyDownload(t *testing.T) {
+	for _, withBody := range []bool{false, true} {
+		t.Run(fmt.Sprintf("withBody=%v", withBody), func(t *testing.T) {
+			testTransportCancelMidBodyDownload(t, withBody)
+		})
+	}
+}
+
+func testTransportCancelMidBodyDownload(t *testing.T, withBody bool) {
+	handlerCanceled := make(chan struct{})
+	unblockOther := make(chan struct{})
+	st := newServerTester(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/other" {
+			<-unblockOther
+			io.WriteString(w, "other")
+			return
+		}
+		io.WriteString(w, "first chunk")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(handlerCanceled)
+	}, optOnlyServer)
+	defer st.Close()
+
+	tr := &Transport{TLSClientConfig: tlsConfigInsecure}
+	defer tr.CloseIdleConnections()
+
+	otherRes := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequest("GET", st.ts.URL+"/other", nil)
+		res, err := tr.RoundTrip(req)
+		if err == nil {
+			var body []byte
+			body, err = ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err == nil && string(body) != "other" {
+				err = fmt.Errorf("other body = %q; want %q", body, "other")
+			}
+		}
+		otherRes <- err
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var body io.Reader
+	if withBody {
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		body = pr
+	}
+	req, _ := http.NewRequest("POST", st.ts.URL, body)
+	req = req.WithContext(ctx)
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	buf := make([]byte, len("first chunk"))
+	if _, err := io.ReadFull(res.Body, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := res.Body.Read(buf)
+		readErr <- err
+	}()
+	cancel()
+	select {
+	case err := <-readErr:
+		if err != context.Canceled {
+			t.Errorf("response body read error = %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for response body read to return")
+	}
+	select {
+	case <-handlerCanceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the server to see RST_STREAM")
+	}
+
+	close(unblockOther)
+	if err := <-otherRes; err != nil {
+		t.Errorf("other request: %v", err)
+	}
+}
+
 // Issue 21316: It should be safe to reuse an http.Request after the
 // request has completed.
 func TestTransportNoRaceOnRequestObjectAfterRequestComplete(t *testing.T) {
@@ -3854,6 +4243,101 @@ func TestTransportRetryAfterRefusedStream(t *testing.T) {
 	ct.run()
 }
 
+// A request with a body refused by the server before it was processed is
+// retried if its body can be rewound with GetBody.
+func TestTransportRetryAfterRefusedStreamWithBody(t *testing.T) {
+	t.Run("GetBody", func(t *testing.T) { testTransportRetryAfterRefusedStreamWithBody(t, true) })
+	t.Run("NoGetBody", func(t *testing.T) { testTransportRetryAfterRefusedStreamWithBody(t, false) })
+}
+
+func testTransportRetryAfterRefusedStreamWithBody(t *testing.T, getBody bool) {
+	reqBody := strings.Repeat("a", 10000)
+	clientDone := make(chan struct{})
+	ct := newClientTester(t)
+	ct.client = func() error {
+		defer ct.cc.(*net.TCPConn).CloseWrite()
+		if runtime.GOOS == "plan9" {
+			// CloseWrite not supported on Plan 9; Issue 17906
+			defer ct.cc.(*net.TCPConn).Close()
+		}
+		defer close(clientDone)
+		req, _ := http.NewRequest("POST", "https://dummy.tld/", strings.NewReader(reqBody))
+		if !getBody {
+			req.GetBody = nil
+		}
+		resp, err := ct.tr.RoundTrip(req)
+		if !getBody {
+			if err == nil {
+				resp.Body.Close()
+				return errors.New("RoundTrip succeeded; want error for a body that cannot be rewound")
+			}
+			if !strings.Contains(err.Error(), "GetBody") {
+				return fmt.Errorf("RoundTrip: %v; want error suggesting GetBody", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("RoundTrip: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 204 {
+			return fmt.Errorf("Status = %v; want 204", resp.StatusCode)
+		}
+		return nil
+	}
+	ct.server = func() error {
+		ct.greet()
+		var buf bytes.Buffer
+		enc := hpack.NewEncoder(&buf)
+		refused := uint32(0)
+		var body bytes.Buffer
+
+		for {
+			f, err := ct.fr.ReadFrame()
+			if err != nil {
+				select {
+				case <-clientDone:
+					// If the client's done, it
+					// will have reported any
+					// errors on its side.
+					return nil
+				default:
+					return err
+				}
+			}
+			switch f := f.(type) {
+			case *WindowUpdateFrame, *SettingsFrame, *RSTStreamFrame:
+			case *HeadersFrame:
+				if refused == 0 {
+					refused = f.StreamID
+					ct.fr.WriteRSTStream(f.StreamID, ErrCodeRefusedStream)
+				}
+			case *DataFrame:
+				if f.StreamID == refused {
+					continue
+				}
+				body.Write(f.Data())
+				if !f.StreamEnded() {
+					continue
+				}
+				if body.String() != reqBody {
+					return fmt.Errorf("retried request body is %d bytes; want %d", body.Len(), len(reqBody))
+				}
+				enc.WriteField(hpack.HeaderField{Name: ":status", Value: "204"})
+				ct.fr.WriteHeaders(HeadersFrameParam{
+					StreamID:      f.StreamID,
+					EndHeaders:    true,
+					EndStream:     true,
+					BlockFragment: buf.Bytes(),
+				})
+			default:
+				return fmt.Errorf("Unexpected client frame %v", f)
+			}
+		}
+	}
+	ct.run()
+}
+
 func TestTransportRetryHasLimit(t *testing.T) {
 	// Skip in short mode because the total expected delay is 1s+2s+4s+8s+16s=29s.
 	if testing.Short() {
@@ -4974,6 +5458,49 @@ func (fce *fakeConnErr) Close() error {
 	return nil
 }
 
+func TestTransportNewClientConnPipe(t *testing.T) {
+	c1, c2 := net.Pipe()
+	s := &Server{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ServeConn(c2, &ServeConnOpts{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor != 2 {
+				t.Errorf("request proto = %q; want HTTP/2.0", r.Proto)
+			}
+			io.WriteString(w, r.URL.Path)
+		})})
+	}()
+
+	tr := &Transport{}
+	cc, err := tr.NewClientConn(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/a", "/b"} {
+		req, err := http.NewRequest("GET", "http://example.com"+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := cc.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != 200 || string(body) != path {
+			t.Errorf("got status %d body %q; want 200 %q", res.StatusCode, body, path)
+		}
+	}
+	if err := cc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
 // issue 39337: close the connection on a failed write
 func TestTransportNewClientConnCloseOnWriteError(t *testing.T) {
 	tr := &Transport{}
@@ -5,6 +5,7 @@
 package icmp_test
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -297,3 +298,74 @@ func supportsNonPrivilegedICMP() (string, bool) {
 	})
 	return nonPrivMsg, nonPrivICMP
 }
+
+func TestPing(t *testing.T) {
+	for _, tt := range []struct {
+		network, address string
+		privileged       bool
+	}{
+		{"udp4", "127.0.0.1", false},
+		{"udp6", "::1", false},
+		{"ip4:icmp", "127.0.0.1", true},
+		{"ip6:ipv6-icmp", "::1", true},
+	} {
+		t.Run(tt.network, func(t *testing.T) {
+			if tt.privileged {
+				if !nettest.SupportsRawSocket() {
+					t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+				}
+			} else if m, ok := supportsNonPrivilegedICMP(); !ok {
+				t.Skip(m)
+			}
+			if tt.address == "::1" && !nettest.SupportsIPv6() {
+				t.Skip("ipv6 is not supported")
+			}
+			c, err := icmp.ListenPacket(tt.network, tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			var dst net.Addr = &net.IPAddr{IP: net.ParseIP(tt.address)}
+			if !tt.privileged {
+				dst = &net.UDPAddr{IP: net.ParseIP(tt.address)}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			id := os.Getpid() & 0xffff
+			for seq := 1; seq <= 3; seq++ {
+				rtt, err := c.Ping(ctx, dst, id, seq, []byte("HELLO-R-U-THERE"))
+				if err != nil {
+					t.Fatalf("seq=%d: %v", seq, err)
+				}
+				if rtt <= 0 {
+					t.Errorf("seq=%d: got rtt %v; want > 0", seq, rtt)
+				}
+			}
+		})
+	}
+}
+
+func TestPingContextDone(t *testing.T) {
+	if m, ok := supportsNonPrivilegedICMP(); !ok {
+		t.Skip(m)
+	}
+	c, err := icmp.ListenPacket("udp4", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	dst := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Ping(ctx, dst, 1, 1, nil); err != context.Canceled {
+		t.Errorf("got %v; want %v", err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := c.Ping(ctx, dst, 1, 2, nil); err != context.DeadlineExceeded {
+		t.Errorf("got %v; want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -7,6 +7,7 @@ package icmp
 import (
 	"encoding/binary"
 
+	"golang.org/x/net/internal/checksum"
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
 )
@@ -30,7 +31,7 @@ func validExtensionHeader(b []byte) bool {
 	v := int(b[0]&0xf0) >> 4
 	s := binary.BigEndian.Uint16(b[2:4])
 	if s != 0 {
-		s = checksum(b)
+		s = checksum.Checksum(b)
 	}
 	if v != extensionVersion || s != 0 {
 		return false
@@ -330,3 +330,125 @@ func TestParseInterfaceName(t *testing.T) {
 		}
 	}
 }
+
+// ICMP time exceeded messages in reply to a UDP traceroute probe, as
+// sent by an MPLS label switching router with RFC 4950 MPLS label stack
+// extensions.
+var (
+	// The length attribute is set, as required by RFC 4884.
+	wireTimeExceededWithMPLS = []byte{
+		0x0b, 0x00, 0xcc, 0x4a, 0x00, 0x20, 0x00, 0x00,
+		0x45, 0x00, 0x00, 0x3c, 0x8c, 0x5d, 0x00, 0x00,
+		0x01, 0x11, 0x41, 0x1e, 0xc0, 0x00, 0x02, 0x01,
+		0xc6, 0x33, 0x64, 0x01, 0xa5, 0xd1, 0x82, 0x9b,
+		0x00, 0x28, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x20, 0x00, 0xa9, 0x32, 0x00, 0x0c, 0x01, 0x01,
+		0x04, 0xbd, 0x20, 0x01, 0x00, 0x01, 0x11, 0x01,
+	}
+
+	// The length attribute is zero, as sent by routers predating RFC
+	// 4884, and the extension structure follows a 128-octet original
+	// datagram.
+	wireNonCompliantTimeExceededWithMPLS = []byte{
+		0x0b, 0x00, 0xcc, 0x6a, 0x00, 0x00, 0x00, 0x00,
+		0x45, 0x00, 0x00, 0x3c, 0x8c, 0x5d, 0x00, 0x00,
+		0x01, 0x11, 0x41, 0x1e, 0xc0, 0x00, 0x02, 0x01,
+		0xc6, 0x33, 0x64, 0x01, 0xa5, 0xd1, 0x82, 0x9b,
+		0x00, 0x28, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x20, 0x00, 0xa9, 0x32, 0x00, 0x0c, 0x01, 0x01,
+		0x04, 0xbd, 0x20, 0x01, 0x00, 0x01, 0x11, 0x01,
+	}
+)
+
+func TestParseTimeExceededWithExtensions(t *testing.T) {
+	wantLabels := &MPLSLabelStack{
+		Class: classMPLSLabelStack,
+		Type:  typeIncomingMPLSLabelStack,
+		Labels: []MPLSLabel{
+			{Label: 19410, TC: 0, S: false, TTL: 1},
+			{Label: 17, TC: 0, S: true, TTL: 1},
+		},
+	}
+	for _, tt := range []struct {
+		name string
+		wire []byte
+	}{
+		{"rfc4884", wireTimeExceededWithMPLS},
+		{"non-compliant", wireNonCompliantTimeExceededWithMPLS},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMessage(1, tt.wire)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Type != ipv4.ICMPTypeTimeExceeded || m.Code != 0 {
+				t.Fatalf("got type=%v code=%d; want type=%v code=0", m.Type, m.Code, ipv4.ICMPTypeTimeExceeded)
+			}
+			body, ok := m.Body.(*TimeExceeded)
+			if !ok {
+				t.Fatalf("got %T; want *TimeExceeded", m.Body)
+			}
+
+			// The original datagram field is padded to 128 octets
+			// and still starts with the probe's IPv4 header.
+			if len(body.Data) != 128 {
+				t.Errorf("got %d octets of original datagram; want 128", len(body.Data))
+			}
+			h, err := ipv4.ParseHeader(body.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.TTL != 1 || h.Protocol != 17 || !h.Dst.Equal(net.IPv4(198, 51, 100, 1)) {
+				t.Errorf("got original datagram header %v", h)
+			}
+
+			if len(body.Extensions) != 1 {
+				t.Fatalf("got %d extensions; want 1", len(body.Extensions))
+			}
+			if !reflect.DeepEqual(body.Extensions[0], wantLabels) {
+				t.Errorf("got %#v; want %#v", body.Extensions[0], wantLabels)
+			}
+		})
+	}
+}
+
+func TestParseTimeExceededWithBadExtensionChecksum(t *testing.T) {
+	b := append([]byte(nil), wireTimeExceededWithMPLS...)
+	b[len(b)-1]++ // corrupt the MPLS label stack entry
+
+	m, err := ParseMessage(1, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := m.Body.(*TimeExceeded)
+	if len(body.Extensions) != 0 {
+		t.Errorf("got %d extensions; want none", len(body.Extensions))
+	}
+	if len(body.Data) != len(b)-8 {
+		t.Errorf("got %d octets of original datagram; want %d", len(body.Data), len(b)-8)
+	}
+}
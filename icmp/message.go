@@ -20,6 +20,7 @@ import (
 	"net"
 	"runtime"
 
+	"golang.org/x/net/internal/checksum"
 	"golang.org/x/net/internal/iana"
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
@@ -36,23 +37,10 @@ var (
 	errInvalidBody      = errors.New("invalid body")
 	errNoExtension      = errors.New("no extension")
 	errInvalidExtension = errors.New("invalid extension")
+	errInvalidNDPOption = errors.New("invalid neighbor discovery option")
 	errNotImplemented   = errors.New("not implemented on " + runtime.GOOS + "/" + runtime.GOARCH)
 )
 
-func checksum(b []byte) uint16 {
-	csumcv := len(b) - 1 // checksum coverage
-	s := uint32(0)
-	for i := 0; i < csumcv; i += 2 {
-		s += uint32(b[i+1])<<8 | uint32(b[i])
-	}
-	if csumcv&1 == 0 {
-		s += uint32(b[csumcv])
-	}
-	s = s>>16 + s&0xffff
-	s = s + s>>16
-	return ^uint16(s)
-}
-
 // A Type represents an ICMP message type.
 type Type interface {
 	Protocol() int
@@ -87,9 +75,6 @@ func (m *Message) Marshal(psh []byte) ([]byte, error) {
 	}
 	b := []byte{mtype, byte(m.Code), 0, 0}
 	proto := m.Type.Protocol()
-	if proto == iana.ProtocolIPv6ICMP && psh != nil {
-		b = append(psh, b...)
-	}
 	if m.Body != nil && m.Body.Len(proto) != 0 {
 		mb, err := m.Body.Marshal(proto)
 		if err != nil {
@@ -97,19 +82,29 @@ func (m *Message) Marshal(psh []byte) ([]byte, error) {
 		}
 		b = append(b, mb...)
 	}
+	var s uint32
 	if proto == iana.ProtocolIPv6ICMP {
 		if psh == nil { // cannot calculate checksum here
 			return b, nil
 		}
-		off, l := 2*net.IPv6len, len(b)-len(psh)
-		binary.BigEndian.PutUint32(b[off:off+4], uint32(l))
+		if len(psh) < ipv6PseudoHeaderLen {
+			return nil, errHeaderTooShort
+		}
+		// The pseudo header carries the upper-layer packet
+		// length in place of its zero length field.
+		off := 2 * net.IPv6len
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+		s = checksum.Add(s, psh[:off])
+		s = checksum.Add(s, l[:])
+		s = checksum.Add(s, psh[off+4:])
 	}
-	s := checksum(b)
+	cs := checksum.Fold(checksum.Add(s, b))
 	// Place checksum back in header; using ^= avoids the
 	// assumption the checksum bytes are zero.
-	b[len(psh)+2] ^= byte(s)
-	b[len(psh)+3] ^= byte(s >> 8)
-	return b[len(psh):], nil
+	b[2] ^= byte(cs >> 8)
+	b[3] ^= byte(cs)
+	return b, nil
 }
 
 var parseFns = map[Type]func(int, Type, []byte) (MessageBody, error){
@@ -127,6 +122,11 @@ var parseFns = map[Type]func(int, Type, []byte) (MessageBody, error){
 	ipv6.ICMPTypeTimeExceeded:           parseTimeExceeded,
 	ipv6.ICMPTypeParameterProblem:       parseParamProb,
 
+	ipv6.ICMPTypeRouterSolicitation:    parseRouterSolicitation,
+	ipv6.ICMPTypeRouterAdvertisement:   parseRouterAdvertisement,
+	ipv6.ICMPTypeNeighborSolicitation:  parseNeighborSolicitation,
+	ipv6.ICMPTypeNeighborAdvertisement: parseNeighborAdvertisement,
+
 	ipv6.ICMPTypeEchoRequest:         parseEcho,
 	ipv6.ICMPTypeEchoReply:           parseEcho,
 	ipv6.ICMPTypeExtendedEchoRequest: parseExtendedEchoRequest,
@@ -4,7 +4,10 @@
 
 package icmp
 
-import "golang.org/x/net/internal/iana"
+import (
+	"golang.org/x/net/internal/checksum"
+	"golang.org/x/net/internal/iana"
+)
 
 // multipartMessageBodyDataLen takes b as an original datagram and
 // exts as extensions, and returns a required length for message body
@@ -88,9 +91,9 @@ func marshalMultipartMessageBody(proto int, withOrigDgram bool, data []byte, ext
 				off += ext.Len(proto)
 			}
 		}
-		s := checksum(b[4+dataLen:])
-		b[4+dataLen+2] ^= byte(s)
-		b[4+dataLen+3] ^= byte(s >> 8)
+		s := checksum.Checksum(b[4+dataLen:])
+		b[4+dataLen+2] ^= byte(s >> 8)
+		b[4+dataLen+3] ^= byte(s)
 		if withOrigDgram {
 			switch proto {
 			case iana.ProtocolICMP:
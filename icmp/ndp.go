@@ -0,0 +1,390 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icmp
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// Neighbor Discovery option types, see RFC 4861.
+const (
+	NDPOptionSourceLinkLayerAddr = 1 // source link-layer address
+	NDPOptionTargetLinkLayerAddr = 2 // target link-layer address
+	NDPOptionPrefixInfo          = 3 // prefix information
+	NDPOptionRedirectedHeader    = 4 // redirected header
+	NDPOptionMTU                 = 5 // MTU
+)
+
+// Router advertisement flags, see RFC 4861.
+const (
+	RouterFlagManagedConfig = 0x80 // addresses are available via DHCPv6
+	RouterFlagOtherConfig   = 0x40 // other configuration is available via DHCPv6
+)
+
+// An NDPOption represents a Neighbor Discovery option.
+type NDPOption interface {
+	// Len returns the length of the option in octets, including
+	// the type and length octets. It is a multiple of 8.
+	Len() int
+
+	// Marshal returns the binary encoding of the option.
+	Marshal() ([]byte, error)
+}
+
+// ndpOptionLen returns the length of an option carrying n octets
+// after the type and length octets, rounded up to a multiple of 8.
+func ndpOptionLen(n int) int {
+	return (2 + n + 7) &^ 7
+}
+
+// A LinkLayerAddrOption represents a source or target link-layer
+// address option.
+type LinkLayerAddrOption struct {
+	Type int              // NDPOptionSourceLinkLayerAddr or NDPOptionTargetLinkLayerAddr
+	Addr net.HardwareAddr // link-layer address, including any trailing padding when parsed
+}
+
+// Len implements the Len method of NDPOption interface.
+func (o *LinkLayerAddrOption) Len() int {
+	if o == nil {
+		return 0
+	}
+	return ndpOptionLen(len(o.Addr))
+}
+
+// Marshal implements the Marshal method of NDPOption interface.
+func (o *LinkLayerAddrOption) Marshal() ([]byte, error) {
+	if o.Type != NDPOptionSourceLinkLayerAddr && o.Type != NDPOptionTargetLinkLayerAddr {
+		return nil, errInvalidNDPOption
+	}
+	return marshalNDPOption(o.Type, o.Addr)
+}
+
+// A PrefixInfoOption represents a prefix information option.
+type PrefixInfoOption struct {
+	PrefixLen         int    // number of leading bits of Prefix that are valid
+	OnLink            bool   // prefix can be used for on-link determination
+	Autonomous        bool   // prefix can be used for stateless address autoconfiguration
+	ValidLifetime     uint32 // in seconds; 0xffffffff means infinity
+	PreferredLifetime uint32 // in seconds; 0xffffffff means infinity
+	Prefix            net.IP // IPv6 prefix
+}
+
+// Len implements the Len method of NDPOption interface.
+func (o *PrefixInfoOption) Len() int {
+	if o == nil {
+		return 0
+	}
+	return 32
+}
+
+// Marshal implements the Marshal method of NDPOption interface.
+func (o *PrefixInfoOption) Marshal() ([]byte, error) {
+	ip := o.Prefix.To16()
+	if ip == nil || o.PrefixLen < 0 || o.PrefixLen > 128 {
+		return nil, errInvalidNDPOption
+	}
+	b := make([]byte, 32)
+	b[0], b[1], b[2] = NDPOptionPrefixInfo, 4, byte(o.PrefixLen)
+	if o.OnLink {
+		b[3] |= 0x80
+	}
+	if o.Autonomous {
+		b[3] |= 0x40
+	}
+	binary.BigEndian.PutUint32(b[4:8], o.ValidLifetime)
+	binary.BigEndian.PutUint32(b[8:12], o.PreferredLifetime)
+	copy(b[16:], ip)
+	return b, nil
+}
+
+// An MTUOption represents an MTU option.
+type MTUOption struct {
+	MTU int // recommended MTU for the link
+}
+
+// Len implements the Len method of NDPOption interface.
+func (o *MTUOption) Len() int {
+	if o == nil {
+		return 0
+	}
+	return 8
+}
+
+// Marshal implements the Marshal method of NDPOption interface.
+func (o *MTUOption) Marshal() ([]byte, error) {
+	b := make([]byte, 8)
+	b[0], b[1] = NDPOptionMTU, 1
+	binary.BigEndian.PutUint32(b[4:8], uint32(o.MTU))
+	return b, nil
+}
+
+// A RawNDPOption represents a Neighbor Discovery option of a type
+// that is not otherwise supported.
+type RawNDPOption struct {
+	Type int    // option type
+	Data []byte // option data, excluding the type and length octets
+}
+
+// Len implements the Len method of NDPOption interface.
+func (o *RawNDPOption) Len() int {
+	if o == nil {
+		return 0
+	}
+	return ndpOptionLen(len(o.Data))
+}
+
+// Marshal implements the Marshal method of NDPOption interface.
+func (o *RawNDPOption) Marshal() ([]byte, error) {
+	return marshalNDPOption(o.Type, o.Data)
+}
+
+// marshalNDPOption encodes an option of type typ carrying data,
+// padding it with zeros to a multiple of 8 octets.
+func marshalNDPOption(typ int, data []byte) ([]byte, error) {
+	l := ndpOptionLen(len(data))
+	if typ <= 0 || typ > 0xff || l/8 > 0xff {
+		return nil, errInvalidNDPOption
+	}
+	b := make([]byte, l)
+	b[0], b[1] = byte(typ), byte(l/8)
+	copy(b[2:], data)
+	return b, nil
+}
+
+func ndpOptionsLen(opts []NDPOption) int {
+	var l int
+	for _, o := range opts {
+		l += o.Len()
+	}
+	return l
+}
+
+func marshalNDPOptions(b []byte, opts []NDPOption) ([]byte, error) {
+	for _, o := range opts {
+		ob, err := o.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, ob...)
+	}
+	return b, nil
+}
+
+// parseNDPOptions parses b as a list of Neighbor Discovery options.
+// An option of a supported type with an unexpected length is returned
+// as a RawNDPOption. It fails only if b cannot be split into options.
+func parseNDPOptions(b []byte) ([]NDPOption, error) {
+	var opts []NDPOption
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, errInvalidNDPOption
+		}
+		l := int(b[1]) * 8
+		if l == 0 || l > len(b) {
+			return nil, errInvalidNDPOption
+		}
+		var o NDPOption
+		switch typ := int(b[0]); {
+		case typ == NDPOptionSourceLinkLayerAddr || typ == NDPOptionTargetLinkLayerAddr:
+			o = &LinkLayerAddrOption{Type: typ, Addr: append(net.HardwareAddr(nil), b[2:l]...)}
+		case typ == NDPOptionPrefixInfo && l == 32:
+			o = &PrefixInfoOption{
+				PrefixLen:         int(b[2]),
+				OnLink:            b[3]&0x80 != 0,
+				Autonomous:        b[3]&0x40 != 0,
+				ValidLifetime:     binary.BigEndian.Uint32(b[4:8]),
+				PreferredLifetime: binary.BigEndian.Uint32(b[8:12]),
+				Prefix:            append(net.IP(nil), b[16:32]...),
+			}
+		case typ == NDPOptionMTU && l == 8:
+			o = &MTUOption{MTU: int(binary.BigEndian.Uint32(b[4:8]))}
+		default:
+			o = &RawNDPOption{Type: typ, Data: append([]byte(nil), b[2:l]...)}
+		}
+		opts = append(opts, o)
+		b = b[l:]
+	}
+	return opts, nil
+}
+
+// A RouterSolicitation represents an ICMPv6 router solicitation
+// message body.
+type RouterSolicitation struct {
+	Options []NDPOption // options
+}
+
+// Len implements the Len method of MessageBody interface.
+func (p *RouterSolicitation) Len(proto int) int {
+	if p == nil {
+		return 0
+	}
+	return 4 + ndpOptionsLen(p.Options)
+}
+
+// Marshal implements the Marshal method of MessageBody interface.
+func (p *RouterSolicitation) Marshal(proto int) ([]byte, error) {
+	return marshalNDPOptions(make([]byte, 4, p.Len(proto)), p.Options)
+}
+
+// parseRouterSolicitation parses b as an ICMPv6 router solicitation
+// message body. It returns a RawBody if b is malformed.
+func parseRouterSolicitation(proto int, _ Type, b []byte) (MessageBody, error) {
+	if len(b) < 4 {
+		return parseRawBody(proto, b)
+	}
+	opts, err := parseNDPOptions(b[4:])
+	if err != nil {
+		return parseRawBody(proto, b)
+	}
+	return &RouterSolicitation{Options: opts}, nil
+}
+
+// A RouterAdvertisement represents an ICMPv6 router advertisement
+// message body.
+type RouterAdvertisement struct {
+	CurHopLimit    int         // default hop limit for outgoing packets; 0 means unspecified
+	Flags          int         // flags, such as RouterFlagManagedConfig
+	RouterLifetime int         // lifetime as a default router in seconds
+	ReachableTime  int         // in milliseconds; 0 means unspecified
+	RetransTimer   int         // in milliseconds; 0 means unspecified
+	Options        []NDPOption // options
+}
+
+// Len implements the Len method of MessageBody interface.
+func (p *RouterAdvertisement) Len(proto int) int {
+	if p == nil {
+		return 0
+	}
+	return 12 + ndpOptionsLen(p.Options)
+}
+
+// Marshal implements the Marshal method of MessageBody interface.
+func (p *RouterAdvertisement) Marshal(proto int) ([]byte, error) {
+	b := make([]byte, 12, p.Len(proto))
+	b[0], b[1] = byte(p.CurHopLimit), byte(p.Flags)
+	binary.BigEndian.PutUint16(b[2:4], uint16(p.RouterLifetime))
+	binary.BigEndian.PutUint32(b[4:8], uint32(p.ReachableTime))
+	binary.BigEndian.PutUint32(b[8:12], uint32(p.RetransTimer))
+	return marshalNDPOptions(b, p.Options)
+}
+
+// parseRouterAdvertisement parses b as an ICMPv6 router advertisement
+// message body. It returns a RawBody if b is malformed.
+func parseRouterAdvertisement(proto int, _ Type, b []byte) (MessageBody, error) {
+	if len(b) < 12 {
+		return parseRawBody(proto, b)
+	}
+	p := &RouterAdvertisement{
+		CurHopLimit:    int(b[0]),
+		Flags:          int(b[1]),
+		RouterLifetime: int(binary.BigEndian.Uint16(b[2:4])),
+		ReachableTime:  int(binary.BigEndian.Uint32(b[4:8])),
+		RetransTimer:   int(binary.BigEndian.Uint32(b[8:12])),
+	}
+	var err error
+	if p.Options, err = parseNDPOptions(b[12:]); err != nil {
+		return parseRawBody(proto, b)
+	}
+	return p, nil
+}
+
+// A NeighborSolicitation represents an ICMPv6 neighbor solicitation
+// message body.
+type NeighborSolicitation struct {
+	TargetAddr net.IP      // address of the target of the solicitation
+	Options    []NDPOption // options
+}
+
+// Len implements the Len method of MessageBody interface.
+func (p *NeighborSolicitation) Len(proto int) int {
+	if p == nil {
+		return 0
+	}
+	return 4 + net.IPv6len + ndpOptionsLen(p.Options)
+}
+
+// Marshal implements the Marshal method of MessageBody interface.
+func (p *NeighborSolicitation) Marshal(proto int) ([]byte, error) {
+	ip := p.TargetAddr.To16()
+	if ip == nil {
+		return nil, errInvalidBody
+	}
+	b := make([]byte, 4+net.IPv6len, p.Len(proto))
+	copy(b[4:], ip)
+	return marshalNDPOptions(b, p.Options)
+}
+
+// parseNeighborSolicitation parses b as an ICMPv6 neighbor
+// solicitation message body. It returns a RawBody if b is malformed.
+func parseNeighborSolicitation(proto int, _ Type, b []byte) (MessageBody, error) {
+	if len(b) < 4+net.IPv6len {
+		return parseRawBody(proto, b)
+	}
+	p := &NeighborSolicitation{TargetAddr: append(net.IP(nil), b[4:4+net.IPv6len]...)}
+	var err error
+	if p.Options, err = parseNDPOptions(b[4+net.IPv6len:]); err != nil {
+		return parseRawBody(proto, b)
+	}
+	return p, nil
+}
+
+// A NeighborAdvertisement represents an ICMPv6 neighbor advertisement
+// message body.
+type NeighborAdvertisement struct {
+	Router     bool        // sender is a router
+	Solicited  bool        // sent in response to a neighbor solicitation
+	Override   bool        // advertisement overrides an existing cache entry
+	TargetAddr net.IP      // address whose link-layer address is advertised
+	Options    []NDPOption // options
+}
+
+// Len implements the Len method of MessageBody interface.
+func (p *NeighborAdvertisement) Len(proto int) int {
+	if p == nil {
+		return 0
+	}
+	return 4 + net.IPv6len + ndpOptionsLen(p.Options)
+}
+
+// Marshal implements the Marshal method of MessageBody interface.
+func (p *NeighborAdvertisement) Marshal(proto int) ([]byte, error) {
+	ip := p.TargetAddr.To16()
+	if ip == nil {
+		return nil, errInvalidBody
+	}
+	b := make([]byte, 4+net.IPv6len, p.Len(proto))
+	if p.Router {
+		b[0] |= 0x80
+	}
+	if p.Solicited {
+		b[0] |= 0x40
+	}
+	if p.Override {
+		b[0] |= 0x20
+	}
+	copy(b[4:], ip)
+	return marshalNDPOptions(b, p.Options)
+}
+
+// parseNeighborAdvertisement parses b as an ICMPv6 neighbor
+// advertisement message body. It returns a RawBody if b is malformed.
+func parseNeighborAdvertisement(proto int, _ Type, b []byte) (MessageBody, error) {
+	if len(b) < 4+net.IPv6len {
+		return parseRawBody(proto, b)
+	}
+	p := &NeighborAdvertisement{
+		Router:     b[0]&0x80 != 0,
+		Solicited:  b[0]&0x40 != 0,
+		Override:   b[0]&0x20 != 0,
+		TargetAddr: append(net.IP(nil), b[4:4+net.IPv6len]...),
+	}
+	var err error
+	if p.Options, err = parseNDPOptions(b[4+net.IPv6len:]); err != nil {
+		return parseRawBody(proto, b)
+	}
+	return p, nil
+}
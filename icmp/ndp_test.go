@@ -0,0 +1,217 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icmp_test
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/internal/iana"
+	"golang.org/x/net/ipv6"
+)
+
+var ndpTests = []struct {
+	src, dst string
+	wire     []byte
+	want     *icmp.Message
+}{
+	// Router advertisement with source link-layer address, MTU and
+	// prefix information options.
+	{
+		"fe80::211:22ff:fe33:4455", "ff02::1",
+		[]byte{
+			0x86, 0x00, 0xa7, 0xdf,
+			0x40, 0xc0, 0x07, 0x08,
+			0x00, 0x00, 0x75, 0x30,
+			0x00, 0x00, 0x03, 0xe8,
+
+			0x01, 0x01, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55,
+
+			0x05, 0x01, 0x00, 0x00, 0x00, 0x00, 0x05, 0xdc,
+
+			0x03, 0x04, 0x40, 0xc0,
+			0x00, 0x27, 0x8d, 0x00,
+			0x00, 0x09, 0x3a, 0x80,
+			0x00, 0x00, 0x00, 0x00,
+			0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		&icmp.Message{
+			Type: ipv6.ICMPTypeRouterAdvertisement,
+			Body: &icmp.RouterAdvertisement{
+				CurHopLimit:    64,
+				Flags:          icmp.RouterFlagManagedConfig | icmp.RouterFlagOtherConfig,
+				RouterLifetime: 1800,
+				ReachableTime:  30000,
+				RetransTimer:   1000,
+				Options: []icmp.NDPOption{
+					&icmp.LinkLayerAddrOption{
+						Type: icmp.NDPOptionSourceLinkLayerAddr,
+						Addr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+					},
+					&icmp.MTUOption{MTU: 1500},
+					&icmp.PrefixInfoOption{
+						PrefixLen:         64,
+						OnLink:            true,
+						Autonomous:        true,
+						ValidLifetime:     2592000,
+						PreferredLifetime: 604800,
+						Prefix:            net.ParseIP("2001:db8:1::"),
+					},
+				},
+			},
+		},
+	},
+	// Router solicitation with a source link-layer address option.
+	{
+		"fe80::2aa:bbff:fecc:ddee", "ff02::2",
+		[]byte{
+			0x85, 0x00, 0x46, 0x63,
+			0x00, 0x00, 0x00, 0x00,
+
+			0x01, 0x01, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee,
+		},
+		&icmp.Message{
+			Type: ipv6.ICMPTypeRouterSolicitation,
+			Body: &icmp.RouterSolicitation{
+				Options: []icmp.NDPOption{
+					&icmp.LinkLayerAddrOption{
+						Type: icmp.NDPOptionSourceLinkLayerAddr,
+						Addr: net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee},
+					},
+				},
+			},
+		},
+	},
+	// Neighbor solicitation for the address of the router above.
+	{
+		"fe80::2aa:bbff:fecc:ddee", "ff02::1:ff33:4455",
+		[]byte{
+			0x87, 0x00, 0x9a, 0xb0,
+			0x00, 0x00, 0x00, 0x00,
+			0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x02, 0x11, 0x22, 0xff, 0xfe, 0x33, 0x44, 0x55,
+
+			0x01, 0x01, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee,
+		},
+		&icmp.Message{
+			Type: ipv6.ICMPTypeNeighborSolicitation,
+			Body: &icmp.NeighborSolicitation{
+				TargetAddr: net.ParseIP("fe80::211:22ff:fe33:4455"),
+				Options: []icmp.NDPOption{
+					&icmp.LinkLayerAddrOption{
+						Type: icmp.NDPOptionSourceLinkLayerAddr,
+						Addr: net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee},
+					},
+				},
+			},
+		},
+	},
+	// Solicited neighbor advertisement in reply, with an unknown
+	// option.
+	{
+		"fe80::211:22ff:fe33:4455", "fe80::2aa:bbff:fecc:ddee",
+		[]byte{
+			0x88, 0x00, 0x9a, 0xa3,
+			0xe0, 0x00, 0x00, 0x00,
+			0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x02, 0x11, 0x22, 0xff, 0xfe, 0x33, 0x44, 0x55,
+
+			0x02, 0x01, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55,
+
+			0xfd, 0x02, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06,
+			0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e,
+		},
+		&icmp.Message{
+			Type: ipv6.ICMPTypeNeighborAdvertisement,
+			Body: &icmp.NeighborAdvertisement{
+				Router:     true,
+				Solicited:  true,
+				Override:   true,
+				TargetAddr: net.ParseIP("fe80::211:22ff:fe33:4455"),
+				Options: []icmp.NDPOption{
+					&icmp.LinkLayerAddrOption{
+						Type: icmp.NDPOptionTargetLinkLayerAddr,
+						Addr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+					},
+					&icmp.RawNDPOption{
+						Type: 0xfd,
+						Data: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestNDPMessages(t *testing.T) {
+	for i, tt := range ndpTests {
+		m, err := icmp.ParseMessage(iana.ProtocolIPv6ICMP, tt.wire)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if m.Type != tt.want.Type || m.Code != tt.want.Code {
+			t.Errorf("#%d: got type=%v code=%d; want type=%v code=%d", i, m.Type, m.Code, tt.want.Type, tt.want.Code)
+		}
+		if !reflect.DeepEqual(m.Body, tt.want.Body) {
+			t.Errorf("#%d: got %#v; want %#v", i, m.Body, tt.want.Body)
+		}
+
+		psh := icmp.IPv6PseudoHeader(net.ParseIP(tt.src), net.ParseIP(tt.dst))
+		b, err := tt.want.Marshal(psh)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if !bytes.Equal(b, tt.wire) {
+			t.Errorf("#%d: got %#v; want %#v", i, b, tt.wire)
+		}
+	}
+}
+
+func TestNDPMessageMalformed(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x85, 0x00, 0x00, 0x00},
+		{0x85, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{0x85, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{0x85, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00},
+		{0x86, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00},
+		{0x87, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfe, 0x80},
+		{0x88, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfe, 0x80},
+	} {
+		// Messages that can't be split into options are kept raw.
+		m, err := icmp.ParseMessage(iana.ProtocolIPv6ICMP, b)
+		if err != nil {
+			t.Errorf("%#v: %v", b, err)
+			continue
+		}
+		if want := (&icmp.RawBody{Data: b[4:]}); !reflect.DeepEqual(m.Body, want) {
+			t.Errorf("%#v: got %#v; want %#v", b, m.Body, want)
+		}
+	}
+
+	// An option of a known type with an unexpected length is kept raw.
+	b := []byte{
+		0x86, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x05, 0x02, 0x00, 0x00, 0x00, 0x00, 0x05, 0xdc,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	m, err := icmp.ParseMessage(iana.ProtocolIPv6ICMP, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &icmp.RouterAdvertisement{
+		CurHopLimit: 64,
+		Options: []icmp.NDPOption{
+			&icmp.RawNDPOption{Type: icmp.NDPOptionMTU, Data: b[18:]},
+		},
+	}
+	if !reflect.DeepEqual(m.Body, want) {
+		t.Errorf("got %#v; want %#v", m.Body, want)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icmp
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"golang.org/x/net/internal/iana"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// aLongTimeAgo is a non-zero time, far in the past, used for
+// immediate cancelation of pending reads.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// Ping sends an ICMP echo request with the identifier id, sequence
+// number seq and payload data to dst, then waits for the matching
+// echo reply and returns the round-trip time.
+//
+// Messages other than an echo reply from dst carrying the same
+// identifier and sequence number are discarded.
+// For non-privileged datagram-oriented ICMP endpoints the kernel
+// replaces the identifier with the local port number of the endpoint
+// and delivers only the replies addressed to it, so only the sequence
+// number is matched.
+//
+// Ping returns ctx.Err() if ctx is done before a matching reply
+// arrives. It uses the read deadline of c, which is cleared on
+// return, so c must not be read concurrently.
+func (c *PacketConn) Ping(ctx context.Context, dst net.Addr, id, seq int, data []byte) (time.Duration, error) {
+	if !c.ok() {
+		return 0, errInvalidConn
+	}
+	var proto int
+	var req, rep Type
+	switch {
+	case c.p4 != nil:
+		proto, req, rep = iana.ProtocolICMP, ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	case c.p6 != nil:
+		proto, req, rep = iana.ProtocolIPv6ICMP, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	default:
+		return 0, errInvalidProtocol
+	}
+	_, datagram := c.LocalAddr().(*net.UDPAddr)
+	m := Message{Type: req, Body: &Echo{ID: id, Seq: seq, Data: data}}
+	wb, err := m.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := c.SetReadDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+	if ctx.Done() != nil {
+		stop, stopped := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(stopped)
+			select {
+			case <-ctx.Done():
+				c.SetReadDeadline(aLongTimeAgo)
+			case <-stop:
+			}
+		}()
+		defer func() {
+			close(stop)
+			<-stopped
+			c.SetReadDeadline(time.Time{})
+		}()
+	}
+
+	start := time.Now()
+	if _, err := c.WriteTo(wb, dst); err != nil {
+		return 0, err
+	}
+	rb := make([]byte, 1500)
+	if len(wb) > len(rb) {
+		rb = make([]byte, len(wb))
+	}
+	for {
+		n, peer, err := c.ReadFrom(rb)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return 0, ctxErr
+			}
+			return 0, err
+		}
+		rtt := time.Since(start)
+		if !sameIP(peer, dst) {
+			continue
+		}
+		rm, err := ParseMessage(proto, rb[:n])
+		if err != nil || rm.Type != rep {
+			continue
+		}
+		if p, ok := rm.Body.(*Echo); ok && p.Seq == seq&0xffff && (datagram || p.ID == id&0xffff) {
+			return rtt, nil
+		}
+	}
+}
+
+// sameIP reports whether the IP addresses of a and b are equal.
+// It returns true if either one is not an IP-based address.
+func sameIP(a, b net.Addr) bool {
+	ip := func(a net.Addr) net.IP {
+		switch a := a.(type) {
+		case *net.IPAddr:
+			return a.IP
+		case *net.UDPAddr:
+			return a.IP
+		}
+		return nil
+	}
+	ipa, ipb := ip(a), ip(b)
+	if ipa == nil || ipb == nil || ipb.IsUnspecified() {
+		return true
+	}
+	return ipa.Equal(ipb)
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package idna
+
+import (
+	"container/list"
+	"sync"
+)
+
+// A Cache memoizes the conversions of a Profile. It keeps the results of
+// the most recently used inputs, including the errors of invalid ones, so
+// that converting the same domain names repeatedly, for instance on every
+// request of a server, does not pay the full cost of the mapping each time.
+//
+// A Cache is safe for concurrent use by multiple goroutines.
+type Cache struct {
+	p    *Profile
+	size int
+
+	mu    sync.Mutex
+	lru   list.List // of *cacheEntry, most recently used first
+	items map[cacheKey]*list.Element
+}
+
+type cacheKey struct {
+	s       string
+	toASCII bool
+}
+
+type cacheEntry struct {
+	key cacheKey
+	s   string
+	err error
+}
+
+// Cached returns a Cache that memoizes the results of p for up to size
+// inputs, evicting the least recently used ones. If size is not positive,
+// nothing is cached.
+func Cached(p *Profile, size int) *Cache {
+	return &Cache{
+		p:     p,
+		size:  size,
+		items: make(map[cacheKey]*list.Element),
+	}
+}
+
+// ToASCII returns the same results as the ToASCII method of the Profile of
+// c, reusing those of a previous call with s if they are still cached.
+func (c *Cache) ToASCII(s string) (string, error) {
+	return c.lookup(cacheKey{s, true})
+}
+
+// ToUnicode returns the same results as the ToUnicode method of the Profile
+// of c, reusing those of a previous call with s if they are still cached.
+func (c *Cache) ToUnicode(s string) (string, error) {
+	return c.lookup(cacheKey{s, false})
+}
+
+func (c *Cache) lookup(k cacheKey) (string, error) {
+	c.mu.Lock()
+	if el, ok := c.items[k]; ok {
+		c.lru.MoveToFront(el)
+		e := el.Value.(*cacheEntry)
+		c.mu.Unlock()
+		return e.s, e.err
+	}
+	c.mu.Unlock()
+
+	// The conversion runs without the lock, so concurrent misses for
+	// the same input may both compute it.
+	e := &cacheEntry{key: k}
+	if k.toASCII {
+		e.s, e.err = c.p.ToASCII(k.s)
+	} else {
+		e.s, e.err = c.p.ToUnicode(k.s)
+	}
+	if c.size <= 0 {
+		return e.s, e.err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[k]; !ok {
+		c.items[k] = c.lru.PushFront(e)
+		if c.lru.Len() > c.size {
+			old := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+			delete(c.items, old.key)
+		}
+	}
+	return e.s, e.err
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package idna
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var cacheTestInputs = []string{
+	"books",
+	"xn--bcher-kva",
+	"bücher",
+	"www.müller.de",
+	"www.xn--mller-kva.de",
+	"example。jp",
+	"Straße.de",
+	"商業.tw",
+	"",
+	"a..b",
+	"xn--",
+	"xn--ls8h.la",
+	"ـ-.com",
+	"-bad-.example",
+	"foo_bar.example",
+}
+
+func TestCache(t *testing.T) {
+	for _, p := range []*Profile{Punycode, Lookup, Display, Registration} {
+		c := Cached(p, len(cacheTestInputs))
+		for i := 0; i < 2; i++ { // miss, then hit
+			for _, s := range cacheTestInputs {
+				for _, f := range []struct {
+					name          string
+					cached, plain func(string) (string, error)
+				}{
+					{"ToASCII", c.ToASCII, p.ToASCII},
+					{"ToUnicode", c.ToUnicode, p.ToUnicode},
+				} {
+					got, gotErr := f.cached(s)
+					want, wantErr := f.plain(s)
+					if got != want || !reflect.DeepEqual(gotErr, wantErr) {
+						t.Errorf("%s: cached %s(%q) = %q, %v; want %q, %v", p, f.name, s, got, gotErr, want, wantErr)
+					}
+				}
+			}
+		}
+		if got, want := len(c.items), 2*len(cacheTestInputs); got > want {
+			t.Errorf("%s: cache holds %d entries; want at most %d", p, got, want)
+		}
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := Cached(Lookup, 2)
+	c.ToASCII("a.example")
+	c.ToASCII("b.example")
+	c.ToASCII("a.example") // a is now more recently used than b
+	c.ToASCII("c.example")
+	if _, ok := c.items[cacheKey{"b.example", true}]; ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	for _, s := range []string{"a.example", "c.example"} {
+		if _, ok := c.items[cacheKey{s, true}]; !ok {
+			t.Errorf("%q was evicted", s)
+		}
+	}
+	if n := c.lru.Len(); n != 2 {
+		t.Errorf("cache holds %d entries; want 2", n)
+	}
+
+	c = Cached(Lookup, 0)
+	if got, err := c.ToASCII("bücher"); got != "xn--bcher-kva" || err != nil {
+		t.Errorf("uncached ToASCII(%q) = %q, %v; want %q, nil", "bücher", got, err, "xn--bcher-kva")
+	}
+	if n := c.lru.Len(); n != 0 {
+		t.Errorf("cache of size 0 holds %d entries", n)
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	c := Cached(Lookup, 4)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s := fmt.Sprintf("bücher%d.example", (i+j)%6)
+				got, err := c.ToASCII(s)
+				want, wantErr := Lookup.ToASCII(s)
+				if got != want || !reflect.DeepEqual(err, wantErr) {
+					t.Errorf("cached ToASCII(%q) = %q, %v; want %q, %v", s, got, err, want, wantErr)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := c.lru.Len(); n > 4 || n != len(c.items) {
+		t.Errorf("cache holds %d entries in its list and %d in its map; want at most 4 of each", n, len(c.items))
+	}
+}
+
+var benchHosts = []string{
+	"www.müller.de",
+	"bücher.example.com",
+	"商業.tw",
+	"example.рф",
+	"golang.org",
+	"www.example.com",
+}
+
+func BenchmarkToASCII(b *testing.B) {
+	b.Run("Profile", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Lookup.ToASCII(benchHosts[i%len(benchHosts)])
+		}
+	})
+	b.Run("Cache", func(b *testing.B) {
+		c := Cached(Lookup, len(benchHosts))
+		for i := 0; i < b.N; i++ {
+			c.ToASCII(benchHosts[i%len(benchHosts)])
+		}
+	})
+}
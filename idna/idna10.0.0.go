@@ -147,6 +147,22 @@ func BidiRule() Option {
 	return func(o *options) { o.bidirule = bidirule.ValidString }
 }
 
+// CheckBidi sets whether to check the Bidi rule as defined in RFC 5893.
+// CheckBidi(true) is equivalent to BidiRule. CheckBidi(false) removes the
+// check from a profile, for instance one created with
+// ValidateForRegistration.
+//
+// This option corresponds to the CheckBidi flag in UTS #46.
+func CheckBidi(enable bool) Option {
+	return func(o *options) {
+		if enable {
+			o.bidirule = bidirule.ValidString
+		} else {
+			o.bidirule = nil
+		}
+	}
+}
+
 // ValidateForRegistration sets validation options to verify that a given IDN is
 // properly formatted for registration as defined by Section 4 of RFC 5891.
 func ValidateForRegistration() Option {
@@ -382,8 +398,14 @@ func (p *Profile) process(s string, toASCII bool) (string, error) {
 				// original profile to preserve options.
 				err = p.validateLabel(u)
 			}
-		} else if err == nil {
-			err = p.validateLabel(label)
+		} else {
+			if p.mapping == nil && p.bidirule != nil {
+				// Without a mapping step, bidi labels are only detected here.
+				isBidi = isBidi || bidirule.DirectionString(label) != bidi.LeftToRight
+			}
+			if err == nil {
+				err = p.validateLabel(label)
+			}
 		}
 	}
 	if isBidi && p.bidirule != nil && err == nil {
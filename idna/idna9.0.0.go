@@ -146,6 +146,22 @@ func BidiRule() Option {
 	return func(o *options) { o.bidirule = bidirule.ValidString }
 }
 
+// CheckBidi sets whether to check the Bidi rule as defined in RFC 5893.
+// CheckBidi(true) is equivalent to BidiRule. CheckBidi(false) removes the
+// check from a profile, for instance one created with
+// ValidateForRegistration.
+//
+// This option corresponds to the CheckBidi flag in UTS #46.
+func CheckBidi(enable bool) Option {
+	return func(o *options) {
+		if enable {
+			o.bidirule = bidirule.ValidString
+		} else {
+			o.bidirule = nil
+		}
+	}
+}
+
 // ValidateForRegistration sets validation options to verify that a given IDN is
 // properly formatted for registration as defined by Section 4 of RFC 5891.
 func ValidateForRegistration() Option {
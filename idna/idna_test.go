@@ -106,3 +106,53 @@ func TestIDNASeparators(t *testing.T) {
 
 // TODO(nigeltao): test errors, once we've specified when ToASCII and ToUnicode
 // return errors.
+
+func TestBidiRule(t *testing.T) {
+	profiles := []struct {
+		name    string
+		profile *Profile
+	}{
+		{"BidiRule", New(BidiRule())},
+		{"CheckBidi", New(CheckBidi(true))},
+		{"MapForLookup+BidiRule", New(MapForLookup(), BidiRule())},
+		{"Registration", Registration},
+	}
+	testCases := []struct {
+		unicode, ascii string
+		valid          bool
+	}{
+		{"مثال", "xn--mgbh0fb", true},   // Arabic
+		{"בדיקה", "xn--5dbedt4e", true}, // Hebrew
+		{"אַ", "xn--fdb3c", true},       // trailing NSM
+		{"אב.example", "xn--4dbc.example", true},
+		{"אa", "xn--a-zhc", false},    // B3: RTL label ending in L
+		{"1א", "xn--1-0hc", false},    // B1: label starting with EN
+		{"ا1١", "xn--1-ymc9o", false}, // B4: EN and AN in one RTL label
+		{"aא", "xn--a-0hc", false},    // B6: LTR label containing R
+		{"א.1a", "xn--4db.1a", false}, // B1: in a bidi domain name
+	}
+	for _, p := range profiles {
+		for _, tc := range testCases {
+			a, err := p.profile.ToASCII(tc.unicode)
+			if (err == nil) != tc.valid {
+				t.Errorf("%s: ToASCII(%q): got err %v, want valid %v", p.name, tc.unicode, err, tc.valid)
+			} else if a != tc.ascii {
+				t.Errorf("%s: ToASCII(%q): got %q, want %q", p.name, tc.unicode, a, tc.ascii)
+			}
+			u, err := p.profile.ToUnicode(tc.ascii)
+			if (err == nil) != tc.valid {
+				t.Errorf("%s: ToUnicode(%q): got err %v, want valid %v", p.name, tc.ascii, err, tc.valid)
+			} else if u != tc.unicode {
+				t.Errorf("%s: ToUnicode(%q): got %q, want %q", p.name, tc.ascii, u, tc.unicode)
+			}
+		}
+	}
+
+	// CheckBidi(false) removes the rule from a profile.
+	p := New(ValidateForRegistration(), CheckBidi(false))
+	for _, tc := range testCases {
+		if a, err := p.ToASCII(tc.unicode); err != nil || a != tc.ascii {
+			t.Errorf("CheckBidi(false): ToASCII(%q): got %q, %v, want %q, nil", tc.unicode, a, err, tc.ascii)
+		}
+	}
+}
@@ -196,3 +196,51 @@ func TestPunycodeErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestPunycodeEncodeDecode(t *testing.T) {
+	for _, tc := range punycodeTestCases {
+		if got, err := PunycodeDecode(tc.encoded); err != nil {
+			t.Errorf("PunycodeDecode(%q): %v", tc.encoded, err)
+		} else if got != tc.s {
+			t.Errorf("PunycodeDecode(%q): got %q, want %q", tc.encoded, got, tc.s)
+		}
+
+		if got, err := PunycodeEncode(tc.s); err != nil {
+			t.Errorf("PunycodeEncode(%q): %v", tc.s, err)
+		} else if got != tc.encoded {
+			t.Errorf("PunycodeEncode(%q): got %q, want %q", tc.s, got, tc.encoded)
+		}
+	}
+
+	// The case of the encoded deltas is not significant.
+	if got, err := PunycodeDecode("bcher-KVA"); err != nil || got != "bücher" {
+		t.Errorf(`PunycodeDecode("bcher-KVA"): got %q, %v, want "bücher", nil`, got, err)
+	}
+}
+
+func TestPunycodeEncodeDecodeErrors(t *testing.T) {
+	for _, s := range []string{
+		"-",
+		"9",
+		"99999a",
+		"9999999999a",
+		"\u00fc-",         // non-basic code point before the delimiter
+		"b\u00fccher-kva", // non-basic code point before the delimiter
+		"bb0c",            // decodes to a surrogate
+	} {
+		if got, err := PunycodeDecode(s); err == nil {
+			t.Errorf("PunycodeDecode(%q): got %q, want error", s, got)
+		}
+	}
+	for _, s := range []string{
+		"\xff",
+		strings.Repeat("x", 65536) + "\uff00",
+	} {
+		if _, err := PunycodeEncode(s); err == nil {
+			if len(s) > 256 {
+				s = s[:100] + "..." + s[len(s)-100:]
+			}
+			t.Errorf("PunycodeEncode(%q): got nil error", s)
+		}
+	}
+}
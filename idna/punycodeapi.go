@@ -0,0 +1,41 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package idna
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// PunycodeEncode returns the Punycode encoding of s, as specified in RFC
+// 3492, without the "xn--" ACE prefix and without any of the mapping or
+// validation performed by the profiles of this package.
+//
+// It returns an error if s is not valid UTF-8 or if the encoding overflows.
+func PunycodeEncode(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", punyError(s)
+	}
+	return encode("", s)
+}
+
+// PunycodeDecode decodes the Punycode string s, as specified in RFC 3492.
+// s must not have the "xn--" ACE prefix.
+//
+// It returns an error if s is malformed, if the decoding overflows, or if s
+// is not the canonical encoding of its result; the case of letters other
+// than the basic code points is ignored.
+func PunycodeDecode(s string) (string, error) {
+	d, err := decode(s)
+	if err != nil {
+		return "", err
+	}
+	// The decoder is lenient, for instance about non-basic code points
+	// before the last delimiter. Encoding the result again catches these.
+	if e, err := encode("", d); err != nil || !strings.EqualFold(e, s) {
+		return "", punyError(s)
+	}
+	return d, nil
+}
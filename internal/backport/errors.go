@@ -11,21 +11,46 @@ import (
 	"errors"
 )
 
-// ErrorIs performs a normal errors.Is then, if false, checks target.Backport_Is against every layer of err
+// ErrorIs performs a normal errors.Is then, if false, checks target.Backport_Is against every layer of err.
+// Errors implementing Unwrap() []error (see Join) are traversed depth-first, regardless of toolchain.
 func ErrorIs(err error, target error) bool {
 	if errors.Is(err, target) {
 		return true
 	}
-	t, ok := target.(interface{ Backport_Is(err error) bool })
-	if !ok {
-		return false
-	}
-	for {
-		if t.Backport_Is(err) {
+	t, _ := target.(interface{ Backport_Is(err error) bool })
+	for err != nil {
+		if t != nil && t.Backport_Is(err) {
 			return true
 		}
-		if err = errors.Unwrap(err); err == nil {
+		if u, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range u.Unwrap() {
+				if err != nil && ErrorIs(err, target) {
+					return true
+				}
+			}
+			return false
+		}
+		err = errors.Unwrap(err)
+	}
+	return false
+}
+
+// ErrorAs performs a normal errors.As then, if false, tries errors.As against every error wrapped by an
+// Unwrap() []error method, which errors.As only supports as of go1.20.
+func ErrorAs(err error, target interface{}) bool {
+	if errors.As(err, target) {
+		return true
+	}
+	for err != nil {
+		if u, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range u.Unwrap() {
+				if err != nil && ErrorAs(err, target) {
+					return true
+				}
+			}
 			return false
 		}
+		err = errors.Unwrap(err)
 	}
+	return false
 }
@@ -0,0 +1,131 @@
+// Copyright 2022 The Go Authors.
+// Copyright 2022 Joseph Cumines.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backport
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type (
+	// multiError implements Unwrap() []error without relying on errors.Join, so the traversal in this
+	// package is exercised even on toolchains where the errors package supports it natively
+	multiError []error
+
+	// markerError is matched by markerTarget.Backport_Is
+	markerError struct{}
+
+	// markerTarget is not equal to any error, it only matches via Backport_Is
+	markerTarget struct{}
+
+	valueError struct{ v int }
+)
+
+func (x multiError) Error() string    { return fmt.Sprint([]error(x)) }
+func (x multiError) Unwrap() []error  { return x }
+func (x markerError) Error() string   { return `marker` }
+func (x *markerTarget) Error() string { return `marker target` }
+func (x *valueError) Error() string   { return fmt.Sprint(`value `, x.v) }
+func (x *markerTarget) Backport_Is(err error) bool {
+	_, ok := err.(markerError)
+	return ok
+}
+
+func TestErrorIs_sliceUnwrap(t *testing.T) {
+	sentinel := errors.New(`sentinel`)
+	other := errors.New(`other`)
+	target := &markerTarget{}
+	for _, tc := range [...]struct {
+		Name     string
+		Err      error
+		Target   error
+		Expected bool
+	}{
+		{
+			Name:     `sentinel in slice`,
+			Err:      multiError{other, sentinel},
+			Target:   sentinel,
+			Expected: true,
+		},
+		{
+			Name:     `sentinel wrapped in slice`,
+			Err:      fmt.Errorf(`outer: %w`, multiError{other, fmt.Errorf(`inner: %w`, sentinel)}),
+			Target:   sentinel,
+			Expected: true,
+		},
+		{
+			Name:     `sentinel in nested slice`,
+			Err:      multiError{nil, multiError{other}, multiError{nil, sentinel}},
+			Target:   sentinel,
+			Expected: true,
+		},
+		{
+			Name:   `sentinel missing`,
+			Err:    multiError{other, fmt.Errorf(`inner: %w`, other)},
+			Target: sentinel,
+		},
+		{
+			Name:     `backport in slice`,
+			Err:      multiError{other, fmt.Errorf(`inner: %w`, markerError{})},
+			Target:   target,
+			Expected: true,
+		},
+		{
+			Name:     `backport wrapped in slice`,
+			Err:      fmt.Errorf(`outer: %w`, multiError{multiError{other}, markerError{}}),
+			Target:   target,
+			Expected: true,
+		},
+		{
+			Name:   `backport missing`,
+			Err:    multiError{other, multiError{sentinel}},
+			Target: target,
+		},
+		{
+			Name:     `join`,
+			Err:      fmt.Errorf(`outer: %w`, Join(other, nil, markerError{})),
+			Target:   target,
+			Expected: true,
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			if ErrorIs(tc.Err, tc.Target) != tc.Expected {
+				t.Errorf(`expected %v for %v`, tc.Expected, tc.Err)
+			}
+		})
+	}
+}
+
+func TestErrorAs_sliceUnwrap(t *testing.T) {
+	var target *valueError
+	if !ErrorAs(fmt.Errorf(`outer: %w`, multiError{errors.New(`other`), multiError{&valueError{v: 3}}}), &target) {
+		t.Fatal(`expected match`)
+	}
+	if target == nil || target.v != 3 {
+		t.Fatal(target)
+	}
+	target = nil
+	if ErrorAs(multiError{errors.New(`other`), nil}, &target) || target != nil {
+		t.Fatal(target)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if err := Join(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	a, b := errors.New(`a`), errors.New(`b`)
+	err := Join(a, nil, b)
+	if s := err.Error(); s != "a\nb" {
+		t.Fatalf(`unexpected error string %q`, s)
+	}
+	if !ErrorIs(err, a) || !ErrorIs(err, b) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The Go Authors.
+// Copyright 2022 Joseph Cumines.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.20
+// +build !go1.20
+
+package backport
+
+type (
+	// joinError matches the behavior of errors.joinError
+	joinError struct {
+		errs []error
+	}
+)
+
+// Join matches the behavior of errors.Join, added in go1.20, and may be used with ErrorIs and ErrorAs.
+func Join(errs ...error) error {
+	n := 0
+	for _, err := range errs {
+		if err != nil {
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	e := &joinError{errs: make([]error, 0, n)}
+	for _, err := range errs {
+		if err != nil {
+			e.errs = append(e.errs, err)
+		}
+	}
+	return e
+}
+
+func (x *joinError) Error() string {
+	var b []byte
+	for i, err := range x.errs {
+		if i > 0 {
+			b = append(b, '\n')
+		}
+		b = append(b, err.Error()...)
+	}
+	return string(b)
+}
+
+func (x *joinError) Unwrap() []error { return x.errs }
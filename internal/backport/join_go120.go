@@ -0,0 +1,18 @@
+// Copyright 2022 The Go Authors.
+// Copyright 2022 Joseph Cumines.
+//
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.20
+// +build go1.20
+
+package backport
+
+import (
+	"errors"
+)
+
+// Join is errors.Join, see https://github.com/golang/go/issues/53435
+func Join(errs ...error) error { return errors.Join(errs...) }
@@ -29,6 +29,11 @@ func (x *deadlineExceededError) Error() string   { return "i/o timeout" }
 func (x *deadlineExceededError) Timeout() bool   { return true }
 func (x *deadlineExceededError) Temporary() bool { return true }
 func (x *deadlineExceededError) Backport_Is(err error) bool {
+	if err, ok := err.(*net.OpError); ok {
+		// OpError.Temporary is false unless the wrapped error implements it, e.g. when the wrapped error
+		// only implements Timeout, so rely on OpError.Timeout, which consults the wrapped error
+		return err.Timeout()
+	}
 	if err, ok := err.(net.Error); ok && x.Timeout() == err.Timeout() && x.Temporary() == err.Temporary() {
 		// there's not much else we can do here (the reason for the change in the first place)
 		return true
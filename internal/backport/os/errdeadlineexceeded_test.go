@@ -13,10 +13,17 @@ import (
 	"fmt"
 	"golang.org/x/net/internal/backport"
 	"net"
+	"os"
 	"testing"
 	"time"
 )
 
+// timeoutError implements Timeout but not Temporary, so it isn't a net.Error
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return `timeout` }
+func (timeoutError) Timeout() bool { return true }
+
 func TestErrDeadlineExceeded_is(t *testing.T) {
 	for _, tc := range [...]struct {
 		Name       string
@@ -59,6 +66,26 @@ func TestErrDeadlineExceeded_is(t *testing.T) {
 			},
 			IsBackport: true,
 		},
+		{
+			Name: `net op error wrapping timeout`,
+			Err: func(t *testing.T) error {
+				return &net.OpError{Op: `read`, Net: `tcp`, Err: timeoutError{}}
+			},
+			IsBackport: true,
+		},
+		{
+			Name: `net op error wrapping syscall timeout`,
+			Err: func(t *testing.T) error {
+				return &net.OpError{Op: `read`, Net: `tcp`, Err: os.NewSyscallError(`read`, timeoutError{})}
+			},
+			IsBackport: true,
+		},
+		{
+			Name: `net op error wrapping non-timeout`,
+			Err: func(t *testing.T) error {
+				return &net.OpError{Op: `read`, Net: `tcp`, Err: errors.New(`connection reset`)}
+			},
+		},
 	} {
 		t.Run(tc.Name, func(t *testing.T) {
 			err := tc.Err(t)
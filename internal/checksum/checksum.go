@@ -0,0 +1,64 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package checksum implements the Internet checksum described in RFC
+// 1071, as used by IPv4, ICMP and the transport protocols.
+//
+// Checksums over data in several buffers, such as a message and its
+// pseudo header, are built by accumulating a partial sum with Add and
+// finishing it with Fold.
+package checksum // import "golang.org/x/net/internal/checksum"
+
+import "encoding/binary"
+
+// Add adds the bytes of b, taken as big-endian 16-bit words, to the
+// partial sum s and returns the new partial sum. A trailing odd byte
+// is padded with a zero byte, so every buffer but the last one of a
+// checksum must have an even length.
+//
+// The initial partial sum is zero.
+func Add(s uint32, b []byte) uint32 {
+	// Summing 32-bit words into a 64-bit accumulator and folding at
+	// the end gives the same one's complement sum as 16-bit words,
+	// as 2^16 and 2^32 are both congruent to 1 modulo 2^16-1.
+	acc := uint64(s)
+	for len(b) >= 32 {
+		acc += uint64(binary.BigEndian.Uint32(b[0:4]))
+		acc += uint64(binary.BigEndian.Uint32(b[4:8]))
+		acc += uint64(binary.BigEndian.Uint32(b[8:12]))
+		acc += uint64(binary.BigEndian.Uint32(b[12:16]))
+		acc += uint64(binary.BigEndian.Uint32(b[16:20]))
+		acc += uint64(binary.BigEndian.Uint32(b[20:24]))
+		acc += uint64(binary.BigEndian.Uint32(b[24:28]))
+		acc += uint64(binary.BigEndian.Uint32(b[28:32]))
+		b = b[32:]
+	}
+	for len(b) >= 4 {
+		acc += uint64(binary.BigEndian.Uint32(b))
+		b = b[4:]
+	}
+	if len(b) >= 2 {
+		acc += uint64(binary.BigEndian.Uint16(b))
+		b = b[2:]
+	}
+	if len(b) == 1 {
+		acc += uint64(b[0]) << 8
+	}
+	acc = acc>>32 + acc&0xffffffff
+	acc = acc>>32 + acc&0xffffffff
+	return uint32(acc)
+}
+
+// Fold folds the partial sum s into 16 bits and returns its one's
+// complement, which is the checksum.
+func Fold(s uint32) uint16 {
+	s = s>>16 + s&0xffff
+	s = s>>16 + s&0xffff
+	return ^uint16(s)
+}
+
+// Checksum returns the Internet checksum of b.
+func Checksum(b []byte) uint16 {
+	return Fold(Add(0, b))
+}
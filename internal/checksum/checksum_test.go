@@ -0,0 +1,118 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checksum
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// naiveChecksum is the straightforward 16-bit loop of RFC 1071,
+// section 4.1.
+func naiveChecksum(b []byte) uint16 {
+	var s uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		s += uint32(b[i])<<8 | uint32(b[i+1])
+		s = s>>16 + s&0xffff
+	}
+	if len(b)%2 == 1 {
+		s += uint32(b[len(b)-1]) << 8
+		s = s>>16 + s&0xffff
+	}
+	return ^uint16(s)
+}
+
+var checksumTests = []struct {
+	b    []byte
+	want uint16
+}{
+	{nil, 0xffff},
+	{[]byte{0x00}, 0xffff},
+	{[]byte{0x01}, 0xfeff},
+	{[]byte{0xff, 0xff}, 0x0000},
+	// RFC 1071, section 3.
+	{[]byte{0x00, 0x01, 0xf2, 0x03, 0xf4, 0xf5, 0xf6, 0xf7}, 0x220d},
+	// An IPv4 header, with its checksum field zeroed.
+	{
+		[]byte{
+			0x45, 0x00, 0x00, 0x73, 0x00, 0x00, 0x40, 0x00,
+			0x40, 0x11, 0x00, 0x00, 0xc0, 0xa8, 0x00, 0x01,
+			0xc0, 0xa8, 0x00, 0xc7,
+		},
+		0xb861,
+	},
+	// An ICMP echo request with a 5-byte payload.
+	{[]byte{0x08, 0x00, 0x00, 0x00, 0x12, 0x34, 0x00, 0x01, 'h', 'e', 'l', 'l', 'o'}, 0xa1f8},
+}
+
+func TestChecksum(t *testing.T) {
+	for _, tt := range checksumTests {
+		if got := Checksum(tt.b); got != tt.want {
+			t.Errorf("Checksum(%#v) = %#04x; want %#04x", tt.b, got, tt.want)
+		}
+		if got := naiveChecksum(tt.b); got != tt.want {
+			t.Errorf("naiveChecksum(%#v) = %#04x; want %#04x", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestChecksumRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	// The largest lengths overflow a 32-bit accumulator of 16-bit
+	// words that is not folded as it goes.
+	for _, n := range []int{1, 2, 3, 31, 32, 33, 63, 64, 1500, 65535, 1 << 20} {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = 0xff - byte(r.Intn(4))
+		}
+		want := naiveChecksum(b)
+		if got := Checksum(b); got != want {
+			t.Errorf("Checksum of %d bytes = %#04x; want %#04x", n, got, want)
+		}
+		// The same sum over discontiguous buffers of even length.
+		var s uint32
+		for rest := b; len(rest) > 0; {
+			l := 2 * (1 + r.Intn(40))
+			if l > len(rest) {
+				l = len(rest)
+			}
+			s = Add(s, rest[:l])
+			rest = rest[l:]
+		}
+		if got := Fold(s); got != want {
+			t.Errorf("Fold of the partial sums of %d bytes = %#04x; want %#04x", n, got, want)
+		}
+	}
+}
+
+func TestChecksumVerify(t *testing.T) {
+	// A buffer that includes its own checksum sums to zero.
+	b := []byte{0x08, 0x00, 0x00, 0x00, 0x12, 0x34, 0x00, 0x01, 'h', 'e', 'l', 'l', 'o', 0x00}
+	s := Checksum(b)
+	b[2], b[3] = byte(s>>8), byte(s)
+	if got := Checksum(b); got != 0 {
+		t.Errorf("Checksum of checksummed buffer = %#04x; want 0", got)
+	}
+}
+
+func BenchmarkChecksum(b *testing.B) {
+	for _, n := range []int{64, 1500, 9000} {
+		buf := make([]byte, n)
+		rand.New(rand.NewSource(1)).Read(buf)
+		b.Run("Add/"+strconv.Itoa(n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for i := 0; i < b.N; i++ {
+				Checksum(buf)
+			}
+		})
+		b.Run("Naive/"+strconv.Itoa(n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for i := 0; i < b.N; i++ {
+				naiveChecksum(buf)
+			}
+		})
+	}
+}
@@ -239,6 +239,13 @@ type Message struct {
 	Flags int // protocol-specific information on the received message
 }
 
+// ControlTruncated reports whether some control messages of the
+// received message were discarded because OOB was too small to hold
+// them.
+func (m *Message) ControlTruncated() bool {
+	return m.Flags&sysMSG_CTRUNC != 0
+}
+
 // RecvMsg wraps recvmsg system call.
 //
 // The provided flags is a set of platform-dependent flags, such as
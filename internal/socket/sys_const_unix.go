@@ -14,6 +14,8 @@ const (
 	sysAF_INET   = unix.AF_INET
 	sysAF_INET6  = unix.AF_INET6
 
+	sysMSG_CTRUNC = unix.MSG_CTRUNC
+
 	sysSOCK_RAW = unix.SOCK_RAW
 
 	sizeofSockaddrInet4 = unix.SizeofSockaddrInet4
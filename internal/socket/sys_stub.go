@@ -14,6 +14,8 @@ const (
 	sysAF_INET   = 0x2
 	sysAF_INET6  = 0xa
 
+	sysMSG_CTRUNC = 0x8
+
 	sysSOCK_RAW = 0x3
 
 	sizeofSockaddrInet4 = 0x10
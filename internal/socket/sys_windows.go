@@ -22,6 +22,8 @@ const (
 	sysAF_INET   = windows.AF_INET
 	sysAF_INET6  = windows.AF_INET6
 
+	sysMSG_CTRUNC = windows.MSG_CTRUNC
+
 	sysSOCK_RAW = windows.SOCK_RAW
 
 	sizeofSockaddrInet4 = 0x10
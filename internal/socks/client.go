@@ -49,17 +49,16 @@ func (d *Dialer) connect(ctx context.Context, c net.Conn, address string) (_ net
 
 	b := make([]byte, 0, 6+len(host)) // the size here is just an estimate
 	b = append(b, Version5)
-	if len(d.AuthMethods) == 0 || d.Authenticate == nil {
-		b = append(b, 1, byte(AuthMethodNotRequired))
-	} else {
-		ams := d.AuthMethods
-		if len(ams) > 255 {
-			return nil, errors.New("too many authentication methods")
-		}
-		b = append(b, byte(len(ams)))
-		for _, am := range ams {
-			b = append(b, byte(am))
-		}
+	ams := []AuthMethod{AuthMethodNotRequired}
+	if len(d.AuthMethods) > 0 && d.Authenticate != nil {
+		ams = d.AuthMethods
+	}
+	if len(ams) > 255 {
+		return nil, errors.New("too many authentication methods")
+	}
+	b = append(b, byte(len(ams)))
+	for _, am := range ams {
+		b = append(b, byte(am))
 	}
 	if _, ctxErr = c.Write(b); ctxErr != nil {
 		return
@@ -73,7 +72,10 @@ func (d *Dialer) connect(ctx context.Context, c net.Conn, address string) (_ net
 	}
 	am := AuthMethod(b[1])
 	if am == AuthMethodNoAcceptableMethods {
-		return nil, errors.New("no acceptable authentication methods")
+		return nil, errors.New("no acceptable authentication methods; offered " + authMethodList(ams))
+	}
+	if !containsAuthMethod(ams, am) {
+		return nil, errors.New("server selected authentication method " + am.String() + ", which was not offered")
 	}
 	if d.Authenticate != nil {
 		if ctxErr = d.Authenticate(ctx, c, am); ctxErr != nil {
@@ -166,3 +168,23 @@ func splitHostPort(address string) (string, int, error) {
 	}
 	return host, portnum, nil
 }
+
+func containsAuthMethod(ams []AuthMethod, am AuthMethod) bool {
+	for _, m := range ams {
+		if m == am {
+			return true
+		}
+	}
+	return false
+}
+
+func authMethodList(ams []AuthMethod) string {
+	var b []byte
+	for i, am := range ams {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = append(b, am.String()...)
+	}
+	return string(b)
+}
@@ -10,6 +10,8 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -129,6 +131,77 @@ func TestDial(t *testing.T) {
 	})
 }
 
+func TestDialAuthNegotiation(t *testing.T) {
+	up := &socks.UsernamePassword{Username: "username", Password: "password"}
+	for _, tt := range []struct {
+		name    string
+		offered []socks.AuthMethod // nil means no Authenticate func
+		reply   socks.AuthMethod
+		wantErr string // substring of the error, or "" for success
+	}{
+		{"NotRequired", nil, socks.AuthMethodNotRequired, ""},
+		{"UsernamePassword", []socks.AuthMethod{socks.AuthMethodNotRequired, socks.AuthMethodGSSAPI, socks.AuthMethodUsernamePassword}, socks.AuthMethodUsernamePassword, ""},
+		{"NoAcceptableMethods", []socks.AuthMethod{socks.AuthMethodNotRequired, socks.AuthMethodUsernamePassword}, socks.AuthMethodNoAcceptableMethods, "no acceptable authentication methods; offered no authentication required, username/password"},
+		{"NotOffered", nil, socks.AuthMethodUsernamePassword, "server selected authentication method username/password, which was not offered"},
+		{"NotOfferedUnknown", []socks.AuthMethod{socks.AuthMethodUsernamePassword}, 0x80, "server selected authentication method unknown method: 128, which was not offered"},
+		{"GSSAPI", []socks.AuthMethod{socks.AuthMethodGSSAPI, socks.AuthMethodUsernamePassword}, socks.AuthMethodGSSAPI, "GSS-API authentication is not supported"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			gotMethods := make(chan []socks.AuthMethod, 1)
+			ss, err := sockstest.NewServer(func(rw io.ReadWriter, b []byte) error {
+				req, err := sockstest.ParseAuthRequest(b)
+				if err != nil {
+					return err
+				}
+				gotMethods <- req.Methods
+				if b, err = sockstest.MarshalAuthReply(req.Version, tt.reply); err != nil {
+					return err
+				}
+				if _, err := rw.Write(b); err != nil {
+					return err
+				}
+				if tt.reply != socks.AuthMethodUsernamePassword {
+					return nil
+				}
+				// Accept any username and password.
+				var buf [512]byte
+				if _, err := rw.Read(buf[:]); err != nil {
+					return err
+				}
+				_, err = rw.Write([]byte{0x01, 0x00})
+				return err
+			}, sockstest.NoProxyRequired)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ss.Close()
+			d := socks.NewDialer(ss.Addr().Network(), ss.Addr().String())
+			if tt.offered != nil {
+				d.AuthMethods = tt.offered
+				d.Authenticate = up.Authenticate
+			}
+			c, err := d.DialContext(context.Background(), ss.TargetAddr().Network(), ss.TargetAddr().String())
+			if err == nil {
+				c.Close()
+			}
+			wantMethods := tt.offered
+			if wantMethods == nil {
+				wantMethods = []socks.AuthMethod{socks.AuthMethodNotRequired}
+			}
+			if got := <-gotMethods; !reflect.DeepEqual(got, wantMethods) {
+				t.Errorf("offered methods %v; want %v", got, wantMethods)
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatal(err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("got %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func blackholeCmdFunc(rw io.ReadWriter, b []byte) error {
 	if _, err := sockstest.ParseCmdRequest(b); err != nil {
 		return err
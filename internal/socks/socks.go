@@ -34,6 +34,21 @@ func (cmd Command) String() string {
 // An AuthMethod represents a SOCKS authentication method.
 type AuthMethod int
 
+func (am AuthMethod) String() string {
+	switch am {
+	case AuthMethodNotRequired:
+		return "no authentication required"
+	case AuthMethodGSSAPI:
+		return "GSS-API"
+	case AuthMethodUsernamePassword:
+		return "username/password"
+	case AuthMethodNoAcceptableMethods:
+		return "no acceptable methods"
+	default:
+		return "unknown method: " + strconv.Itoa(int(am))
+	}
+}
+
 // A Reply represents a SOCKS command reply code.
 type Reply int
 
@@ -74,6 +89,7 @@ const (
 	cmdBind    Command = 0x02 // establishes a passive-open forward proxy connection
 
 	AuthMethodNotRequired         AuthMethod = 0x00 // no authentication required
+	AuthMethodGSSAPI              AuthMethod = 0x01 // use GSS-API; not supported by UsernamePassword
 	AuthMethodUsernamePassword    AuthMethod = 0x02 // use username/password
 	AuthMethodNoAcceptableMethods AuthMethod = 0xff // no acceptable authentication methods
 
@@ -312,6 +328,8 @@ func (up *UsernamePassword) Authenticate(ctx context.Context, rw io.ReadWriter,
 			return errors.New("username/password authentication failed")
 		}
 		return nil
+	case AuthMethodGSSAPI:
+		return errors.New("GSS-API authentication is not supported")
 	}
 	return errors.New("unsupported authentication method " + strconv.Itoa(int(auth)))
 }
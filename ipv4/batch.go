@@ -61,7 +61,9 @@ type Message = socket.Message
 // syscall.MSG_PEEK.
 //
 // On a successful read it returns the number of messages received, up
-// to len(ms).
+// to len(ms). A message whose control messages did not fit in its OOB
+// field is received like any other; its ControlTruncated method
+// reports the truncation.
 //
 // On Linux, a batch read will be optimized.
 // On other platforms, this method will read only a single message.
@@ -133,7 +135,9 @@ func (c *payloadHandler) WriteBatch(ms []Message, flags int) (int, error) {
 // syscall.MSG_PEEK.
 //
 // On a successful read it returns the number of messages received, up
-// to len(ms).
+// to len(ms). A message whose control messages did not fit in its OOB
+// field is received like any other; its ControlTruncated method
+// reports the truncation.
 //
 // On Linux, a batch read will be optimized.
 // On other platforms, this method will read only a single message.
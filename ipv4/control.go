@@ -16,12 +16,22 @@ import (
 type rawOpt struct {
 	sync.RWMutex
 	cflags ControlFlags
+	oobLen int // size of the control message buffer, or 0 for automatic sizing
 }
 
 func (c *rawOpt) set(f ControlFlags)        { c.cflags |= f }
 func (c *rawOpt) clear(f ControlFlags)      { c.cflags &^= f }
 func (c *rawOpt) isset(f ControlFlags) bool { return c.cflags&f != 0 }
 
+// newControlMessage returns a buffer for the control messages of a
+// received packet. The caller must hold at least a read lock on c.
+func (c *rawOpt) newControlMessage() []byte {
+	if c.oobLen > 0 {
+		return make([]byte, c.oobLen)
+	}
+	return NewControlMessage(c.cflags)
+}
+
 type ControlFlags uint
 
 const (
@@ -45,13 +55,18 @@ type ControlMessage struct {
 	Src     net.IP // source address, specifying only
 	Dst     net.IP // destination address, receiving only
 	IfIndex int    // interface index, must be 1 <= value when specifying
+
+	// Truncated reports whether some of the received options were
+	// discarded because they did not fit in the control message
+	// buffer. See SetControlMessageBufferSize.
+	Truncated bool
 }
 
 func (cm *ControlMessage) String() string {
 	if cm == nil {
 		return "<nil>"
 	}
-	return fmt.Sprintf("ttl=%d src=%v dst=%v ifindex=%d", cm.TTL, cm.Src, cm.Dst, cm.IfIndex)
+	return fmt.Sprintf("ttl=%d src=%v dst=%v ifindex=%d truncated=%t", cm.TTL, cm.Src, cm.Dst, cm.IfIndex, cm.Truncated)
 }
 
 // Marshal returns the binary encoding of cm.
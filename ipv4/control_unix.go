@@ -72,5 +72,11 @@ func marshalTTL(b []byte, cm *ControlMessage) []byte {
 }
 
 func parseTTL(cm *ControlMessage, b []byte) {
+	// Some platforms, such as Linux, carry the TTL in an int
+	// rather than a single octet.
+	if len(b) >= 4 {
+		cm.TTL = int(socket.NativeEndian.Uint32(b[:4]))
+		return
+	}
 	cm.TTL = int(*(*byte)(unsafe.Pointer(&b[:1][0])))
 }
@@ -249,6 +249,41 @@ func (c *dgramOpt) SetICMPFilter(f *ICMPFilter) error {
 	return so.setICMPFilter(c.Conn, f)
 }
 
+// HeaderPrepend reports whether the user prepends the IPv4 header to
+// outgoing packets instead of the kernel building it.
+func (c *dgramOpt) HeaderPrepend() (bool, error) {
+	if !c.ok() {
+		return false, errInvalidConn
+	}
+	so, ok := sockOpts[ssoHeaderPrepend]
+	if !ok {
+		return false, errNotImplemented
+	}
+	on, err := so.GetInt(c.Conn)
+	if err != nil {
+		return false, err
+	}
+	return on == 1, nil
+}
+
+// SetHeaderPrepend sets whether the user prepends the IPv4 header to
+// outgoing packets on a raw socket. When on is true, the kernel
+// still fills in the header checksum, and on most platforms the
+// source address and identification fields when they are zero.
+//
+// A RawConn requires the option to be enabled; it is set by
+// NewRawConn.
+func (c *dgramOpt) SetHeaderPrepend(on bool) error {
+	if !c.ok() {
+		return errInvalidConn
+	}
+	so, ok := sockOpts[ssoHeaderPrepend]
+	if !ok {
+		return errNotImplemented
+	}
+	return so.SetInt(c.Conn, boolint(on))
+}
+
 // SetBPF attaches a BPF program to the connection.
 //
 // Only supported on Linux.
@@ -62,6 +62,24 @@ func (c *PacketConn) SetControlMessage(cf ControlFlags, on bool) error {
 	return setControlMessage(c.dgramOpt.Conn, &c.payloadHandler.rawOpt, cf, on)
 }
 
+// SetControlMessageBufferSize sets the size of the buffer ReadFrom
+// uses to receive control messages. By default the buffer is just
+// large enough for the options enabled by SetControlMessage; a larger
+// one is needed when other socket options add control messages of
+// their own. A size of zero or less restores the default.
+func (c *PacketConn) SetControlMessageBufferSize(n int) error {
+	if !c.payloadHandler.ok() {
+		return errInvalidConn
+	}
+	if n < 0 {
+		n = 0
+	}
+	c.payloadHandler.rawOpt.Lock()
+	c.payloadHandler.rawOpt.oobLen = n
+	c.payloadHandler.rawOpt.Unlock()
+	return nil
+}
+
 // SetDeadline sets the read and write deadlines associated with the
 // endpoint.
 func (c *PacketConn) SetDeadline(t time.Time) error {
@@ -128,6 +146,24 @@ func (c *RawConn) SetControlMessage(cf ControlFlags, on bool) error {
 	return setControlMessage(c.dgramOpt.Conn, &c.packetHandler.rawOpt, cf, on)
 }
 
+// SetControlMessageBufferSize sets the size of the buffer ReadFrom
+// uses to receive control messages. By default the buffer is just
+// large enough for the options enabled by SetControlMessage; a larger
+// one is needed when other socket options add control messages of
+// their own. A size of zero or less restores the default.
+func (c *RawConn) SetControlMessageBufferSize(n int) error {
+	if !c.packetHandler.ok() {
+		return errInvalidConn
+	}
+	if n < 0 {
+		n = 0
+	}
+	c.packetHandler.rawOpt.Lock()
+	c.packetHandler.rawOpt.oobLen = n
+	c.packetHandler.rawOpt.Unlock()
+	return nil
+}
+
 // SetDeadline sets the read and write deadlines associated with the
 // endpoint.
 func (c *RawConn) SetDeadline(t time.Time) error {
@@ -39,7 +39,7 @@ type Header struct {
 	Checksum int         // checksum
 	Src      net.IP      // source address
 	Dst      net.IP      // destination address
-	Options  []byte      // options, extension headers
+	Options  []byte      // options, extension headers; see ParseOptions
 }
 
 func (h *Header) String() string {
@@ -12,6 +12,11 @@ import (
 	"golang.org/x/net/internal/socket"
 )
 
+// ErrControlMessageTruncated is wrapped by the error returned from
+// ReadFrom when the control messages of a received packet did not fit
+// in the buffer provided for them. See SetControlMessageBufferSize.
+var ErrControlMessageTruncated = errors.New("control message truncated")
+
 var (
 	errInvalidConn       = errors.New("invalid connection")
 	errMissingAddress    = errors.New("missing address")
@@ -0,0 +1,332 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+const maxOptionsLen = 40 // maximum length of the options area
+
+var (
+	errOptionsTooLong    = errors.New("options too long")
+	errInvalidOptionsLen = errors.New("options length does not match header length")
+	errInvalidOption     = errors.New("invalid option")
+)
+
+// An OptionType represents an IPv4 header option type.
+type OptionType int
+
+// IPv4 header option types, see
+// https://www.iana.org/assignments/ip-parameters.
+const (
+	OptionEndOfList         OptionType = 0   // end of options list
+	OptionNoOperation       OptionType = 1   // no operation
+	OptionRecordRoute       OptionType = 7   // record route
+	OptionTimestamp         OptionType = 68  // internet timestamp
+	OptionLooseSourceRoute  OptionType = 131 // loose source and record route
+	OptionStrictSourceRoute OptionType = 137 // strict source and record route
+)
+
+var optionTypeNames = map[OptionType]string{
+	OptionEndOfList:         "end of options list",
+	OptionNoOperation:       "no operation",
+	OptionRecordRoute:       "record route",
+	OptionTimestamp:         "timestamp",
+	OptionLooseSourceRoute:  "loose source route",
+	OptionStrictSourceRoute: "strict source route",
+}
+
+func (typ OptionType) String() string {
+	s, ok := optionTypeNames[typ]
+	if !ok {
+		return "<nil>"
+	}
+	return s
+}
+
+// An Option represents an IPv4 header option.
+type Option interface {
+	// Len returns the length of the option in octets, including
+	// the type and length octets.
+	Len() int
+
+	// Marshal returns the binary encoding of the option.
+	Marshal() ([]byte, error)
+}
+
+// A RouteOption represents a record route, loose source route or
+// strict source route option.
+type RouteOption struct {
+	Type    OptionType // OptionRecordRoute, OptionLooseSourceRoute or OptionStrictSourceRoute
+	Pointer int        // octet offset of the next route data slot, starting at 4
+	Addrs   []net.IP   // route data slots, including unused ones
+}
+
+// Len implements the Len method of Option interface.
+func (o *RouteOption) Len() int {
+	if o == nil {
+		return 0
+	}
+	return 3 + net.IPv4len*len(o.Addrs)
+}
+
+// Marshal implements the Marshal method of Option interface.
+func (o *RouteOption) Marshal() ([]byte, error) {
+	if o == nil {
+		return nil, errInvalidOption
+	}
+	switch o.Type {
+	case OptionRecordRoute, OptionLooseSourceRoute, OptionStrictSourceRoute:
+	default:
+		return nil, errInvalidOption
+	}
+	l := o.Len()
+	if l > maxOptionsLen || o.Pointer < 4 || o.Pointer > 0xff {
+		return nil, errInvalidOption
+	}
+	b := make([]byte, l)
+	b[0], b[1], b[2] = byte(o.Type), byte(l), byte(o.Pointer)
+	for i, ip := range o.Addrs {
+		if ip = ip.To4(); ip != nil {
+			copy(b[3+net.IPv4len*i:], ip)
+		}
+	}
+	return b, nil
+}
+
+// Recorded returns the route data slots before the pointer, that is,
+// the addresses that have already been recorded or visited.
+func (o *RouteOption) Recorded() []net.IP {
+	n := (o.Pointer - 4) / net.IPv4len
+	if n < 0 {
+		n = 0
+	}
+	if n > len(o.Addrs) {
+		n = len(o.Addrs)
+	}
+	return o.Addrs[:n]
+}
+
+func parseRouteOption(typ OptionType, b []byte) (Option, error) {
+	if len(b) < 3 || (len(b)-3)%net.IPv4len != 0 || b[2] < 4 {
+		return nil, errInvalidOption
+	}
+	o := &RouteOption{Type: typ, Pointer: int(b[2])}
+	for b = b[3:]; len(b) > 0; b = b[net.IPv4len:] {
+		o.Addrs = append(o.Addrs, net.IPv4(b[0], b[1], b[2], b[3]))
+	}
+	return o, nil
+}
+
+// TimestampFlags represents the flags field of a timestamp option.
+type TimestampFlags int
+
+const (
+	TimestampOnly         TimestampFlags = 0 // timestamps only
+	TimestampAndAddr      TimestampFlags = 1 // each timestamp is preceded by the address of the registering entity
+	TimestampPrespecified TimestampFlags = 3 // timestamps are registered by the prespecified addresses only
+)
+
+// A TimestampEntry represents a slot of a timestamp option.
+type TimestampEntry struct {
+	Addr net.IP // registering address; nil for TimestampOnly
+	Time uint32 // milliseconds since midnight UT, unless the high-order bit is set
+}
+
+// A TimestampOption represents an internet timestamp option.
+type TimestampOption struct {
+	Pointer  int              // octet offset of the next timestamp slot, starting at 5
+	Overflow int              // number of entities unable to register a timestamp
+	Flags    TimestampFlags   // flags
+	Entries  []TimestampEntry // timestamp slots, including unused ones
+}
+
+func (o *TimestampOption) entryLen() int {
+	if o.Flags == TimestampOnly {
+		return 4
+	}
+	return 4 + net.IPv4len
+}
+
+// Len implements the Len method of Option interface.
+func (o *TimestampOption) Len() int {
+	if o == nil {
+		return 0
+	}
+	return 4 + o.entryLen()*len(o.Entries)
+}
+
+// Marshal implements the Marshal method of Option interface.
+func (o *TimestampOption) Marshal() ([]byte, error) {
+	if o == nil {
+		return nil, errInvalidOption
+	}
+	switch o.Flags {
+	case TimestampOnly, TimestampAndAddr, TimestampPrespecified:
+	default:
+		return nil, errInvalidOption
+	}
+	l := o.Len()
+	if l > maxOptionsLen || o.Pointer < 5 || o.Pointer > 0xff || o.Overflow < 0 || o.Overflow > 0xf {
+		return nil, errInvalidOption
+	}
+	b := make([]byte, l)
+	b[0], b[1], b[2] = byte(OptionTimestamp), byte(l), byte(o.Pointer)
+	b[3] = byte(o.Overflow<<4) | byte(o.Flags)
+	off := 4
+	for _, e := range o.Entries {
+		if o.Flags != TimestampOnly {
+			if ip := e.Addr.To4(); ip != nil {
+				copy(b[off:], ip)
+			}
+			off += net.IPv4len
+		}
+		binary.BigEndian.PutUint32(b[off:], e.Time)
+		off += 4
+	}
+	return b, nil
+}
+
+// Recorded returns the timestamp slots before the pointer, that is,
+// the entries that have already been registered.
+func (o *TimestampOption) Recorded() []TimestampEntry {
+	n := (o.Pointer - 5) / o.entryLen()
+	if n < 0 {
+		n = 0
+	}
+	if n > len(o.Entries) {
+		n = len(o.Entries)
+	}
+	return o.Entries[:n]
+}
+
+func parseTimestampOption(b []byte) (Option, error) {
+	if len(b) < 4 || b[2] < 5 {
+		return nil, errInvalidOption
+	}
+	o := &TimestampOption{Pointer: int(b[2]), Overflow: int(b[3] >> 4), Flags: TimestampFlags(b[3] & 0x0f)}
+	switch o.Flags {
+	case TimestampOnly, TimestampAndAddr, TimestampPrespecified:
+	default:
+		return nil, errInvalidOption
+	}
+	el := o.entryLen()
+	if (len(b)-4)%el != 0 {
+		return nil, errInvalidOption
+	}
+	for b = b[4:]; len(b) > 0; b = b[el:] {
+		var e TimestampEntry
+		if o.Flags != TimestampOnly {
+			e.Addr = net.IPv4(b[0], b[1], b[2], b[3])
+		}
+		e.Time = binary.BigEndian.Uint32(b[el-4 : el])
+		o.Entries = append(o.Entries, e)
+	}
+	return o, nil
+}
+
+// A RawOption represents an option of a type that is not otherwise
+// supported.
+type RawOption struct {
+	Type OptionType // option type
+	Data []byte     // option data, excluding the type and length octets
+}
+
+// Len implements the Len method of Option interface.
+func (o *RawOption) Len() int {
+	if o == nil {
+		return 0
+	}
+	return 2 + len(o.Data)
+}
+
+// Marshal implements the Marshal method of Option interface.
+func (o *RawOption) Marshal() ([]byte, error) {
+	if o == nil || o.Type <= OptionNoOperation || o.Type > 0xff || o.Len() > maxOptionsLen {
+		return nil, errInvalidOption
+	}
+	b := make([]byte, o.Len())
+	b[0], b[1] = byte(o.Type), byte(o.Len())
+	copy(b[2:], o.Data)
+	return b, nil
+}
+
+// ParseOptions parses the options area of h into typed options.
+//
+// No-operation options are skipped and parsing stops at the first
+// end-of-options-list option.
+// It returns an error if the length of h.Options does not match the
+// header length h.Len, or if any option overruns the options area.
+func (h *Header) ParseOptions() ([]Option, error) {
+	if h == nil {
+		return nil, errNilHeader
+	}
+	if h.Len-HeaderLen != len(h.Options) {
+		return nil, errInvalidOptionsLen
+	}
+	var opts []Option
+	b := h.Options
+	for len(b) > 0 {
+		typ := OptionType(b[0])
+		if typ == OptionEndOfList {
+			break
+		}
+		if typ == OptionNoOperation {
+			b = b[1:]
+			continue
+		}
+		if len(b) < 2 || int(b[1]) < 2 || int(b[1]) > len(b) {
+			return nil, errInvalidOption
+		}
+		var o Option
+		var err error
+		switch l := int(b[1]); typ {
+		case OptionRecordRoute, OptionLooseSourceRoute, OptionStrictSourceRoute:
+			o, err = parseRouteOption(typ, b[:l])
+		case OptionTimestamp:
+			o, err = parseTimestampOption(b[:l])
+		default:
+			o = &RawOption{Type: typ, Data: append([]byte(nil), b[2:l]...)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, o)
+		b = b[b[1]:]
+	}
+	return opts, nil
+}
+
+// SetOptions encodes opts into the options area of h, padding it with
+// end-of-options-list octets to a multiple of 4 octets, and updates the
+// header length h.Len accordingly.
+func (h *Header) SetOptions(opts ...Option) error {
+	if h == nil {
+		return errNilHeader
+	}
+	var b []byte
+	for _, o := range opts {
+		if o == nil {
+			return errInvalidOption
+		}
+		ob, err := o.Marshal()
+		if err != nil {
+			return err
+		}
+		b = append(b, ob...)
+	}
+	if pad := len(b) % 4; pad != 0 {
+		b = append(b, make([]byte, 4-pad)...)
+	}
+	if len(b) > maxOptionsLen {
+		return errOptionsTooLong
+	}
+	h.Options = b
+	h.Len = HeaderLen + len(b)
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv4
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+var (
+	// ICMP echo reply to "ping -R 127.0.0.1", with a record route
+	// option holding two recorded hops.
+	wireHeaderWithRecordRoute = []byte{
+		0x4f, 0x00, 0x00, 0x7c,
+		0x8b, 0x1d, 0x00, 0x00,
+		0x40, 0x01, 0x71, 0xa4,
+		127, 0, 0, 1,
+		127, 0, 0, 1,
+		0x01, 0x07, 0x27, 0x0c,
+		127, 0, 0, 1,
+		127, 0, 0, 1,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+	}
+
+	// ICMP echo reply to "ping -T tsonly 127.0.0.1", with a timestamp
+	// option holding two registered timestamps.
+	wireHeaderWithTimestamp = []byte{
+		0x4f, 0x00, 0x00, 0x7c,
+		0x9d, 0x40, 0x00, 0x00,
+		0x40, 0x01, 0x5f, 0x81,
+		127, 0, 0, 1,
+		127, 0, 0, 1,
+		0x44, 0x28, 0x0d, 0x00,
+		0x03, 0x36, 0xa4, 0xb1,
+		0x03, 0x36, 0xa4, 0xb1,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+	}
+)
+
+func TestParseRecordRouteOption(t *testing.T) {
+	h, err := ParseHeader(wireHeaderWithRecordRoute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := h.ParseOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 1 {
+		t.Fatalf("got %d options; want 1", len(opts))
+	}
+	rr, ok := opts[0].(*RouteOption)
+	if !ok {
+		t.Fatalf("got %T; want *RouteOption", opts[0])
+	}
+	if rr.Type != OptionRecordRoute || rr.Pointer != 12 || len(rr.Addrs) != 9 || rr.Len() != 39 {
+		t.Fatalf("got %+v", rr)
+	}
+	lo := net.IPv4(127, 0, 0, 1)
+	if got := rr.Recorded(); len(got) != 2 || !got[0].Equal(lo) || !got[1].Equal(lo) {
+		t.Errorf("got recorded route %v; want [%v %v]", got, lo, lo)
+	}
+
+	// The leading no-operation option is replaced with trailing
+	// end-of-options-list padding.
+	var nh Header
+	if err := nh.SetOptions(opts...); err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([]byte(nil), wireHeaderWithRecordRoute[HeaderLen+1:]...), 0)
+	if nh.Len != 60 || !bytes.Equal(nh.Options, want) {
+		t.Errorf("got len=%d options=%#v; want len=60 options=%#v", nh.Len, nh.Options, want)
+	}
+}
+
+func TestParseTimestampOption(t *testing.T) {
+	h, err := ParseHeader(wireHeaderWithTimestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := h.ParseOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 1 {
+		t.Fatalf("got %d options; want 1", len(opts))
+	}
+	ts, ok := opts[0].(*TimestampOption)
+	if !ok {
+		t.Fatalf("got %T; want *TimestampOption", opts[0])
+	}
+	if ts.Pointer != 13 || ts.Overflow != 0 || ts.Flags != TimestampOnly || len(ts.Entries) != 9 {
+		t.Fatalf("got %+v", ts)
+	}
+	want := []TimestampEntry{{Time: 0x0336a4b1}, {Time: 0x0336a4b1}}
+	if got := ts.Recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got recorded timestamps %+v; want %+v", got, want)
+	}
+
+	var nh Header
+	if err := nh.SetOptions(opts...); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(nh.Options, wireHeaderWithTimestamp[HeaderLen:]) {
+		t.Errorf("got %#v; want %#v", nh.Options, wireHeaderWithTimestamp[HeaderLen:])
+	}
+}
+
+func TestOptionsRoundTrip(t *testing.T) {
+	opts := []Option{
+		&RouteOption{
+			Type:    OptionLooseSourceRoute,
+			Pointer: 4,
+			Addrs:   []net.IP{net.IPv4(192, 0, 2, 1), net.IPv4(198, 51, 100, 1)},
+		},
+		&TimestampOption{
+			Pointer: 13,
+			Flags:   TimestampAndAddr,
+			Entries: []TimestampEntry{
+				{Addr: net.IPv4(192, 0, 2, 1), Time: 1},
+				{Addr: net.IPv4(0, 0, 0, 0), Time: 0},
+			},
+		},
+		&RawOption{Type: 148, Data: []byte{0, 0}}, // router alert
+	}
+	var h Header
+	if err := h.SetOptions(opts...); err != nil {
+		t.Fatal(err)
+	}
+	if h.Len != HeaderLen+36 {
+		t.Fatalf("got header length %d; want %d", h.Len, HeaderLen+36)
+	}
+	got, err := h.ParseOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, opts) {
+		t.Errorf("got %+v; want %+v", got, opts)
+	}
+}
+
+func TestParseOptionsErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		h    *Header
+	}{
+		{"length mismatch", &Header{Len: 24, Options: []byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01}}},
+		{"truncated option", &Header{Len: 24, Options: []byte{0x01, 0x01, 0x01, 0x07}}},
+		{"option overruns IHL", &Header{Len: 24, Options: []byte{0x07, 0x07, 0x04, 0x00}}},
+		{"option too short", &Header{Len: 24, Options: []byte{0x94, 0x01, 0x00, 0x00}}},
+		{"bad route length", &Header{Len: 24, Options: []byte{0x07, 0x04, 0x04, 0x00}}},
+		{"bad route pointer", &Header{Len: 28, Options: []byte{0x07, 0x07, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00}}},
+		{"bad timestamp flags", &Header{Len: 28, Options: []byte{0x44, 0x08, 0x05, 0x02, 0x00, 0x00, 0x00, 0x00}}},
+		{"bad timestamp length", &Header{Len: 28, Options: []byte{0x44, 0x08, 0x05, 0x01, 0x00, 0x00, 0x00, 0x00}}},
+	} {
+		if _, err := tt.h.ParseOptions(); err == nil {
+			t.Errorf("%s: got nil error", tt.name)
+		}
+	}
+}
+
+func TestMarshalNilOption(t *testing.T) {
+	for _, o := range []Option{(*RouteOption)(nil), (*TimestampOption)(nil), (*RawOption)(nil)} {
+		if _, err := o.Marshal(); err != errInvalidOption {
+			t.Errorf("%T.Marshal() = %v; want %v", o, err, errInvalidOption)
+		}
+		var h Header
+		if err := h.SetOptions(o); err != errInvalidOption {
+			t.Errorf("SetOptions(%T) = %v; want %v", o, err, errInvalidOption)
+		}
+	}
+	var h Header
+	if err := h.SetOptions(nil); err != errInvalidOption {
+		t.Errorf("SetOptions(nil) = %v; want %v", err, errInvalidOption)
+	}
+}
+
+func TestSetOptionsTooLong(t *testing.T) {
+	var h Header
+	err := h.SetOptions(
+		&RouteOption{Type: OptionRecordRoute, Pointer: 4, Addrs: make([]net.IP, 9)},
+		&RawOption{Type: 148, Data: []byte{0, 0}},
+	)
+	if err == nil {
+		t.Fatal("got nil error")
+	}
+}
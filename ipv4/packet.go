@@ -25,6 +25,11 @@ func (c *packetHandler) ok() bool { return c != nil && c.IPConn != nil && c.Conn
 // ReadFrom reads an IPv4 datagram from the endpoint c, copying the
 // datagram into b. It returns the received datagram as the IPv4
 // header h, the payload p and the control message cm.
+//
+// If some control messages did not fit in the buffer provided for
+// them, ReadFrom returns the datagram together with what could be
+// parsed of cm, with its Truncated field set, and an error wrapping
+// ErrControlMessageTruncated.
 func (c *packetHandler) ReadFrom(b []byte) (h *Header, p []byte, cm *ControlMessage, err error) {
 	if !c.ok() {
 		return nil, nil, nil, errInvalidConn
@@ -32,7 +37,7 @@ func (c *packetHandler) ReadFrom(b []byte) (h *Header, p []byte, cm *ControlMess
 	c.rawOpt.RLock()
 	m := socket.Message{
 		Buffers: [][]byte{b},
-		OOB:     NewControlMessage(c.rawOpt.cflags),
+		OOB:     c.rawOpt.newControlMessage(),
 	}
 	c.rawOpt.RUnlock()
 	if err := c.RecvMsg(&m, 0); err != nil {
@@ -50,13 +55,22 @@ func (c *packetHandler) ReadFrom(b []byte) (h *Header, p []byte, cm *ControlMess
 			adjustFreeBSD32(&m)
 		}
 		cm = new(ControlMessage)
-		if err := cm.Parse(m.OOB[:m.NN]); err != nil {
+		if err := cm.Parse(m.OOB[:m.NN]); err != nil && !m.ControlTruncated() {
 			return nil, nil, nil, &net.OpError{Op: "read", Net: c.IPConn.LocalAddr().Network(), Source: c.IPConn.LocalAddr(), Err: err}
 		}
 	}
+	if m.ControlTruncated() {
+		if cm == nil {
+			cm = new(ControlMessage)
+		}
+		cm.Truncated = true
+	}
 	if src, ok := m.Addr.(*net.IPAddr); ok && cm != nil {
 		cm.Src = src.IP
 	}
+	if m.ControlTruncated() {
+		err = &net.OpError{Op: "read", Net: c.IPConn.LocalAddr().Network(), Source: c.IPConn.LocalAddr(), Err: ErrControlMessageTruncated}
+	}
 	return
 }
 
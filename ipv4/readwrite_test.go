@@ -6,6 +6,7 @@ package ipv4_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net"
 	"runtime"
@@ -265,7 +266,9 @@ func TestPacketConnConcurrentReadWriteUnicastUDP(t *testing.T) {
 	reader := func() {
 		defer wg.Done()
 		rb := make([]byte, 128)
-		if n, cm, _, err := p.ReadFrom(rb); err != nil {
+		// The control flags set concurrently may not leave room
+		// for all the control messages.
+		if n, cm, _, err := p.ReadFrom(rb); err != nil && !errors.Is(err, ipv4.ErrControlMessageTruncated) {
 			fatalf("%v", err)
 		} else if !bytes.Equal(rb[:n], wb) {
 			fatalf("got %v; want %v", rb[:n], wb)
@@ -322,6 +325,78 @@ func TestPacketConnConcurrentReadWriteUnicastUDP(t *testing.T) {
 	wg.Wait()
 }
 
+func TestPacketConnReadControlMessageTruncated(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+
+	c, err := nettest.NewLocalPacketListener("udp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	defer p.Close()
+
+	dst := c.LocalAddr()
+	cf := ipv4.FlagTTL | ipv4.FlagDst | ipv4.FlagInterface
+	if err := p.SetControlMessage(cf, true); err != nil {
+		t.Fatal(err)
+	}
+	wb, rb := []byte("HELLO-R-U-THERE"), make([]byte, 128)
+
+	// Linux delivers the packet information before the TTL, so
+	// only the former fits in a 32-byte buffer.
+	if err := p.SetControlMessageBufferSize(32); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.WriteTo(wb, nil, dst); err != nil {
+		t.Fatal(err)
+	}
+	n, cm, _, err := p.ReadFrom(rb)
+	if !errors.Is(err, ipv4.ErrControlMessageTruncated) {
+		t.Fatalf("got error %v; want %v", err, ipv4.ErrControlMessageTruncated)
+	}
+	if cm == nil || !cm.Truncated || !strings.Contains(cm.String(), "truncated=true") {
+		t.Errorf("got control message %v; want it reported as truncated", cm)
+	}
+	if !bytes.Equal(rb[:n], wb) {
+		t.Errorf("got %v; want %v", rb[:n], wb)
+	}
+	if cm == nil || !cm.Dst.Equal(net.IPv4(127, 0, 0, 1)) || cm.TTL != 0 {
+		t.Errorf("got control message %v; want only the packet information", cm)
+	}
+
+	if err := p.SetControlMessageBufferSize(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.WriteTo(wb, nil, dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, cm, _, err = p.ReadFrom(rb); err != nil {
+		t.Fatal(err)
+	}
+	if cm == nil || cm.Dst == nil || cm.TTL == 0 || cm.Truncated {
+		t.Errorf("got control message %v; want all requested options", cm)
+	}
+
+	// ReadBatch reports the truncation through the message flags
+	// rather than failing.
+	if _, err := p.WriteTo(wb, nil, dst); err != nil {
+		t.Fatal(err)
+	}
+	ms := []ipv4.Message{{Buffers: [][]byte{rb}, OOB: make([]byte, 32)}}
+	if _, err := p.ReadBatch(ms, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rb[:ms[0].N], wb) {
+		t.Errorf("got %v; want %v", rb[:ms[0].N], wb)
+	}
+	if !ms[0].ControlTruncated() {
+		t.Error("got untruncated control messages; want them truncated")
+	}
+}
+
 func TestPacketConnConcurrentReadWriteUnicast(t *testing.T) {
 	switch runtime.GOOS {
 	case "fuchsia", "hurd", "js", "nacl", "plan9", "windows":
@@ -416,7 +491,9 @@ func testPacketConnConcurrentReadWriteUnicast(t *testing.T, p *ipv4.PacketConn,
 		defer wg.Done()
 		b := make([]byte, 128)
 		n, cm, _, err := p.ReadFrom(b)
-		if err != nil {
+		// The control flags set concurrently may not leave room
+		// for all the control messages.
+		if err != nil && !errors.Is(err, ipv4.ErrControlMessageTruncated) {
 			fatalf("%v", err)
 		}
 		if !bytes.Equal(b[:n], data) {
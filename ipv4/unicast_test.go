@@ -262,3 +262,150 @@ func TestRawConnReadWriteUnicastICMP(t *testing.T) {
 		}
 	}
 }
+
+func TestPacketConnReadTTL(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+	if _, err := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagLoopback); err != nil {
+		t.Skipf("not available on %s", runtime.GOOS)
+	}
+
+	c, err := nettest.NewLocalPacketListener("udp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	defer p.Close()
+
+	if err := p.SetControlMessage(ipv4.FlagTTL, true); err != nil {
+		t.Fatal(err)
+	}
+	wb := []byte("HELLO-R-U-THERE")
+	rb := make([]byte, 128)
+	for _, ttl := range []int{1, 42, 255} {
+		if err := p.SetTTL(ttl); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.WriteTo(wb, nil, c.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		_, cm, _, err := p.ReadFrom(rb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cm == nil || cm.TTL != ttl {
+			t.Fatalf("got control message %v; want TTL %d", cm, ttl)
+		}
+	}
+}
+
+func TestPacketConnHeaderPrepend(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+	if !nettest.SupportsRawSocket() {
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	if _, err := nettest.RoutedInterface("ip4", net.FlagUp|net.FlagLoopback); err != nil {
+		t.Skipf("not available on %s", runtime.GOOS)
+	}
+
+	const proto = 253 // experimentation and testing, see RFC 3692
+	c, err := net.ListenPacket("ip4:253", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	rc, err := net.ListenPacket("ip4:253", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	r, err := ipv4.NewRawConn(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lo := net.IPv4(127, 0, 0, 1)
+	dst := &net.IPAddr{IP: lo}
+	rb := make([]byte, 128)
+	read := func(payload []byte) *ipv4.Header {
+		t.Helper()
+		if err := r.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			h, b, _, err := r.ReadFrom(rb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(b, payload) {
+				return h
+			}
+		}
+	}
+
+	for _, on := range []bool{true, false} {
+		if err := p.SetHeaderPrepend(on); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := p.HeaderPrepend(); err != nil {
+			t.Fatal(err)
+		} else if v != on {
+			t.Fatalf("got %v; want %v", v, on)
+		}
+	}
+
+	// With the option enabled the crafted header is sent as is,
+	// apart from the header checksum filled in by the kernel.
+	if err := p.SetHeaderPrepend(true); err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte("HELLO-R-U-THERE-PREPENDED")
+	wh := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(payload),
+		ID:       0x1234,
+		TTL:      42,
+		Protocol: proto,
+		Src:      lo,
+		Dst:      lo,
+	}
+	wb, err := wh.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.WriteTo(append(wb, payload...), nil, dst); err != nil {
+		t.Fatal(err)
+	}
+	h := read(payload)
+	if h.TTL != 42 || h.ID != 0x1234 || h.Checksum == 0 {
+		t.Fatalf("got %v; want ttl=42 id=0x1234 and a header checksum", h)
+	}
+
+	// With the option disabled the kernel builds the header.
+	if err := p.SetHeaderPrepend(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetTTL(7); err != nil {
+		t.Fatal(err)
+	}
+	payload = []byte("HELLO-R-U-THERE-PLAIN")
+	if _, err := p.WriteTo(payload, nil, dst); err != nil {
+		t.Fatal(err)
+	}
+	if h := read(payload); h.TTL != 7 {
+		t.Fatalf("got %v; want ttl=7", h)
+	}
+}
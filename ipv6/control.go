@@ -21,12 +21,22 @@ import (
 type rawOpt struct {
 	sync.RWMutex
 	cflags ControlFlags
+	oobLen int // size of the control message buffer, or 0 for automatic sizing
 }
 
 func (c *rawOpt) set(f ControlFlags)        { c.cflags |= f }
 func (c *rawOpt) clear(f ControlFlags)      { c.cflags &^= f }
 func (c *rawOpt) isset(f ControlFlags) bool { return c.cflags&f != 0 }
 
+// newControlMessage returns a buffer for the control messages of a
+// received packet. The caller must hold at least a read lock on c.
+func (c *rawOpt) newControlMessage() []byte {
+	if c.oobLen > 0 {
+		return make([]byte, c.oobLen)
+	}
+	return NewControlMessage(c.cflags)
+}
+
 // A ControlFlags represents per packet basis IP-level socket option
 // control flags.
 type ControlFlags uint
@@ -60,13 +70,18 @@ type ControlMessage struct {
 	IfIndex      int    // interface index, must be 1 <= value when specifying
 	NextHop      net.IP // next hop address, specifying only
 	MTU          int    // path MTU, receiving only
+
+	// Truncated reports whether some of the received options were
+	// discarded because they did not fit in the control message
+	// buffer. See SetControlMessageBufferSize.
+	Truncated bool
 }
 
 func (cm *ControlMessage) String() string {
 	if cm == nil {
 		return "<nil>"
 	}
-	return fmt.Sprintf("tclass=%#x hoplim=%d src=%v dst=%v ifindex=%d nexthop=%v mtu=%d", cm.TrafficClass, cm.HopLimit, cm.Src, cm.Dst, cm.IfIndex, cm.NextHop, cm.MTU)
+	return fmt.Sprintf("tclass=%#x hoplim=%d src=%v dst=%v ifindex=%d nexthop=%v mtu=%d truncated=%t", cm.TrafficClass, cm.HopLimit, cm.Src, cm.Dst, cm.IfIndex, cm.NextHop, cm.MTU, cm.Truncated)
 }
 
 // Marshal returns the binary encoding of cm.
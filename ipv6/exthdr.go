@@ -0,0 +1,162 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv6
+
+import (
+	"errors"
+	"net"
+)
+
+var (
+	errInvalidExtHeader = errors.New("invalid extension header")
+	errExtHeaderTooLong = errors.New("extension header too long")
+	errInvalidExtOption = errors.New("invalid extension header option")
+)
+
+// Padding options, see RFC 8200.
+const (
+	OptionPad1 = 0 // single octet of padding
+	OptionPadN = 1 // two or more octets of padding
+)
+
+// An Option represents a type-length-value encoded option carried in
+// a Hop-by-Hop Options or Destination Options header.
+type Option struct {
+	Type int    // option type
+	Data []byte // option data, excluding the type and length octets
+
+	// Align and AlignOffset hold the alignment requirement of the
+	// option, written xn+y in RFC 8200: the option type octet is
+	// placed AlignOffset octets past a multiple of Align octets from
+	// the start of the header. An Align of 0 or 1 means the option
+	// has no alignment requirement. They are not set by parsing.
+	Align       int
+	AlignOffset int
+}
+
+// An OptionsHeader represents a Hop-by-Hop Options or Destination
+// Options extension header.
+type OptionsHeader struct {
+	NextHeader int      // next header
+	Options    []Option // options, excluding padding
+}
+
+// Marshal returns the binary encoding of h.
+//
+// Pad1 and PadN options are inserted as needed to satisfy the
+// alignment requirement of each option and to make the header length
+// a multiple of 8 octets.
+func (h *OptionsHeader) Marshal() ([]byte, error) {
+	if h == nil {
+		return nil, errNilHeader
+	}
+	b := []byte{byte(h.NextHeader), 0}
+	for _, o := range h.Options {
+		if o.Type <= OptionPadN || o.Type > 0xff || len(o.Data) > 0xff {
+			return nil, errInvalidExtOption
+		}
+		if o.Align > 1 {
+			if o.Align > 8 || o.Align&(o.Align-1) != 0 || o.AlignOffset < 0 || o.AlignOffset >= o.Align {
+				return nil, errInvalidExtOption
+			}
+			b = appendPadding(b, ((o.AlignOffset-len(b))%o.Align+o.Align)%o.Align)
+		}
+		b = append(b, byte(o.Type), byte(len(o.Data)))
+		b = append(b, o.Data...)
+	}
+	b = appendPadding(b, (8-len(b)%8)%8)
+	if len(b)/8-1 > 0xff {
+		return nil, errExtHeaderTooLong
+	}
+	b[1] = byte(len(b)/8 - 1)
+	return b, nil
+}
+
+// appendPadding appends n octets of padding options to b.
+func appendPadding(b []byte, n int) []byte {
+	switch n {
+	case 0:
+	case 1:
+		b = append(b, OptionPad1)
+	default:
+		b = append(b, OptionPadN, byte(n-2))
+		b = append(b, make([]byte, n-2)...)
+	}
+	return b
+}
+
+// ParseOptionsHeader parses b as a Hop-by-Hop Options or Destination
+// Options extension header. Padding options are skipped.
+func ParseOptionsHeader(b []byte) (*OptionsHeader, error) {
+	if len(b) < 8 {
+		return nil, errHeaderTooShort
+	}
+	l := (int(b[1]) + 1) * 8
+	if len(b) < l {
+		return nil, errHeaderTooShort
+	}
+	h := &OptionsHeader{NextHeader: int(b[0])}
+	for p := b[2:l]; len(p) > 0; {
+		if p[0] == OptionPad1 {
+			p = p[1:]
+			continue
+		}
+		if len(p) < 2 || 2+int(p[1]) > len(p) {
+			return nil, errInvalidExtOption
+		}
+		n := 2 + int(p[1])
+		if p[0] != OptionPadN {
+			h.Options = append(h.Options, Option{Type: int(p[0]), Data: append([]byte(nil), p[2:n]...)})
+		}
+		p = p[n:]
+	}
+	return h, nil
+}
+
+// routingType2HeaderLen is the length of a type 2 routing header.
+const routingType2HeaderLen = 8 + net.IPv6len
+
+// A RoutingType2Header represents a type 2 routing header, which
+// carries the home address of a mobile node as specified in RFC 6275.
+type RoutingType2Header struct {
+	NextHeader   int    // next header
+	SegmentsLeft int    // segments left; 1 when sent by a correspondent node
+	HomeAddr     net.IP // home address of the mobile node
+}
+
+// Marshal returns the binary encoding of h.
+func (h *RoutingType2Header) Marshal() ([]byte, error) {
+	if h == nil {
+		return nil, errNilHeader
+	}
+	ip := h.HomeAddr.To16()
+	if ip == nil || h.SegmentsLeft < 0 || h.SegmentsLeft > 0xff {
+		return nil, errInvalidExtHeader
+	}
+	b := make([]byte, routingType2HeaderLen)
+	b[0] = byte(h.NextHeader)
+	b[1] = routingType2HeaderLen/8 - 1
+	b[2] = 2
+	b[3] = byte(h.SegmentsLeft)
+	copy(b[8:], ip)
+	return b, nil
+}
+
+// ParseRoutingType2Header parses b as a type 2 routing header.
+func ParseRoutingType2Header(b []byte) (*RoutingType2Header, error) {
+	if len(b) < routingType2HeaderLen {
+		return nil, errHeaderTooShort
+	}
+	if b[1] != routingType2HeaderLen/8-1 || b[2] != 2 {
+		return nil, errInvalidExtHeader
+	}
+	h := &RoutingType2Header{
+		NextHeader:   int(b[0]),
+		SegmentsLeft: int(b[3]),
+		HomeAddr:     make(net.IP, net.IPv6len),
+	}
+	copy(h.HomeAddr, b[8:routingType2HeaderLen])
+	return h, nil
+}
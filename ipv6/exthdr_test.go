@@ -0,0 +1,158 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ipv6_test
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/internal/iana"
+	"golang.org/x/net/ipv6"
+)
+
+var (
+	testHomeAddr = net.ParseIP("2001:db8::1")
+
+	// Destination options header carrying a home address option,
+	// aligned 8n+6, followed by an experimental option.
+	wireDestinationOptions = []byte{
+		iana.ProtocolIPv6ICMP, 0x03,
+		0x01, 0x02, 0x00, 0x00, // PadN
+		0xc9, 0x10, // home address
+		0x20, 0x01, 0x0d, 0xb8,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x01,
+		0x1e, 0x01, 0xff, // experimental
+		0x01, 0x03, 0x00, 0x00, 0x00, // PadN
+	}
+
+	testDestinationOptions = &ipv6.OptionsHeader{
+		NextHeader: iana.ProtocolIPv6ICMP,
+		Options: []ipv6.Option{
+			{Type: 0xc9, Data: testHomeAddr, Align: 8, AlignOffset: 6},
+			{Type: 0x1e, Data: []byte{0xff}},
+		},
+	}
+
+	wireRoutingType2 = []byte{
+		iana.ProtocolIPv6ICMP, 0x02, 0x02, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x20, 0x01, 0x0d, 0xb8,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x01,
+	}
+)
+
+func TestOptionsHeader(t *testing.T) {
+	b, err := testDestinationOptions.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, wireDestinationOptions) {
+		t.Fatalf("got %#v; want %#v", b, wireDestinationOptions)
+	}
+
+	h, err := ipv6.ParseOptionsHeader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ipv6.OptionsHeader{
+		NextHeader: iana.ProtocolIPv6ICMP,
+		Options: []ipv6.Option{
+			{Type: 0xc9, Data: []byte(testHomeAddr)},
+			{Type: 0x1e, Data: []byte{0xff}},
+		},
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Fatalf("got %#v; want %#v", h, want)
+	}
+}
+
+func TestOptionsHeaderPadding(t *testing.T) {
+	for _, tt := range []struct {
+		opts []ipv6.Option
+		want []byte
+	}{
+		{nil, []byte{0, 0, 0x01, 0x04, 0, 0, 0, 0}},
+		{[]ipv6.Option{{Type: 0x1e, Data: []byte{1, 2, 3}}}, []byte{0, 0, 0x1e, 0x03, 1, 2, 3, 0x00}},
+		{[]ipv6.Option{{Type: 0x1e, Data: []byte{1, 2}}}, []byte{0, 0, 0x1e, 0x02, 1, 2, 0x01, 0x00}},
+		{[]ipv6.Option{{Type: 0x1e, Align: 4, AlignOffset: 3}}, []byte{0, 0, 0x00, 0x1e, 0x00, 0x01, 0x01, 0x00}},
+	} {
+		b, err := (&ipv6.OptionsHeader{Options: tt.opts}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, tt.want) {
+			t.Errorf("%v: got %#v; want %#v", tt.opts, b, tt.want)
+		}
+		if _, err := ipv6.ParseOptionsHeader(b); err != nil {
+			t.Errorf("%v: %v", tt.opts, err)
+		}
+	}
+}
+
+func TestOptionsHeaderErrors(t *testing.T) {
+	for _, o := range []ipv6.Option{
+		{Type: ipv6.OptionPad1},
+		{Type: ipv6.OptionPadN},
+		{Type: 0x100},
+		{Type: 0x1e, Data: make([]byte, 0x100)},
+		{Type: 0x1e, Align: 3},
+		{Type: 0x1e, Align: 16},
+		{Type: 0x1e, Align: 8, AlignOffset: 8},
+	} {
+		if _, err := (&ipv6.OptionsHeader{Options: []ipv6.Option{o}}).Marshal(); err == nil {
+			t.Errorf("%v: got nil error", o)
+		}
+	}
+	for _, b := range [][]byte{
+		{0, 0, 0x01, 0x04, 0, 0, 0},
+		{0, 1, 0x01, 0x04, 0, 0, 0, 0},
+		{0, 0, 0x1e, 0x05, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0x1e},
+	} {
+		if _, err := ipv6.ParseOptionsHeader(b); err == nil {
+			t.Errorf("%#v: got nil error", b)
+		}
+	}
+}
+
+func TestRoutingType2Header(t *testing.T) {
+	h := &ipv6.RoutingType2Header{
+		NextHeader:   iana.ProtocolIPv6ICMP,
+		SegmentsLeft: 1,
+		HomeAddr:     testHomeAddr,
+	}
+	b, err := h.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, wireRoutingType2) {
+		t.Fatalf("got %#v; want %#v", b, wireRoutingType2)
+	}
+	nh, err := ipv6.ParseRoutingType2Header(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nh, h) {
+		t.Fatalf("got %#v; want %#v", nh, h)
+	}
+
+	if _, err := (&ipv6.RoutingType2Header{}).Marshal(); err == nil {
+		t.Error("missing home address: got nil error")
+	}
+	bad := append([]byte(nil), wireRoutingType2...)
+	bad[2] = 0 // type 0
+	if _, err := ipv6.ParseRoutingType2Header(bad); err == nil {
+		t.Error("wrong routing type: got nil error")
+	}
+	if _, err := ipv6.ParseRoutingType2Header(wireRoutingType2[:16]); err == nil {
+		t.Error("short header: got nil error")
+	}
+}
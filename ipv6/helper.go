@@ -10,10 +10,16 @@ import (
 	"runtime"
 )
 
+// ErrControlMessageTruncated is wrapped by the error returned from
+// ReadFrom when the control messages of a received packet did not fit
+// in the buffer provided for them. See SetControlMessageBufferSize.
+var ErrControlMessageTruncated = errors.New("control message truncated")
+
 var (
 	errInvalidConn     = errors.New("invalid connection")
 	errMissingAddress  = errors.New("missing address")
 	errHeaderTooShort  = errors.New("header too short")
+	errNilHeader       = errors.New("nil header")
 	errInvalidConnType = errors.New("invalid conn type")
 	errNotImplemented  = errors.New("not implemented on " + runtime.GOOS + "/" + runtime.GOARCH)
 )
@@ -17,6 +17,11 @@ import (
 // endpoint c, copying the payload into b. It returns the number of
 // bytes copied into b, the control message cm and the source address
 // src of the received datagram.
+//
+// If some control messages did not fit in the buffer provided for
+// them, ReadFrom returns the datagram together with what could be
+// parsed of cm, with its Truncated field set, and an error wrapping
+// ErrControlMessageTruncated.
 func (c *payloadHandler) ReadFrom(b []byte) (n int, cm *ControlMessage, src net.Addr, err error) {
 	if !c.ok() {
 		return 0, nil, nil, errInvalidConn
@@ -24,7 +29,7 @@ func (c *payloadHandler) ReadFrom(b []byte) (n int, cm *ControlMessage, src net.
 	c.rawOpt.RLock()
 	m := socket.Message{
 		Buffers: [][]byte{b},
-		OOB:     NewControlMessage(c.rawOpt.cflags),
+		OOB:     c.rawOpt.newControlMessage(),
 	}
 	c.rawOpt.RUnlock()
 	switch c.PacketConn.(type) {
@@ -41,11 +46,18 @@ func (c *payloadHandler) ReadFrom(b []byte) (n int, cm *ControlMessage, src net.
 	}
 	if m.NN > 0 {
 		cm = new(ControlMessage)
-		if err := cm.Parse(m.OOB[:m.NN]); err != nil {
+		if err := cm.Parse(m.OOB[:m.NN]); err != nil && !m.ControlTruncated() {
 			return 0, nil, nil, &net.OpError{Op: "read", Net: c.PacketConn.LocalAddr().Network(), Source: c.PacketConn.LocalAddr(), Err: err}
 		}
 		cm.Src = netAddrToIP16(m.Addr)
 	}
+	if m.ControlTruncated() {
+		if cm == nil {
+			cm = new(ControlMessage)
+		}
+		cm.Truncated = true
+		return m.N, cm, m.Addr, &net.OpError{Op: "read", Net: c.PacketConn.LocalAddr().Network(), Source: c.PacketConn.LocalAddr(), Err: ErrControlMessageTruncated}
+	}
 	return m.N, cm, m.Addr, nil
 }
 
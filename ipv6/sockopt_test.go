@@ -5,10 +5,12 @@
 package ipv6_test
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"runtime"
 	"testing"
+	"time"
 
 	"golang.org/x/net/internal/iana"
 	"golang.org/x/net/ipv6"
@@ -119,3 +121,76 @@ func TestPacketConnChecksum(t *testing.T) {
 		}
 	}
 }
+
+func TestPacketConnChecksumTransmit(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+	if !nettest.SupportsIPv6() {
+		t.Skip("ipv6 is not supported")
+	}
+	if !nettest.SupportsRawSocket() {
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	const proto = 253 // experimentation and testing, see RFC 3692
+	c, err := net.ListenPacket(fmt.Sprintf("ip6:%d", proto), "::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	rc, err := net.ListenPacket(fmt.Sprintf("ip6:%d", proto), "::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	p := ipv6.NewPacketConn(c)
+	const offset = 2
+	if err := p.SetChecksum(true, offset); err != nil {
+		t.Fatal(err)
+	}
+
+	// The checksum field of the crafted packet is left zero for the
+	// kernel to fill in.
+	wb := []byte{0xca, 0xfe, 0x00, 0x00, 'H', 'E', 'L', 'L', 'O'}
+	if _, err := p.WriteTo(wb, nil, &net.IPAddr{IP: net.IPv6loopback}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	rb := make([]byte, 128)
+	n, _, err := rc.ReadFrom(rb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb = rb[:n]
+	if n != len(wb) || !bytes.Equal(rb[:offset], wb[:offset]) || !bytes.Equal(rb[offset+2:], wb[offset+2:]) {
+		t.Fatalf("got %#v; want %#v with a checksum", rb, wb)
+	}
+
+	// Summing the pseudo-header and the packet, including the
+	// checksum, must yield all ones.
+	var s uint32
+	add := func(b []byte) {
+		for ; len(b) >= 2; b = b[2:] {
+			s += uint32(b[0])<<8 | uint32(b[1])
+		}
+		if len(b) == 1 {
+			s += uint32(b[0]) << 8
+		}
+	}
+	add(net.IPv6loopback)
+	add(net.IPv6loopback)
+	add([]byte{0, 0, 0, byte(len(rb)), 0, 0, 0, proto})
+	add(rb)
+	for s > 0xffff {
+		s = s>>16 + s&0xffff
+	}
+	if s != 0xffff {
+		t.Fatalf("got checksum %#04x; sum over pseudo-header is %#04x, want 0xffff", uint16(rb[offset])<<8|uint16(rb[offset+1]), s)
+	}
+}
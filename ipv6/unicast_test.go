@@ -205,3 +205,46 @@ func TestPacketConnReadWriteUnicastICMP(t *testing.T) {
 		}
 	}
 }
+
+func TestPacketConnReadHopLimit(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+	if !nettest.SupportsIPv6() {
+		t.Skip("ipv6 is not supported")
+	}
+
+	c, err := nettest.NewLocalPacketListener("udp6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p := ipv6.NewPacketConn(c)
+	defer p.Close()
+
+	if err := p.SetControlMessage(ipv6.FlagHopLimit, true); err != nil {
+		t.Fatal(err)
+	}
+	wb := []byte("HELLO-R-U-THERE")
+	rb := make([]byte, 128)
+	for _, hoplim := range []int{1, 42, 255} {
+		if err := p.SetHopLimit(hoplim); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.WriteTo(wb, nil, c.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		_, cm, _, err := p.ReadFrom(rb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cm == nil || cm.HopLimit != hoplim {
+			t.Fatalf("got control message %v; want hop limit %d", cm, hoplim)
+		}
+	}
+}
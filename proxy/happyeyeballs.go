@@ -0,0 +1,205 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// A Resolver looks up the IP addresses of a host. *net.Resolver
+// implements Resolver.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// defaultFallbackDelay is the delay used when HappyEyeballs.FallbackDelay
+// is zero. It matches that of net.Dialer.
+const defaultFallbackDelay = 300 * time.Millisecond
+
+var errNoSuitableAddress = errors.New("proxy: no suitable address found")
+
+// HappyEyeballs is a Dialer that resolves the host of the address to dial
+// itself, and races connections to its IPv4 and IPv6 addresses through
+// Forward, as described in RFC 8305 ("Happy Eyeballs"). The addresses of
+// the family of the first resolved address are tried first, one at a
+// time. If none has connected after FallbackDelay, the addresses of the
+// other family are tried concurrently. The first connection to succeed is
+// returned and the other attempt is canceled.
+//
+// Addresses whose host is an IP address are dialed directly through
+// Forward.
+type HappyEyeballs struct {
+	// Forward dials the resolved addresses. If nil, Direct is used.
+	Forward ContextDialer
+
+	// Resolver looks up the addresses of a host. If nil,
+	// net.DefaultResolver is used.
+	Resolver Resolver
+
+	// FallbackDelay is how long to wait before trying the addresses of
+	// the other family. If zero, a default delay of 300ms is used.
+	FallbackDelay time.Duration
+}
+
+var (
+	_ Dialer        = (*HappyEyeballs)(nil)
+	_ ContextDialer = (*HappyEyeballs)(nil)
+)
+
+// Dial connects to the address addr on the given network through Forward.
+func (h *HappyEyeballs) Dial(network, addr string) (net.Conn, error) {
+	return h.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to the address addr on the given network through
+// Forward, racing the IPv4 and IPv6 addresses of its host.
+func (h *HappyEyeballs) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return h.forward().DialContext(ctx, network, addr)
+	}
+	var r Resolver = net.DefaultResolver
+	if h.Resolver != nil {
+		r = h.Resolver
+	}
+	ips, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var primaries, fallbacks []net.IPAddr
+	for _, ip := range ips {
+		is4 := ip.IP.To4() != nil
+		if (network == "tcp4" && !is4) || (network == "tcp6" && is4) {
+			continue
+		}
+		if len(primaries) == 0 || (primaries[0].IP.To4() != nil) == is4 {
+			primaries = append(primaries, ip)
+		} else {
+			fallbacks = append(fallbacks, ip)
+		}
+	}
+	if len(primaries) == 0 {
+		return nil, errNoSuitableAddress
+	}
+	return h.dialParallel(ctx, network, port, primaries, fallbacks)
+}
+
+func (h *HappyEyeballs) forward() ContextDialer {
+	if h.Forward != nil {
+		return h.Forward
+	}
+	return Direct
+}
+
+// dialParallel races two copies of dialSerial, giving the first a head
+// start. It returns the first established connection and closes the
+// others. Otherwise it returns an error from the first primary address.
+func (h *HappyEyeballs) dialParallel(ctx context.Context, network, port string, primaries, fallbacks []net.IPAddr) (net.Conn, error) {
+	if len(fallbacks) == 0 {
+		return h.dialSerial(ctx, network, port, primaries)
+	}
+
+	returned := make(chan struct{})
+	defer close(returned)
+
+	type dialResult struct {
+		net.Conn
+		error
+		primary bool
+		done    bool
+	}
+	results := make(chan dialResult) // unbuffered
+
+	startRacer := func(ctx context.Context, primary bool) {
+		ips := primaries
+		if !primary {
+			ips = fallbacks
+		}
+		c, err := h.dialSerial(ctx, network, port, ips)
+		select {
+		case results <- dialResult{Conn: c, error: err, primary: primary, done: true}:
+		case <-returned:
+			if c != nil {
+				c.Close()
+			}
+		}
+	}
+
+	var primary, fallback dialResult
+
+	// Start the main racer.
+	primaryCtx, primaryCancel := context.WithCancel(ctx)
+	defer primaryCancel()
+	go startRacer(primaryCtx, true)
+
+	// Start the timer for the fallback racer.
+	delay := h.FallbackDelay
+	if delay <= 0 {
+		delay = defaultFallbackDelay
+	}
+	fallbackTimer := time.NewTimer(delay)
+	defer fallbackTimer.Stop()
+
+	for {
+		select {
+		case <-fallbackTimer.C:
+			fallbackCtx, fallbackCancel := context.WithCancel(ctx)
+			defer fallbackCancel()
+			go startRacer(fallbackCtx, false)
+
+		case res := <-results:
+			if res.error == nil {
+				return res.Conn, nil
+			}
+			if res.primary {
+				primary = res
+			} else {
+				fallback = res
+			}
+			if primary.done && fallback.done {
+				return nil, primary.error
+			}
+			if res.primary && fallbackTimer.Stop() {
+				// If we were able to stop the timer, that means it
+				// was running (hadn't yet started the fallback), but
+				// we just got an error on the primary path, so start
+				// the fallback immediately (in 0 nanoseconds).
+				fallbackTimer.Reset(0)
+			}
+		}
+	}
+}
+
+// dialSerial connects to a list of addresses in sequence, returning
+// either the first successful connection, or the first error.
+func (h *HappyEyeballs) dialSerial(ctx context.Context, network, port string, ips []net.IPAddr) (net.Conn, error) {
+	var firstErr error
+	for _, ip := range ips {
+		if err := ctx.Err(); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			break
+		}
+		host := ip.IP.String()
+		if ip.Zone != "" {
+			host += "%" + ip.Zone
+		}
+		c, err := h.forward().DialContext(ctx, network, net.JoinHostPort(host, port))
+		if err == nil {
+			return c, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
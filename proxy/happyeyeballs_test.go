@@ -0,0 +1,156 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubResolver map[string][]net.IPAddr
+
+func (r stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return ips, nil
+}
+
+// stubConn is a net.Conn that only reports the address it was dialed to.
+type stubConn struct {
+	net.Conn
+	addr string
+}
+
+func (c *stubConn) Close() error { return nil }
+
+// stubDialer connects immediately to the addresses in fast, fails to
+// connect to those in fail, and blocks dialing any other address until
+// its context is done.
+type stubDialer struct {
+	fast, fail map[string]bool
+
+	mu       sync.Mutex
+	dialed   []string
+	canceled []string
+}
+
+func (d *stubDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	d.dialed = append(d.dialed, addr)
+	d.mu.Unlock()
+	switch {
+	case d.fast[addr]:
+		return &stubConn{addr: addr}, nil
+	case d.fail[addr]:
+		return nil, errors.New("connection refused: " + addr)
+	}
+	<-ctx.Done()
+	d.mu.Lock()
+	d.canceled = append(d.canceled, addr)
+	d.mu.Unlock()
+	return nil, ctx.Err()
+}
+
+func TestHappyEyeballs(t *testing.T) {
+	r := stubResolver{
+		"dual.example": {
+			{IP: net.ParseIP("2001:db8::1")},
+			{IP: net.ParseIP("2001:db8::2")},
+			{IP: net.ParseIP("192.0.2.1")},
+		},
+		"v4.example": {
+			{IP: net.ParseIP("192.0.2.2")},
+		},
+	}
+
+	t.Run("SlowIPv6", func(t *testing.T) {
+		d := &stubDialer{fast: map[string]bool{"192.0.2.1:80": true}}
+		h := &HappyEyeballs{Forward: d, Resolver: r, FallbackDelay: 10 * time.Millisecond}
+		c, err := h.DialContext(context.Background(), "tcp", "dual.example:80")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c.(*stubConn).addr, "192.0.2.1:80"; got != want {
+			t.Errorf("connected to %s, want %s", got, want)
+		}
+		// The slow IPv6 attempt is canceled once IPv4 wins.
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+			d.mu.Lock()
+			canceled := append([]string(nil), d.canceled...)
+			d.mu.Unlock()
+			if len(canceled) == 1 && canceled[0] == "[2001:db8::1]:80" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("canceled dials %q, want [[2001:db8::1]:80]", canceled)
+			}
+		}
+	})
+
+	t.Run("FailedIPv6", func(t *testing.T) {
+		// A failure on the primary family starts the fallback at once.
+		d := &stubDialer{
+			fast: map[string]bool{"192.0.2.1:80": true},
+			fail: map[string]bool{"[2001:db8::1]:80": true, "[2001:db8::2]:80": true},
+		}
+		h := &HappyEyeballs{Forward: d, Resolver: r, FallbackDelay: time.Hour}
+		c, err := h.DialContext(context.Background(), "tcp", "dual.example:80")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c.(*stubConn).addr, "192.0.2.1:80"; got != want {
+			t.Errorf("connected to %s, want %s", got, want)
+		}
+	})
+
+	t.Run("FastIPv6", func(t *testing.T) {
+		d := &stubDialer{fast: map[string]bool{"[2001:db8::1]:80": true}}
+		h := &HappyEyeballs{Forward: d, Resolver: r, FallbackDelay: time.Hour}
+		c, err := h.DialContext(context.Background(), "tcp", "dual.example:80")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c.(*stubConn).addr, "[2001:db8::1]:80"; got != want {
+			t.Errorf("connected to %s, want %s", got, want)
+		}
+	})
+
+	t.Run("AllFail", func(t *testing.T) {
+		d := &stubDialer{fail: map[string]bool{"[2001:db8::1]:80": true, "[2001:db8::2]:80": true, "192.0.2.1:80": true}}
+		h := &HappyEyeballs{Forward: d, Resolver: r, FallbackDelay: time.Millisecond}
+		_, err := h.DialContext(context.Background(), "tcp", "dual.example:80")
+		if err == nil || err.Error() != "connection refused: [2001:db8::1]:80" {
+			t.Errorf("got error %v, want the error of the first IPv6 address", err)
+		}
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		d := &stubDialer{fast: map[string]bool{"192.0.2.1:80": true}}
+		h := &HappyEyeballs{Forward: d, Resolver: r}
+		if _, err := h.DialContext(context.Background(), "tcp4", "dual.example:80"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.DialContext(context.Background(), "tcp6", "v4.example:80"); err != errNoSuitableAddress {
+			t.Errorf("got error %v, want %v", err, errNoSuitableAddress)
+		}
+		if len(d.dialed) != 1 || d.dialed[0] != "192.0.2.1:80" {
+			t.Errorf("dialed %q, want [192.0.2.1:80]", d.dialed)
+		}
+	})
+
+	t.Run("IPLiteral", func(t *testing.T) {
+		d := &stubDialer{fast: map[string]bool{"[2001:db8::3]:80": true}}
+		h := &HappyEyeballs{Forward: d, Resolver: r}
+		if _, err := h.DialContext(context.Background(), "tcp", "[2001:db8::3]:80"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -15,10 +15,12 @@ import (
 type PerHost struct {
 	def, bypass Dialer
 
+	bypassAll      bool
 	bypassNetworks []*net.IPNet
 	bypassIPs      []net.IP
 	bypassZones    []string
 	bypassHosts    []string
+	bypassPorts    map[string]*PerHost // rules that only apply to a port
 }
 
 // NewPerHost returns a PerHost Dialer that directs connections to either
@@ -34,66 +36,84 @@ func NewPerHost(defaultDialer, bypass Dialer) *PerHost {
 // Dial connects to the address addr on the given network through either
 // defaultDialer or bypass.
 func (p *PerHost) Dial(network, addr string) (c net.Conn, err error) {
-	host, _, err := net.SplitHostPort(addr)
+	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, err
 	}
 
-	return p.dialerForRequest(host).Dial(network, addr)
+	return p.dialerForRequest(host, port).Dial(network, addr)
 }
 
 // DialContext connects to the address addr on the given network through either
 // defaultDialer or bypass.
 func (p *PerHost) DialContext(ctx context.Context, network, addr string) (c net.Conn, err error) {
-	host, _, err := net.SplitHostPort(addr)
+	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, err
 	}
-	d := p.dialerForRequest(host)
+	d := p.dialerForRequest(host, port)
 	if x, ok := d.(ContextDialer); ok {
 		return x.DialContext(ctx, network, addr)
 	}
 	return dialContext(ctx, d, network, addr)
 }
 
-func (p *PerHost) dialerForRequest(host string) Dialer {
+func (p *PerHost) dialerForRequest(host, port string) Dialer {
+	if p.matches(host) {
+		return p.bypass
+	}
+	if pp := p.bypassPorts[port]; pp != nil && pp.matches(host) {
+		return p.bypass
+	}
+	return p.def
+}
+
+// matches reports whether host matches one of the rules of p that are
+// not specific to a port.
+func (p *PerHost) matches(host string) bool {
+	if p.bypassAll {
+		return true
+	}
 	if ip := net.ParseIP(host); ip != nil {
 		for _, net := range p.bypassNetworks {
 			if net.Contains(ip) {
-				return p.bypass
+				return true
 			}
 		}
 		for _, bypassIP := range p.bypassIPs {
 			if bypassIP.Equal(ip) {
-				return p.bypass
+				return true
 			}
 		}
-		return p.def
+		return false
 	}
 
 	for _, zone := range p.bypassZones {
 		if strings.HasSuffix(host, zone) {
-			return p.bypass
+			return true
 		}
 		if host == zone[1:] {
 			// For a zone ".example.com", we match "example.com"
 			// too.
-			return p.bypass
+			return true
 		}
 	}
 	for _, bypassHost := range p.bypassHosts {
 		if bypassHost == host {
-			return p.bypass
+			return true
 		}
 	}
-	return p.def
+	return false
 }
 
 // AddFromString parses a string that contains comma-separated values
 // specifying hosts that should use the bypass proxy. Each value is either an
-// IP address, a CIDR range, a zone (*.example.com) or a host name
-// (localhost). A best effort is made to parse the string and errors are
-// ignored.
+// IP address, a CIDR range, a zone (*.example.com or .example.com), a host
+// name (localhost) or "*", which matches every host. An IP address, zone or
+// host name may be followed by a port (example.com:8080, [::1]:443), in
+// which case it only matches connections to that port. This is the syntax
+// commonly used for the NO_PROXY environment variable. A best effort is
+// made to parse the string and errors are ignored.
 func (p *PerHost) AddFromString(s string) {
 	hosts := strings.Split(s, ",")
 	for _, host := range hosts {
@@ -101,6 +121,10 @@ func (p *PerHost) AddFromString(s string) {
 		if len(host) == 0 {
 			continue
 		}
+		if host == "*" {
+			p.bypassAll = true
+			continue
+		}
 		if strings.Contains(host, "/") {
 			// We assume that it's a CIDR address like 127.0.0.0/8
 			if _, net, err := net.ParseCIDR(host); err == nil {
@@ -112,14 +136,51 @@ func (p *PerHost) AddFromString(s string) {
 			p.AddIP(ip)
 			continue
 		}
+		if h, port, err := net.SplitHostPort(host); err == nil {
+			if h != "" && isPort(port) {
+				p.addPortRule(h, port)
+			}
+			continue
+		}
 		if strings.HasPrefix(host, "*.") {
 			p.AddZone(host[1:])
 			continue
 		}
+		if strings.HasPrefix(host, ".") {
+			p.AddZone(host)
+			continue
+		}
 		p.AddHost(host)
 	}
 }
 
+// addPortRule adds host, which is parsed as by AddFromString, as a rule
+// that only matches connections to port.
+func (p *PerHost) addPortRule(host, port string) {
+	if p.bypassPorts == nil {
+		p.bypassPorts = make(map[string]*PerHost)
+	}
+	pp := p.bypassPorts[port]
+	if pp == nil {
+		pp = new(PerHost)
+		p.bypassPorts[port] = pp
+	}
+	pp.AddFromString(host)
+}
+
+// isPort reports whether s is a decimal port number.
+func isPort(s string) bool {
+	if s == "" || len(s) > 5 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // AddIP specifies an IP address that will use the bypass proxy. Note that
 // this will only take effect if a literal IP address is dialed. A connection
 // to a named host will never match an IP.
@@ -74,3 +74,57 @@ func TestPerHost(t *testing.T) {
 		}
 	})
 }
+
+func TestPerHostFromString(t *testing.T) {
+	for _, tt := range []struct {
+		rules  string
+		def    []string
+		bypass []string
+	}{
+		{
+			rules:  "10.0.0.0/8, 192.168.1.0/24, fd00::/8",
+			def:    []string{"11.0.0.1:80", "192.168.2.1:80", "[fe80::1]:80", "10.example.com:80"},
+			bypass: []string{"10.1.2.3:80", "192.168.1.254:443", "[fd12::1]:80"},
+		},
+		{
+			rules:  "*.example.com, .example.org",
+			def:    []string{"example.net:80", "badexample.com:80", "example.com.evil.net:80"},
+			bypass: []string{"example.com:80", "www.example.com:80", "a.b.example.org:443", "example.org:80"},
+		},
+		{
+			rules:  "internal, example.com.",
+			def:    []string{"www.internal:80", "www.example.com:80"},
+			bypass: []string{"internal:80", "example.com:80"},
+		},
+		{
+			rules:  "example.com:8080, [::1]:443, 127.0.0.1:22, *.example.org:443",
+			def:    []string{"example.com:80", "[::1]:80", "127.0.0.1:80", "www.example.org:80", "example.net:8080"},
+			bypass: []string{"example.com:8080", "[::1]:443", "127.0.0.1:22", "www.example.org:443"},
+		},
+		{
+			rules:  "example.com, example.com:8080, example.net:bogus, :80, 10.0.0.0/8:22",
+			def:    []string{"example.net:80", "example.org:80", "10.0.0.1:22"},
+			bypass: []string{"example.com:80", "example.com:8080"},
+		},
+		{
+			rules:  "*",
+			bypass: []string{"example.com:80", "10.0.0.1:22", "[::1]:443"},
+		},
+	} {
+		var def, bypass recordingProxy
+		perHost := NewPerHost(&def, &bypass)
+		perHost.AddFromString(tt.rules)
+		for _, addr := range tt.def {
+			perHost.Dial("tcp", addr)
+		}
+		for _, addr := range tt.bypass {
+			perHost.Dial("tcp", addr)
+		}
+		if !reflect.DeepEqual(tt.def, def.addrs) {
+			t.Errorf("%q: hosts which went to the default proxy didn't match. Got %v, want %v", tt.rules, def.addrs, tt.def)
+		}
+		if !reflect.DeepEqual(tt.bypass, bypass.addrs) {
+			t.Errorf("%q: hosts which went to the bypass proxy didn't match. Got %v, want %v", tt.rules, bypass.addrs, tt.bypass)
+		}
+	}
+}
@@ -45,13 +45,16 @@
 // https://publicsuffix.org/
 package publicsuffix // import "golang.org/x/net/publicsuffix"
 
-// TODO: specify case sensitivity and leading/trailing dot behavior for
-// func PublicSuffix and func EffectiveTLDPlusOne.
+// TODO: specify leading/trailing dot behavior for func PublicSuffix and func
+// EffectiveTLDPlusOne.
 
 import (
+	"errors"
 	"fmt"
 	"net/http/cookiejar"
 	"strings"
+
+	"golang.org/x/net/idna"
 )
 
 // List implements the cookiejar.PublicSuffixList interface by calling the
@@ -83,6 +86,10 @@ func (list) String() string {
 // Use cases for distinguishing ICANN domains like "foo.com" from private
 // domains like "foo.appspot.com" can be found at
 // https://wiki.mozilla.org/Public_Suffix_List/Use_Cases
+//
+// The lookup is case sensitive and the list holds only lower case A-labels,
+// so domain should be normalized first, for example by Normalize, unless it
+// is already known to be in that form.
 func PublicSuffix(domain string) (publicSuffix string, icann bool) {
 	lo, hi := uint32(0), uint32(numTLD)
 	s, suffix, icannNode, wildcard := domain, len(domain), false, false
@@ -172,20 +179,66 @@ func nodeLabel(i uint32) string {
 	return text[offset : offset+length]
 }
 
+// Errors returned by EffectiveTLDPlusOne. The returned errors wrap one of
+// these and can be tested with errors.Is.
+var (
+	// ErrDomainIsSuffix reports that the domain is itself a public suffix,
+	// such as "com" or "co.uk", and so has no eTLD+1. A cookie must not be
+	// scoped to such a domain.
+	ErrDomainIsSuffix = errors.New("publicsuffix: domain is a public suffix")
+
+	// ErrMalformed reports that the domain is not well formed, for example
+	// because it has an empty label.
+	ErrMalformed = errors.New("publicsuffix: malformed domain")
+)
+
+// domainError is an error about a specific domain. Its message is more
+// detailed than that of err, which it wraps.
+type domainError struct {
+	err error
+	msg string
+}
+
+func (e *domainError) Error() string { return e.msg }
+func (e *domainError) Unwrap() error { return e.err }
+
+// Normalize returns domain in the form expected by PublicSuffix and
+// EffectiveTLDPlusOne: ASCII letters are lower cased and, if domain is not
+// all ASCII, Unicode labels (U-labels) are converted to A-labels, such as
+// "xn--ls8h", as for a DNS lookup.
+//
+// Callers that only ever pass normalized domains, such as the hosts
+// canonicalized by net/http/cookiejar, need not call Normalize.
+func Normalize(domain string) (string, error) {
+	for i := 0; i < len(domain); i++ {
+		if domain[i] >= 0x80 {
+			return idna.Lookup.ToASCII(domain)
+		}
+	}
+	return strings.ToLower(domain), nil
+}
+
 // EffectiveTLDPlusOne returns the effective top level domain plus one more
 // label. For example, the eTLD+1 for "foo.bar.golang.org" is "golang.org".
+//
+// If domain is itself a public suffix, the returned error wraps
+// ErrDomainIsSuffix. If domain is malformed, it wraps ErrMalformed.
 func EffectiveTLDPlusOne(domain string) (string, error) {
 	if strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
-		return "", fmt.Errorf("publicsuffix: empty label in domain %q", domain)
+		return "", &domainError{ErrMalformed, fmt.Sprintf("publicsuffix: empty label in domain %q", domain)}
 	}
 
 	suffix, _ := PublicSuffix(domain)
 	if len(domain) <= len(suffix) {
-		return "", fmt.Errorf("publicsuffix: cannot derive eTLD+1 for domain %q", domain)
+		err := ErrDomainIsSuffix
+		if domain == "" {
+			err = ErrMalformed
+		}
+		return "", &domainError{err, fmt.Sprintf("publicsuffix: cannot derive eTLD+1 for domain %q", domain)}
 	}
 	i := len(domain) - len(suffix) - 1
 	if domain[i] != '.' {
-		return "", fmt.Errorf("publicsuffix: invalid public suffix %q for domain %q", suffix, domain)
+		return "", &domainError{ErrMalformed, fmt.Sprintf("publicsuffix: invalid public suffix %q for domain %q", suffix, domain)}
 	}
 	return domain[1+strings.LastIndex(domain[:i], "."):], nil
 }
@@ -5,6 +5,7 @@
 package publicsuffix
 
 import (
+	"errors"
 	"sort"
 	"strings"
 	"testing"
@@ -507,3 +508,56 @@ func TestEffectiveTLDPlusOne(t *testing.T) {
 		}
 	}
 }
+
+func TestEffectiveTLDPlusOneErrors(t *testing.T) {
+	testCases := []struct {
+		domain  string
+		wantErr error
+		wantMsg string
+	}{
+		{"com", ErrDomainIsSuffix, `publicsuffix: cannot derive eTLD+1 for domain "com"`},
+		{"co.uk", ErrDomainIsSuffix, `publicsuffix: cannot derive eTLD+1 for domain "co.uk"`},
+		{"", ErrMalformed, `publicsuffix: cannot derive eTLD+1 for domain ""`},
+		{"example.com.", ErrMalformed, `publicsuffix: empty label in domain "example.com."`},
+		{"foo..example.com", ErrMalformed, `publicsuffix: empty label in domain "foo..example.com"`},
+	}
+	for _, tc := range testCases {
+		_, err := EffectiveTLDPlusOne(tc.domain)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%q: got error %v, want %v", tc.domain, err, tc.wantErr)
+			continue
+		}
+		if err.Error() != tc.wantMsg {
+			t.Errorf("%q: got message %q, want %q", tc.domain, err.Error(), tc.wantMsg)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		domain, want, wantPS, wantETLD1 string
+	}{
+		{"WWW.Amazon.CO.UK", "www.amazon.co.uk", "co.uk", "amazon.co.uk"},
+		{"foo.Example.Com", "foo.example.com", "com", "example.com"},
+		{"www.bücher.example.de", "www.xn--bcher-kva.example.de", "de", "example.de"},
+		{"bücher.中国", "xn--bcher-kva.xn--fiqs8s", "xn--fiqs8s", "xn--bcher-kva.xn--fiqs8s"},
+		{"Foo.BLOGSPOT.co.UK", "foo.blogspot.co.uk", "blogspot.co.uk", "foo.blogspot.co.uk"},
+	}
+	for _, tc := range testCases {
+		got, err := Normalize(tc.domain)
+		if err != nil {
+			t.Errorf("%q: %v", tc.domain, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.domain, got, tc.want)
+			continue
+		}
+		if ps, _ := PublicSuffix(got); ps != tc.wantPS {
+			t.Errorf("%q: got public suffix %q, want %q", tc.domain, ps, tc.wantPS)
+		}
+		if etld1, _ := EffectiveTLDPlusOne(got); etld1 != tc.wantETLD1 {
+			t.Errorf("%q: got eTLD+1 %q, want %q", tc.domain, etld1, tc.wantETLD1)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"log"
 	"math"
 	"sync"
+	"time"
 
 	"golang.org/x/net/internal/timeseries"
 )
@@ -249,6 +250,85 @@ func bucketBoundary(bucket uint8) int64 {
 	return 1 << bucket
 }
 
+// A Histogram is a snapshot of the latency distribution of the completed
+// traces of a family, as shown on the /debug/requests page. Latencies are
+// measured in microseconds and counted in buckets whose bounds are powers
+// of two, so values computed from a Histogram are estimates within the
+// resolution of a bucket.
+type Histogram struct {
+	h histogram
+}
+
+// A HistogramBucket is a bucket of a Histogram. It counts the latencies v
+// with Lower <= v < Upper.
+type HistogramBucket struct {
+	Lower, Upper int64
+	Count        int64
+}
+
+// LatencyHistogram returns a snapshot of the latency histogram of family
+// over the given window of time, up to an hour, before now. Longer windows
+// are treated as an hour. A zero or negative window covers all the traces
+// completed since the program started. It returns nil if no trace of
+// family has been created.
+func LatencyHistogram(family string, window time.Duration) *Histogram {
+	f := getFamily(family, false)
+	if f == nil {
+		return nil
+	}
+	if window > time.Hour {
+		window = time.Hour
+	}
+	h := new(Histogram)
+	f.LatencyMu.RLock()
+	var obs timeseries.Observable
+	if window <= 0 {
+		obs = f.Latency.Total()
+	} else {
+		obs = f.Latency.Recent(window)
+	}
+	// The observable may alias the timeseries, so copy it before
+	// unlocking.
+	h.h.CopyFrom(obs)
+	f.LatencyMu.RUnlock()
+	h.h.allocateBuckets()
+	return h
+}
+
+// Count returns the number of latencies recorded in h.
+func (h *Histogram) Count() int64 {
+	return h.h.total()
+}
+
+// Quantile returns an estimate of the latency, in microseconds, that the
+// fraction q of the recorded latencies are less than. For example,
+// Quantile(0.99) estimates the 99th percentile. q is clamped to [0, 1].
+func (h *Histogram) Quantile(q float64) float64 {
+	switch {
+	case q < 0:
+		q = 0
+	case q > 1:
+		q = 1
+	}
+	return float64(h.h.percentileBoundary(q))
+}
+
+// Snapshot returns the non-empty buckets of h in increasing order.
+func (h *Histogram) Snapshot() []HistogramBucket {
+	var bs []HistogramBucket
+	for i, n := range h.h.buckets {
+		if n == 0 {
+			continue
+		}
+		upper := int64(math.MaxInt64)
+		if i < bucketCount-1 {
+			upper = bucketBoundary(uint8(i + 1))
+		}
+		bs = append(bs, HistogramBucket{Lower: bucketBoundary(uint8(i)), Upper: upper, Count: n})
+	}
+	return bs
+}
+
 // bucketData holds data about a specific bucket for use in distTmpl.
 type bucketData struct {
 	Lower, Upper       int64
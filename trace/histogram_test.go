@@ -6,7 +6,11 @@ package trace
 
 import (
 	"math"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"golang.org/x/net/internal/timeseries"
 )
 
 type sumTest struct {
@@ -323,3 +327,93 @@ func add(h *histogram, times int, val int64) {
 func isApproximate(x, y float64) bool {
 	return math.Abs(x-y) < 1e-2
 }
+
+func TestLatencyHistogram(t *testing.T) {
+	const fam = "latency.histogram"
+	if h := LatencyHistogram(fam, 0); h != nil {
+		t.Fatalf("got histogram %v for an unknown family; want nil", h)
+	}
+
+	// Latencies of 1, 2, ..., 1000µs.
+	f := getFamily(fam, true)
+	for i := 1; i <= 1000; i++ {
+		f.addLatency(time.Duration(i) * time.Microsecond)
+	}
+
+	for _, window := range []time.Duration{0, -time.Minute, time.Minute, 2 * time.Hour} {
+		h := LatencyHistogram(fam, window)
+		if h == nil {
+			t.Fatalf("window %v: got nil histogram", window)
+		}
+		if got := h.Count(); got != 1000 {
+			t.Errorf("window %v: Count = %d; want 1000", window, got)
+		}
+		for _, q := range []float64{0.5, 0.9, 0.99} {
+			// The estimate must be within the bucket of the exact value.
+			want := int64(q * 1000)
+			lo, hi := bucketBoundary(uint8(getBucket(want))), bucketBoundary(uint8(getBucket(want)+1))
+			if got := h.Quantile(q); got < float64(lo) || got > float64(hi) {
+				t.Errorf("window %v: Quantile(%v) = %v; want within [%d, %d]", window, q, got, lo, hi)
+			}
+		}
+
+		var total int64
+		buckets := h.Snapshot()
+		for i, b := range buckets {
+			if b.Upper != 2*b.Lower && b.Lower != 0 {
+				t.Errorf("window %v: bucket %d is [%d, %d)", window, i, b.Lower, b.Upper)
+			}
+			if i > 0 && b.Lower != buckets[i-1].Upper {
+				t.Errorf("window %v: bucket %d starts at %d; want %d", window, i, b.Lower, buckets[i-1].Upper)
+			}
+			total += b.Count
+		}
+		if total != 1000 {
+			t.Errorf("window %v: bucket counts sum to %d; want 1000", window, total)
+		}
+		if n := len(buckets); n == 0 || buckets[0].Count != 1 || buckets[n-1].Count != 1000-512+1 {
+			t.Errorf("window %v: got buckets %v", window, buckets)
+		}
+	}
+}
+
+// tickingClock is a timeseries.Clock that moves one second forward each
+// time it is read.
+type tickingClock struct {
+	n int64
+}
+
+func (c *tickingClock) Time() time.Time {
+	return time.Unix(1e9+atomic.AddInt64(&c.n, 1), 0)
+}
+
+func TestLatencyHistogramConcurrentFinish(t *testing.T) {
+	const fam = "latency.histogram.concurrent"
+	f := getFamily(fam, true)
+	// With a clock that ticks on every read, each Finish starts a new
+	// bucket and merges the pending latencies into the totals.
+	f.LatencyMu.Lock()
+	f.Latency = timeseries.NewMinuteHourSeriesWithClock(func() timeseries.Observable { return new(histogram) }, new(tickingClock))
+	f.LatencyMu.Unlock()
+	New(fam, "warm up").Finish()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			// The second Finish merges the latency of the first.
+			New(fam, "concurrent").Finish()
+			New(fam, "concurrent").Finish()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		for _, window := range []time.Duration{time.Minute, 0} {
+			if h := LatencyHistogram(fam, window); h == nil {
+				t.Fatalf("window %v: got nil histogram", window)
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	<-done
+}
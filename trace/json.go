@@ -0,0 +1,245 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+// This file implements the JSON views of /debug/requests and /debug/events.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// jsonFormat is the value of the "format" query parameter that selects
+// the JSON view of the Traces and Events handlers.
+const jsonFormat = "json"
+
+// wantJSON reports whether req asks for the JSON view.
+func wantJSON(req *http.Request) bool {
+	return req != nil && req.FormValue("format") == jsonFormat
+}
+
+// TracesJSON is the JSON form of the /debug/requests page.
+type TracesJSON struct {
+	Families []TraceFamilyJSON `json:"families"`
+}
+
+// TraceFamilyJSON describes the active and completed traces of a family.
+type TraceFamilyJSON struct {
+	Family  string            `json:"family"`
+	Active  []TraceJSON       `json:"active"`
+	Buckets []TraceBucketJSON `json:"buckets"`
+}
+
+// TraceBucketJSON holds the retained traces matching a bucket condition,
+// such as a minimum latency or "errors".
+type TraceBucketJSON struct {
+	Cond   string      `json:"cond"`
+	Traces []TraceJSON `json:"traces"`
+}
+
+// TraceJSON is a single active or completed trace.
+type TraceJSON struct {
+	ID       uint64      `json:"id"`
+	ParentID uint64      `json:"parent_id,omitempty"` // zero if not a child trace
+	Family   string      `json:"family"`
+	Title    string      `json:"title"`
+	Start    time.Time   `json:"start"`
+	Elapsed  float64     `json:"elapsed"` // seconds; time so far for active traces
+	Active   bool        `json:"active"`
+	Error    bool        `json:"error"`
+	Events   []EventJSON `json:"events"`
+}
+
+// EventsJSON is the JSON form of the /debug/events page.
+type EventsJSON struct {
+	Families []EventFamilyJSON `json:"families"`
+}
+
+// EventFamilyJSON describes the active event logs of a family.
+type EventFamilyJSON struct {
+	Family    string         `json:"family"`
+	EventLogs []EventLogJSON `json:"event_logs"`
+}
+
+// EventLogJSON is a single active event log.
+type EventLogJSON struct {
+	Family        string      `json:"family"`
+	Title         string      `json:"title"`
+	Start         time.Time   `json:"start"`
+	Elapsed       float64     `json:"elapsed"` // seconds
+	LastErrorTime *time.Time  `json:"last_error_time,omitempty"`
+	Events        []EventJSON `json:"events"`
+}
+
+// EventJSON is a timestamped entry in a trace or event log.
+type EventJSON struct {
+	When      time.Time `json:"when"`
+	Elapsed   float64   `json:"elapsed"` // seconds since the previous event
+	What      string    `json:"what"`
+	Error     bool      `json:"error,omitempty"`
+	Sensitive bool      `json:"sensitive,omitempty"` // What is redacted
+}
+
+// RenderJSON writes the traces shown on the /debug/requests page as JSON.
+// It does not do any auth checking. The request may be nil; if it has a
+// "fam" parameter, only that family is included.
+//
+// Most users will use the Traces handler with the "format=json" parameter.
+func RenderJSON(w io.Writer, req *http.Request, sensitive bool) {
+	var fam string
+	if req != nil {
+		fam = req.FormValue("fam")
+		if req.FormValue("show_sensitive") == "0" {
+			sensitive = false
+		}
+	}
+
+	completedMu.RLock()
+	names := make([]string, 0, len(completedTraces))
+	for name := range completedTraces {
+		if fam == "" || name == fam {
+			names = append(names, name)
+		}
+	}
+	completedMu.RUnlock()
+	sort.Strings(names)
+
+	data := TracesJSON{Families: make([]TraceFamilyJSON, 0, len(names))}
+	for _, name := range names {
+		f := getFamily(name, false)
+		if f == nil {
+			continue
+		}
+		jf := TraceFamilyJSON{Family: name}
+
+		trl := getActiveTraces(name)
+		sort.Sort(trl)
+		jf.Active = trl.json(sensitive)
+		trl.Free()
+
+		jf.Buckets = make([]TraceBucketJSON, len(f.Buckets))
+		for i, b := range f.Buckets {
+			trl := b.Copy(false)
+			sort.Sort(trl)
+			jf.Buckets[i] = TraceBucketJSON{Cond: b.Cond.String(), Traces: trl.json(sensitive)}
+			trl.Free()
+		}
+
+		data.Families = append(data.Families, jf)
+	}
+
+	if err := json.NewEncoder(w).Encode(&data); err != nil {
+		log.Printf("net/trace: Failed encoding JSON: %v", err)
+	}
+}
+
+// RenderEventsJSON writes the event logs shown on the /debug/events page
+// as JSON. It does not do any auth checking. The request may be nil; if it
+// has a "fam" parameter, only that family is included.
+//
+// Most users will use the Events handler with the "format=json" parameter.
+func RenderEventsJSON(w io.Writer, req *http.Request, sensitive bool) {
+	var fam string
+	if req != nil {
+		fam = req.FormValue("fam")
+	}
+
+	famMu.RLock()
+	names := make([]string, 0, len(families))
+	for name := range families {
+		if fam == "" || name == fam {
+			names = append(names, name)
+		}
+	}
+	famMu.RUnlock()
+	sort.Strings(names)
+
+	now := time.Now()
+	data := EventsJSON{Families: make([]EventFamilyJSON, 0, len(names))}
+	for _, name := range names {
+		els := getEventFamily(name).Copy(now, 0)
+		sort.Sort(els)
+		jf := EventFamilyJSON{Family: name, EventLogs: make([]EventLogJSON, 0, len(els))}
+		for _, el := range els {
+			jf.EventLogs = append(jf.EventLogs, el.json())
+		}
+		els.Free()
+		data.Families = append(data.Families, jf)
+	}
+
+	if err := json.NewEncoder(w).Encode(&data); err != nil {
+		log.Printf("net/trace: Failed encoding JSON: %v", err)
+	}
+}
+
+func (trl traceList) json(sensitive bool) []TraceJSON {
+	ts := make([]TraceJSON, 0, len(trl))
+	for _, tr := range trl {
+		ts = append(ts, tr.json(sensitive))
+	}
+	return ts
+}
+
+func (tr *trace) json(sensitive bool) TraceJSON {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	t := TraceJSON{
+		ID:       tr.ID,
+		ParentID: tr.ParentID,
+		Family:   tr.Family,
+		Title:    tr.Title,
+		Start:    tr.Start,
+		Error:    tr.IsError,
+		Events:   make([]EventJSON, 0, len(tr.events)),
+	}
+	if tr.Elapsed == 0 {
+		t.Active = true
+		t.Elapsed = time.Since(tr.Start).Seconds()
+	} else {
+		t.Elapsed = tr.Elapsed.Seconds()
+	}
+	for _, e := range tr.events {
+		je := EventJSON{When: e.When, Elapsed: e.Elapsed.Seconds()}
+		if e.Sensitive && !sensitive {
+			je.Sensitive = true
+		} else {
+			je.What = fmt.Sprint(e.What)
+		}
+		t.Events = append(t.Events, je)
+	}
+	return t
+}
+
+func (el *eventLog) json() EventLogJSON {
+	el.mu.RLock()
+	defer el.mu.RUnlock()
+
+	l := EventLogJSON{
+		Family:  el.Family,
+		Title:   el.Title,
+		Start:   el.Start,
+		Elapsed: time.Since(el.Start).Seconds(),
+		Events:  make([]EventJSON, 0, len(el.events)),
+	}
+	if !el.LastErrorTime.IsZero() {
+		t := el.LastErrorTime
+		l.LastErrorTime = &t
+	}
+	for _, e := range el.events {
+		l.Events = append(l.Events, EventJSON{
+			When:    e.When,
+			Elapsed: e.Elapsed.Seconds(),
+			What:    e.What,
+			Error:   e.IsErr,
+		})
+	}
+	return l
+}
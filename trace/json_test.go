@@ -0,0 +1,142 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracesJSON(t *testing.T) {
+	const fam = "json.traces"
+
+	active := New(fam, "active")
+	defer active.Finish()
+	active.LazyPrintf("still %s", "going")
+
+	tr := New(fam, "done")
+	tr.LazyPrintf("step %d", 1)
+	tr.LazyLog(s{}, true)
+	tr.SetError()
+	tr.Finish()
+
+	req := httptest.NewRequest("GET", "/debug/requests?format=json&fam="+fam, nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	Traces(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q; want application/json", ct)
+	}
+
+	var got TracesJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, rec.Body.Bytes())
+	}
+	if len(got.Families) != 1 || got.Families[0].Family != fam {
+		t.Fatalf("families = %+v; want only %q", got.Families, fam)
+	}
+	f := got.Families[0]
+
+	if len(f.Active) != 1 {
+		t.Fatalf("got %d active traces; want 1", len(f.Active))
+	}
+	if a := f.Active[0]; a.Title != "active" || !a.Active || len(a.Events) != 1 || a.Events[0].What != "still going" {
+		t.Errorf("active trace = %+v", a)
+	}
+
+	var errBucket *TraceBucketJSON
+	for i := range f.Buckets {
+		if f.Buckets[i].Cond == "errors" {
+			errBucket = &f.Buckets[i]
+		}
+	}
+	if errBucket == nil || len(errBucket.Traces) != 1 {
+		t.Fatalf("errors bucket = %+v; want one trace", errBucket)
+	}
+	done := errBucket.Traces[0]
+	if done.Family != fam || done.Title != "done" || done.Active || !done.Error {
+		t.Errorf("completed trace = %+v", done)
+	}
+	if len(done.Events) != 2 {
+		t.Fatalf("got %d events; want 2", len(done.Events))
+	}
+	if e := done.Events[0]; e.What != "step 1" || e.When.IsZero() {
+		t.Errorf("first event = %+v", e)
+	}
+	if e := done.Events[1]; e.What != "lazy string" || e.Sensitive {
+		t.Errorf("second event = %+v", e)
+	}
+}
+
+func TestTracesJSONRedactsSensitive(t *testing.T) {
+	const fam = "json.sensitive"
+
+	tr := New(fam, "title")
+	tr.LazyLog(s{}, true)
+	tr.Finish()
+
+	req := httptest.NewRequest("GET", "/debug/requests?format=json&show_sensitive=0&fam="+fam, nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	Traces(rec, req)
+
+	var got TracesJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	e := got.Families[0].Buckets[0].Traces[0].Events[0]
+	if !e.Sensitive || e.What != "" {
+		t.Errorf("event = %+v; want redacted", e)
+	}
+}
+
+func TestTracesJSONAuth(t *testing.T) {
+	for _, path := range []string{"/debug/requests?format=json", "/debug/events?format=json"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.23.1:1234"
+		rec := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d; want %d", path, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestEventsJSON(t *testing.T) {
+	const fam = "json.events"
+
+	el := NewEventLog(fam, "conn")
+	defer el.Finish()
+	el.Printf("hello %s", "world")
+	el.Errorf("oops")
+
+	req := httptest.NewRequest("GET", "/debug/events?format=json&fam="+fam, nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	Events(rec, req)
+
+	var got EventsJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, rec.Body.Bytes())
+	}
+	if len(got.Families) != 1 || len(got.Families[0].EventLogs) != 1 {
+		t.Fatalf("families = %+v; want one family with one event log", got.Families)
+	}
+	l := got.Families[0].EventLogs[0]
+	if l.Title != "conn" || l.LastErrorTime == nil || len(l.Events) != 2 {
+		t.Fatalf("event log = %+v", l)
+	}
+	if e := l.Events[0]; e.What != "hello world" || e.Error {
+		t.Errorf("first event = %+v", e)
+	}
+	if e := l.Events[1]; e.What != "oops" || !e.Error {
+		t.Errorf("second event = %+v", e)
+	}
+}
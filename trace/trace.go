@@ -59,6 +59,9 @@ connections.
 The /debug/events HTTP endpoint organizes the event logs by family and
 by time since the last error.  The expanded view displays recent log
 entries and the log's call stack.
+
+Both endpoints also serve their data as JSON, for programmatic scraping,
+when the "format=json" query parameter is given.
 */
 package trace // import "golang.org/x/net/trace"
 
@@ -147,6 +150,9 @@ func FromContext(ctx context.Context) (tr Trace, ok bool) {
 // The package initialization registers it in http.DefaultServeMux
 // at /debug/requests.
 //
+// If the request has the "format=json" parameter, the traces are
+// rendered as JSON by RenderJSON.
+//
 // It performs authorization by running AuthRequest.
 func Traces(w http.ResponseWriter, req *http.Request) {
 	any, sensitive := AuthRequest(req)
@@ -154,6 +160,11 @@ func Traces(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "not allowed", http.StatusUnauthorized)
 		return
 	}
+	if wantJSON(req) {
+		w.Header().Set("Content-Type", "application/json")
+		RenderJSON(w, req, sensitive)
+		return
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	Render(w, req, sensitive)
 }
@@ -162,6 +173,9 @@ func Traces(w http.ResponseWriter, req *http.Request) {
 // The package initialization registers it in http.DefaultServeMux
 // at /debug/events.
 //
+// If the request has the "format=json" parameter, the event logs are
+// rendered as JSON by RenderEventsJSON.
+//
 // It performs authorization by running AuthRequest.
 func Events(w http.ResponseWriter, req *http.Request) {
 	any, sensitive := AuthRequest(req)
@@ -169,6 +183,11 @@ func Events(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "not allowed", http.StatusUnauthorized)
 		return
 	}
+	if wantJSON(req) {
+		w.Header().Set("Content-Type", "application/json")
+		RenderEventsJSON(w, req, sensitive)
+		return
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	RenderEvents(w, req, sensitive)
 }
@@ -247,15 +266,17 @@ func Render(w io.Writer, req *http.Request, sensitive bool) {
 		if len(data.Traces) < n {
 			data.Total = n
 		}
-	case data.Bucket < bucketsPerFamily:
-		if b := lookupBucket(data.Family, data.Bucket); b != nil {
-			data.Traces = b.Copy(data.Traced)
-		}
 	default:
-		if f := getFamily(data.Family, false); f != nil {
+		f := getFamily(data.Family, false)
+		if f == nil {
+			break
+		}
+		if data.Bucket < len(f.Buckets) {
+			data.Traces = f.Buckets[data.Bucket].Copy(data.Traced)
+		} else {
 			var obs timeseries.Observable
 			f.LatencyMu.RLock()
-			switch o := data.Bucket - bucketsPerFamily; o {
+			switch o := data.Bucket - len(f.Buckets); o {
 			case 0:
 				obs = f.Latency.Minute()
 				data.HistogramWindow = "last minute"
@@ -276,6 +297,7 @@ func Render(w io.Writer, req *http.Request, sensitive bool) {
 	if data.Traces != nil {
 		defer data.Traces.Free()
 		sort.Sort(data.Traces)
+		data.Traces.nest()
 	}
 
 	completedMu.RLock()
@@ -301,14 +323,6 @@ func parseArgs(req *http.Request) (fam string, b int, ok bool) {
 	return fam, b, true
 }
 
-func lookupBucket(fam string, b int) *traceBucket {
-	f := getFamily(fam, false)
-	if f == nil || b < 0 || b >= len(f.Buckets) {
-		return nil
-	}
-	return f.Buckets[b]
-}
-
 type contextKeyT string
 
 var contextKey = contextKeyT("golang.org/x/net/trace.Trace")
@@ -357,11 +371,81 @@ func (l *lazySprintf) String() string {
 	return fmt.Sprintf(l.format, l.a...)
 }
 
+var (
+	samplerMu sync.RWMutex
+	sampler   func(family, title string) bool
+)
+
+// SetSampler sets the function consulted by New to decide whether a trace
+// is recorded. If f returns false, New returns a Trace that discards its
+// events and is not shown on the /debug/requests page; its latency is still
+// recorded in the family's histogram. A nil f, the default, samples every
+// trace.
+//
+// f may be called concurrently from multiple goroutines.
+func SetSampler(f func(family, title string) bool) {
+	samplerMu.Lock()
+	sampler = f
+	samplerMu.Unlock()
+}
+
+func sampled(family, title string) bool {
+	samplerMu.RLock()
+	f := sampler
+	samplerMu.RUnlock()
+	return f == nil || f(family, title)
+}
+
+// lastTraceID is the id of the most recently created trace.
+var lastTraceID uint64
+
 // New returns a new Trace with the specified family and title.
 func New(family, title string) Trace {
+	if !sampled(family, title) {
+		return &unsampledTrace{family: family, start: time.Now()}
+	}
+	return newActiveTrace(family, title, nil)
+}
+
+// NewChild returns a new Trace with the specified family and title for
+// work done on behalf of parent. The /debug/requests page shows the child
+// below its parent, if both are listed, and the events of the child are
+// also logged, prefixed by the child's id and title, to the parent while
+// it is active. The child must be finished separately.
+//
+// If parent was not returned by this package, NewChild is equivalent to
+// New.
+func NewChild(parent Trace, family, title string) Trace {
+	if p, ok := parent.(childTracer); ok {
+		return p.newChild(family, title)
+	}
+	return New(family, title)
+}
+
+// childTracer is implemented by the Traces that can have children.
+type childTracer interface {
+	newChild(family, title string) Trace
+}
+
+func (tr *trace) newChild(family, title string) Trace {
+	if !sampled(family, title) {
+		return &unsampledTrace{family: family, start: time.Now()}
+	}
+	return newActiveTrace(family, title, tr)
+}
+
+// newActiveTrace returns a new trace, a child of parent if it is non-nil,
+// and adds it to the active traces of its family.
+func newActiveTrace(family, title string, parent *trace) *trace {
 	tr := newTrace()
 	tr.ref()
+	tr.ID = atomic.AddUint64(&lastTraceID, 1)
 	tr.Family, tr.Title = family, title
+	if parent != nil {
+		parent.ref() // released in Finish
+		tr.ParentID = parent.ID
+		tr.parent = parent
+	}
 	tr.Start = time.Now()
 	tr.maxEvents = maxEventsPerTrace
 	tr.events = tr.eventsBuf[:0]
@@ -398,6 +482,8 @@ func (tr *trace) Finish() {
 	elapsed := time.Since(tr.Start)
 	tr.mu.Lock()
 	tr.Elapsed = elapsed
+	parent := tr.parent
+	tr.parent = nil
 	tr.mu.Unlock()
 
 	if DebugUseAfterFinish {
@@ -420,18 +506,39 @@ func (tr *trace) Finish() {
 	}
 	tr.mu.RUnlock()
 
-	// Add a sample of elapsed time as microseconds to the family's timeseries
-	h := new(histogram)
-	h.addMeasurement(elapsed.Nanoseconds() / 1e3)
-	f.LatencyMu.Lock()
-	f.Latency.Add(h)
-	f.LatencyMu.Unlock()
+	f.addLatency(elapsed)
+
+	if parent != nil {
+		parent.unref() // matches ref in newActiveTrace
+	}
+	tr.unref() // matches ref in newActiveTrace
+}
+
+// unsampledTrace is the Trace returned by New for traces rejected by the
+// sampler. It records nothing but the latency of the trace.
+type unsampledTrace struct {
+	family string
+	start  time.Time
+}
 
-	tr.unref() // matches ref in New
+func (tr *unsampledTrace) LazyLog(x fmt.Stringer, sensitive bool)     {}
+func (tr *unsampledTrace) LazyPrintf(format string, a ...interface{}) {}
+func (tr *unsampledTrace) SetError()                                  {}
+func (tr *unsampledTrace) SetRecycler(f func(interface{}))            {}
+func (tr *unsampledTrace) SetTraceInfo(traceID, spanID uint64)        {}
+func (tr *unsampledTrace) SetMaxEvents(m int)                         {}
+
+// newChild returns an unsampled trace: the children of a trace that is
+// not recorded are not recorded either.
+func (tr *unsampledTrace) newChild(family, title string) Trace {
+	return &unsampledTrace{family: family, start: time.Now()}
+}
+
+func (tr *unsampledTrace) Finish() {
+	getFamily(tr.family, true).addLatency(time.Since(tr.start))
 }
 
 const (
-	bucketsPerFamily    = 9
 	tracesPerBucket     = 10
 	maxActiveTraces     = 20 // Maximum number of active traces to show.
 	maxEventsPerTrace   = 10
@@ -446,8 +553,53 @@ var (
 	// Families of completed traces.
 	completedMu     sync.RWMutex
 	completedTraces = make(map[string]*family) // family -> traces
+
+	// Minimum latencies of the buckets of newly allocated families.
+	latencyBucketsMu sync.RWMutex
+	latencyBuckets   = defaultLatencyBuckets
 )
 
+var defaultLatencyBuckets = []time.Duration{
+	0,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	10 * time.Second,
+	100 * time.Second,
+}
+
+// SetLatencyBuckets sets the minimum latencies of the buckets into which
+// completed traces are grouped on the /debug/requests page. A trace is
+// retained in every bucket whose minimum it meets, in addition to the
+// errors bucket if it resulted in an error. The durations are sorted and
+// duplicates are removed. An empty b restores the default buckets of
+// 0s, 50ms, 100ms, 200ms, 500ms, 1s, 10s and 100s.
+//
+// SetLatencyBuckets only affects trace families that are first used
+// after it is called, so it should be called during program
+// initialization, before any traces are created.
+func SetLatencyBuckets(b []time.Duration) {
+	if len(b) == 0 {
+		b = defaultLatencyBuckets
+	} else {
+		b = append([]time.Duration(nil), b...)
+		sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+		n := 1
+		for _, d := range b[1:] {
+			if d != b[n-1] {
+				b[n] = d
+				n++
+			}
+		}
+		b = b[:n]
+	}
+	latencyBucketsMu.Lock()
+	latencyBuckets = b
+	latencyBucketsMu.Unlock()
+}
+
 type traceSet struct {
 	mu sync.RWMutex
 	m  map[*trace]bool
@@ -563,7 +715,8 @@ func allocFamily(fam string) *family {
 // family represents a set of trace buckets and associated latency information.
 type family struct {
 	// traces may occur in multiple buckets.
-	Buckets [bucketsPerFamily]*traceBucket
+	// The last bucket holds the traces that resulted in an error.
+	Buckets []*traceBucket
 
 	// latency time series
 	LatencyMu sync.RWMutex
@@ -571,22 +724,32 @@ type family struct {
 }
 
 func newFamily() *family {
+	latencyBucketsMu.RLock()
+	lb := latencyBuckets
+	latencyBucketsMu.RUnlock()
+
+	buckets := make([]*traceBucket, 0, len(lb)+1)
+	for _, d := range lb {
+		buckets = append(buckets, &traceBucket{Cond: minCond(d)})
+	}
+	buckets = append(buckets, &traceBucket{Cond: errorCond{}})
+
 	return &family{
-		Buckets: [bucketsPerFamily]*traceBucket{
-			{Cond: minCond(0)},
-			{Cond: minCond(50 * time.Millisecond)},
-			{Cond: minCond(100 * time.Millisecond)},
-			{Cond: minCond(200 * time.Millisecond)},
-			{Cond: minCond(500 * time.Millisecond)},
-			{Cond: minCond(1 * time.Second)},
-			{Cond: minCond(10 * time.Second)},
-			{Cond: minCond(100 * time.Second)},
-			{Cond: errorCond{}},
-		},
+		Buckets: buckets,
 		Latency: timeseries.NewMinuteHourSeries(func() timeseries.Observable { return new(histogram) }),
 	}
 }
 
+// addLatency adds a sample of elapsed time as microseconds to the family's
+// timeseries.
+func (f *family) addLatency(elapsed time.Duration) {
+	h := new(histogram)
+	h.addMeasurement(elapsed.Nanoseconds() / 1e3)
+	f.LatencyMu.Lock()
+	f.Latency.Add(h)
+	f.LatencyMu.Unlock()
+}
+
 // traceBucket represents a size-capped bucket of historic traces,
 // along with a condition for a trace to belong to the bucket.
 type traceBucket struct {
@@ -677,6 +840,36 @@ func (trl traceList) Free() {
 	}
 }
 
+// nest reorders trl so that each trace is followed by those of its
+// children that are in trl. The order is otherwise unchanged.
+func (trl traceList) nest() {
+	listed := make(map[uint64]bool, len(trl))
+	for _, tr := range trl {
+		listed[tr.ID] = true
+	}
+	var roots traceList
+	children := make(map[uint64]traceList)
+	for _, tr := range trl {
+		if listed[tr.ParentID] {
+			children[tr.ParentID] = append(children[tr.ParentID], tr)
+		} else {
+			roots = append(roots, tr)
+		}
+	}
+	nested := trl[:0:0]
+	var add func(tr *trace)
+	add = func(tr *trace) {
+		nested = append(nested, tr)
+		for _, c := range children[tr.ID] {
+			add(c)
+		}
+	}
+	for _, tr := range roots {
+		add(tr)
+	}
+	copy(trl, nested)
+}
+
 // traceList may be sorted in reverse chronological order.
 func (trl traceList) Len() int           { return len(trl) }
 func (trl traceList) Less(i, j int) bool { return trl[i].Start.After(trl[j].Start) }
@@ -701,6 +894,17 @@ func (e event) WhenString() string {
 	return e.When.Format("15:04:05.000000")
 }
 
+// childEvent is an event of a child trace, as logged to its parent.
+type childEvent struct {
+	id    uint64
+	title string
+	what  interface{} // string or fmt.Stringer
+}
+
+func (e *childEvent) String() string {
+	return fmt.Sprintf("[#%d %s] %v", e.id, e.title, e.what)
+}
+
 // discarded represents a number of discarded events.
 // It is stored as *discarded to make it easier to update in-place.
 type discarded int
@@ -721,6 +925,11 @@ type trace struct {
 	// Start time of the this trace.
 	Start time.Time
 
+	// ID identifies this trace. ParentID is the ID of the trace that
+	// this is a child of, or zero.
+	ID       uint64
+	ParentID uint64
+
 	mu        sync.RWMutex
 	events    []event // Append-only sequence of events (modulo discards).
 	maxEvents int
@@ -729,6 +938,7 @@ type trace struct {
 	Elapsed   time.Duration // Elapsed time for this trace, zero while active.
 	traceID   uint64        // Trace information if non-zero.
 	spanID    uint64
+	parent    *trace // The parent of this trace while it is active.
 
 	refs int32     // how many buckets this is in
 	disc discarded // scratch space to avoid allocation
@@ -743,11 +953,14 @@ func (tr *trace) reset() {
 	tr.Family = ""
 	tr.Title = ""
 	tr.Start = time.Time{}
+	tr.ID = 0
+	tr.ParentID = 0
 
 	tr.mu.Lock()
 	tr.Elapsed = 0
 	tr.traceID = 0
 	tr.spanID = 0
+	tr.parent = nil
 	tr.IsError = false
 	tr.maxEvents = 0
 	tr.events = nil
@@ -796,6 +1009,32 @@ func (tr *trace) addEvent(x interface{}, recyclable, sensitive bool) {
 
 	e := event{When: time.Now(), What: x, Recyclable: recyclable, Sensitive: sensitive}
 	tr.mu.Lock()
+	tr.appendEvent(e)
+	parent := tr.parent
+	if parent != nil && recyclable && tr.recycler != nil {
+		// x may be recycled while the parent still shows it.
+		x = fmt.Sprint(x)
+	}
+	tr.mu.Unlock()
+
+	if parent != nil {
+		parent.addChildEvent(e.When, &childEvent{id: tr.ID, title: tr.Title, what: x}, sensitive)
+	}
+}
+
+// addChildEvent logs x, an event of a child trace, unless tr is finished.
+func (tr *trace) addChildEvent(when time.Time, x *childEvent, sensitive bool) {
+	tr.mu.Lock()
+	if tr.Elapsed == 0 {
+		tr.appendEvent(event{When: when, What: x, Sensitive: sensitive})
+	}
+	tr.mu.Unlock()
+}
+
+// appendEvent appends e to the events of tr, discarding the middle
+// events if there are too many.
+// L >= tr.mu
+func (tr *trace) appendEvent(e event) {
 	e.Elapsed, e.NewDay = tr.delta(e.When)
 	if len(tr.events) < tr.maxEvents {
 		tr.events = append(tr.events, e)
@@ -823,7 +1062,6 @@ func (tr *trace) addEvent(x interface{}, recyclable, sensitive bool) {
 		copy(tr.events[di+1:], tr.events[di+2:])
 		tr.events[tr.maxEvents-1] = e
 	}
-	tr.mu.Unlock()
 }
 
 func (tr *trace) LazyLog(x fmt.Stringer, sensitive bool) {
@@ -1004,6 +1242,12 @@ const pageHTML = `
 			text-align: right;
 			white-space: nowrap;
 		}
+		table#reqs tr.child td.title {
+			padding-left: 2em;
+		}
+		table#reqs span.id {
+			color: #aaa;
+		}
 		table#reqs td.elapsed {
 			padding: 0 0.5em;
 			text-align: right;
@@ -1100,10 +1344,10 @@ const pageHTML = `
 	</caption>
 	<tr><th>When</th><th>Elapsed&nbsp;(s)</th></tr>
 	{{range $tr := $.Traces}}
-	<tr class="first">
+	<tr class="first{{if $tr.ParentID}} child{{end}}">
 		<td class="when">{{$tr.When}}</td>
 		<td class="elapsed">{{$tr.ElapsedTime}}</td>
-		<td>{{$tr.Title}}</td>
+		<td class="title">{{$tr.Title}} <span class="id">#{{$tr.ID}}{{if $tr.ParentID}}, child of #{{$tr.ParentID}}{{end}}</span></td>
 		{{/* TODO: include traceID/spanID */}}
 	</tr>
 	{{if $.Expanded}}
@@ -5,9 +5,15 @@
 package trace
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 type s struct{}
@@ -70,6 +76,185 @@ func TestAuthRequest(t *testing.T) {
 	}
 }
 
+func TestSampler(t *testing.T) {
+	const fam = "sampler"
+	SetSampler(func(family, title string) bool {
+		return family != fam || title == "keep"
+	})
+	defer SetSampler(nil)
+
+	for i := 0; i < 3; i++ {
+		tr := New(fam, "keep")
+		tr.LazyPrintf("kept %d", i)
+		tr.Finish()
+	}
+	for i := 0; i < 5; i++ {
+		tr := New(fam, "drop")
+		if _, ok := tr.(*unsampledTrace); !ok {
+			t.Fatalf("New returned %T for unsampled trace", tr)
+		}
+		tr.LazyPrintf("dropped %d", i)
+		tr.SetError()
+		tr.Finish()
+	}
+
+	if n := getActiveTraces(fam).Len(); n != 0 {
+		t.Errorf("got %d active traces; want 0", n)
+	}
+
+	f := getFamily(fam, false)
+	trl := f.Buckets[0].Copy(false)
+	defer trl.Free()
+	if len(trl) != 3 {
+		t.Errorf("got %d traces in bucket; want 3", len(trl))
+	}
+	for _, tr := range trl {
+		if tr.Title != "keep" {
+			t.Errorf("unsampled trace %q in bucket", tr.Title)
+		}
+	}
+	if !f.Buckets[len(f.Buckets)-1].Empty() {
+		t.Error("unsampled error trace in errors bucket")
+	}
+
+	f.LatencyMu.RLock()
+	total := f.Latency.Total().(*histogram).total()
+	f.LatencyMu.RUnlock()
+	if total != 8 {
+		t.Errorf("latency histogram has %d samples; want 8", total)
+	}
+}
+
+func TestUnsampledTraceAllocs(t *testing.T) {
+	SetSampler(func(family, title string) bool { return false })
+	defer SetSampler(nil)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tr := New("unsampled.allocs", "title")
+		tr.LazyLog(s{}, false)
+		tr.LazyPrintf("event")
+		tr.Finish()
+	})
+	// The trace itself plus the histogram sample recorded on Finish.
+	if allocs > 3 {
+		t.Errorf("unsampled trace made %v allocations; want at most 3", allocs)
+	}
+}
+
+func TestLatencyBuckets(t *testing.T) {
+	SetLatencyBuckets([]time.Duration{2 * time.Second, 0, 500 * time.Microsecond, 2 * time.Second})
+	defer SetLatencyBuckets(nil)
+
+	const fam = "latency.buckets"
+	for _, d := range []time.Duration{0, time.Millisecond, 3 * time.Second} {
+		tr := New(fam, d.String())
+		tr.(*trace).Start = time.Now().Add(-d)
+		tr.Finish()
+	}
+
+	f := getFamily(fam, false)
+	want := []struct {
+		cond   string
+		titles []string
+	}{
+		{"≥0s", []string{"0s", "1ms", "3s"}},
+		{"≥0.0005s", []string{"1ms", "3s"}},
+		{"≥2s", []string{"3s"}},
+		{"errors", nil},
+	}
+	if len(f.Buckets) != len(want) {
+		t.Fatalf("got %d buckets; want %d", len(f.Buckets), len(want))
+	}
+	for i, w := range want {
+		b := f.Buckets[i]
+		if got := b.Cond.String(); got != w.cond {
+			t.Errorf("bucket %d: cond = %q; want %q", i, got, w.cond)
+		}
+		trl := b.Copy(false)
+		var titles []string
+		for _, tr := range trl {
+			titles = append(titles, tr.Title)
+		}
+		trl.Free()
+		sort.Strings(titles)
+		if !reflect.DeepEqual(titles, w.titles) {
+			t.Errorf("bucket %d (%s): traces = %q; want %q", i, w.cond, titles, w.titles)
+		}
+	}
+
+	var buf bytes.Buffer
+	Render(&buf, httptest.NewRequest("GET", "/debug/requests", nil), false)
+	if !strings.Contains(buf.String(), "[≥0.0005s]") {
+		t.Error("rendered page does not include custom latency bucket")
+	}
+
+	SetLatencyBuckets(nil)
+	if f := newFamily(); len(f.Buckets) != len(defaultLatencyBuckets)+1 {
+		t.Errorf("got %d buckets after reset; want %d", len(f.Buckets), len(defaultLatencyBuckets)+1)
+	}
+}
+
+func TestChildTraces(t *testing.T) {
+	const fam = "child.traces"
+	parent := New(fam, "parent")
+	parent.LazyPrintf("start")
+	child1 := NewChild(parent, fam, "child1")
+	child1.LazyPrintf("child1 event")
+	child2 := NewChild(parent, fam, "child2")
+	child2.LazyLog(s{}, false)
+
+	pid, id1, id2 := parent.(*trace).ID, child1.(*trace).ID, child2.(*trace).ID
+	if got := child1.(*trace).ParentID; got != pid {
+		t.Errorf("child1 ParentID = %d; want %d", got, pid)
+	}
+	if got := child2.(*trace).ParentID; got != pid {
+		t.Errorf("child2 ParentID = %d; want %d", got, pid)
+	}
+
+	var buf bytes.Buffer
+	Render(&buf, httptest.NewRequest("GET", "/debug/requests?fam="+fam+"&b=-1&exp=1", nil), false)
+	page := buf.String()
+	for _, want := range []string{
+		fmt.Sprintf("#%d, child of #%d", id1, pid),
+		fmt.Sprintf("#%d, child of #%d", id2, pid),
+		fmt.Sprintf("[#%d child1] child1 event", id1),
+		fmt.Sprintf("[#%d child2] lazy string", id2),
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("rendered page does not include %q:\n%s", want, page)
+		}
+	}
+	// The children are listed after their parent, although they started
+	// later.
+	p := strings.Index(page, ">parent <")
+	if c1, c2 := strings.Index(page, ">child1 <"), strings.Index(page, ">child2 <"); p < 0 || c1 < p || c2 < p {
+		t.Errorf("children are not listed after their parent:\n%s", page)
+	}
+
+	child1.Finish()
+	child2.Finish()
+	parent.Finish()
+	// Events of a child that outlives its parent are not logged to it.
+	parent2 := New(fam, "parent2")
+	child3 := NewChild(parent2, fam, "child3")
+	parent2.Finish()
+	child3.LazyPrintf("late")
+	if n := len(parent2.(*trace).Events()); n != 0 {
+		t.Errorf("finished parent has %d events; want 0", n)
+	}
+	child3.Finish()
+
+	// A Trace from elsewhere can't have children, so it gets a plain one.
+	child4 := NewChild(foreignTrace{parent2}, fam, "child4")
+	if got := child4.(*trace).ParentID; got != 0 {
+		t.Errorf("child of a foreign trace has ParentID %d; want 0", got)
+	}
+	child4.Finish()
+}
+
+// foreignTrace is a Trace implemented outside of this package.
+type foreignTrace struct{ Trace }
+
 // TestParseTemplate checks that all templates used by this package are valid
 // as they are parsed on first usage
 func TestParseTemplate(t *testing.T) {
@@ -0,0 +1,219 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdav
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+)
+
+// ChrootFileSystem returns a FileSystem that serves the tree rooted at the
+// directory root of fs. The name "/" refers to root, and names are cleaned
+// before they are joined to root, so a name such as "/../x" cannot escape
+// it.
+//
+// The virtual root directory can be neither removed nor renamed.
+func ChrootFileSystem(fs FileSystem, root string) FileSystem {
+	return &chrootFS{fs: fs, root: slashClean(root)}
+}
+
+type chrootFS struct {
+	fs   FileSystem
+	root string
+}
+
+func (c *chrootFS) resolve(name string) string {
+	if strings.Contains(name, "\x00") {
+		return ""
+	}
+	return path.Join(c.root, slashClean(name))
+}
+
+func (c *chrootFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if name = c.resolve(name); name == "" {
+		return os.ErrNotExist
+	}
+	return c.fs.Mkdir(ctx, name, perm)
+}
+
+func (c *chrootFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (File, error) {
+	if name = c.resolve(name); name == "" {
+		return nil, os.ErrNotExist
+	}
+	return c.fs.OpenFile(ctx, name, flag, perm)
+}
+
+func (c *chrootFS) RemoveAll(ctx context.Context, name string) error {
+	if name = c.resolve(name); name == "" {
+		return os.ErrNotExist
+	}
+	if name == c.root {
+		// Prohibit removing the virtual root directory.
+		return os.ErrInvalid
+	}
+	return c.fs.RemoveAll(ctx, name)
+}
+
+func (c *chrootFS) Rename(ctx context.Context, oldName, newName string) error {
+	if oldName = c.resolve(oldName); oldName == "" {
+		return os.ErrNotExist
+	}
+	if newName = c.resolve(newName); newName == "" {
+		return os.ErrNotExist
+	}
+	if oldName == c.root || newName == c.root {
+		// Prohibit renaming from or to the virtual root directory.
+		return os.ErrInvalid
+	}
+	return c.fs.Rename(ctx, oldName, newName)
+}
+
+func (c *chrootFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if name = c.resolve(name); name == "" {
+		return nil, os.ErrNotExist
+	}
+	return c.fs.Stat(ctx, name)
+}
+
+// Quota implements the QuotaFileSystem interface. It returns
+// ErrNotImplemented if the underlying FileSystem does not.
+func (c *chrootFS) Quota(ctx context.Context, name string) (used, available int64, err error) {
+	if name = c.resolve(name); name == "" {
+		return 0, 0, os.ErrNotExist
+	}
+	return findQuota(ctx, c.fs, name)
+}
+
+// ETag implements the ETagFileSystem interface. It returns
+// ErrNotImplemented if the underlying FileSystem does not.
+func (c *chrootFS) ETag(ctx context.Context, name string, fi os.FileInfo) (string, error) {
+	if name = c.resolve(name); name == "" {
+		return "", os.ErrNotExist
+	}
+	efs, ok := c.fs.(ETagFileSystem)
+	if !ok {
+		return "", ErrNotImplemented
+	}
+	return efs.ETag(ctx, name, fi)
+}
+
+// DeadPropsHolder implements the DeadPropsFileSystem interface. It returns
+// ErrNotImplemented if the underlying FileSystem does not.
+func (c *chrootFS) DeadPropsHolder(ctx context.Context, name string) (DeadPropsHolder, error) {
+	if name = c.resolve(name); name == "" {
+		return nil, os.ErrNotExist
+	}
+	dfs, ok := c.fs.(DeadPropsFileSystem)
+	if !ok {
+		return nil, ErrNotImplemented
+	}
+	return dfs.DeadPropsHolder(ctx, name)
+}
+
+// PrefixFileSystem returns a FileSystem that serves the tree of fs under
+// the directory name prefix, so that the name prefix+"/x" refers to the
+// name "/x" of fs. Names that are not prefix itself or below it do not
+// exist.
+//
+// PrefixFileSystem is the inverse of ChrootFileSystem.
+func PrefixFileSystem(fs FileSystem, prefix string) FileSystem {
+	return &prefixFS{fs: fs, prefix: slashClean(prefix)}
+}
+
+type prefixFS struct {
+	fs     FileSystem
+	prefix string
+}
+
+func (p *prefixFS) resolve(name string) string {
+	if strings.Contains(name, "\x00") {
+		return ""
+	}
+	name = slashClean(name)
+	switch {
+	case p.prefix == "/":
+		return name
+	case name == p.prefix:
+		return "/"
+	case strings.HasPrefix(name, p.prefix) && name[len(p.prefix)] == '/':
+		return name[len(p.prefix):]
+	}
+	return ""
+}
+
+func (p *prefixFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if name = p.resolve(name); name == "" {
+		return os.ErrNotExist
+	}
+	return p.fs.Mkdir(ctx, name, perm)
+}
+
+func (p *prefixFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (File, error) {
+	if name = p.resolve(name); name == "" {
+		return nil, os.ErrNotExist
+	}
+	return p.fs.OpenFile(ctx, name, flag, perm)
+}
+
+func (p *prefixFS) RemoveAll(ctx context.Context, name string) error {
+	if name = p.resolve(name); name == "" {
+		return os.ErrNotExist
+	}
+	return p.fs.RemoveAll(ctx, name)
+}
+
+func (p *prefixFS) Rename(ctx context.Context, oldName, newName string) error {
+	if oldName = p.resolve(oldName); oldName == "" {
+		return os.ErrNotExist
+	}
+	if newName = p.resolve(newName); newName == "" {
+		return os.ErrNotExist
+	}
+	return p.fs.Rename(ctx, oldName, newName)
+}
+
+func (p *prefixFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if name = p.resolve(name); name == "" {
+		return nil, os.ErrNotExist
+	}
+	return p.fs.Stat(ctx, name)
+}
+
+// Quota implements the QuotaFileSystem interface. It returns
+// ErrNotImplemented if the underlying FileSystem does not.
+func (p *prefixFS) Quota(ctx context.Context, name string) (used, available int64, err error) {
+	if name = p.resolve(name); name == "" {
+		return 0, 0, os.ErrNotExist
+	}
+	return findQuota(ctx, p.fs, name)
+}
+
+// ETag implements the ETagFileSystem interface. It returns
+// ErrNotImplemented if the underlying FileSystem does not.
+func (p *prefixFS) ETag(ctx context.Context, name string, fi os.FileInfo) (string, error) {
+	if name = p.resolve(name); name == "" {
+		return "", os.ErrNotExist
+	}
+	efs, ok := p.fs.(ETagFileSystem)
+	if !ok {
+		return "", ErrNotImplemented
+	}
+	return efs.ETag(ctx, name, fi)
+}
+
+// DeadPropsHolder implements the DeadPropsFileSystem interface. It returns
+// ErrNotImplemented if the underlying FileSystem does not.
+func (p *prefixFS) DeadPropsHolder(ctx context.Context, name string) (DeadPropsHolder, error) {
+	if name = p.resolve(name); name == "" {
+		return nil, os.ErrNotExist
+	}
+	dfs, ok := p.fs.(DeadPropsFileSystem)
+	if !ok {
+		return nil, ErrNotImplemented
+	}
+	return dfs.DeadPropsHolder(ctx, name)
+}
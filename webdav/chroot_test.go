@@ -0,0 +1,165 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdav
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeMemFile(t *testing.T, fs FileSystem, name, content string) {
+	t.Helper()
+	f, err := fs.OpenFile(context.Background(), name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		t.Fatalf("OpenFile(%q): %v", name, err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte(content)); err != nil {
+		t.Fatalf("Write(%q): %v", name, err)
+	}
+}
+
+func readMemFile(t *testing.T, fs FileSystem, name string) string {
+	t.Helper()
+	f, err := fs.OpenFile(context.Background(), name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile(%q): %v", name, err)
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll(%q): %v", name, err)
+	}
+	return string(b)
+}
+
+// serveFS sends a request to a Handler serving fs, and returns the status
+// code and the body of the response.
+func serveFS(fs FileSystem, method, target, body string, headers ...string) (int, string) {
+	h := &Handler{
+		FileSystem: fs,
+		LockSystem: NewMemLS(),
+	}
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	// Set the path directly, as httptest.NewRequest would clean it.
+	req.URL.Path = target
+	for len(headers) >= 2 {
+		req.Header.Add(headers[0], headers[1])
+		headers = headers[2:]
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+var hrefRe = regexp.MustCompile(`<D:href>([^<]*)</D:href>`)
+
+func propfindHrefs(fs FileSystem, target string) []string {
+	_, body := serveFS(fs, "PROPFIND", target, "", "Depth", "1")
+	var hrefs []string
+	for _, m := range hrefRe.FindAllStringSubmatch(body, -1) {
+		hrefs = append(hrefs, m[1])
+	}
+	sort.Strings(hrefs)
+	return hrefs
+}
+
+func TestChrootFileSystem(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemFS()
+	for _, name := range []string{"/home", "/home/alice"} {
+		if err := mem.Mkdir(ctx, name, 0777); err != nil {
+			t.Fatalf("Mkdir(%q): %v", name, err)
+		}
+	}
+	writeMemFile(t, mem, "/secret.txt", "secret")
+	writeMemFile(t, mem, "/home/alice/doc.txt", "doc")
+	fs := ChrootFileSystem(mem, "/home/alice")
+
+	// Escapes from the root are blocked.
+	for _, name := range []string{"../secret.txt", "/../secret.txt", "/../../secret.txt", "/a/../../secret.txt"} {
+		if _, err := fs.Stat(ctx, name); !os.IsNotExist(err) {
+			t.Errorf("Stat(%q): got %v, want not exist", name, err)
+		}
+		if code, _ := serveFS(fs, "GET", name, ""); code != http.StatusNotFound {
+			t.Errorf("GET %q: got status code %d, want %d", name, code, http.StatusNotFound)
+		}
+	}
+	if code, _ := serveFS(fs, "PUT", "/../escaped.txt", "x"); code != http.StatusCreated {
+		t.Errorf("PUT: got status code %d, want %d", code, http.StatusCreated)
+	}
+	if _, err := mem.Stat(ctx, "/escaped.txt"); !os.IsNotExist(err) {
+		t.Errorf("PUT escaped the root: Stat: %v", err)
+	}
+	if _, err := mem.Stat(ctx, "/home/alice/escaped.txt"); err != nil {
+		t.Errorf("Stat: %v", err)
+	}
+
+	// Paths are remapped below the root.
+	if code, body := serveFS(fs, "GET", "/doc.txt", ""); code != http.StatusOK || body != "doc" {
+		t.Errorf("GET: got %d %q, want %d %q", code, body, http.StatusOK, "doc")
+	}
+	if code, _ := serveFS(fs, "PUT", "/new.txt", "new"); code != http.StatusCreated {
+		t.Errorf("PUT: got status code %d, want %d", code, http.StatusCreated)
+	}
+	if got := readMemFile(t, mem, "/home/alice/new.txt"); got != "new" {
+		t.Errorf("PUT: got content %q, want %q", got, "new")
+	}
+	want := []string{"/", "/doc.txt", "/escaped.txt", "/new.txt"}
+	if got := propfindHrefs(fs, "/"); !reflect.DeepEqual(got, want) {
+		t.Errorf("PROPFIND: got hrefs %q, want %q", got, want)
+	}
+
+	// The virtual root is protected.
+	if err := fs.RemoveAll(ctx, "/"); err != os.ErrInvalid {
+		t.Errorf("RemoveAll: got %v, want %v", err, os.ErrInvalid)
+	}
+	if err := fs.Rename(ctx, "/", "/x"); err != os.ErrInvalid {
+		t.Errorf("Rename: got %v, want %v", err, os.ErrInvalid)
+	}
+}
+
+func TestPrefixFileSystem(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemFS()
+	writeMemFile(t, mem, "/doc.txt", "doc")
+	fs := PrefixFileSystem(mem, "/alias")
+
+	for _, name := range []string{"/doc.txt", "/aliasdoc.txt", "/alias/../doc.txt", "/other/doc.txt"} {
+		if _, err := fs.Stat(ctx, name); !os.IsNotExist(err) {
+			t.Errorf("Stat(%q): got %v, want not exist", name, err)
+		}
+		if code, _ := serveFS(fs, "GET", name, ""); code != http.StatusNotFound {
+			t.Errorf("GET %q: got status code %d, want %d", name, code, http.StatusNotFound)
+		}
+	}
+
+	if code, body := serveFS(fs, "GET", "/alias/doc.txt", ""); code != http.StatusOK || body != "doc" {
+		t.Errorf("GET: got %d %q, want %d %q", code, body, http.StatusOK, "doc")
+	}
+	if code, _ := serveFS(fs, "PUT", "/alias/new.txt", "new"); code != http.StatusCreated {
+		t.Errorf("PUT: got status code %d, want %d", code, http.StatusCreated)
+	}
+	if got := readMemFile(t, mem, "/new.txt"); got != "new" {
+		t.Errorf("PUT: got content %q, want %q", got, "new")
+	}
+	want := []string{"/alias/", "/alias/doc.txt", "/alias/new.txt"}
+	if got := propfindHrefs(fs, "/alias/"); !reflect.DeepEqual(got, want) {
+		t.Errorf("PROPFIND: got hrefs %q, want %q", got, want)
+	}
+
+	// ChrootFileSystem undoes PrefixFileSystem.
+	if code, body := serveFS(ChrootFileSystem(fs, "/alias"), "GET", "/doc.txt", ""); code != http.StatusOK || body != "doc" {
+		t.Errorf("GET: got %d %q, want %d %q", code, body, http.StatusOK, "doc")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdav
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressedTypes lists the media types whose representations are
+// already compressed, and so are not worth encoding again.
+var compressedTypes = map[string]bool{
+	"application/gzip":             true,
+	"application/pdf":              true,
+	"application/vnd.rar":          true,
+	"application/x-7z-compressed":  true,
+	"application/x-bzip2":          true,
+	"application/x-compress":       true,
+	"application/x-gzip":           true,
+	"application/x-rar-compressed": true,
+	"application/x-xz":             true,
+	"application/zip":              true,
+	"application/zstd":             true,
+	"font/woff":                    true,
+	"font/woff2":                   true,
+}
+
+// isCompressible reports whether content of type ctype is likely to
+// benefit from a content coding.
+func isCompressible(ctype string) bool {
+	mt, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return false
+	}
+	if compressedTypes[mt] {
+		return false
+	}
+	switch {
+	case mt == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mt, "image/"),
+		strings.HasPrefix(mt, "audio/"),
+		strings.HasPrefix(mt, "video/"):
+		return false
+	}
+	return true
+}
+
+// negotiateEncoding returns the content coding, "gzip" or "deflate",
+// to use for a request with the Accept-Encoding header value hdr, or
+// the empty string if the client accepts neither. Gzip is preferred
+// when both are equally acceptable.
+func negotiateEncoding(hdr string) string {
+	best, bestQ := "", 0.0
+	q := map[string]float64{}
+	for _, s := range strings.Split(hdr, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		coding, v := s, 1.0
+		if i := strings.IndexByte(s, ';'); i >= 0 {
+			coding = strings.TrimSpace(s[:i])
+			param := strings.TrimSpace(s[i+1:])
+			if !strings.HasPrefix(param, "q=") && !strings.HasPrefix(param, "Q=") {
+				continue
+			}
+			f, err := strconv.ParseFloat(param[2:], 64)
+			if err != nil || f < 0 || f > 1 {
+				continue
+			}
+			v = f
+		}
+		q[strings.ToLower(coding)] = v
+	}
+	for _, coding := range []string{"gzip", "deflate"} {
+		v, ok := q[coding]
+		if !ok {
+			if v, ok = q["*"]; !ok {
+				continue
+			}
+		}
+		if v > bestQ {
+			best, bestQ = coding, v
+		}
+	}
+	return best
+}
+
+// compressResponseWriter encodes the body of a 200 OK response with
+// the given content coding. Other responses are passed through as is.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	wc          io.WriteCloser // non-nil if the body is being encoded
+	wroteHeader bool
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusOK {
+		h := w.Header()
+		// The length of the encoded body is not known in advance.
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		switch w.encoding {
+		case "gzip":
+			w.wc = gzip.NewWriter(w.ResponseWriter)
+		case "deflate":
+			// The "deflate" content coding is the zlib format, as per
+			// RFC 7230 section 4.2.2.
+			w.wc = zlib.NewWriter(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.wc != nil {
+		return w.wc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Close flushes any buffered encoded data and writes the trailer of
+// the encoded stream.
+func (w *compressResponseWriter) Close() error {
+	if w.wc != nil {
+		return w.wc.Close()
+	}
+	return nil
+}
@@ -128,6 +128,15 @@ func (d Dir) Stat(ctx context.Context, name string) (os.FileInfo, error) {
 	return os.Stat(name)
 }
 
+// Quota implements the QuotaFileSystem interface, reporting the space
+// used and available on the native file system containing the named file.
+func (d Dir) Quota(ctx context.Context, name string) (used, available int64, err error) {
+	if name = d.resolve(name); name == "" {
+		return 0, 0, os.ErrNotExist
+	}
+	return statfs(name)
+}
+
 // NewMemFS returns a new in-memory FileSystem implementation.
 func NewMemFS() FileSystem {
 	return &memFS{
@@ -609,6 +618,12 @@ func (f *memFile) Write(p []byte) (int, error) {
 //
 // See section 9.9.4 for when various HTTP status codes apply.
 func moveFiles(ctx context.Context, fs FileSystem, src, dst string, overwrite bool) (status int, err error) {
+	if _, err := fs.Stat(ctx, src); err != nil {
+		if os.IsNotExist(err) {
+			return http.StatusNotFound, err
+		}
+		return http.StatusInternalServerError, err
+	}
 	created := false
 	if _, err := fs.Stat(ctx, dst); err != nil {
 		if !os.IsNotExist(err) {
@@ -627,6 +642,11 @@ func moveFiles(ctx context.Context, fs FileSystem, src, dst string, overwrite bo
 		return http.StatusPreconditionFailed, os.ErrExist
 	}
 	if err := fs.Rename(ctx, src, dst); err != nil {
+		if os.IsNotExist(err) {
+			// Section 9.9.4 says that a 409 (Conflict) status is returned
+			// when intermediate collections of the destination are missing.
+			return http.StatusConflict, err
+		}
 		return http.StatusForbidden, err
 	}
 	if created {
@@ -635,14 +655,14 @@ func moveFiles(ctx context.Context, fs FileSystem, src, dst string, overwrite bo
 	return http.StatusNoContent, nil
 }
 
-func copyProps(dst, src File) error {
-	d, ok := dst.(DeadPropsHolder)
-	if !ok {
-		return nil
+func copyProps(ctx context.Context, fs FileSystem, dst, src string, dstFile, srcFile File) error {
+	d, err := findDeadPropsHolder(ctx, fs, dst, dstFile)
+	if err != nil || d == nil {
+		return err
 	}
-	s, ok := src.(DeadPropsHolder)
-	if !ok {
-		return nil
+	s, err := findDeadPropsHolder(ctx, fs, src, srcFile)
+	if err != nil || s == nil {
+		return err
 	}
 	m, err := s.DeadProps()
 	if err != nil {
@@ -703,6 +723,9 @@ func copyFiles(ctx context.Context, fs FileSystem, src, dst string, overwrite bo
 
 	if srcStat.IsDir() {
 		if err := fs.Mkdir(ctx, dst, srcPerm); err != nil {
+			if os.IsNotExist(err) {
+				return http.StatusConflict, err
+			}
 			return http.StatusForbidden, err
 		}
 		if depth == infiniteDepth {
@@ -732,7 +755,7 @@ func copyFiles(ctx context.Context, fs FileSystem, src, dst string, overwrite bo
 
 		}
 		_, copyErr := io.Copy(dstFile, srcFile)
-		propsErr := copyProps(dstFile, srcFile)
+		propsErr := copyProps(ctx, fs, dst, src, dstFile, srcFile)
 		closeErr := dstFile.Close()
 		if copyErr != nil {
 			return http.StatusInternalServerError, copyErr
@@ -106,6 +106,9 @@ var liveProps = map[xml.Name]struct {
 	findFn func(context.Context, FileSystem, LockSystem, string, os.FileInfo) (string, error)
 	// dir is true if the property applies to directories.
 	dir bool
+	// named is true if the property is only returned when requested by
+	// name, and not by an allprop or propname PROPFIND.
+	named bool
 }{
 	{Space: "DAV:", Local: "resourcetype"}: {
 		findFn: findResourceType,
@@ -158,6 +161,19 @@ var liveProps = map[xml.Name]struct {
 		findFn: findSupportedLock,
 		dir:    true,
 	},
+
+	// RFC 4331 section 3 says that the quota properties "SHOULD NOT be
+	// returned by an allprop PROPFIND".
+	{Space: "DAV:", Local: "quota-available-bytes"}: {
+		findFn: findQuotaAvailableBytes,
+		dir:    true,
+		named:  true,
+	},
+	{Space: "DAV:", Local: "quota-used-bytes"}: {
+		findFn: findQuotaUsedBytes,
+		dir:    true,
+		named:  true,
+	},
 }
 
 // TODO(nigeltao) merge props and allprop?
@@ -179,7 +195,11 @@ func props(ctx context.Context, fs FileSystem, ls LockSystem, name string, pname
 	isDir := fi.IsDir()
 
 	var deadProps map[xml.Name]Property
-	if dph, ok := f.(DeadPropsHolder); ok {
+	dph, err := findDeadPropsHolder(ctx, fs, name, f)
+	if err != nil {
+		return nil, err
+	}
+	if dph != nil {
 		deadProps, err = dph.DeadProps()
 		if err != nil {
 			return nil, err
@@ -197,6 +217,12 @@ func props(ctx context.Context, fs FileSystem, ls LockSystem, name string, pname
 		// Otherwise, it must either be a live property or we don't know it.
 		if prop := liveProps[pn]; prop.findFn != nil && (prop.dir || !isDir) {
 			innerXML, err := prop.findFn(ctx, fs, ls, name, fi)
+			if err == ErrNotImplemented {
+				pstatNotFound.Props = append(pstatNotFound.Props, Property{
+					XMLName: pn,
+				})
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -227,7 +253,11 @@ func propnames(ctx context.Context, fs FileSystem, ls LockSystem, name string) (
 	isDir := fi.IsDir()
 
 	var deadProps map[xml.Name]Property
-	if dph, ok := f.(DeadPropsHolder); ok {
+	dph, err := findDeadPropsHolder(ctx, fs, name, f)
+	if err != nil {
+		return nil, err
+	}
+	if dph != nil {
 		deadProps, err = dph.DeadProps()
 		if err != nil {
 			return nil, err
@@ -236,7 +266,7 @@ func propnames(ctx context.Context, fs FileSystem, ls LockSystem, name string) (
 
 	pnames := make([]xml.Name, 0, len(liveProps)+len(deadProps))
 	for pn, prop := range liveProps {
-		if prop.findFn != nil && (prop.dir || !isDir) {
+		if prop.findFn != nil && !prop.named && (prop.dir || !isDir) {
 			pnames = append(pnames, pn)
 		}
 	}
@@ -305,12 +335,19 @@ loop:
 		return makePropstats(pstatForbidden, pstatFailedDep), nil
 	}
 
-	f, err := fs.OpenFile(ctx, name, os.O_RDWR, 0)
+	dph, err := findDeadPropsHolder(ctx, fs, name, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	if dph, ok := f.(DeadPropsHolder); ok {
+	if dph == nil {
+		f, err := fs.OpenFile(ctx, name, os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		dph, _ = f.(DeadPropsHolder)
+	}
+	if dph != nil {
 		ret, err := dph.Patch(patches)
 		if err != nil {
 			return nil, err
@@ -325,8 +362,8 @@ loop:
 		}
 		return ret, nil
 	}
-	// The file doesn't implement the optional DeadPropsHolder interface, so
-	// all patches are forbidden.
+	// Neither the file nor the file system provides the optional
+	// DeadPropsHolder interface, so all patches are forbidden.
 	pstat := Propstat{Status: http.StatusForbidden}
 	for _, patch := range patches {
 		for _, p := range patch.Props {
@@ -336,6 +373,42 @@ loop:
 	return []Propstat{pstat}, nil
 }
 
+// DeadPropsFileSystem is an optional interface for a FileSystem.
+//
+// If this interface is defined then it will be used to hold the dead
+// properties of the named resource, in preference to the optional
+// DeadPropsHolder interface of the File returned by OpenFile. This lets a
+// FileSystem persist dead properties independently of open files, for
+// example in extended attributes or in a sidecar file, so that they survive
+// a restart of the Handler.
+//
+// The FileSystem is responsible for keeping the dead properties with the
+// resource when it is renamed, and for discarding them when it is removed.
+type DeadPropsFileSystem interface {
+	// DeadPropsHolder returns the DeadPropsHolder for the named resource,
+	// or an error such as os.ErrNotExist if there is no such resource.
+	//
+	// If this returns error ErrNotImplemented then the DeadPropsHolder
+	// interface of the opened File, if any, is used instead.
+	DeadPropsHolder(ctx context.Context, name string) (DeadPropsHolder, error)
+}
+
+// findDeadPropsHolder returns the DeadPropsHolder for the resource name,
+// which has been opened as f, or nil if it has not been opened. It returns a
+// nil DeadPropsHolder if neither fs nor f hold dead properties.
+func findDeadPropsHolder(ctx context.Context, fs FileSystem, name string, f File) (DeadPropsHolder, error) {
+	if dfs, ok := fs.(DeadPropsFileSystem); ok {
+		dph, err := dfs.DeadPropsHolder(ctx, name)
+		if err != ErrNotImplemented {
+			return dph, err
+		}
+	}
+	if dph, ok := f.(DeadPropsHolder); ok {
+		return dph, nil
+	}
+	return nil, nil
+}
+
 func escapeXML(s string) string {
 	for i := 0; i < len(s); i++ {
 		// As an optimization, if s contains only ASCII letters, digits or a
@@ -447,7 +520,31 @@ type ETager interface {
 	ETag(ctx context.Context) (string, error)
 }
 
+// ETagFileSystem is an optional interface for a FileSystem.
+//
+// If this interface is defined then it will be used to read the ETag
+// for the named resource, in preference to the ETager interface of its
+// os.FileInfo. The ETag is reported by GET, HEAD and PUT responses and
+// the getetag property, and is used to evaluate conditional GET and HEAD
+// requests. This lets a FileSystem supply, for example, an ETag derived
+// from a hash of the content.
+type ETagFileSystem interface {
+	// ETag returns an ETag for the named resource, which has the given
+	// os.FileInfo. This should be of the form "value" or W/"value"
+	//
+	// If this returns error ErrNotImplemented then the error will
+	// be ignored and the ETager interface or the base implementation
+	// will be used instead.
+	ETag(ctx context.Context, name string, fi os.FileInfo) (string, error)
+}
+
 func findETag(ctx context.Context, fs FileSystem, ls LockSystem, name string, fi os.FileInfo) (string, error) {
+	if efs, ok := fs.(ETagFileSystem); ok {
+		etag, err := efs.ETag(ctx, name, fi)
+		if err != ErrNotImplemented {
+			return etag, err
+		}
+	}
 	if do, ok := fi.(ETager); ok {
 		etag, err := do.ETag(ctx)
 		if err != ErrNotImplemented {
@@ -467,3 +564,43 @@ func findSupportedLock(ctx context.Context, fs FileSystem, ls LockSystem, name s
 		`<D:locktype><D:write/></D:locktype>` +
 		`</D:lockentry>`, nil
 }
+
+// QuotaFileSystem is an optional interface for a FileSystem.
+//
+// If this interface is defined then it will be used to report the
+// quota-available-bytes and quota-used-bytes properties of RFC 4331.
+//
+// If this interface is not defined those properties are reported as
+// not found.
+type QuotaFileSystem interface {
+	// Quota returns the number of bytes used by and still available
+	// to the named resource.
+	//
+	// If this returns error ErrNotImplemented then the properties are
+	// reported as not found.
+	Quota(ctx context.Context, name string) (used, available int64, err error)
+}
+
+func findQuota(ctx context.Context, fs FileSystem, name string) (used, available int64, err error) {
+	qfs, ok := fs.(QuotaFileSystem)
+	if !ok {
+		return 0, 0, ErrNotImplemented
+	}
+	return qfs.Quota(ctx, name)
+}
+
+func findQuotaAvailableBytes(ctx context.Context, fs FileSystem, ls LockSystem, name string, fi os.FileInfo) (string, error) {
+	_, available, err := findQuota(ctx, fs, name)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(available, 10), nil
+}
+
+func findQuotaUsedBytes(ctx context.Context, fs FileSystem, ls LockSystem, name string, fi os.FileInfo) (string, error) {
+	used, _, err := findQuota(ctx, fs, name)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(used, 10), nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package webdav
+
+import "golang.org/x/sys/unix"
+
+// statfs returns the number of bytes in use and available to an
+// unprivileged user on the file system containing the named file.
+func statfs(name string) (used, available int64, err error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(name, &st); err != nil {
+		return 0, 0, err
+	}
+	bsize := int64(st.Bsize)
+	used = (int64(st.Blocks) - int64(st.Bfree)) * bsize
+	if avail := int64(st.Bavail); avail > 0 {
+		available = avail * bsize
+	}
+	return used, available, nil
+}
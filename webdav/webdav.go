@@ -28,6 +28,11 @@ type Handler struct {
 	// Logger is an optional error logger. If non-nil, it will be called
 	// for all HTTP requests.
 	Logger func(*http.Request, error)
+	// Compress enables transparent gzip or deflate encoding of GET
+	// responses, as negotiated by the request's Accept-Encoding header.
+	// Range requests and content types that are already compressed,
+	// such as most images and archives, are served unencoded.
+	Compress bool
 }
 
 func (h *Handler) stripPrefix(p string) (string, int, error) {
@@ -215,8 +220,28 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request) (sta
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
+	if h.Compress && r.Method == "GET" && r.Header.Get("Range") == "" {
+		ctype, err := findContentType(ctx, h.FileSystem, h.LockSystem, reqPath, fi)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if isCompressible(ctype) {
+			w.Header().Set("Content-Type", ctype)
+			w.Header().Add("Vary", "Accept-Encoding")
+			if enc := negotiateEncoding(r.Header.Get("Accept-Encoding")); enc != "" {
+				// The encoded representation needs an entity tag of its own.
+				w.Header().Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+enc+`"`)
+				cw := &compressResponseWriter{ResponseWriter: w, encoding: enc}
+				http.ServeContent(cw, r, reqPath, fi.ModTime(), f)
+				return 0, cw.Close()
+			}
+		}
+	}
 	w.Header().Set("ETag", etag)
-	// Let ServeContent determine the Content-Type header.
+	// Let ServeContent determine the Content-Type header. It also serves
+	// Range requests, as multipart/byteranges for more than one range, and
+	// validates If-Range against the ETag set above and the modification
+	// time.
 	http.ServeContent(w, r, reqPath, fi.ModTime(), f)
 	return 0, nil
 }
@@ -345,6 +370,18 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request) (status
 		return http.StatusForbidden, errDestinationEqualsSource
 	}
 
+	// Section 10.6 says that "If the Overwrite header is not included in a
+	// COPY or MOVE request, then the resource MUST treat the request as if it
+	// has an Overwrite header of value "T"."
+	overwrite := true
+	switch r.Header.Get("Overwrite") {
+	case "", "T":
+	case "F":
+		overwrite = false
+	default:
+		return http.StatusBadRequest, errInvalidOverwrite
+	}
+
 	ctx := r.Context()
 
 	if r.Method == "COPY" {
@@ -370,7 +407,7 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request) (status
 				return http.StatusBadRequest, errInvalidDepth
 			}
 		}
-		return copyFiles(ctx, h.FileSystem, src, dst, r.Header.Get("Overwrite") != "F", depth, 0)
+		return copyFiles(ctx, h.FileSystem, src, dst, overwrite, depth, 0)
 	}
 
 	release, status, err := h.confirmLocks(r, src, dst)
@@ -387,7 +424,7 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request) (status
 			return http.StatusBadRequest, errInvalidDepth
 		}
 	}
-	return moveFiles(ctx, h.FileSystem, src, dst, r.Header.Get("Overwrite") == "T")
+	return moveFiles(ctx, h.FileSystem, src, dst, overwrite)
 }
 
 func (h *Handler) handleLock(w http.ResponseWriter, r *http.Request) (retStatus int, retErr error) {
@@ -722,6 +759,7 @@ var (
 	errInvalidIfHeader         = errors.New("webdav: invalid If header")
 	errInvalidLockInfo         = errors.New("webdav: invalid lock info")
 	errInvalidLockToken        = errors.New("webdav: invalid lock token")
+	errInvalidOverwrite        = errors.New("webdav: invalid overwrite")
 	errInvalidPropfind         = errors.New("webdav: invalid propfind")
 	errInvalidProppatch        = errors.New("webdav: invalid proppatch")
 	errInvalidResponse         = errors.New("webdav: invalid response")
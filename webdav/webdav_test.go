@@ -5,11 +5,17 @@
 package webdav
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/sha256"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -18,6 +24,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -347,3 +354,613 @@ func TestFilenameEscape(t *testing.T) {
 		}
 	}
 }
+
+func TestCompress(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	content := strings.Repeat("All work and no play makes Jack a dull boy.\n", 1000)
+	for _, name := range []string{"/a.txt", "/b.png"} {
+		f, err := fs.OpenFile(ctx, name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+		if err != nil {
+			t.Fatalf("name=%q: OpenFile: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("name=%q: Write: %v", name, err)
+		}
+		f.Close()
+	}
+	h := &Handler{
+		FileSystem: fs,
+		LockSystem: NewMemLS(),
+		Compress:   true,
+	}
+
+	testCases := []struct {
+		desc     string
+		name     string
+		headers  []string
+		wantCode int
+		wantEnc  string
+	}{
+		{"gzip", "/a.txt", []string{"Accept-Encoding", "gzip, deflate"}, http.StatusOK, "gzip"},
+		{"deflate", "/a.txt", []string{"Accept-Encoding", "deflate, gzip;q=0.5"}, http.StatusOK, "deflate"},
+		{"wildcard", "/a.txt", []string{"Accept-Encoding", "*"}, http.StatusOK, "gzip"},
+		{"refused", "/a.txt", []string{"Accept-Encoding", "gzip;q=0, br"}, http.StatusOK, ""},
+		{"identity", "/a.txt", nil, http.StatusOK, ""},
+		{"range", "/a.txt", []string{"Accept-Encoding", "gzip", "Range", "bytes=0-99"}, http.StatusPartialContent, ""},
+		{"already compressed", "/b.png", []string{"Accept-Encoding", "gzip"}, http.StatusOK, ""},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", tc.name, nil)
+		for hs := tc.headers; len(hs) >= 2; hs = hs[2:] {
+			req.Header.Add(hs[0], hs[1])
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.wantCode {
+			t.Errorf("%s: got status code %d, want %d", tc.desc, rec.Code, tc.wantCode)
+			continue
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != tc.wantEnc {
+			t.Errorf("%s: got Content-Encoding %q, want %q", tc.desc, got, tc.wantEnc)
+			continue
+		}
+		var r io.Reader = rec.Body
+		switch tc.wantEnc {
+		case "gzip":
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Errorf("%s: gzip.NewReader: %v", tc.desc, err)
+				continue
+			}
+			r = zr
+		case "deflate":
+			zr, err := zlib.NewReader(rec.Body)
+			if err != nil {
+				t.Errorf("%s: zlib.NewReader: %v", tc.desc, err)
+				continue
+			}
+			r = zr
+		}
+		if tc.wantEnc != "" {
+			if rec.Body.Len() >= len(content) {
+				t.Errorf("%s: got %d encoded bytes, want fewer than %d", tc.desc, rec.Body.Len(), len(content))
+			}
+			if got := rec.Header().Get("Content-Length"); got != "" {
+				t.Errorf("%s: got Content-Length %q, want none", tc.desc, got)
+			}
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("%s: ReadAll: %v", tc.desc, err)
+			continue
+		}
+		want := content
+		if tc.wantCode == http.StatusPartialContent {
+			want = content[:100]
+		}
+		if string(b) != want {
+			t.Errorf("%s: decoded body does not match the original content", tc.desc)
+		}
+	}
+}
+
+func TestGetRanges(t *testing.T) {
+	const content = "0123456789abcdefghijklmnopqrstuvwxyz"
+	fs := NewMemFS()
+	f, err := fs.OpenFile(context.Background(), "/file", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	if _, err := f.Write([]byte(content)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	f.Close()
+	h := &Handler{
+		FileSystem: fs,
+		LockSystem: NewMemLS(),
+	}
+	get := func(hdrs ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/file", nil)
+		for len(hdrs) >= 2 {
+			req.Header.Set(hdrs[0], hdrs[1])
+			hdrs = hdrs[2:]
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	etag := get().Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET: no ETag")
+	}
+
+	// A single range.
+	rec := get("Range", "bytes=10-15", "If-Range", etag)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("single range: got status code %d, want %d", rec.Code, http.StatusPartialContent)
+	}
+	if got, want := rec.Header().Get("Content-Range"), "bytes 10-15/36"; got != want {
+		t.Errorf("single range: got Content-Range %q, want %q", got, want)
+	}
+	if got, want := rec.Body.String(), content[10:16]; got != want {
+		t.Errorf("single range: got body %q, want %q", got, want)
+	}
+
+	// Multiple ranges.
+	rec = get("Range", "bytes=0-3,-4")
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("multiple ranges: got status code %d, want %d", rec.Code, http.StatusPartialContent)
+	}
+	mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("multiple ranges: got Content-Type %q, want multipart/byteranges", rec.Header().Get("Content-Type"))
+	}
+	wantParts := []struct{ contentRange, body string }{
+		{"bytes 0-3/36", content[:4]},
+		{"bytes 32-35/36", content[32:]},
+	}
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	for i, want := range wantParts {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("multiple ranges: part %d: %v", i, err)
+		}
+		if got := part.Header.Get("Content-Range"); got != want.contentRange {
+			t.Errorf("multiple ranges: part %d: got Content-Range %q, want %q", i, got, want.contentRange)
+		}
+		b, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatalf("multiple ranges: part %d: %v", i, err)
+		}
+		if string(b) != want.body {
+			t.Errorf("multiple ranges: part %d: got body %q, want %q", i, b, want.body)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("multiple ranges: got extra part, err=%v", err)
+	}
+
+	// An unsatisfiable range.
+	rec = get("Range", "bytes=100-200")
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable range: got status code %d, want %d", rec.Code, http.StatusRequestedRangeNotSatisfiable)
+	}
+	if got, want := rec.Header().Get("Content-Range"), "bytes */36"; got != want {
+		t.Errorf("unsatisfiable range: got Content-Range %q, want %q", got, want)
+	}
+
+	// A stale If-Range, by entity tag or by date, yields the whole content.
+	for _, ifRange := range []string{`"stale"`, "Mon, 02 Jan 2006 15:04:05 GMT"} {
+		rec = get("Range", "bytes=10-15", "If-Range", ifRange)
+		if rec.Code != http.StatusOK || rec.Body.String() != content {
+			t.Errorf("If-Range %s: got status code %d and body %q, want %d and the whole content",
+				ifRange, rec.Code, rec.Body.String(), http.StatusOK)
+		}
+	}
+}
+
+type quotaFS struct {
+	FileSystem
+	used, available int64
+}
+
+func (fs *quotaFS) Quota(ctx context.Context, name string) (used, available int64, err error) {
+	return fs.used, fs.available, nil
+}
+
+func TestQuotaProps(t *testing.T) {
+	const body = `<?xml version="1.0" encoding="utf-8" ?>
+		<D:propfind xmlns:D="DAV:">
+			<D:prop>
+				<D:quota-available-bytes/>
+				<D:quota-used-bytes/>
+			</D:prop>
+		</D:propfind>
+	`
+	availableRe := regexp.MustCompile(`<D:quota-available-bytes>([0-9]+)</D:quota-available-bytes>`)
+	usedRe := regexp.MustCompile(`<D:quota-used-bytes>([0-9]+)</D:quota-used-bytes>`)
+	propfind := func(fs FileSystem, body string) string {
+		h := &Handler{
+			FileSystem: fs,
+			LockSystem: NewMemLS(),
+		}
+		req := httptest.NewRequest("PROPFIND", "/", strings.NewReader(body))
+		req.Header.Set("Depth", "0")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != StatusMulti {
+			t.Fatalf("got status code %d, want %d", rec.Code, StatusMulti)
+		}
+		return rec.Body.String()
+	}
+
+	ms := propfind(&quotaFS{NewMemFS(), 1234, 5678}, body)
+	if m := availableRe.FindStringSubmatch(ms); len(m) != 2 || m[1] != "5678" {
+		t.Errorf("got quota-available-bytes %q, want 5678 in:\n%s", m, ms)
+	}
+	if m := usedRe.FindStringSubmatch(ms); len(m) != 2 || m[1] != "1234" {
+		t.Errorf("got quota-used-bytes %q, want 1234 in:\n%s", m, ms)
+	}
+
+	// The quota properties are not returned by an allprop PROPFIND.
+	ms = propfind(&quotaFS{NewMemFS(), 1234, 5678}, "")
+	if strings.Contains(ms, "quota") {
+		t.Errorf("allprop: got quota properties in:\n%s", ms)
+	}
+
+	// Without quota support, the properties are not found.
+	ms = propfind(NewMemFS(), body)
+	if availableRe.MatchString(ms) || usedRe.MatchString(ms) || !strings.Contains(ms, "404 Not Found") {
+		t.Errorf("got quota properties from a FileSystem without quota support:\n%s", ms)
+	}
+}
+
+// deadPropsFS is a DeadPropsFileSystem that keeps dead properties in a
+// store which outlives any one Handler, much like extended attributes.
+type deadPropsFS struct {
+	FileSystem
+	store *deadPropsStore
+}
+
+type deadPropsStore struct {
+	mu    sync.Mutex
+	props map[string]map[xml.Name]Property
+}
+
+func (fs *deadPropsFS) DeadPropsHolder(ctx context.Context, name string) (DeadPropsHolder, error) {
+	if _, err := fs.Stat(ctx, name); err != nil {
+		return nil, err
+	}
+	return &deadPropsStoreHolder{fs.store, slashClean(name)}, nil
+}
+
+type deadPropsStoreHolder struct {
+	store *deadPropsStore
+	name  string
+}
+
+func (h *deadPropsStoreHolder) DeadProps() (map[xml.Name]Property, error) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	m := make(map[xml.Name]Property)
+	for k, v := range h.store.props[h.name] {
+		m[k] = v
+	}
+	return m, nil
+}
+
+func (h *deadPropsStoreHolder) Patch(patches []Proppatch) ([]Propstat, error) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	pstat := Propstat{Status: http.StatusOK}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			pstat.Props = append(pstat.Props, Property{XMLName: p.XMLName})
+			if patch.Remove {
+				delete(h.store.props[h.name], p.XMLName)
+				continue
+			}
+			if h.store.props[h.name] == nil {
+				h.store.props[h.name] = make(map[xml.Name]Property)
+			}
+			h.store.props[h.name][p.XMLName] = p
+		}
+	}
+	return []Propstat{pstat}, nil
+}
+
+func TestDeadPropsFileSystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webdav-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(dir+"/a", []byte("content"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(dir+"/d", 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &deadPropsStore{props: make(map[string]map[xml.Name]Property)}
+	do := func(method, target, body string, hdrs ...string) (int, string) {
+		// Each request is served by a new Handler, with a new LockSystem,
+		// as if the server had been restarted in between.
+		h := &Handler{
+			FileSystem: &deadPropsFS{Dir(dir), store},
+			LockSystem: NewMemLS(),
+		}
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for len(hdrs) >= 2 {
+			req.Header.Add(hdrs[0], hdrs[1])
+			hdrs = hdrs[2:]
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	const proppatch = `<?xml version="1.0" encoding="utf-8" ?>
+		<D:propertyupdate xmlns:D="DAV:" xmlns:Z="http://ns.example.com/z/">
+			<D:set><D:prop><Z:author>Jim Whitehead</Z:author></D:prop></D:set>
+		</D:propertyupdate>
+	`
+	const propfind = `<?xml version="1.0" encoding="utf-8" ?>
+		<D:propfind xmlns:D="DAV:">
+			<D:prop><Z:author xmlns:Z="http://ns.example.com/z/"/></D:prop>
+		</D:propfind>
+	`
+	authorRe := regexp.MustCompile(`<author xmlns="http://ns.example.com/z/">Jim Whitehead</author>`)
+
+	// Dir files do not hold dead properties, and directories cannot be
+	// opened for writing, so these only succeed through the FileSystem.
+	for _, name := range []string{"/a", "/d"} {
+		if code, body := do("PROPPATCH", name, proppatch); code != StatusMulti || !strings.Contains(body, "200 OK") {
+			t.Fatalf("PROPPATCH %s: got %d\n%s\nwant %d with 200 OK", name, code, body, StatusMulti)
+		}
+		code, body := do("PROPFIND", name, propfind, "Depth", "0")
+		if code != StatusMulti || !authorRe.MatchString(body) {
+			t.Errorf("PROPFIND %s: got %d\n%s\nwant %d with the patched property", name, code, body, StatusMulti)
+		}
+	}
+
+	// Dead properties are also copied through the FileSystem.
+	if code, _ := do("COPY", "/a", "", "Destination", "/b"); code != http.StatusCreated {
+		t.Fatalf("COPY: got status code %d, want %d", code, http.StatusCreated)
+	}
+	if code, body := do("PROPFIND", "/b", propfind, "Depth", "0"); code != StatusMulti || !authorRe.MatchString(body) {
+		t.Errorf("PROPFIND /b after COPY: got %d\n%s\nwant %d with the copied property", code, body, StatusMulti)
+	}
+
+	// A missing resource is reported as such by the FileSystem.
+	if code, _ := do("PROPPATCH", "/missing", proppatch); code != http.StatusNotFound {
+		t.Errorf("PROPPATCH /missing: got status code %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+// hashETagFS is an ETagFileSystem whose ETags are content hashes.
+type hashETagFS struct {
+	FileSystem
+}
+
+func (fs *hashETagFS) ETag(ctx context.Context, name string, fi os.FileInfo) (string, error) {
+	if fi.IsDir() {
+		return "", ErrNotImplemented
+	}
+	f, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum(nil)), nil
+}
+
+func TestETagFileSystem(t *testing.T) {
+	const content = "hello, world"
+	wantETag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(content)))
+
+	h := &Handler{
+		FileSystem: &hashETagFS{NewMemFS()},
+		LockSystem: NewMemLS(),
+	}
+	do := func(method, body string, hdrs ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/file", strings.NewReader(body))
+		for len(hdrs) >= 2 {
+			req.Header.Set(hdrs[0], hdrs[1])
+			hdrs = hdrs[2:]
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("PUT", content); rec.Header().Get("ETag") != wantETag {
+		t.Errorf("PUT: got ETag %q, want %q", rec.Header().Get("ETag"), wantETag)
+	}
+	if rec := do("GET", ""); rec.Header().Get("ETag") != wantETag {
+		t.Errorf("GET: got ETag %q, want %q", rec.Header().Get("ETag"), wantETag)
+	}
+
+	rec := do("PROPFIND", `<?xml version="1.0" encoding="utf-8" ?>
+		<D:propfind xmlns:D="DAV:"><D:prop><D:getetag/></D:prop></D:propfind>
+	`, "Depth", "0")
+	if want := "<D:getetag>" + wantETag + "</D:getetag>"; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("PROPFIND: got\n%s\nwant it to contain %s", rec.Body.String(), want)
+	}
+
+	if rec := do("GET", "", "If-None-Match", wantETag); rec.Code != http.StatusNotModified {
+		t.Errorf("GET If-None-Match: got status code %d, want %d", rec.Code, http.StatusNotModified)
+	}
+	if rec := do("GET", "", "If-Match", `"stale"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("GET If-Match: got status code %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestCopyMove(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		method     string
+		src, dst   string
+		headers    []string
+		wantStatus int
+		// wantExist and wantNotExist list names that must and must not
+		// exist after the request.
+		wantExist, wantNotExist []string
+	}{{
+		desc:       "copy collection",
+		method:     "COPY",
+		src:        "/a",
+		dst:        "/c",
+		wantStatus: http.StatusCreated,
+		wantExist:  []string{"/a/x", "/c/x"},
+	}, {
+		desc:         "copy collection depth 0",
+		method:       "COPY",
+		src:          "/a",
+		dst:          "/c",
+		headers:      []string{"Depth", "0"},
+		wantStatus:   http.StatusCreated,
+		wantExist:    []string{"/a/x", "/c"},
+		wantNotExist: []string{"/c/x"},
+	}, {
+		desc:       "copy collection depth infinity",
+		method:     "COPY",
+		src:        "/a",
+		dst:        "/c",
+		headers:    []string{"Depth", "infinity"},
+		wantStatus: http.StatusCreated,
+		wantExist:  []string{"/a/x", "/c/x"},
+	}, {
+		desc:       "copy collection depth 1",
+		method:     "COPY",
+		src:        "/a",
+		dst:        "/c",
+		headers:    []string{"Depth", "1"},
+		wantStatus: http.StatusBadRequest,
+	}, {
+		desc:         "copy overwrite",
+		method:       "COPY",
+		src:          "/a",
+		dst:          "/b",
+		headers:      []string{"Overwrite", "T"},
+		wantStatus:   http.StatusNoContent,
+		wantExist:    []string{"/a/x", "/b/x"},
+		wantNotExist: []string{"/b/y"},
+	}, {
+		desc:         "copy overwrite depth 0",
+		method:       "COPY",
+		src:          "/a",
+		dst:          "/b",
+		headers:      []string{"Overwrite", "T", "Depth", "0"},
+		wantStatus:   http.StatusNoContent,
+		wantExist:    []string{"/a/x", "/b"},
+		wantNotExist: []string{"/b/x", "/b/y"},
+	}, {
+		desc:         "copy implicit overwrite",
+		method:       "COPY",
+		src:          "/a",
+		dst:          "/b",
+		wantStatus:   http.StatusNoContent,
+		wantExist:    []string{"/a/x", "/b/x"},
+		wantNotExist: []string{"/b/y"},
+	}, {
+		desc:         "copy no overwrite",
+		method:       "COPY",
+		src:          "/a",
+		dst:          "/b",
+		headers:      []string{"Overwrite", "F"},
+		wantStatus:   http.StatusPreconditionFailed,
+		wantExist:    []string{"/b/y"},
+		wantNotExist: []string{"/b/x"},
+	}, {
+		desc:       "copy invalid overwrite",
+		method:     "COPY",
+		src:        "/a",
+		dst:        "/c",
+		headers:    []string{"Overwrite", "yes"},
+		wantStatus: http.StatusBadRequest,
+	}, {
+		desc:         "move collection",
+		method:       "MOVE",
+		src:          "/a",
+		dst:          "/c",
+		wantStatus:   http.StatusCreated,
+		wantExist:    []string{"/c/x"},
+		wantNotExist: []string{"/a"},
+	}, {
+		desc:       "move collection depth 0",
+		method:     "MOVE",
+		src:        "/a",
+		dst:        "/c",
+		headers:    []string{"Depth", "0"},
+		wantStatus: http.StatusBadRequest,
+		wantExist:  []string{"/a/x"},
+	}, {
+		desc:         "move overwrite",
+		method:       "MOVE",
+		src:          "/a",
+		dst:          "/b",
+		headers:      []string{"Overwrite", "T"},
+		wantStatus:   http.StatusNoContent,
+		wantExist:    []string{"/b/x"},
+		wantNotExist: []string{"/a", "/b/y"},
+	}, {
+		desc:         "move implicit overwrite",
+		method:       "MOVE",
+		src:          "/a",
+		dst:          "/b",
+		wantStatus:   http.StatusNoContent,
+		wantExist:    []string{"/b/x"},
+		wantNotExist: []string{"/a", "/b/y"},
+	}, {
+		desc:         "move no overwrite",
+		method:       "MOVE",
+		src:          "/a",
+		dst:          "/b",
+		headers:      []string{"Overwrite", "F"},
+		wantStatus:   http.StatusPreconditionFailed,
+		wantExist:    []string{"/a/x", "/b/y"},
+		wantNotExist: []string{"/b/x"},
+	}, {
+		desc:       "move missing intermediate collection",
+		method:     "MOVE",
+		src:        "/a",
+		dst:        "/z/c",
+		wantStatus: http.StatusConflict,
+		wantExist:  []string{"/a/x"},
+	}, {
+		desc:       "move missing source",
+		method:     "MOVE",
+		src:        "/z",
+		dst:        "/c",
+		wantStatus: http.StatusNotFound,
+	}}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		fs := NewMemFS()
+		for _, name := range []string{"/a", "/b"} {
+			if err := fs.Mkdir(ctx, name, 0755); err != nil {
+				t.Fatalf("%s: Mkdir: %v", tc.desc, err)
+			}
+		}
+		for _, name := range []string{"/a/x", "/b/y"} {
+			f, err := fs.OpenFile(ctx, name, os.O_CREATE, 0644)
+			if err != nil {
+				t.Fatalf("%s: OpenFile: %v", tc.desc, err)
+			}
+			f.Close()
+		}
+		h := &Handler{
+			FileSystem: fs,
+			LockSystem: NewMemLS(),
+		}
+
+		req := httptest.NewRequest(tc.method, tc.src, nil)
+		req.Header.Set("Destination", "http://"+req.Host+tc.dst)
+		for hs := tc.headers; len(hs) >= 2; hs = hs[2:] {
+			req.Header.Add(hs[0], hs[1])
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s: got status code %d, want %d", tc.desc, rec.Code, tc.wantStatus)
+			continue
+		}
+		for _, name := range tc.wantExist {
+			if _, err := fs.Stat(ctx, name); err != nil {
+				t.Errorf("%s: Stat(%q): %v", tc.desc, name, err)
+			}
+		}
+		for _, name := range tc.wantNotExist {
+			if _, err := fs.Stat(ctx, name); !os.IsNotExist(err) {
+				t.Errorf("%s: Stat(%q): got %v, want not exist", tc.desc, name, err)
+			}
+		}
+	}
+}
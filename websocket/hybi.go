@@ -471,6 +471,9 @@ func hybiClientHandshake(config *Config, br *bufio.Reader, bw *bufio.Writer) (er
 			return ErrBadWebSocketProtocol
 		}
 		config.Protocol = []string{offeredProtocol}
+	} else {
+		// The server accepted none of the offered subprotocols.
+		config.Protocol = nil
 	}
 
 	return nil
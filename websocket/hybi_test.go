@@ -9,8 +9,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -103,6 +105,47 @@ Sec-WebSocket-Protocol: chat
 	}
 }
 
+func TestHybiClientHandshakeSubProtocol(t *testing.T) {
+	for _, tt := range []struct {
+		offered []string
+		echoed  string
+		want    []string
+		err     error
+	}{
+		{[]string{"chat", "superchat"}, "superchat", []string{"superchat"}, nil},
+		{[]string{"chat", "superchat"}, "", nil, nil},
+		{[]string{"chat", "superchat"}, "otherchat", nil, ErrBadWebSocketProtocol},
+		{[]string{"chat", "superchat"}, "chat, superchat", nil, ErrBadWebSocketProtocol},
+		{nil, "chat", nil, ErrBadWebSocketProtocol},
+	} {
+		resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n"
+		if tt.echoed != "" {
+			resp += "Sec-WebSocket-Protocol: " + tt.echoed + "\r\n"
+		}
+		resp += "\r\n"
+		config := &Config{
+			Location: &url.URL{Scheme: "ws", Host: "server.example.com", Path: "/chat"},
+			Origin:   &url.URL{Scheme: "http", Host: "example.com"},
+			Protocol: tt.offered,
+			Version:  ProtocolVersionHybi13,
+			handshakeData: map[string]string{
+				"key": "dGhlIHNhbXBsZSBub25jZQ==",
+			},
+		}
+		err := hybiClientHandshake(config, bufio.NewReader(strings.NewReader(resp)), bufio.NewWriter(ioutil.Discard))
+		if err != tt.err {
+			t.Errorf("offered %q, echoed %q: got error %v, want %v", tt.offered, tt.echoed, err, tt.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(config.Protocol, tt.want) {
+			t.Errorf("offered %q, echoed %q: got protocol %q, want %q", tt.offered, tt.echoed, config.Protocol, tt.want)
+		}
+	}
+}
+
 func TestHybiClientHandshakeWithHeader(t *testing.T) {
 	b := bytes.NewBuffer([]byte{})
 	bw := bufio.NewWriter(b)
@@ -558,6 +601,266 @@ func TestHybiClientReadWithMasking(t *testing.T) {
 	}
 }
 
+func TestHybiReadMessage(t *testing.T) {
+	wireData := []byte{0x01, 0x03, 'h', 'e', 'l', // text fragment: hel
+		0x89, 0x00, // ping
+		0x80, 0x02, 'l', 'o', // final continuation: lo
+		0x82, 0x03, 0x00, 0xff, 0x01, // binary
+		0x81, 0x00, // empty text
+	}
+	br := bufio.NewReader(bytes.NewBuffer(wireData))
+	bw := bufio.NewWriter(bytes.NewBuffer([]byte{}))
+	conn := newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, nil)
+
+	for _, want := range []struct {
+		opcode byte
+		data   []byte
+	}{
+		{TextFrame, []byte("hello")},
+		{BinaryFrame, []byte{0x00, 0xff, 0x01}},
+		{TextFrame, []byte{}},
+	} {
+		opcode, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if opcode != want.opcode || !bytes.Equal(data, want.data) {
+			t.Errorf("ReadMessage = %d, %q; want %d, %q", opcode, data, want.opcode, want.data)
+		}
+	}
+	if _, _, err := conn.ReadMessage(); err != io.EOF {
+		t.Errorf("ReadMessage at end of input: got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestHybiReadMessageTooLarge(t *testing.T) {
+	wireData := []byte{0x02, 0x03, 'a', 'b', 'c', // binary fragment within the limit
+		0x00, 0x03, 'd', 'e', 'f', // continuation exceeding it
+		0x89, 0x00, // ping
+		0x80, 0x02, 'g', 'h', // final continuation
+		0x81, 0x02, 'o', 'k', // text
+	}
+	br := bufio.NewReader(bytes.NewBuffer(wireData))
+	var out bytes.Buffer
+	bw := bufio.NewWriter(&out)
+	conn := newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, nil)
+	conn.MaxPayloadBytes = 4
+
+	if _, _, err := conn.ReadMessage(); err != ErrFrameTooLarge {
+		t.Fatalf("ReadMessage: got %v, want %v", err, ErrFrameTooLarge)
+	}
+	// The rest of the oversized message is discarded, rather than being
+	// taken for stray continuation frames.
+	opcode, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if opcode != TextFrame || string(data) != "ok" {
+		t.Errorf("ReadMessage = %d, %q; want %d, %q", opcode, data, TextFrame, "ok")
+	}
+	// The ping within the discarded message was still answered.
+	if frame, err := (hybiFrameReaderFactory{bufio.NewReader(&out)}).NewFrameReader(); err != nil || frame.PayloadType() != PongFrame {
+		t.Errorf("expected a pong to be written, got %v", err)
+	}
+}
+
+func TestHybiReadMessageBadFragments(t *testing.T) {
+	for _, wireData := range [][]byte{
+		{0x80, 0x02, 'h', 'i'},                       // continuation without a first frame
+		{0x01, 0x02, 'h', 'i', 0x81, 0x02, 'h', 'i'}, // new message before the final fragment
+	} {
+		br := bufio.NewReader(bytes.NewBuffer(wireData))
+		bw := bufio.NewWriter(bytes.NewBuffer([]byte{}))
+		conn := newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, nil)
+		if _, _, err := conn.ReadMessage(); err != ErrBadFrame {
+			t.Errorf("ReadMessage(%#v): got %v, want %v", wireData, err, ErrBadFrame)
+		}
+	}
+}
+
+func TestHybiNextReader(t *testing.T) {
+	wireData := []byte{0x02, 0x03, 'h', 'e', 'l', // binary fragment: hel
+		0x89, 0x00, // ping
+		0x00, 0x00, // empty continuation
+		0x8a, 0x00, // pong
+		0x80, 0x02, 'l', 'o', // final continuation: lo
+		0x01, 0x02, 'a', 'b', // text fragment, partly read below
+		0x80, 0x01, 'c',
+		0x81, 0x03, 'x', 'y', 'z', // text
+	}
+	br := bufio.NewReader(bytes.NewBuffer(wireData))
+	var out bytes.Buffer
+	bw := bufio.NewWriter(&out)
+	conn := newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, nil)
+
+	opcode, r, err := conn.NextReader()
+	if err != nil {
+		t.Fatalf("NextReader: %v", err)
+	}
+	if opcode != BinaryFrame {
+		t.Errorf("opcode = %d, want %d", opcode, BinaryFrame)
+	}
+	// Read a byte at a time to cross the fragment boundaries.
+	var got []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		got = append(got, b[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want %q", got, "hello")
+	}
+	if n, err := r.Read(b); n != 0 || err != io.EOF {
+		t.Errorf("Read after EOF = %d, %v; want 0, %v", n, err, io.EOF)
+	}
+	// The ping was answered.
+	if frame, err := (hybiFrameReaderFactory{bufio.NewReader(&out)}).NewFrameReader(); err != nil || frame.PayloadType() != PongFrame {
+		t.Errorf("expected a pong to be written, got %v", err)
+	}
+
+	// The unread remainder of a message is skipped.
+	if _, r, err = conn.NextReader(); err != nil {
+		t.Fatalf("NextReader: %v", err)
+	}
+	if n, err := r.Read(b); n != 1 || err != nil || b[0] != 'a' {
+		t.Fatalf("Read = %d, %v, %q; want 1, nil, %q", n, err, b[:n], "a")
+	}
+	opcode, r, err = conn.NextReader()
+	if err != nil {
+		t.Fatalf("NextReader: %v", err)
+	}
+	if got, err := ioutil.ReadAll(r); opcode != TextFrame || err != nil || string(got) != "xyz" {
+		t.Errorf("got %d, %q, %v; want %d, %q, nil", opcode, got, err, TextFrame, "xyz")
+	}
+	if _, _, err := conn.NextReader(); err != io.EOF {
+		t.Errorf("NextReader at end of input: got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestHybiNextReaderBadFragments(t *testing.T) {
+	br := bufio.NewReader(bytes.NewBuffer([]byte{0x80, 0x02, 'h', 'i'}))
+	bw := bufio.NewWriter(bytes.NewBuffer([]byte{}))
+	conn := newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, nil)
+	if _, _, err := conn.NextReader(); err != ErrBadFrame {
+		t.Errorf("NextReader: got %v, want %v", err, ErrBadFrame)
+	}
+
+	br = bufio.NewReader(bytes.NewBuffer([]byte{0x01, 0x02, 'h', 'i', 0x81, 0x02, 'h', 'i'}))
+	conn = newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, nil)
+	_, r, err := conn.NextReader()
+	if err != nil {
+		t.Fatalf("NextReader: %v", err)
+	}
+	if _, err := ioutil.ReadAll(r); err != ErrBadFrame {
+		t.Errorf("ReadAll: got %v, want %v", err, ErrBadFrame)
+	}
+}
+
+func TestHybiNextReaderThenReadMessage(t *testing.T) {
+	wireData := []byte{0x01, 0x02, 'a', 'b', // text fragment, partly read below
+		0x80, 0x01, 'c',
+		0x82, 0x03, 'x', 'y', 'z', // binary
+		0x01, 0x02, 'd', 'e', // text fragment, partly read below
+		0x80, 0x01, 'f',
+		0x81, 0x01, 'g', // text
+	}
+	br := bufio.NewReader(bytes.NewBuffer(wireData))
+	bw := bufio.NewWriter(bytes.NewBuffer([]byte{}))
+	conn := newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, nil)
+
+	b := make([]byte, 1)
+	_, r, err := conn.NextReader()
+	if err != nil {
+		t.Fatalf("NextReader: %v", err)
+	}
+	if n, err := r.Read(b); n != 1 || err != nil || b[0] != 'a' {
+		t.Fatalf("Read = %d, %v, %q; want 1, nil, %q", n, err, b[:n], "a")
+	}
+	// ReadMessage skips the rest of the message, including its final
+	// fragment.
+	opcode, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if opcode != BinaryFrame || string(data) != "xyz" {
+		t.Errorf("ReadMessage = %d, %q; want %d, %q", opcode, data, BinaryFrame, "xyz")
+	}
+
+	// So does Read.
+	if _, r, err = conn.NextReader(); err != nil {
+		t.Fatalf("NextReader: %v", err)
+	}
+	if n, err := r.Read(b); n != 1 || err != nil || b[0] != 'd' {
+		t.Fatalf("Read = %d, %v, %q; want 1, nil, %q", n, err, b[:n], "d")
+	}
+	msg := make([]byte, 512)
+	n, err := conn.Read(msg)
+	if err != nil || string(msg[:n]) != "g" {
+		t.Errorf("Read = %q, %v; want %q, nil", msg[:n], err, "g")
+	}
+	// The skipped message reader is at its end.
+	if n, err := r.Read(b); n != 0 || err != io.EOF {
+		t.Errorf("Read of skipped message = %d, %v; want 0, %v", n, err, io.EOF)
+	}
+}
+
+func TestHybiReadMessageInvalidUTF8(t *testing.T) {
+	wireData := []byte{0x01, 0x01, 0xe2, // first byte of a three-byte sequence
+		0x80, 0x02, 0x28, 0xa1,
+	}
+	br := bufio.NewReader(bytes.NewBuffer(wireData))
+	var out bytes.Buffer
+	bw := bufio.NewWriter(&out)
+	conn := newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, nil)
+
+	if _, _, err := conn.ReadMessage(); err != ErrBadUTF8 {
+		t.Fatalf("ReadMessage: got %v, want %v", err, ErrBadUTF8)
+	}
+	frame, err := hybiFrameReaderFactory{bufio.NewReader(&out)}.NewFrameReader()
+	if err != nil {
+		t.Fatalf("reading close frame: %v", err)
+	}
+	if frame.PayloadType() != CloseFrame {
+		t.Fatalf("got frame type %d, want %d", frame.PayloadType(), CloseFrame)
+	}
+	status, err := ioutil.ReadAll(frame)
+	if err != nil {
+		t.Fatalf("reading close status: %v", err)
+	}
+	if want := []byte{0x03, 0xef}; !bytes.Equal(status, want) { // 1007
+		t.Errorf("got close status %#v, want %#v", status, want)
+	}
+}
+
+func TestHybiWriteMessage(t *testing.T) {
+	var out bytes.Buffer
+	br := bufio.NewReader(bytes.NewBuffer([]byte{}))
+	bw := bufio.NewWriter(&out)
+	conn := newHybiConn(newConfig(t, "/"), bufio.NewReadWriter(br, bw), nil, new(http.Request))
+
+	if err := conn.WriteText([]byte("hello")); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	if err := conn.WriteBinary([]byte{0x00, 0xff}); err != nil {
+		t.Fatalf("WriteBinary: %v", err)
+	}
+	if err := conn.WriteText([]byte{0xff, 0xfe}); err != ErrBadUTF8 {
+		t.Errorf("WriteText with invalid UTF-8: got %v, want %v", err, ErrBadUTF8)
+	}
+	expected := []byte{0x81, 0x05, 'h', 'e', 'l', 'l', 'o',
+		0x82, 0x02, 0x00, 0xff,
+	}
+	if !bytes.Equal(expected, out.Bytes()) {
+		t.Errorf("expected %#v, got %#v", expected, out.Bytes())
+	}
+}
+
 // Test the hybiServerHandshaker supports firefox implementation and
 // checks Connection request header include (but it's not necessary
 // equal to) "upgrade"
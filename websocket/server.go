@@ -6,12 +6,16 @@ package websocket
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
-func newServerConn(rwc io.ReadWriteCloser, buf *bufio.ReadWriter, req *http.Request, config *Config, handshake func(*Config, *http.Request) error) (conn *Conn, err error) {
+func newServerConn(rwc io.ReadWriteCloser, buf *bufio.ReadWriter, req *http.Request, config *Config, handshake func(*Config, *http.Request) error, selectProtocol func([]string) string) (conn *Conn, err error) {
 	var hs serverHandshaker = &hybiServerHandshaker{Config: config}
 	code, err := hs.ReadHandshake(buf.Reader, req)
 	if err == ErrBadWebSocketVersion {
@@ -39,6 +43,21 @@ func newServerConn(rwc io.ReadWriteCloser, buf *bufio.ReadWriter, req *http.Requ
 			return
 		}
 	}
+	if selectProtocol != nil && len(config.Protocol) > 0 {
+		offered := config.Protocol
+		config.Protocol = nil
+		if protocol := selectProtocol(offered); protocol != "" {
+			if !containsProtocol(offered, protocol) {
+				err = ErrBadWebSocketProtocol
+				code = http.StatusInternalServerError
+				fmt.Fprintf(buf, "HTTP/1.1 %03d %s\r\n", code, http.StatusText(code))
+				buf.WriteString("\r\n")
+				buf.Flush()
+				return
+			}
+			config.Protocol = []string{protocol}
+		}
+	}
 	err = hs.AcceptHandshake(buf.Writer)
 	if err != nil {
 		code = http.StatusBadRequest
@@ -61,6 +80,14 @@ type Server struct {
 	// Another example, you can select config.Protocol.
 	Handshake func(*Config, *http.Request) error
 
+	// SelectProtocol optionally selects the subprotocol for a new
+	// connection from the ones offered by the client, in the client's
+	// order of preference. It is called after Handshake, and only if
+	// the client offered at least one subprotocol. Returning "" accepts
+	// the connection without a subprotocol; returning a subprotocol
+	// that was not offered fails the handshake.
+	SelectProtocol func(offered []string) string
+
 	// Handler handles a WebSocket connection.
 	Handler
 }
@@ -79,7 +106,7 @@ func (s Server) serveWebSocket(w http.ResponseWriter, req *http.Request) {
 	// the client did not send a handshake that matches with protocol
 	// specification.
 	defer rwc.Close()
-	conn, err := newServerConn(rwc, buf, req, &s.Config, s.Handshake)
+	conn, err := newServerConn(rwc, buf, req, &s.Config, s.Handshake, s.SelectProtocol)
 	if err != nil {
 		return
 	}
@@ -111,3 +138,107 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	s := Server{Handler: h, Handshake: checkOrigin}
 	s.serveWebSocket(w, req)
 }
+
+// Upgrader upgrades HTTP requests to WebSocket connections, for use from
+// within an http.Handler. Unlike Server, it returns the connection to the
+// caller instead of passing it to a Handler.
+type Upgrader struct {
+	// CheckOrigin reports whether the Origin header of the request is
+	// acceptable. If it returns false, the handshake fails with
+	// 403 Forbidden. If CheckOrigin is nil, requests without an Origin
+	// header are accepted, as are those whose Origin host matches the
+	// request's Host header.
+	CheckOrigin func(req *http.Request) bool
+
+	// ReadBufferSize and WriteBufferSize specify the sizes in bytes of
+	// the connection's read and write buffers. If a size is zero, the
+	// buffer allocated by the HTTP server is used.
+	ReadBufferSize, WriteBufferSize int
+
+	// Subprotocols lists the server's supported subprotocols in order of
+	// preference. The first one that was also offered by the client is
+	// selected; if there is none, the connection has no subprotocol.
+	Subprotocols []string
+}
+
+// Upgrade performs the WebSocket server handshake for req, hijacking the
+// connection underlying w. If the handshake fails, Upgrade writes an HTTP
+// error response and closes the connection. On success, the caller is
+// responsible for closing the returned Conn.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, req *http.Request) (*Conn, error) {
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		err := errors.New("websocket: response does not implement http.Hijacker")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, err
+	}
+	rwc, buf, err := h.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	br, bw := buf.Reader, buf.Writer
+	if u.ReadBufferSize > 0 {
+		var r io.Reader = rwc
+		if n := br.Buffered(); n > 0 {
+			// Keep any bytes the HTTP server has already read.
+			p, _ := br.Peek(n)
+			r = io.MultiReader(bytes.NewReader(p), rwc)
+		}
+		br = bufio.NewReaderSize(r, u.ReadBufferSize)
+	}
+	if u.WriteBufferSize > 0 {
+		bw = bufio.NewWriterSize(rwc, u.WriteBufferSize)
+	}
+	buf = bufio.NewReadWriter(br, bw)
+	conn, err := newServerConn(rwc, buf, req, new(Config), u.handshake, u.selectProtocol)
+	if err != nil {
+		rwc.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (u *Upgrader) handshake(config *Config, req *http.Request) error {
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(req) {
+		return ErrBadWebSocketOrigin
+	}
+	// Opaque origins such as "null" are left nil.
+	config.Origin, _ = Origin(config, req)
+	return nil
+}
+
+func (u *Upgrader) selectProtocol(offered []string) string {
+	for _, p := range u.Subprotocols {
+		if containsProtocol(offered, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// sameOrigin reports whether req has no Origin header, or one whose host
+// matches the request's Host header.
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host)
+}
+
+func containsProtocol(protocols []string, protocol string) bool {
+	for _, p := range protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
@@ -24,6 +24,7 @@ import (
 	"net/url"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -64,6 +65,7 @@ var (
 	ErrNotWebSocket         = &ProtocolError{"not websocket protocol"}
 	ErrBadRequestMethod     = &ProtocolError{"bad method"}
 	ErrNotSupported         = &ProtocolError{"not supported"}
+	ErrBadUTF8              = &ProtocolError{"invalid UTF-8 in text message"}
 )
 
 // ErrFrameTooLarge is returned by Codec's Receive method if payload size
@@ -86,7 +88,9 @@ type Config struct {
 	// A Websocket client origin.
 	Origin *url.URL
 
-	// WebSocket subprotocols.
+	// WebSocket subprotocols. A client offers them in order of
+	// preference; once the handshake completes, Protocol holds at most
+	// the one subprotocol that was negotiated.
 	Protocol []string
 
 	// WebSocket protocol version.
@@ -171,6 +175,7 @@ type Conn struct {
 	rio sync.Mutex
 	frameReaderFactory
 	frameReader
+	messageReader *messageReader
 
 	wio sync.Mutex
 	frameWriterFactory
@@ -180,7 +185,8 @@ type Conn struct {
 	defaultCloseStatus int
 
 	// MaxPayloadBytes limits the size of frame payload received over Conn
-	// by Codec's Receive method. If zero, DefaultMaxPayloadBytes is used.
+	// by Codec's Receive method, and the size of message payload received
+	// by ReadMessage. If zero, DefaultMaxPayloadBytes is used.
 	MaxPayloadBytes int
 }
 
@@ -192,6 +198,9 @@ type Conn struct {
 func (ws *Conn) Read(msg []byte) (n int, err error) {
 	ws.rio.Lock()
 	defer ws.rio.Unlock()
+	if err := ws.discardMessage(); err != nil {
+		return 0, err
+	}
 again:
 	if ws.frameReader == nil {
 		frame, err := ws.frameReaderFactory.NewFrameReader()
@@ -231,6 +240,205 @@ func (ws *Conn) Write(msg []byte) (n int, err error) {
 	return n, err
 }
 
+// WriteText writes data to ws as a single text frame.
+// It returns ErrBadUTF8 without writing anything if data is not valid
+// UTF-8.
+func (ws *Conn) WriteText(data []byte) error {
+	if !utf8.Valid(data) {
+		return ErrBadUTF8
+	}
+	return ws.writeMessage(TextFrame, data)
+}
+
+// WriteBinary writes data to ws as a single binary frame.
+func (ws *Conn) WriteBinary(data []byte) error {
+	return ws.writeMessage(BinaryFrame, data)
+}
+
+func (ws *Conn) writeMessage(payloadType byte, data []byte) error {
+	ws.wio.Lock()
+	defer ws.wio.Unlock()
+	w, err := ws.frameWriterFactory.NewFrameWriter(payloadType)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	w.Close()
+	return err
+}
+
+// ReadMessage reads the next text or binary message from ws, joining
+// the payloads of its fragments, and returns its opcode (TextFrame or
+// BinaryFrame) and payload. Control frames are handled as by Read.
+//
+// If the message payload exceeds ws.MaxPayloadBytes, ReadMessage returns
+// ErrFrameTooLarge, and the rest of the message is discarded by the next
+// read. If a text message is not valid UTF-8, ReadMessage
+// closes the connection with status 1007 and returns ErrBadUTF8.
+func (ws *Conn) ReadMessage() (opcode byte, data []byte, err error) {
+	ws.rio.Lock()
+	defer ws.rio.Unlock()
+	if err := ws.discardMessage(); err != nil {
+		return 0, nil, err
+	}
+	if ws.frameReader != nil {
+		_, err = io.Copy(ioutil.Discard, ws.frameReader)
+		if err != nil {
+			return 0, nil, err
+		}
+		ws.frameReader = nil
+	}
+	maxPayloadBytes := ws.MaxPayloadBytes
+	if maxPayloadBytes == 0 {
+		maxPayloadBytes = DefaultMaxPayloadBytes
+	}
+	data = []byte{}
+	for {
+		// A message starts with a text or binary frame, and any
+		// further fragments are continuation frames.
+		frame, payloadType, fin, err := ws.nextDataFrame(opcode != 0)
+		if err != nil {
+			return 0, nil, err
+		}
+		if opcode == 0 {
+			opcode = payloadType
+		}
+		if hf, ok := frame.(*hybiFrameReader); ok && int64(len(data))+hf.header.Length > int64(maxPayloadBytes) {
+			// Leave the rest of the message, up to its final
+			// fragment, to be discarded by the next read.
+			ws.messageReader = &messageReader{ws: ws, frame: frame, fin: fin}
+			return 0, nil, ErrFrameTooLarge
+		}
+		b, err := ioutil.ReadAll(frame)
+		if err != nil {
+			return 0, nil, err
+		}
+		data = append(data, b...)
+		if fin {
+			break
+		}
+	}
+	if opcode == TextFrame && !utf8.Valid(data) {
+		ws.frameHandler.WriteClose(closeStatusBadMessageData)
+		return 0, nil, ErrBadUTF8
+	}
+	return opcode, data, nil
+}
+
+// NextReader returns the opcode of the next text or binary message
+// received, TextFrame or BinaryFrame, and a reader for its payload.
+// The reader spans all fragments of the message and returns io.EOF
+// after the final one, so a message of any size can be read without
+// buffering it. Control frames received between fragments are handled
+// as they arrive. Unlike ReadMessage, NextReader neither enforces
+// MaxPayloadBytes nor validates the UTF-8 encoding of text messages.
+//
+// Any unread remainder of the previous message returned by NextReader
+// is discarded, by NextReader as well as by Read, ReadMessage and
+// Codec.Receive. The reader must not be used concurrently with them.
+func (ws *Conn) NextReader() (opcode byte, r io.Reader, err error) {
+	ws.rio.Lock()
+	defer ws.rio.Unlock()
+	if err := ws.discardMessage(); err != nil {
+		return 0, nil, err
+	}
+	if ws.frameReader != nil {
+		_, err = io.Copy(ioutil.Discard, ws.frameReader)
+		if err != nil {
+			return 0, nil, err
+		}
+		ws.frameReader = nil
+	}
+	frame, opcode, fin, err := ws.nextDataFrame(false)
+	if err != nil {
+		return 0, nil, err
+	}
+	ws.messageReader = &messageReader{ws: ws, frame: frame, fin: fin}
+	return opcode, ws.messageReader, nil
+}
+
+// discardMessage reads and discards the unread remainder of the message
+// last returned by NextReader, if any, up to its final fragment. The
+// caller must hold ws.rio.
+func (ws *Conn) discardMessage() error {
+	mr := ws.messageReader
+	if mr == nil {
+		return nil
+	}
+	ws.messageReader = nil
+	b := make([]byte, 512)
+	for {
+		if _, err := mr.read(b); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// nextDataFrame reads frames until it finds a data frame, handling any
+// control frames on the way. It fails with ErrBadFrame, and closes the
+// connection, if the data frame is a continuation frame and continuation
+// is false, or vice versa. The caller must hold ws.rio.
+func (ws *Conn) nextDataFrame(continuation bool) (frame frameReader, payloadType byte, fin bool, err error) {
+	for {
+		frame, err = ws.frameReaderFactory.NewFrameReader()
+		if err != nil {
+			return nil, 0, false, err
+		}
+		payloadType = frame.PayloadType()
+		frame, err = ws.frameHandler.HandleFrame(frame)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		if frame != nil {
+			break
+		}
+	}
+	if (payloadType == ContinuationFrame) != continuation {
+		ws.frameHandler.WriteClose(closeStatusProtocolError)
+		return nil, 0, false, ErrBadFrame
+	}
+	hf, ok := frame.(*hybiFrameReader)
+	return frame, payloadType, !ok || hf.header.Fin, nil
+}
+
+// messageReader reads the payload of a fragmented message.
+type messageReader struct {
+	ws    *Conn
+	frame frameReader // current fragment; nil once it has been read
+	fin   bool        // whether frame is the final fragment
+	err   error       // sticky error; io.EOF at the end of the message
+}
+
+func (mr *messageReader) Read(p []byte) (n int, err error) {
+	mr.ws.rio.Lock()
+	defer mr.ws.rio.Unlock()
+	return mr.read(p)
+}
+
+func (mr *messageReader) read(p []byte) (n int, err error) {
+	for mr.err == nil {
+		if mr.frame == nil {
+			mr.frame, _, mr.fin, mr.err = mr.ws.nextDataFrame(true)
+			continue
+		}
+		n, err = mr.frame.Read(p)
+		if err == io.EOF {
+			mr.frame = nil
+			if mr.fin {
+				mr.err = io.EOF
+			}
+		} else if err != nil {
+			mr.err = err
+		}
+		if n > 0 || len(p) == 0 {
+			return n, nil
+		}
+	}
+	return 0, mr.err
+}
+
 // Close implements the io.Closer interface.
 func (ws *Conn) Close() error {
 	err := ws.frameHandler.WriteClose(ws.defaultCloseStatus)
@@ -294,6 +502,15 @@ func (ws *Conn) SetWriteDeadline(t time.Time) error {
 // Config returns the WebSocket config.
 func (ws *Conn) Config() *Config { return ws.config }
 
+// Subprotocol returns the subprotocol negotiated in the opening
+// handshake, or "" if no subprotocol was agreed on.
+func (ws *Conn) Subprotocol() string {
+	if len(ws.config.Protocol) != 1 {
+		return ""
+	}
+	return ws.config.Protocol[0]
+}
+
 // Request returns the http request upgraded to the WebSocket.
 // It is nil for client side.
 func (ws *Conn) Request() *http.Request { return ws.request }
@@ -330,6 +547,9 @@ func (cd Codec) Send(ws *Conn, v interface{}) (err error) {
 func (cd Codec) Receive(ws *Conn, v interface{}) (err error) {
 	ws.rio.Lock()
 	defer ws.rio.Unlock()
+	if err := ws.discardMessage(); err != nil {
+		return err
+	}
 	if ws.frameReader != nil {
 		_, err = io.Copy(ioutil.Discard, ws.frameReader)
 		if err != nil {
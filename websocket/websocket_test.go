@@ -307,6 +307,210 @@ func TestWithBadProtocol(t *testing.T) {
 	}
 }
 
+func selectProtoServer(selectProtocol func([]string) string) *httptest.Server {
+	return httptest.NewServer(Server{
+		SelectProtocol: selectProtocol,
+		Handler: func(ws *Conn) {
+			io.WriteString(ws, "["+ws.Subprotocol()+"]")
+			ws.Close()
+		},
+	})
+}
+
+func testSelectProtocol(t *testing.T, s *httptest.Server, subproto []string) (client, server string, err error) {
+	config, err := NewConfig("ws"+strings.TrimPrefix(s.URL, "http"), "http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Protocol = subproto
+	ws, err := DialConfig(config)
+	if err != nil {
+		return "", "", err
+	}
+	defer ws.Close()
+	msg := make([]byte, 16)
+	n, err := ws.Read(msg)
+	if err != nil {
+		return "", "", err
+	}
+	return ws.Subprotocol(), string(msg[:n]), nil
+}
+
+func TestSelectProtocol(t *testing.T) {
+	s := selectProtoServer(func(offered []string) string {
+		// Prefer "chat" over "superchat", regardless of the client's order.
+		for _, p := range []string{"chat", "superchat"} {
+			for _, o := range offered {
+				if o == p {
+					return p
+				}
+			}
+		}
+		return ""
+	})
+	defer s.Close()
+
+	for _, tt := range []struct {
+		offered []string
+		want    string
+	}{
+		{[]string{"superchat", "chat"}, "chat"},
+		{[]string{"test", "superchat"}, "superchat"},
+		{[]string{"test"}, ""},
+		{nil, ""},
+	} {
+		client, server, err := testSelectProtocol(t, s, tt.offered)
+		if err != nil {
+			t.Errorf("offered %q: unexpected error: %v", tt.offered, err)
+			continue
+		}
+		if client != tt.want {
+			t.Errorf("offered %q: client Subprotocol() = %q, want %q", tt.offered, client, tt.want)
+		}
+		if server != "["+tt.want+"]" {
+			t.Errorf("offered %q: server Subprotocol() = %s, want [%s]", tt.offered, server, tt.want)
+		}
+	}
+}
+
+func TestSelectProtocolNotOffered(t *testing.T) {
+	s := selectProtoServer(func(offered []string) string { return "superchat" })
+	defer s.Close()
+
+	_, _, err := testSelectProtocol(t, s, []string{"chat"})
+	if de, ok := err.(*DialError); !ok || de.Err != ErrBadStatus {
+		t.Errorf("got %v, want DialError with %v", err, ErrBadStatus)
+	}
+}
+
+func upgraderServer(u *Upgrader, errc chan<- error) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ws, err := u.Upgrade(w, req)
+		errc <- err
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		io.WriteString(ws, "["+ws.Subprotocol()+"]")
+		var msg string
+		if Message.Receive(ws, &msg) == nil {
+			Message.Send(ws, msg)
+		}
+	}))
+}
+
+func upgradeRequest(t *testing.T, s *httptest.Server, header http.Header) *http.Response {
+	req, err := http.NewRequest("GET", s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	for k, vv := range header {
+		req.Header[k] = vv
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestUpgrader(t *testing.T) {
+	errc := make(chan error, 1)
+	s := upgraderServer(&Upgrader{
+		ReadBufferSize:  16,
+		WriteBufferSize: 16,
+		Subprotocols:    []string{"chat", "superchat"},
+	}, errc)
+	defer s.Close()
+
+	config, err := NewConfig("ws"+strings.TrimPrefix(s.URL, "http"), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Protocol = []string{"superchat", "chat"}
+	ws, err := DialConfig(config)
+	if err != nil {
+		t.Fatalf("DialConfig: %v", err)
+	}
+	defer ws.Close()
+	if err := <-errc; err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+	if got := ws.Subprotocol(); got != "chat" {
+		t.Errorf("Subprotocol() = %q, want %q", got, "chat")
+	}
+
+	// Messages larger than the buffers still make it through.
+	msg := strings.Repeat("hello, world\n", 10)
+	if err := Message.Send(ws, msg); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[chat]", msg} {
+		var got string
+		if err := Message.Receive(ws, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestUpgraderOrigin(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		check    func(*http.Request) bool
+		origin   string
+		wantCode int
+	}{
+		{"no origin", nil, "", http.StatusSwitchingProtocols},
+		{"same origin", nil, "http://HOST", http.StatusSwitchingProtocols},
+		{"cross origin", nil, "http://example.com", http.StatusForbidden},
+		{"bad origin", nil, "::", http.StatusForbidden},
+		{"allowed by CheckOrigin", func(*http.Request) bool { return true }, "null", http.StatusSwitchingProtocols},
+		{"rejected by CheckOrigin", func(*http.Request) bool { return false }, "", http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			errc := make(chan error, 1)
+			s := upgraderServer(&Upgrader{CheckOrigin: tt.check}, errc)
+			defer s.Close()
+
+			h := http.Header{"Sec-Websocket-Key": {"dGhlIHNhbXBsZSBub25jZQ=="}}
+			if tt.origin != "" {
+				h.Set("Origin", strings.Replace(tt.origin, "HOST", strings.TrimPrefix(s.URL, "http://"), 1))
+			}
+			res := upgradeRequest(t, s, h)
+			if res.StatusCode != tt.wantCode {
+				t.Errorf("status = %v, want %v", res.StatusCode, tt.wantCode)
+			}
+			err := <-errc
+			if tt.wantCode == http.StatusForbidden && err != ErrBadWebSocketOrigin {
+				t.Errorf("Upgrade error = %v, want %v", err, ErrBadWebSocketOrigin)
+			} else if tt.wantCode != http.StatusForbidden && err != nil {
+				t.Errorf("Upgrade error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestUpgraderMissingKey(t *testing.T) {
+	errc := make(chan error, 1)
+	s := upgraderServer(&Upgrader{}, errc)
+	defer s.Close()
+
+	res := upgradeRequest(t, s, nil)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %v, want %v", res.StatusCode, http.StatusBadRequest)
+	}
+	if err := <-errc; err != ErrChallengeResponse {
+		t.Errorf("Upgrade error = %v, want %v", err, ErrChallengeResponse)
+	}
+}
+
 func TestHTTP(t *testing.T) {
 	once.Do(startServer)
 